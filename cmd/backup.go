@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tkowalski/socgo/internal/backup"
+)
+
+const backupUsage = `Usage: socgo backup [-tenant key] [-list] [-remote]
+
+Backs up the registry and every tenant database, or only -tenant. With -list
+the existing backups of -tenant are listed instead.
+
+Flags:
+`
+
+const restoreUsage = `Usage: socgo restore -tenant key <backup>
+
+Replaces the database of -tenant with <backup>, a name listed by
+"socgo backup -list" (downloaded from the bucket when missing locally) or a
+path to a backup file. Stop the server first.
+
+Flags:
+`
+
+// runBackup implements the backup command and returns the exit code
+func runBackup(service *backup.Service, args []string) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	tenantKey := flags.String("tenant", "", "only this user or workspace database, or _registry")
+	list := flags.Bool("list", false, "list the backups of -tenant")
+	remote := flags.Bool("remote", false, "with -list, list the backups in the bucket")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), backupUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 || (*list && *tenantKey == "") {
+		flags.Usage()
+		return 2
+	}
+
+	ctx := context.Background()
+	if *list {
+		var backups []backup.Backup
+		var err error
+		if *remote {
+			backups, err = service.ListRemote(ctx, *tenantKey)
+		} else {
+			backups, err = service.List(*tenantKey)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list backups: %v\n", err)
+			return 1
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %10d  %s\n", b.CreatedAt.Format("2006-01-02 15:04:05"), b.Size, b.Name)
+		}
+		return 0
+	}
+
+	if *tenantKey == "" {
+		if err := service.BackupAll(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Backup failed: %v\n", err)
+			return 1
+		}
+		fmt.Println("All databases backed up")
+		return 0
+	}
+
+	b, err := service.BackupDB(ctx, *tenantKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Backup failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Backed up %s to %s\n", *tenantKey, b.Name)
+	return 0
+}
+
+// runRestore implements the restore command and returns the exit code
+func runRestore(service *backup.Service, args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	tenantKey := flags.String("tenant", "", "user or workspace database to replace, or _registry")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), restoreUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *tenantKey == "" {
+		flags.Usage()
+		return 2
+	}
+
+	if err := service.Restore(context.Background(), *tenantKey, flags.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "Restore failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Restored %s from %s\n", *tenantKey, flags.Arg(0))
+	return 0
+}
@@ -7,11 +7,13 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/tkowalski/socgo/internal/backup"
 	"github.com/tkowalski/socgo/internal/config"
 	"github.com/tkowalski/socgo/internal/database"
 	"github.com/tkowalski/socgo/internal/di"
 	"github.com/tkowalski/socgo/internal/oauth"
 	"github.com/tkowalski/socgo/internal/providers"
+	"github.com/tkowalski/socgo/internal/retention"
 	"github.com/tkowalski/socgo/internal/scheduler"
 	"github.com/tkowalski/socgo/internal/server"
 )
@@ -29,20 +31,58 @@ func main() {
 	container := di.NewContainer()
 	container.Register("config", cfg)
 
-	dbManager := database.NewManager(cfg.Database.DataDir)
+	dbManager, err := database.NewManagerFromConfig(cfg)
+	if err != nil {
+		log.Fatal("Failed to create database manager:", err)
+	}
+	log.Printf("Using %s storage backend", dbManager.Driver())
+
+	backupService := backup.NewService(dbManager, cfg.Backup)
+	container.Register("backup_service", backupService)
+
+	retentionService := retention.NewService(dbManager, cfg.Retention)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(runMigrate(dbManager, os.Args[2:]))
+		case "backup":
+			os.Exit(runBackup(backupService, os.Args[2:]))
+		case "restore":
+			os.Exit(runRestore(backupService, os.Args[2:]))
+		case "prune":
+			os.Exit(runPrune(retentionService, os.Args[2:]))
+		}
+	}
 	container.Register("database", dbManager)
 
 	oauthService := oauth.NewService(dbManager, cfg)
 	container.Register("oauth_service", oauthService)
 
 	providerService := providers.NewProviderService(dbManager, oauthService)
+	providerService.SetBaseURL(cfg.Server.BaseURL)
 	container.Register("provider_service", providerService)
 
+	// Catch up on databases changed while the server was down
+	if err := dbManager.SyncControlPlane(); err != nil {
+		log.Printf("Warning: failed to sync control plane: %v", err)
+	}
+
 	// Create and start scheduler
 	jobScheduler := scheduler.New(dbManager, providerService)
+	jobScheduler.SetTrashRetention(cfg.Trash.Retention)
+	jobScheduler.SetRetention(retentionService, cfg.Retention.Interval)
 	container.Register("scheduler", jobScheduler)
 	jobScheduler.Start()
 
+	if cfg.Backup.Interval > 0 {
+		if dbManager.Driver() == database.DriverSQLite {
+			backupService.Start(cfg.Backup.Interval)
+		} else {
+			log.Printf("Scheduled backups are not available with the %s backend", dbManager.Driver())
+		}
+	}
+
 	srv := server.New(container)
 	container.Register("server", srv)
 
@@ -62,5 +102,6 @@ func main() {
 	<-stopChan
 	log.Println("Shutting down...")
 	jobScheduler.Stop()
+	backupService.Stop()
 	log.Println("Goodbye!")
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `Usage: socgo migrate [flags] up|down|status
+
+  up      apply pending migrations
+  down    revert the last -steps migrations (requires -tenant or -registry)
+  status  list applied and pending migrations and check them against the models
+
+Flags:
+`
+
+// runMigrate implements the migrate command and returns the exit code. Without
+// -tenant or -registry it works on the registry and every tenant database.
+func runMigrate(dbManager *database.Manager, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	tenantKey := flags.String("tenant", "", "only this user or workspace database, e.g. default_user or workspace_1")
+	registry := flags.Bool("registry", false, "only the registry database")
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	command := flags.Arg(0)
+
+	if command == "down" && *tenantKey == "" && !*registry {
+		fmt.Fprintln(os.Stderr, "migrate down needs -tenant or -registry")
+		return 2
+	}
+
+	dbManager.SetAutoMigrate(false)
+	defer dbManager.CloseAll()
+
+	type target struct {
+		name string
+		set  *database.MigrationSet
+		open func() (*gorm.DB, error)
+	}
+	var targets []target
+	if *tenantKey == "" {
+		targets = append(targets, target{"registry", database.RegistryMigrations, dbManager.GetRegistryDB})
+	}
+	if !*registry {
+		keys := []string{*tenantKey}
+		if *tenantKey == "" {
+			var err error
+			if keys, err = dbManager.TenantKeys(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to list tenant databases: %v\n", err)
+				return 1
+			}
+		}
+		for _, key := range keys {
+			targets = append(targets, target{key, database.TenantMigrations, func() (*gorm.DB, error) {
+				return dbManager.GetDB(key)
+			}})
+		}
+	}
+
+	exitCode := 0
+	for _, t := range targets {
+		db, err := t.open()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.name, err)
+			exitCode = 1
+			continue
+		}
+
+		switch command {
+		case "up":
+			count, err := database.MigrateUp(db, t.set)
+			fmt.Printf("%s: applied %d migration(s)\n", t.name, count)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", t.name, err)
+				exitCode = 1
+			}
+		case "down":
+			count, err := database.MigrateDown(db, t.set, *steps)
+			fmt.Printf("%s: reverted %d migration(s)\n", t.name, count)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", t.name, err)
+				exitCode = 1
+			}
+		case "status":
+			if !printMigrationStatus(t.name, db, t.set) {
+				exitCode = 1
+			}
+		default:
+			flags.Usage()
+			return 2
+		}
+	}
+	return exitCode
+}
+
+// printMigrationStatus prints the migrations of one database and reports
+// whether it is fully migrated and matches the models
+func printMigrationStatus(name string, db *gorm.DB, set *database.MigrationSet) bool {
+	statuses, err := database.GetMigrationStatus(db, set)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return false
+	}
+
+	fmt.Printf("%s (%s):\n", name, set.Name)
+	pending := 0
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		fmt.Printf("  %03d_%-30s %s\n", status.Version, status.Name, state)
+	}
+	if pending > 0 {
+		return true
+	}
+
+	if err := database.CheckSchema(db, set); err != nil {
+		fmt.Printf("  schema differs from models:\n%v\n", err)
+		return false
+	}
+	fmt.Println("  schema matches models")
+	return true
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/retention"
+)
+
+const pruneUsage = `Usage: socgo prune [-tenant key] [-dry-run]
+
+Deletes the records the retention rules in config.yml have expired from every
+tenant database, or only -tenant. With -dry-run the records are only counted.
+
+Flags:
+`
+
+// runPrune implements the prune command and returns the exit code
+func runPrune(service *retention.Service, args []string) int {
+	flags := flag.NewFlagSet("prune", flag.ContinueOnError)
+	tenantKey := flags.String("tenant", "", "only this user or workspace database")
+	dryRun := flags.Bool("dry-run", false, "report what would be deleted without deleting it")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), pruneUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	rules := service.Rules()
+	if len(rules) == 0 {
+		fmt.Println("No retention rules are configured; nothing to prune")
+		return 0
+	}
+	for _, rule := range rules {
+		action := "delete"
+		if rule.Archive {
+			action = "archive and delete"
+		}
+		fmt.Printf("Rule: %s older than %s (%s)\n", rule.Entity, rule.MaxAge, action)
+	}
+
+	now := time.Now()
+	var reports []retention.Report
+	var err error
+	if *tenantKey != "" {
+		var report retention.Report
+		report, err = service.Prune(*tenantKey, now, *dryRun)
+		if err == nil {
+			reports = append(reports, report)
+		}
+	} else {
+		reports, err = service.PruneAll(now, *dryRun)
+	}
+
+	verb := "Deleted"
+	if *dryRun {
+		verb = "Would delete"
+	}
+	var total int64
+	for _, report := range reports {
+		for _, count := range report.Counts {
+			if count.Records > 0 {
+				fmt.Printf("%-24s  %-20s  %8d  before %s\n", report.Key, count.Entity, count.Records, count.Cutoff.Format("2006-01-02 15:04:05"))
+			}
+		}
+		total += report.Total()
+	}
+	fmt.Printf("%s %d records in %d databases\n", verb, total, len(reports))
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Prune failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
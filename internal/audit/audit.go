@@ -0,0 +1,194 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// Audited actions
+const (
+	ActionPostPublished        = "post.published"
+	ActionPostCreated          = "post.created"
+	ActionPostUpdated          = "post.updated"
+	ActionPostSubmitted        = "post.submitted"
+	ActionPostApproved         = "post.approved"
+	ActionPostRejected         = "post.rejected"
+	ActionPostCommented        = "post.commented"
+	ActionPostScheduled        = "post.scheduled"
+	ActionPostDeleted          = "post.deleted"
+	ActionPostRestored         = "post.restored"
+	ActionPostPurged           = "post.purged"
+	ActionJobScheduled         = "job.scheduled"
+	ActionJobRescheduled       = "job.rescheduled"
+	ActionJobDeferred          = "job.deferred"
+	ActionJobCompleted         = "job.completed"
+	ActionJobFailed            = "job.failed"
+	ActionProviderConnected    = "provider.connected"
+	ActionProviderDisconnected = "provider.disconnected"
+	ActionProviderDeleted      = "provider.deleted"
+	ActionProviderRestored     = "provider.restored"
+	ActionProviderPurged       = "provider.purged"
+	ActionCampaignCreated      = "campaign.created"
+	ActionCampaignUpdated      = "campaign.updated"
+	ActionCampaignDeleted      = "campaign.deleted"
+	ActionSnippetCreated       = "snippet.created"
+	ActionSnippetUpdated       = "snippet.updated"
+	ActionSnippetDeleted       = "snippet.deleted"
+	ActionTemplateCreated      = "template.created"
+	ActionTemplateUpdated      = "template.updated"
+	ActionTemplateDeleted      = "template.deleted"
+	ActionFeedCreated          = "feed.created"
+	ActionFeedUpdated          = "feed.updated"
+	ActionFeedDeleted          = "feed.deleted"
+	ActionCalendarImported     = "calendar.imported"
+	ActionCalendarSynced       = "calendar.synced"
+	ActionCalendarDeleted      = "calendar.deleted"
+	ActionLinkRuleCreated      = "link_rule.created"
+	ActionLinkRuleUpdated      = "link_rule.updated"
+	ActionLinkRuleDeleted      = "link_rule.deleted"
+	ActionBioPageUpdated       = "bio_page.updated"
+	ActionBioLinkCreated       = "bio_link.created"
+	ActionBioLinkUpdated       = "bio_link.updated"
+	ActionBioLinkDeleted       = "bio_link.deleted"
+	ActionBlackoutCreated      = "blackout.created"
+	ActionBlackoutUpdated      = "blackout.updated"
+	ActionBlackoutDeleted      = "blackout.deleted"
+	ActionPublishingPaused     = "publishing.paused"
+	ActionPublishingResumed    = "publishing.resumed"
+	ActionLimitUpdated         = "limit.updated"
+	ActionLimitDeleted         = "limit.deleted"
+	ActionTokenCreated         = "token.created"
+	ActionAccountExported      = "account.exported"
+	ActionAccountImported      = "account.imported"
+)
+
+// Target types
+const (
+	TargetPost     = "post"
+	TargetJob      = "job"
+	TargetProvider = "provider"
+	TargetCampaign = "campaign"
+	TargetSnippet  = "snippet"
+	TargetTemplate = "template"
+	TargetFeed     = "feed"
+	TargetCalendar = "calendar"
+	TargetLinkRule = "link_rule"
+	TargetBioPage  = "bio_page"
+	TargetBioLink  = "bio_link"
+	TargetBlackout = "blackout"
+	TargetPause    = "publishing_pause"
+	TargetLimit    = "posting_limit"
+	TargetToken    = "token"
+	TargetAccount  = "account"
+)
+
+// Entry describes an action to record. Before and After are snapshots of the
+// target and are stored as JSON; either may be nil.
+type Entry struct {
+	// UserID is the tenant the entry belongs to; it defaults to the database
+	// key of the tenant context and must be set for background work
+	UserID     string
+	Action     string
+	TargetType string
+	TargetID   uint
+	Before     interface{}
+	After      interface{}
+}
+
+// Record appends an entry to the audit log of db. The actor, tenant and source
+// come from the tenant context in ctx.
+func Record(ctx context.Context, db *gorm.DB, entry Entry) error {
+	ident := tenant.FromContext(ctx)
+
+	before, err := snapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	userID := entry.UserID
+	if userID == "" {
+		userID = ident.DBKey()
+	}
+
+	log := database.AuditLog{
+		UserID:     userID,
+		ActorID:    ident.UserID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Before:     before,
+		After:      after,
+		Source:     ident.Source,
+		CreatedAt:  time.Now(),
+	}
+	if err := db.Create(&log).Error; err != nil {
+		return fmt.Errorf("failed to record audit entry %s: %w", entry.Action, err)
+	}
+	return nil
+}
+
+// Filter narrows down audit log queries. Zero values are ignored.
+type Filter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   uint
+	Source     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// Query returns audit entries matching filter, newest first
+func Query(db *gorm.DB, filter Filter) ([]database.AuditLog, error) {
+	query := db.Model(&database.AuditLog{})
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var logs []database.AuditLog
+	err := query.Order("created_at DESC, id DESC").Find(&logs).Error
+	return logs, err
+}
+
+func snapshot(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+)
+
+func TestRecordAndQuery(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+
+	apiCtx := tenant.WithContext(context.Background(), tenant.Context{
+		UserID: "default_user", Role: database.WorkspaceRoleOwner, Source: tenant.SourceAPI,
+	})
+	before := database.Post{ID: 7, Content: "old"}
+	after := database.Post{ID: 7, Content: "new"}
+	if err := Record(apiCtx, db, Entry{Action: ActionPostUpdated, TargetType: TargetPost, TargetID: 7, Before: before, After: after}); err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+	if err := Record(context.Background(), db, Entry{Action: ActionTokenCreated, TargetType: TargetToken, TargetID: 1}); err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+
+	logs, err := Query(db, Filter{Source: tenant.SourceAPI})
+	if err != nil {
+		t.Fatalf("Failed to query audit log: %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("Expected 1 API entry, got %d", len(logs))
+	}
+	entry := logs[0]
+	if entry.ActorID != "default_user" || entry.UserID != "default_user" || entry.Action != ActionPostUpdated {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if !strings.Contains(entry.Before, `"content":"old"`) || !strings.Contains(entry.After, `"content":"new"`) {
+		t.Errorf("Expected before/after snapshots, got %s / %s", entry.Before, entry.After)
+	}
+
+	logs, err = Query(db, Filter{From: time.Now().Add(time.Hour)})
+	if err != nil || len(logs) != 0 {
+		t.Errorf("Expected no entries in the future, got %d (%v)", len(logs), err)
+	}
+
+	// Entries cannot be changed or removed
+	if err := db.Model(&entry).Update("action", "tampered").Error; !errors.Is(err, database.ErrAuditLogImmutable) {
+		t.Errorf("Expected update to be rejected, got %v", err)
+	}
+	if err := db.Delete(&entry).Error; !errors.Is(err, database.ErrAuditLogImmutable) {
+		t.Errorf("Expected delete to be rejected, got %v", err)
+	}
+}
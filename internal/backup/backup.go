@@ -0,0 +1,368 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/config"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// ErrUnsupportedDriver is returned when the storage backend is not SQLite
+var ErrUnsupportedDriver = errors.New("backups are only supported with the sqlite driver; back up Postgres with pg_dump")
+
+// timestampLayout sorts backup file names chronologically
+const timestampLayout = "20060102T150405.000Z"
+
+// Backup is one backup file of a database
+type Backup struct {
+	Key       string    `json:"key"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Service takes online backups of the tenant and registry databases, keeps
+// the newest few of each and optionally mirrors them to an S3 bucket. Backups
+// of a database live in <dir>/<key>/<key>-<timestamp>.db.
+type Service struct {
+	dbManager *database.Manager
+	dir       string
+	retain    int
+	store     *S3Store
+	ticker    *time.Ticker
+	stopChan  chan struct{}
+}
+
+// NewService creates a backup service; backups are uploaded when cfg.S3.Bucket
+// is set
+func NewService(dbManager *database.Manager, cfg config.BackupConfig) *Service {
+	s := &Service{
+		dbManager: dbManager,
+		dir:       cfg.Dir,
+		retain:    cfg.Retain,
+		stopChan:  make(chan struct{}),
+	}
+	if s.retain <= 0 {
+		s.retain = 1
+	}
+	if cfg.S3.Bucket != "" {
+		s.store = NewS3Store(cfg.S3, nil)
+	}
+	return s
+}
+
+// Start backs up every database each interval
+func (s *Service) Start(interval time.Duration) {
+	log.Printf("Starting database backups every %s...", interval)
+
+	s.ticker = time.NewTicker(interval)
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				if err := s.BackupAll(context.Background()); err != nil {
+					log.Printf("Error backing up databases: %v", err)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops scheduled backups
+func (s *Service) Stop() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+	close(s.stopChan)
+}
+
+// BackupAll backs up the registry and every tenant database
+func (s *Service) BackupAll(ctx context.Context) error {
+	if s.dbManager.Driver() != database.DriverSQLite {
+		return ErrUnsupportedDriver
+	}
+
+	keys, err := s.dbManager.TenantKeys()
+	if err != nil {
+		return fmt.Errorf("failed to list databases: %w", err)
+	}
+
+	var errs []error
+	for _, key := range append([]string{database.RegistryDBName}, keys...) {
+		if _, err := s.BackupDB(ctx, key); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BackupDB takes a consistent snapshot of the database of key while it stays
+// in use, uploads it when a bucket is configured and prunes old backups
+func (s *Service) BackupDB(ctx context.Context, key string) (*Backup, error) {
+	backup, err := s.snapshot(key, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if s.store != nil {
+		if err := s.store.Put(ctx, remoteKey(key, backup.Name), filepath.Join(s.dir, key, backup.Name)); err != nil {
+			return backup, fmt.Errorf("failed to upload backup: %w", err)
+		}
+	}
+
+	if err := s.prune(ctx, key); err != nil {
+		return backup, fmt.Errorf("failed to prune old backups: %w", err)
+	}
+	return backup, nil
+}
+
+// List returns the local backups of key, newest first
+func (s *Service) List(key string) ([]Backup, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".db") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Backup{Key: key, Name: entry.Name(), Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name > backups[j].Name
+	})
+	return backups, nil
+}
+
+// ListRemote returns the backups of key stored in the bucket, newest first
+func (s *Service) ListRemote(ctx context.Context, key string) ([]Backup, error) {
+	if s.store == nil {
+		return nil, errors.New("no backup bucket is configured")
+	}
+	objects, err := s.store.List(ctx, key+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]Backup, 0, len(objects))
+	for i := len(objects) - 1; i >= 0; i-- {
+		backups = append(backups, Backup{
+			Key:       key,
+			Name:      strings.TrimPrefix(objects[i].Key, key+"/"),
+			Size:      objects[i].Size,
+			CreatedAt: objects[i].LastModified,
+		})
+	}
+	return backups, nil
+}
+
+// Restore replaces the database of key with a backup. name is a backup file
+// name as returned by List (downloaded from the bucket if it is not present
+// locally) or a path to a backup file. The current database is kept as a
+// "pre-restore" backup and the restored one is migrated to the current
+// schema. The server must not be using the database while it is restored.
+func (s *Service) Restore(ctx context.Context, key, name string) error {
+	if s.dbManager.Driver() != database.DriverSQLite {
+		return ErrUnsupportedDriver
+	}
+
+	source, err := s.locate(ctx, key, name)
+	if err != nil {
+		return err
+	}
+	if err := Verify(source); err != nil {
+		return err
+	}
+
+	target := s.dbManager.GetDBPath(key)
+	if _, err := os.Stat(target); err == nil {
+		if _, err := s.snapshot(key, "-pre-restore"); err != nil {
+			return fmt.Errorf("failed to save the current database: %w", err)
+		}
+	}
+	if err := s.dbManager.CloseDB(key); err != nil {
+		return fmt.Errorf("failed to close the current database: %w", err)
+	}
+
+	if err := copyFile(source, target+".restore"); err != nil {
+		return err
+	}
+	if err := os.Rename(target+".restore", target); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		os.Remove(target + suffix)
+	}
+
+	if _, err := s.open(key); err != nil {
+		return fmt.Errorf("failed to open the restored database: %w", err)
+	}
+	if key != database.RegistryDBName {
+		if err := s.dbManager.SyncTenant(key); err != nil {
+			return fmt.Errorf("failed to update the control plane: %w", err)
+		}
+	}
+	return nil
+}
+
+// Snapshot writes a consistent copy of db to path with VACUUM INTO. The
+// database stays readable and writable while the copy is made.
+func Snapshot(db *gorm.DB, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return db.Exec("VACUUM INTO ?", path).Error
+}
+
+// Verify checks that path is an intact SQLite database
+func Verify(path string) error {
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var result string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("%s is not a valid database: %w", path, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup failed the integrity check: %s", result)
+	}
+	return nil
+}
+
+// snapshot writes a new backup of key; suffix marks special backups
+func (s *Service) snapshot(key, suffix string) (*Backup, error) {
+	if s.dbManager.Driver() != database.DriverSQLite {
+		return nil, ErrUnsupportedDriver
+	}
+
+	db, err := s.open(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s%s.db", key, now.UTC().Format(timestampLayout), suffix)
+	path := filepath.Join(s.dir, key, name)
+	if err := Snapshot(db, path); err != nil {
+		return nil, fmt.Errorf("failed to back up %s: %w", key, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Backup{Key: key, Name: name, Size: info.Size(), CreatedAt: now}, nil
+}
+
+// prune removes all but the newest retain backups of key, locally and in the
+// bucket
+func (s *Service) prune(ctx context.Context, key string) error {
+	backups, err := s.List(key)
+	if err != nil {
+		return err
+	}
+	for i := s.retain; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(s.dir, key, backups[i].Name)); err != nil {
+			return err
+		}
+	}
+
+	if s.store == nil {
+		return nil
+	}
+	remote, err := s.ListRemote(ctx, key)
+	if err != nil {
+		return err
+	}
+	for i := s.retain; i < len(remote); i++ {
+		if err := s.store.Delete(ctx, remoteKey(key, remote[i].Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// locate returns the local path of the backup to restore
+func (s *Service) locate(ctx context.Context, key, name string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) {
+		if _, err := os.Stat(name); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+
+	path := filepath.Join(s.dir, key, name)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	} else if !errors.Is(err, os.ErrNotExist) || s.store == nil {
+		return "", fmt.Errorf("backup %s of %s not found", name, key)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := s.store.Get(ctx, remoteKey(key, name), path); err != nil {
+		return "", fmt.Errorf("failed to download backup: %w", err)
+	}
+	return path, nil
+}
+
+func (s *Service) open(key string) (*gorm.DB, error) {
+	if key == database.RegistryDBName {
+		return s.dbManager.GetRegistryDB()
+	}
+	return s.dbManager.GetDB(key)
+}
+
+func remoteKey(key, name string) string {
+	return key + "/" + name
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/config"
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func newTestService(t *testing.T, cfg config.BackupConfig) (*Service, *database.Manager) {
+	t.Helper()
+	dir := t.TempDir()
+	manager := database.NewManager(filepath.Join(dir, "data"))
+	t.Cleanup(func() { manager.Close() })
+
+	cfg.Dir = filepath.Join(dir, "backups")
+	if cfg.Retain == 0 {
+		cfg.Retain = 7
+	}
+	return NewService(manager, cfg), manager
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	service, manager := newTestService(t, config.BackupConfig{})
+	ctx := context.Background()
+	userID := "backup_user"
+
+	db, err := manager.GetDB(userID)
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	if err := db.Create(&database.Post{Content: "before backup", UserID: userID}).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	backup, err := service.BackupDB(ctx, userID)
+	if err != nil {
+		t.Fatalf("BackupDB failed: %v", err)
+	}
+	if !strings.HasPrefix(backup.Name, userID+"-") || backup.Size == 0 {
+		t.Errorf("Unexpected backup: %+v", backup)
+	}
+
+	// Changes after the backup are lost on restore
+	if err := db.Create(&database.Post{Content: "after backup", UserID: userID}).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	if err := service.Restore(ctx, userID, backup.Name); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	restored, err := manager.GetDB(userID)
+	if err != nil {
+		t.Fatalf("GetDB after restore failed: %v", err)
+	}
+	var posts []database.Post
+	restored.Find(&posts)
+	if len(posts) != 1 || posts[0].Content != "before backup" {
+		t.Errorf("Expected only the backed up post, got %+v", posts)
+	}
+
+	backups, err := service.List(userID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(backups) != 2 || !strings.HasSuffix(backups[0].Name, "-pre-restore.db") {
+		t.Errorf("Expected the backup and a pre-restore copy, got %+v", backups)
+	}
+}
+
+func TestRestore_RejectsInvalidBackup(t *testing.T) {
+	service, manager := newTestService(t, config.BackupConfig{})
+	userID := "backup_user"
+
+	db, err := manager.GetDB(userID)
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	db.Create(&database.Post{Content: "keep me", UserID: userID})
+
+	invalid := filepath.Join(t.TempDir(), "invalid.db")
+	if err := os.WriteFile(invalid, []byte("not a database"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := service.Restore(context.Background(), userID, invalid); err == nil {
+		t.Fatal("Expected restoring an invalid file to fail")
+	}
+
+	var count int64
+	db.Model(&database.Post{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Current database should be untouched, got %d posts", count)
+	}
+}
+
+func TestBackupDB_PrunesOldBackups(t *testing.T) {
+	service, manager := newTestService(t, config.BackupConfig{Retain: 2})
+	userID := "backup_user"
+	if _, err := manager.GetDB(userID); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		backup, err := service.BackupDB(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("BackupDB failed: %v", err)
+		}
+		names = append(names, backup.Name)
+	}
+
+	backups, err := service.List(userID)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(backups) != 2 || backups[0].Name != names[3] || backups[1].Name != names[2] {
+		t.Errorf("Expected the two newest backups, got %+v", backups)
+	}
+}
+
+func TestBackupAll_UploadsAndRestoresFromBucket(t *testing.T) {
+	fake, server := newFakeS3(t, "backups")
+	service, manager := newTestService(t, config.BackupConfig{Retain: 1, S3: testS3Config(server.URL)})
+	service.store = NewS3Store(testS3Config(server.URL), server.Client())
+	ctx := context.Background()
+
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	db.Create(&database.Post{Content: "alice's post", UserID: "alice"})
+	if _, err := manager.GetDB("bob"); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := service.BackupAll(ctx); err != nil {
+			t.Fatalf("BackupAll failed: %v", err)
+		}
+	}
+
+	keys := fake.keys()
+	if len(keys) != 3 {
+		t.Fatalf("Expected one remote backup per database, got %v", keys)
+	}
+	for i, prefix := range []string{"socgo/_registry/", "socgo/alice/", "socgo/bob/"} {
+		if !strings.HasPrefix(keys[i], prefix) {
+			t.Errorf("Expected key with prefix %s, got %s", prefix, keys[i])
+		}
+	}
+
+	remote, err := service.ListRemote(ctx, "alice")
+	if err != nil || len(remote) != 1 {
+		t.Fatalf("ListRemote = %+v, %v", remote, err)
+	}
+
+	// Lose the local copies and restore from the bucket
+	if err := os.RemoveAll(filepath.Join(service.dir, "alice")); err != nil {
+		t.Fatalf("Failed to remove local backups: %v", err)
+	}
+	db.Where("1 = 1").Delete(&database.Post{})
+
+	if err := service.Restore(ctx, "alice", remote[0].Name); err != nil {
+		t.Fatalf("Restore from bucket failed: %v", err)
+	}
+	restored, _ := manager.GetDB("alice")
+	var count int64
+	restored.Model(&database.Post{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the post to be restored, got %d posts", count)
+	}
+}
+
+func TestBackupAll_RequiresSQLite(t *testing.T) {
+	service := NewService(database.NewPostgresManager("host=localhost", ""), config.BackupConfig{Dir: t.TempDir()})
+	if err := service.BackupAll(context.Background()); err != ErrUnsupportedDriver {
+		t.Errorf("Expected ErrUnsupportedDriver, got %v", err)
+	}
+}
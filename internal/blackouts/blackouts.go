@@ -0,0 +1,446 @@
+// Package blackouts keeps the scheduler from publishing during blackout
+// windows, recurring quiet hours and while publishing is paused.
+package blackouts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when the blackout window does not exist
+	ErrNotFound = errors.New("blackout window not found")
+	// ErrInvalid wraps validation errors of an Input
+	ErrInvalid = errors.New("invalid blackout window")
+)
+
+// localLayout is the format of times without a zone, read in the timezone of
+// the window
+const localLayout = "2006-01-02T15:04"
+
+// weekdays are the names Weekdays are written with, in time.Weekday order
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Input holds the editable fields of a blackout window. StartsAt and EndsAt
+// are RFC 3339 times, or 2006-01-02T15:04 in Timezone.
+type Input struct {
+	Name       string   `json:"name"`
+	ProviderID *uint    `json:"provider_id"`
+	Recurring  bool     `json:"recurring"`
+	StartsAt   string   `json:"starts_at"`
+	EndsAt     string   `json:"ends_at"`
+	StartTime  string   `json:"start_time"`
+	EndTime    string   `json:"end_time"`
+	Weekdays   []string `json:"weekdays"`
+	Timezone   string   `json:"timezone"`
+	Action     string   `json:"action"`
+}
+
+// Decision is what to do with a job falling due
+type Decision struct {
+	// Blocked is set when the job must not be published now
+	Blocked bool
+	// Hold leaves the job due until Until; otherwise it is moved to Until.
+	// A paused account holds jobs with a zero Until.
+	Hold  bool
+	Until time.Time
+	// Reason names the pause or the windows blocking the job
+	Reason string
+}
+
+// Status is the publishing state of a user or workspace
+type Status struct {
+	Paused  *database.PublishingPause `json:"paused"`
+	Active  []ActiveWindow            `json:"active"`
+	Windows int                       `json:"windows"`
+}
+
+// ActiveWindow is a blackout window in force and when it ends
+type ActiveWindow struct {
+	database.BlackoutWindow
+	Until time.Time `json:"until"`
+}
+
+// Rules are the blackout windows and pause of a user or workspace, loaded
+// once per scheduler run
+type Rules struct {
+	pause   *database.PublishingPause
+	windows []database.BlackoutWindow
+}
+
+// List returns the blackout windows of userID
+func List(db *gorm.DB, userID string) ([]database.BlackoutWindow, error) {
+	var windows []database.BlackoutWindow
+	err := db.Where("user_id = ?", userID).Order("recurring, starts_at, start_time, id").Find(&windows).Error
+	return windows, err
+}
+
+// Get returns the blackout window id of userID
+func Get(db *gorm.DB, userID string, id uint) (*database.BlackoutWindow, error) {
+	var window database.BlackoutWindow
+	result := db.Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&window)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &window, nil
+}
+
+// Create adds a blackout window for userID
+func Create(ctx context.Context, db *gorm.DB, userID string, in Input) (*database.BlackoutWindow, error) {
+	window := &database.BlackoutWindow{UserID: userID}
+	if err := apply(db, window, in); err != nil {
+		return nil, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(window).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionBlackoutCreated, TargetType: audit.TargetBlackout, TargetID: window.ID, After: window,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// Update replaces the fields of the blackout window id with in
+func Update(ctx context.Context, db *gorm.DB, userID string, id uint, in Input) (*database.BlackoutWindow, error) {
+	window, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *window
+	if err := apply(db, window, in); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(window).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionBlackoutUpdated, TargetType: audit.TargetBlackout, TargetID: window.ID, Before: before, After: window,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// Delete removes the blackout window id. Jobs held by it are published on
+// the next scheduler run.
+func Delete(ctx context.Context, db *gorm.DB, userID string, id uint) error {
+	window, err := Get(db, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(window).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionBlackoutDeleted, TargetType: audit.TargetBlackout, TargetID: id, Before: window,
+		})
+	})
+}
+
+// GetPause returns the publishing pause of userID, or nil when publishing is
+// not paused
+func GetPause(db *gorm.DB, userID string) (*database.PublishingPause, error) {
+	var pause database.PublishingPause
+	result := db.Where("user_id = ?", userID).Limit(1).Find(&pause)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &pause, nil
+}
+
+// Pause stops all publishing of userID until Resume. Pausing twice keeps the
+// first pause.
+func Pause(ctx context.Context, db *gorm.DB, userID, reason string) (*database.PublishingPause, error) {
+	if pause, err := GetPause(db, userID); err != nil || pause != nil {
+		return pause, err
+	}
+
+	pause := &database.PublishingPause{
+		Reason: strings.TrimSpace(reason), PausedBy: tenant.FromContext(ctx).UserID, UserID: userID,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(pause).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionPublishingPaused, TargetType: audit.TargetPause, TargetID: pause.ID, After: pause,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pause, nil
+}
+
+// Resume lifts the publishing pause of userID. Jobs that fell due meanwhile
+// are published on the next scheduler run.
+func Resume(ctx context.Context, db *gorm.DB, userID string) error {
+	pause, err := GetPause(db, userID)
+	if err != nil || pause == nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(pause).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionPublishingResumed, TargetType: audit.TargetPause, TargetID: pause.ID, Before: pause,
+		})
+	})
+}
+
+// Load returns the blackout rules of userID
+func Load(db *gorm.DB, userID string) (*Rules, error) {
+	pause, err := GetPause(db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load publishing pause: %w", err)
+	}
+	windows, err := List(db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blackout windows: %w", err)
+	}
+	return &Rules{pause: pause, windows: windows}, nil
+}
+
+// GetStatus returns whether userID is paused and the windows in force at now
+func GetStatus(db *gorm.DB, userID string, now time.Time) (*Status, error) {
+	rules, err := Load(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Paused: rules.pause, Active: []ActiveWindow{}, Windows: len(rules.windows)}
+	for _, window := range rules.windows {
+		if until, ok := activeUntil(window, now); ok {
+			status.Active = append(status.Active, ActiveWindow{BlackoutWindow: window, Until: until})
+		}
+	}
+	return status, nil
+}
+
+// Check decides whether a job publishing to providerID may run at now. A
+// pause holds every job. Otherwise the windows in force for the provider
+// block it until the last of them ends, holding it when any of them holds.
+// Windows starting as others end are chained, so a deferred job does not
+// fall due inside the next one.
+func (r *Rules) Check(providerID uint, now time.Time) Decision {
+	if r.pause != nil {
+		return Decision{Blocked: true, Hold: true, Reason: "publishing is paused"}
+	}
+
+	var decision Decision
+	var names []string
+	at := now
+	// Each round moves past at least one window, so the chain is bounded
+	for round := 0; round <= len(r.windows); round++ {
+		var next time.Time
+		for _, window := range r.windows {
+			if window.ProviderID != nil && *window.ProviderID != providerID {
+				continue
+			}
+			until, ok := activeUntil(window, at)
+			if !ok {
+				continue
+			}
+			decision.Blocked = true
+			decision.Hold = decision.Hold || window.Action == database.BlackoutHold
+			if !contains(names, window.Name) {
+				names = append(names, window.Name)
+			}
+			if until.After(next) {
+				next = until
+			}
+		}
+		if next.IsZero() {
+			break
+		}
+		at = next
+	}
+
+	if decision.Blocked {
+		decision.Until = at
+		decision.Reason = "blackout " + strings.Join(names, ", ")
+	}
+	return decision
+}
+
+// activeUntil reports whether window is in force at now and when it ends
+func activeUntil(window database.BlackoutWindow, now time.Time) (time.Time, bool) {
+	if !window.Recurring {
+		if window.StartsAt == nil || window.EndsAt == nil {
+			return time.Time{}, false
+		}
+		return *window.EndsAt, !now.Before(*window.StartsAt) && now.Before(*window.EndsAt)
+	}
+
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, errStart := time.Parse("15:04", window.StartTime)
+	end, errEnd := time.Parse("15:04", window.EndTime)
+	if errStart != nil || errEnd != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	// Quiet hours past midnight may have started the day before
+	for _, offset := range []int{-1, 0} {
+		day := local.AddDate(0, 0, offset)
+		if !onWeekday(window.Weekdays, day.Weekday()) {
+			continue
+		}
+		from := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		to := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		if !to.After(from) {
+			to = to.AddDate(0, 0, 1)
+		}
+		if !now.Before(from) && now.Before(to) {
+			return to, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// onWeekday reports whether the comma separated days include day; no days
+// means every day
+func onWeekday(days string, day time.Weekday) bool {
+	if days == "" {
+		return true
+	}
+	return contains(strings.Split(days, ","), weekdays[day])
+}
+
+// apply validates in and copies it onto window
+func apply(db *gorm.DB, window *database.BlackoutWindow, in Input) error {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if in.ProviderID != nil {
+		var provider database.Provider
+		result := db.Where("user_id = ? AND id = ?", window.UserID, *in.ProviderID).Limit(1).Find(&provider)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: provider not found", ErrInvalid)
+		}
+	}
+	action := in.Action
+	if action == "" {
+		action = database.BlackoutDefer
+	}
+	if action != database.BlackoutDefer && action != database.BlackoutHold {
+		return fmt.Errorf("%w: action must be %s or %s", ErrInvalid, database.BlackoutDefer, database.BlackoutHold)
+	}
+	timezone := strings.TrimSpace(in.Timezone)
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("%w: unknown timezone %s", ErrInvalid, timezone)
+	}
+
+	window.Name = name
+	window.ProviderID = in.ProviderID
+	window.Recurring = in.Recurring
+	window.Timezone = timezone
+	window.Action = action
+	window.StartsAt, window.EndsAt = nil, nil
+	window.StartTime, window.EndTime, window.Weekdays = "", "", ""
+
+	if !in.Recurring {
+		startsAt, err := parseTime(in.StartsAt, loc)
+		if err != nil {
+			return fmt.Errorf("%w: starts_at: %v", ErrInvalid, err)
+		}
+		endsAt, err := parseTime(in.EndsAt, loc)
+		if err != nil {
+			return fmt.Errorf("%w: ends_at: %v", ErrInvalid, err)
+		}
+		if !endsAt.After(startsAt) {
+			return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalid)
+		}
+		window.StartsAt, window.EndsAt = &startsAt, &endsAt
+		return nil
+	}
+
+	start, errStart := time.Parse("15:04", strings.TrimSpace(in.StartTime))
+	end, errEnd := time.Parse("15:04", strings.TrimSpace(in.EndTime))
+	if errStart != nil || errEnd != nil {
+		return fmt.Errorf("%w: start_time and end_time must look like 22:00", ErrInvalid)
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("%w: start_time and end_time must differ", ErrInvalid)
+	}
+	var days []string
+	for _, day := range in.Weekdays {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if len(day) > 3 {
+			day = day[:3]
+		}
+		if !contains(weekdays, day) {
+			return fmt.Errorf("%w: unknown weekday %s", ErrInvalid, day)
+		}
+		if !contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	window.StartTime, window.EndTime = start.Format("15:04"), end.Format("15:04")
+	window.Weekdays = strings.Join(days, ",")
+	return nil
+}
+
+// parseTime reads an RFC 3339 time, or a time without a zone in loc
+func parseTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("required")
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(localLayout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must look like %s", localLayout)
+	}
+	return t, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
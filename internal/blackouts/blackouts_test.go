@@ -0,0 +1,115 @@
+package blackouts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestRules_Check(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Skipf("No timezone data: %v", err)
+	}
+	facebook := uint(1)
+	startsAt := time.Date(2026, 12, 24, 0, 0, 0, 0, warsaw)
+	endsAt := time.Date(2026, 12, 24, 23, 0, 0, 0, warsaw)
+
+	rules := &Rules{windows: []database.BlackoutWindow{
+		{Name: "Christmas Eve", StartsAt: &startsAt, EndsAt: &endsAt, Action: database.BlackoutDefer},
+		{Name: "Nights", ProviderID: &facebook, Recurring: true, StartTime: "22:00", EndTime: "07:00",
+			Weekdays: "thu,fri", Timezone: "Europe/Warsaw", Action: database.BlackoutHold},
+	}}
+
+	for _, tc := range []struct {
+		name       string
+		providerID uint
+		now        time.Time
+		want       Decision
+	}{
+		{"outside every window", 2, time.Date(2026, 12, 22, 12, 0, 0, 0, warsaw), Decision{}},
+		{"one-off window", 2, time.Date(2026, 12, 24, 12, 0, 0, 0, warsaw),
+			Decision{Blocked: true, Until: endsAt, Reason: "blackout Christmas Eve"}},
+		// Thursday 24 December: the night starts at 22:00 while the one-off
+		// window still runs, and both are chained
+		{"chained windows", facebook, time.Date(2026, 12, 24, 12, 0, 0, 0, warsaw),
+			Decision{Blocked: true, Hold: true, Until: time.Date(2026, 12, 25, 7, 0, 0, 0, warsaw), Reason: "blackout Christmas Eve, Nights"}},
+		// Saturday morning is still inside the Friday night
+		{"quiet hours past midnight", facebook, time.Date(2026, 12, 26, 6, 30, 0, 0, warsaw),
+			Decision{Blocked: true, Hold: true, Until: time.Date(2026, 12, 26, 7, 0, 0, 0, warsaw), Reason: "blackout Nights"}},
+		{"quiet hours on other days", facebook, time.Date(2026, 12, 27, 6, 30, 0, 0, warsaw), Decision{}},
+		{"quiet hours of another provider", 2, time.Date(2026, 12, 26, 6, 30, 0, 0, warsaw), Decision{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rules.Check(tc.providerID, tc.now)
+			if got.Blocked != tc.want.Blocked || got.Hold != tc.want.Hold || !got.Until.Equal(tc.want.Until) || got.Reason != tc.want.Reason {
+				t.Errorf("Check = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	rules.pause = &database.PublishingPause{}
+	if got := rules.Check(2, time.Date(2026, 12, 22, 12, 0, 0, 0, warsaw)); !got.Blocked || !got.Hold {
+		t.Errorf("Expected a pause to hold every job, got %+v", got)
+	}
+}
+
+func TestWindows_ValidateAndPause(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	missing := uint(42)
+
+	for _, in := range []Input{
+		{StartsAt: "2026-12-24T00:00", EndsAt: "2026-12-25T00:00"},
+		{Name: "Backwards", StartsAt: "2026-12-25T00:00", EndsAt: "2026-12-24T00:00"},
+		{Name: "Open", StartsAt: "2026-12-24T00:00"},
+		{Name: "Nowhere", StartsAt: "2026-12-24T00:00", EndsAt: "2026-12-25T00:00", Timezone: "Mars/Olympus"},
+		{Name: "Unknown", ProviderID: &missing, StartsAt: "2026-12-24T00:00", EndsAt: "2026-12-25T00:00"},
+		{Name: "Skip", StartsAt: "2026-12-24T00:00", EndsAt: "2026-12-25T00:00", Action: "skip"},
+		{Name: "Nights", Recurring: true, StartTime: "22:00", EndTime: "22:00"},
+		{Name: "Nights", Recurring: true, StartTime: "late", EndTime: "07:00"},
+		{Name: "Nights", Recurring: true, StartTime: "22:00", EndTime: "07:00", Weekdays: []string{"someday"}},
+	} {
+		if _, err := Create(ctx, db, "alice", in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for %+v, got %v", in, err)
+		}
+	}
+
+	window, err := Create(ctx, db, "alice", Input{
+		Name: "Nights", Recurring: true, StartTime: "22:00", EndTime: "7:00", Weekdays: []string{"Monday", "tue", "mon"},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if window.EndTime != "07:00" || window.Weekdays != "mon,tue" || window.Timezone != "UTC" || window.Action != database.BlackoutDefer {
+		t.Errorf("Unexpected window: %+v", window)
+	}
+
+	pause, err := Pause(ctx, db, "alice", " Incident ")
+	if err != nil || pause.Reason != "Incident" {
+		t.Fatalf("Pause returned %+v, %v", pause, err)
+	}
+	if again, err := Pause(ctx, db, "alice", "Again"); err != nil || again.ID != pause.ID {
+		t.Errorf("Expected pausing twice to keep the first pause, got %+v, %v", again, err)
+	}
+	status, err := GetStatus(db, "alice", time.Now())
+	if err != nil || status.Paused == nil || status.Windows != 1 {
+		t.Errorf("Unexpected status %+v, %v", status, err)
+	}
+
+	if err := Resume(ctx, db, "alice"); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if pause, err := GetPause(db, "alice"); err != nil || pause != nil {
+		t.Errorf("Expected no pause after resuming, got %+v, %v", pause, err)
+	}
+	if err := Delete(ctx, db, "alice", window.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Get(db, "alice", window.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+// Package bulkimport schedules many posts at once from CSV or JSON rows.
+// Every row is validated before anything is written, and the jobs of all
+// rows are created in one transaction, so an import succeeds or changes
+// nothing.
+package bulkimport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"github.com/tkowalski/socgo/internal/providers"
+	"github.com/tkowalski/socgo/internal/webhooks"
+	"gorm.io/gorm"
+)
+
+// MaxRows limits the number of rows of one import
+const MaxRows = 1000
+
+// Formats of an import
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// ErrInvalid is returned when rows fail validation; the Result lists the
+// row errors
+var ErrInvalid = errors.New("invalid import")
+
+// scheduleLayouts are the accepted formats of schedule_at. Times without a
+// zone are in the row's timezone.
+var scheduleLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// Row is one post to schedule. Providers are provider names; in CSV they are
+// separated by '|', ';' or ',' within the column.
+type Row struct {
+	// Line is the line of the row in a CSV file or its index, from 1, in a
+	// JSON array
+	Line       int           `json:"-"`
+	Content    string        `json:"content"`
+	Providers  names         `json:"providers"`
+	ScheduleAt string        `json:"schedule_at"`
+	Timezone   string        `json:"timezone"`
+	Tags       database.Tags `json:"tags"`
+}
+
+// RowError is a validation error of one row
+type RowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+}
+
+// Result reports an import. Jobs are only set when it succeeded and was not
+// a dry run.
+type Result struct {
+	Rows   int                     `json:"rows"`
+	Posts  int                     `json:"posts"`
+	DryRun bool                    `json:"dry_run"`
+	Errors []RowError              `json:"errors"`
+	Jobs   []database.ScheduledJob `json:"jobs"`
+}
+
+// names is a list of provider names that JSON may give as an array or as a
+// separated string, like the CSV column
+type names []string
+
+func (n *names) UnmarshalJSON(data []byte) error {
+	var list string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*n = splitNames(list)
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*n = values
+	return nil
+}
+
+func splitNames(list string) names {
+	return strings.FieldsFunc(list, func(r rune) bool {
+		return r == '|' || r == ';' || r == ','
+	})
+}
+
+// Parse reads the rows of an import in format
+func Parse(format string, r io.Reader) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r)
+	case FormatJSON:
+		return ParseJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q, use %s or %s", ErrInvalid, format, FormatCSV, FormatJSON)
+	}
+}
+
+// ParseCSV reads rows from CSV with a header row naming the columns content,
+// providers, schedule_at, timezone and tags in any order; only content,
+// providers and schedule_at are required
+func ParseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"content", "providers", "schedule_at"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalid, name)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+		rows = append(rows, Row{
+			Line:       line,
+			Content:    field("content"),
+			Providers:  splitNames(field("providers")),
+			ScheduleAt: field("schedule_at"),
+			Timezone:   field("timezone"),
+			Tags:       database.ParseTags(field("tags")),
+		})
+		if len(rows) > MaxRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalid, MaxRows)
+		}
+	}
+	return rows, nil
+}
+
+// ParseJSON reads rows from a JSON array of objects with the fields of Row
+func ParseJSON(r io.Reader) ([]Row, error) {
+	var rows []Row
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if len(rows) > MaxRows {
+		return nil, fmt.Errorf("%w: more than %d rows", ErrInvalid, MaxRows)
+	}
+	for i := range rows {
+		rows[i].Line = i + 1
+	}
+	return rows, nil
+}
+
+// Import validates rows and schedules a job for every provider of every row
+// in one transaction. When any row is invalid or would break a posting limit
+// nothing is created and the result lists the row errors with an error
+// wrapping ErrInvalid. A dry run only validates, against the posts already
+// scheduled.
+func Import(ctx context.Context, db *gorm.DB, userID string, rows []Row, dryRun bool, now time.Time) (*Result, error) {
+	result := &Result{Rows: len(rows), DryRun: dryRun, Errors: []RowError{}, Jobs: []database.ScheduledJob{}}
+	if len(rows) == 0 {
+		return result, fmt.Errorf("%w: no rows to import", ErrInvalid)
+	}
+
+	var active []database.Provider
+	if err := db.Where("user_id = ? AND is_active = ?", userID, true).Find(&active).Error; err != nil {
+		return nil, fmt.Errorf("failed to load providers: %w", err)
+	}
+	byName := make(map[string]database.Provider, len(active))
+	for _, provider := range active {
+		byName[strings.ToLower(provider.Name)] = provider
+	}
+
+	var jobs []database.ScheduledJob
+	var lines []int // the line of the row of each job
+	for _, row := range rows {
+		fail := func(field, message string) {
+			result.Errors = append(result.Errors, RowError{Line: row.Line, Field: field, Message: message})
+		}
+
+		scheduledAt, err := parseScheduleAt(row.ScheduleAt, row.Timezone)
+		if err != nil {
+			fail("schedule_at", err.Error())
+		} else if !scheduledAt.After(now) {
+			fail("schedule_at", "must be in the future")
+		}
+
+		if strings.TrimSpace(row.Content) == "" {
+			fail("content", "is required")
+		}
+		if len(row.Providers) == 0 {
+			fail("providers", "at least one provider is required")
+		}
+		seen := map[uint]bool{}
+		for _, name := range row.Providers {
+			provider, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				fail("providers", fmt.Sprintf("provider %q is not connected", strings.TrimSpace(name)))
+				continue
+			}
+			if seen[provider.ID] {
+				continue
+			}
+			seen[provider.ID] = true
+			if strings.TrimSpace(row.Content) != "" {
+				if err := providers.ValidateContent(providers.ProviderType(provider.Name), row.Content); err != nil {
+					fail("content", err.Error())
+					continue
+				}
+			}
+			if !scheduledAt.IsZero() {
+				if err := limits.Validate(db, userID, provider.ID, scheduledAt, 0); err != nil {
+					if !errors.Is(err, limits.ErrConflict) {
+						return nil, err
+					}
+					fail("schedule_at", fmt.Sprintf("%s: %v", provider.Name, err))
+					continue
+				}
+			}
+			jobs = append(jobs, database.ScheduledJob{
+				JobType:     "publish_post",
+				PayloadData: row.Content,
+				UserID:      userID,
+				ProviderID:  provider.ID,
+				ScheduledAt: scheduledAt,
+				Tags:        database.NormalizeTags(row.Tags),
+				Status:      database.JobStatusPending,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			})
+			lines = append(lines, row.Line)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("%w: %d errors", ErrInvalid, len(result.Errors))
+	}
+	result.Posts = len(jobs)
+	if dryRun {
+		return result, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range jobs {
+			// Rows may crowd each other, which shows once the earlier ones are
+			// created
+			if err := limits.Validate(tx, userID, jobs[i].ProviderID, jobs[i].ScheduledAt, 0); err != nil {
+				if !errors.Is(err, limits.ErrConflict) {
+					return err
+				}
+				result.Errors = append(result.Errors, RowError{Line: lines[i], Field: "schedule_at", Message: err.Error()})
+				continue
+			}
+			if err := tx.Create(&jobs[i]).Error; err != nil {
+				return err
+			}
+			if err := audit.Record(ctx, tx, audit.Entry{
+				Action: audit.ActionJobScheduled, TargetType: audit.TargetJob, TargetID: jobs[i].ID, After: jobs[i],
+			}); err != nil {
+				return err
+			}
+			if err := webhooks.Emit(tx, userID, webhooks.EventJobScheduled, jobs[i]); err != nil {
+				return err
+			}
+		}
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("%w: %d errors", ErrInvalid, len(result.Errors))
+		}
+		return nil
+	})
+	if errors.Is(err, ErrInvalid) {
+		result.Posts = 0
+		return result, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule posts: %w", err)
+	}
+	result.Jobs = jobs
+	return result, nil
+}
+
+// parseScheduleAt parses value in RFC 3339, or in one of scheduleLayouts in
+// timezone, an IANA zone name defaulting to UTC
+func parseScheduleAt(value, timezone string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("is required")
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+
+	location := time.UTC
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return time.Time{}, fmt.Errorf("unknown timezone %q", timezone)
+		}
+	}
+	for _, layout := range scheduleLayouts {
+		if at, err := time.ParseInLocation(layout, value, location); err == nil {
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use YYYY-MM-DD HH:MM or RFC 3339", value)
+}
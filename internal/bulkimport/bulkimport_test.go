@@ -0,0 +1,123 @@
+package bulkimport
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := database.NewTestDB(t, "alice")
+	db.Create(&database.Provider{Name: "facebook", Type: "facebook", UserID: "alice", IsActive: true})
+	db.Create(&database.Provider{Name: "instagram", Type: "instagram", UserID: "alice", IsActive: true})
+	return db
+}
+
+func TestImport_CSVIsAllOrNothing(t *testing.T) {
+	db := setup(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	rows, err := ParseCSV(strings.NewReader("Content,Providers,schedule_at,timezone,tags\n" +
+		"\"Hello, world\",facebook|Instagram,2026-03-02 09:00,Europe/Warsaw,\"launch, promo\"\n" +
+		"\n" +
+		"Second,facebook,2026-03-03T10:00:00Z,,\n"))
+	if err != nil {
+		t.Fatalf("ParseCSV failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Line != 2 || rows[1].Line != 4 || len(rows[0].Providers) != 2 {
+		t.Fatalf("Unexpected rows: %+v", rows)
+	}
+
+	// One bad row keeps every row from being scheduled
+	bad := append(append([]Row{}, rows...),
+		Row{Line: 5, Content: "", Providers: names{"mastodon"}, ScheduleAt: "2026-02-01 10:00"},
+		Row{Line: 6, Content: "x", Providers: names{"facebook"}, ScheduleAt: "2026-03-05 10:00", Timezone: "Mars/Olympus"})
+	result, err := Import(ctx, db, "alice", bad, false, now)
+	if !errors.Is(err, ErrInvalid) || len(result.Errors) != 4 {
+		t.Fatalf("Expected 4 row errors, got %v %+v", err, result)
+	}
+	if result.Errors[0].Line != 5 || result.Errors[0].Field != "schedule_at" {
+		t.Errorf("Unexpected first error: %+v", result.Errors[0])
+	}
+	var count int64
+	db.Model(&database.ScheduledJob{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("Expected nothing to be scheduled, got %d jobs", count)
+	}
+
+	result, err = Import(ctx, db, "alice", rows, true, now)
+	if err != nil || result.Posts != 3 || len(result.Jobs) != 0 {
+		t.Fatalf("Unexpected dry run: %v %+v", err, result)
+	}
+
+	result, err = Import(ctx, db, "alice", rows, false, now)
+	if err != nil || len(result.Jobs) != 3 {
+		t.Fatalf("Import failed: %v %+v", err, result)
+	}
+	job := result.Jobs[0]
+	if !job.ScheduledAt.Equal(time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)) || job.Tags.String() != "launch,promo" || job.PayloadData != "Hello, world" {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+}
+
+func TestImport_RespectsPostingLimits(t *testing.T) {
+	db := setup(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var facebook database.Provider
+	db.Where("name = ?", "facebook").First(&facebook)
+	if _, err := limits.Set(ctx, db, "alice", facebook.ID, limits.Input{MinGapMinutes: 60}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&database.ScheduledJob{JobType: database.JobTypePublishPost, UserID: "alice", ProviderID: facebook.ID,
+		ScheduledAt: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), Status: database.JobStatusPending})
+
+	// Rows crowding scheduled posts fail already in a dry run
+	rows := []Row{{Line: 2, Content: "Early", Providers: names{"facebook"}, ScheduleAt: "2026-03-02T09:30:00Z"}}
+	result, err := Import(ctx, db, "alice", rows, true, now)
+	if !errors.Is(err, ErrInvalid) || len(result.Errors) != 1 || result.Errors[0].Line != 2 || result.Errors[0].Field != "schedule_at" {
+		t.Fatalf("Expected the row to break the posting limit, got %v %+v", err, result)
+	}
+
+	// Rows crowding each other fail when they are scheduled
+	rows = []Row{
+		{Line: 2, Content: "First", Providers: names{"facebook", "instagram"}, ScheduleAt: "2026-03-03T09:00:00Z"},
+		{Line: 3, Content: "Second", Providers: names{"facebook"}, ScheduleAt: "2026-03-03T09:20:00Z"},
+	}
+	result, err = Import(ctx, db, "alice", rows, false, now)
+	if !errors.Is(err, ErrInvalid) || len(result.Errors) != 1 || result.Errors[0].Line != 3 || len(result.Jobs) != 0 {
+		t.Fatalf("Expected the second row to break the posting limit, got %v %+v", err, result)
+	}
+	var count int64
+	db.Model(&database.ScheduledJob{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected nothing to be scheduled, got %d jobs", count)
+	}
+}
+
+func TestParseJSON_AcceptsProviderLists(t *testing.T) {
+	rows, err := ParseJSON(strings.NewReader(`[
+		{"content": "a", "providers": ["facebook"], "schedule_at": "2026-03-02 09:00", "tags": ["x"]},
+		{"content": "b", "providers": "facebook|instagram", "schedule_at": "2026-03-02 10:00", "tags": "y, z"}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseJSON failed: %v", err)
+	}
+	if len(rows) != 2 || rows[1].Line != 2 || len(rows[1].Providers) != 2 || rows[1].Tags.String() != "y,z" {
+		t.Errorf("Unexpected rows: %+v", rows)
+	}
+	if _, err := Parse("xlsx", strings.NewReader("")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected an unsupported format to be rejected, got %v", err)
+	}
+	if _, err := ParseCSV(strings.NewReader("content,schedule_at\n")); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a missing column to be rejected, got %v", err)
+	}
+}
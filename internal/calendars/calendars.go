@@ -0,0 +1,298 @@
+// Package calendars exports the publishing schedule as iCalendar events and
+// imports external calendars, such as holidays or launches, whose events are
+// shown next to it on the calendar.
+package calendars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/ical"
+	"gorm.io/gorm"
+)
+
+// DefaultColor is used for calendars imported without a color
+const DefaultColor = "#6b7280"
+
+const (
+	// FeedPast and FeedFuture bound the posts included in the feed
+	FeedPast   = 90 * 24 * time.Hour
+	FeedFuture = 365 * 24 * time.Hour
+	// ImportHorizon is how far ahead recurring events are expanded
+	ImportHorizon = 2 * 365 * 24 * time.Hour
+
+	// eventDuration is the length of a post in the feed
+	eventDuration = 15 * time.Minute
+	// summaryLength is the number of characters of content in a summary
+	summaryLength = 60
+	// maxCalendarSize limits the size of an imported calendar
+	maxCalendarSize = 5 << 20
+)
+
+var (
+	// ErrNotFound is returned when the calendar does not exist
+	ErrNotFound = errors.New("calendar not found")
+	// ErrInvalid wraps validation errors of an import
+	ErrInvalid = errors.New("invalid calendar")
+	// ErrFetch wraps errors fetching a calendar from its URL
+	ErrFetch = errors.New("failed to fetch calendar")
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ImportInput describes an external calendar. Without a file to import, its
+// events are fetched from URL.
+type ImportInput struct {
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Color string `json:"color"`
+}
+
+// Occurrence is an external event with the calendar it belongs to
+type Occurrence struct {
+	database.ExternalEvent
+	CalendarName string `json:"calendar_name"`
+	Color        string `json:"color"`
+}
+
+// Schedule returns the publishing jobs and published posts of userID in
+// [from, to) as events, ordered by time
+func Schedule(db *gorm.DB, userID string, from, to time.Time) ([]ical.Event, error) {
+	var jobs []database.ScheduledJob
+	if err := db.Preload("Provider").
+		Where("user_id = ? AND job_type = ? AND scheduled_at >= ? AND scheduled_at < ? AND status <> ?",
+			userID, database.JobTypePublishPost, from, to, database.JobStatusCompleted).
+		Order("scheduled_at, id").Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scheduled jobs: %w", err)
+	}
+	var posts []database.Post
+	if err := db.Preload("Provider").
+		Where("user_id = ? AND status = ? AND published_at >= ? AND published_at < ?",
+			userID, database.PostStatusPublished, from, to).
+		Order("published_at, id").Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load posts: %w", err)
+	}
+
+	events := make([]ical.Event, 0, len(jobs)+len(posts))
+	for _, job := range jobs {
+		status := "TENTATIVE"
+		if job.Status == database.JobStatusFailed {
+			status = "CANCELLED"
+		}
+		events = append(events, newEvent(fmt.Sprintf("job-%d@socgo", job.ID), job.Provider.Name, job.Status, job.PayloadData, job.Tags, job.ScheduledAt, status))
+	}
+	for _, post := range posts {
+		events = append(events, newEvent(fmt.Sprintf("post-%d@socgo", post.ID), post.Provider.Name, post.Status, post.Content, post.Tags, *post.PublishedAt, "CONFIRMED"))
+	}
+	return events, nil
+}
+
+func newEvent(uid, provider, status, content string, tags database.Tags, at time.Time, eventStatus string) ical.Event {
+	if provider == "" {
+		provider = "no provider"
+	}
+	summary := strings.Join(strings.Fields(content), " ")
+	if runes := []rune(summary); len(runes) > summaryLength {
+		summary = string(runes[:summaryLength-1]) + "…"
+	}
+	return ical.Event{
+		UID:         uid,
+		Summary:     fmt.Sprintf("[%s] %s: %s", provider, status, summary),
+		Description: content + "\n\nProvider: " + provider + "\nStatus: " + status,
+		Status:      eventStatus,
+		Categories:  tags,
+		Start:       at,
+		End:         at.Add(eventDuration),
+	}
+}
+
+// List returns the external calendars of userID ordered by name
+func List(db *gorm.DB, userID string) ([]database.ExternalCalendar, error) {
+	var calendars []database.ExternalCalendar
+	err := db.Where("user_id = ?", userID).Order("name, id").Find(&calendars).Error
+	return calendars, err
+}
+
+// Get returns the external calendar id of userID
+func Get(db *gorm.DB, userID string, id uint) (*database.ExternalCalendar, error) {
+	var calendar database.ExternalCalendar
+	result := db.Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&calendar)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &calendar, nil
+}
+
+// Import adds an external calendar with the events of body, or when body is
+// nil with the events fetched from in.URL with client
+func Import(ctx context.Context, db *gorm.DB, userID string, in ImportInput, body io.Reader, client *http.Client, now time.Time) (*database.ExternalCalendar, error) {
+	calendar := &database.ExternalCalendar{UserID: userID}
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	calendarURL := strings.TrimSpace(in.URL)
+	if calendarURL != "" {
+		if parsed, err := url.Parse(calendarURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("%w: url must be an http or https URL", ErrInvalid)
+		}
+	} else if body == nil {
+		return nil, fmt.Errorf("%w: a file or url is required", ErrInvalid)
+	}
+	color := strings.TrimSpace(in.Color)
+	if color == "" {
+		color = DefaultColor
+	}
+	if !colorPattern.MatchString(color) {
+		return nil, fmt.Errorf("%w: color must be a hex color such as %s", ErrInvalid, DefaultColor)
+	}
+	calendar.Name = name
+	calendar.URL = calendarURL
+	calendar.Color = strings.ToLower(color)
+
+	events, err := load(ctx, calendarURL, body, client, now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		calendar.LastSyncedAt = &now
+		if err := tx.Create(calendar).Error; err != nil {
+			return err
+		}
+		if err := replaceEvents(tx, calendar.ID, events); err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionCalendarImported, TargetType: audit.TargetCalendar, TargetID: calendar.ID, After: calendar,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return calendar, nil
+}
+
+// Sync fetches the events of the calendar id from its URL again, replacing
+// the stored ones
+func Sync(ctx context.Context, db *gorm.DB, userID string, id uint, client *http.Client, now time.Time) (*database.ExternalCalendar, error) {
+	calendar, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if calendar.URL == "" {
+		return nil, fmt.Errorf("%w: the calendar was imported from a file", ErrInvalid)
+	}
+
+	events, err := load(ctx, calendar.URL, nil, client, now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		calendar.LastSyncedAt = &now
+		if err := tx.Save(calendar).Error; err != nil {
+			return err
+		}
+		if err := replaceEvents(tx, calendar.ID, events); err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionCalendarSynced, TargetType: audit.TargetCalendar, TargetID: calendar.ID, After: calendar,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return calendar, nil
+}
+
+// Delete removes the calendar id with its events
+func Delete(ctx context.Context, db *gorm.DB, userID string, id uint) error {
+	calendar, err := Get(db, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", id).Delete(&database.ExternalEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(calendar).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionCalendarDeleted, TargetType: audit.TargetCalendar, TargetID: id, Before: calendar,
+		})
+	})
+}
+
+// Events returns the external events of userID overlapping [from, to),
+// ordered by start
+func Events(db *gorm.DB, userID string, from, to time.Time) ([]Occurrence, error) {
+	var occurrences []Occurrence
+	err := db.Table("external_events").
+		Select("external_events.*, external_calendars.name AS calendar_name, external_calendars.color AS color").
+		Joins("JOIN external_calendars ON external_calendars.id = external_events.calendar_id").
+		Where("external_calendars.user_id = ? AND external_events.starts_at < ? AND external_events.ends_at > ?", userID, to, from).
+		Order("external_events.starts_at, external_events.id").
+		Scan(&occurrences).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load external events: %w", err)
+	}
+	return occurrences, nil
+}
+
+// load reads the events of body, or fetches them from calendarURL
+func load(ctx context.Context, calendarURL string, body io.Reader, client *http.Client, now time.Time) ([]ical.Event, error) {
+	if body == nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, calendarURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		req.Header.Set("Accept", "text/calendar")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFetch, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%w: %s", ErrFetch, resp.Status)
+		}
+		body = resp.Body
+	}
+
+	events, err := ical.Parse(io.LimitReader(body, maxCalendarSize), now.Add(ImportHorizon))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, strings.TrimPrefix(err.Error(), ical.ErrInvalid.Error()+": "))
+	}
+	return events, nil
+}
+
+func replaceEvents(tx *gorm.DB, calendarID uint, events []ical.Event) error {
+	if err := tx.Where("calendar_id = ?", calendarID).Delete(&database.ExternalEvent{}).Error; err != nil {
+		return err
+	}
+	rows := make([]database.ExternalEvent, len(events))
+	for i, e := range events {
+		rows[i] = database.ExternalEvent{
+			CalendarID: calendarID, UID: e.UID, Summary: e.Summary, StartsAt: e.Start, EndsAt: e.End, AllDay: e.AllDay,
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, 100).Error
+}
@@ -0,0 +1,147 @@
+package calendars
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	t.Helper()
+	manager := database.NewTestManager(t)
+	t.Cleanup(func() { manager.Close() })
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	return db
+}
+
+func holidays(summary string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:h1\r\nSUMMARY:" + summary +
+		"\r\nDTSTART;VALUE=DATE:20260501\r\nDTEND;VALUE=DATE:20260504\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+}
+
+func TestSchedule_JobsAndPublishedPosts(t *testing.T) {
+	db := setup(t)
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "alice", IsActive: true}
+	db.Create(&provider)
+
+	publishedAt := now.Add(-time.Hour)
+	db.Create(&database.Post{Content: "Already out", UserID: "alice", ProviderID: provider.ID, Status: database.PostStatusPublished, PublishedAt: &publishedAt})
+	db.Create(&database.Post{Content: "Just a draft", UserID: "alice", Status: database.PostStatusDraft})
+	db.Create(&database.ScheduledJob{JobType: database.JobTypePublishPost, PayloadData: strings.Repeat("Long post ", 20), UserID: "alice",
+		ProviderID: provider.ID, ScheduledAt: now.Add(time.Hour), Status: database.JobStatusPending, Tags: database.Tags{"launch"}})
+	db.Create(&database.ScheduledJob{JobType: database.JobTypePollFeed, PayloadData: "1", UserID: "alice", ScheduledAt: now.Add(time.Hour), Status: database.JobStatusPending})
+
+	events, err := Schedule(db, "alice", now.Add(-FeedPast), now.Add(FeedFuture))
+	if err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected the job and the published post, got %+v", events)
+	}
+	job := events[0]
+	if job.UID != "job-1@socgo" || job.Status != "TENTATIVE" || !strings.HasPrefix(job.Summary, "[facebook] pending: Long post") ||
+		len([]rune(job.Summary)) > len("[facebook] pending: ")+summaryLength || job.Categories[0] != "launch" {
+		t.Errorf("Unexpected job event: %+v", job)
+	}
+	if events[1].UID != "post-1@socgo" || events[1].Status != "CONFIRMED" || !events[1].Start.Equal(publishedAt) {
+		t.Errorf("Unexpected post event: %+v", events[1])
+	}
+}
+
+func TestImport_FileAndURL(t *testing.T) {
+	db := setup(t)
+	ctx := context.Background()
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	calendar, err := Import(ctx, db, "alice", ImportInput{Name: "Holidays"}, strings.NewReader(holidays("May weekend")), nil, now)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if calendar.Color != DefaultColor || calendar.LastSyncedAt == nil {
+		t.Errorf("Unexpected calendar: %+v", calendar)
+	}
+
+	events, err := Events(db, "alice", time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Summary != "May weekend" || events[0].CalendarName != "Holidays" || !events[0].AllDay {
+		t.Fatalf("Expected the overlapping all-day event, got %+v", events)
+	}
+
+	summary := "Launch"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, holidays(summary))
+	}))
+	defer server.Close()
+
+	remote, err := Import(ctx, db, "alice", ImportInput{Name: "Launches", URL: server.URL, Color: "#FF0000"}, nil, server.Client(), now)
+	if err != nil {
+		t.Fatalf("Import from URL failed: %v", err)
+	}
+	summary = "Launch moved"
+	if _, err := Sync(ctx, db, "alice", remote.ID, server.Client(), now); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	var synced []database.ExternalEvent
+	db.Where("calendar_id = ?", remote.ID).Find(&synced)
+	if len(synced) != 1 || synced[0].Summary != "Launch moved" {
+		t.Errorf("Expected the synced event to replace the old one, got %+v", synced)
+	}
+	if _, err := Sync(ctx, db, "alice", calendar.ID, server.Client(), now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a file calendar not to sync, got %v", err)
+	}
+
+	if err := Delete(ctx, db, "alice", remote.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	var left int64
+	db.Model(&database.ExternalEvent{}).Where("calendar_id = ?", remote.ID).Count(&left)
+	if left != 0 {
+		t.Errorf("Expected the events to be deleted with the calendar, got %d", left)
+	}
+}
+
+func TestImport_Validates(t *testing.T) {
+	db := setup(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		in   ImportInput
+		body string
+	}{
+		{ImportInput{}, holidays("x")},
+		{ImportInput{Name: "No source"}, ""},
+		{ImportInput{Name: "Local", URL: "file:///etc/hosts"}, ""},
+		{ImportInput{Name: "Color", Color: "red"}, holidays("x")},
+		{ImportInput{Name: "Not ICS"}, "<html></html>"},
+	} {
+		var err error
+		if tc.body == "" {
+			_, err = Import(ctx, db, "alice", tc.in, nil, http.DefaultClient, time.Now())
+		} else {
+			_, err = Import(ctx, db, "alice", tc.in, strings.NewReader(tc.body), nil, time.Now())
+		}
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for %+v, got %v", tc.in, err)
+		}
+	}
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	if _, err := Import(ctx, db, "alice", ImportInput{Name: "Gone", URL: server.URL}, nil, server.Client(), time.Now()); !errors.Is(err, ErrFetch) {
+		t.Errorf("Expected ErrFetch, got %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+// Package campaigns manages campaigns, which group the posts and scheduled
+// jobs of a launch, and reports per-campaign statistics.
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// DateLayout is the format of campaign start and end dates
+const DateLayout = "2006-01-02"
+
+// DefaultColor is used for campaigns created without a color
+const DefaultColor = "#3b82f6"
+
+var (
+	// ErrNotFound is returned when the campaign does not exist
+	ErrNotFound = errors.New("campaign not found")
+	// ErrInvalid wraps validation errors of an Input
+	ErrInvalid = errors.New("invalid campaign")
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Input holds the editable fields of a campaign. Dates use DateLayout and
+// may be empty.
+type Input struct {
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	StartsOn string `json:"starts_on"`
+	EndsOn   string `json:"ends_on"`
+}
+
+// Stats counts the posts and jobs of a campaign
+type Stats struct {
+	database.Campaign
+	// Posts counts every post of the campaign, Drafts those still in review
+	Posts     int64 `json:"posts"`
+	Drafts    int64 `json:"drafts"`
+	Published int64 `json:"published"`
+	// Scheduled counts pending jobs, Failed failed ones
+	Scheduled int64 `json:"scheduled"`
+	Failed    int64 `json:"failed"`
+	// Providers counts the distinct providers the campaign posts to
+	Providers int64 `json:"providers"`
+}
+
+// List returns the campaigns of userID, the most recent start date first and
+// undated ones last
+func List(db *gorm.DB, userID string) ([]database.Campaign, error) {
+	var campaigns []database.Campaign
+	err := db.Where("user_id = ?", userID).
+		Order("starts_on IS NULL, starts_on DESC, name").
+		Find(&campaigns).Error
+	return campaigns, err
+}
+
+// Get returns the campaign id of userID
+func Get(db *gorm.DB, userID string, id uint) (*database.Campaign, error) {
+	var campaign database.Campaign
+	result := db.Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&campaign)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &campaign, nil
+}
+
+// Create adds a campaign for userID
+func Create(ctx context.Context, db *gorm.DB, userID string, in Input) (*database.Campaign, error) {
+	campaign := &database.Campaign{UserID: userID}
+	if err := apply(db, campaign, in); err != nil {
+		return nil, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(campaign).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionCampaignCreated, TargetType: audit.TargetCampaign, TargetID: campaign.ID, After: campaign,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// Update replaces the fields of the campaign id with in
+func Update(ctx context.Context, db *gorm.DB, userID string, id uint, in Input) (*database.Campaign, error) {
+	campaign, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *campaign
+	if err := apply(db, campaign, in); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(campaign).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionCampaignUpdated, TargetType: audit.TargetCampaign, TargetID: campaign.ID, Before: before, After: campaign,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// Delete removes the campaign id with its link rules. Its posts, jobs and
+// short links are kept without a campaign.
+func Delete(ctx context.Context, db *gorm.DB, userID string, id uint) error {
+	campaign, err := Get(db, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&database.Post{}, &database.ScheduledJob{}, &database.ShortLink{}} {
+			if err := tx.Unscoped().Model(model).Where("campaign_id = ?", id).Update("campaign_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("campaign_id = ?", id).Delete(&database.LinkRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(campaign).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionCampaignDeleted, TargetType: audit.TargetCampaign, TargetID: id, Before: campaign,
+		})
+	})
+}
+
+// AllStats returns the statistics of every campaign of userID, in List order
+func AllStats(db *gorm.DB, userID string) ([]Stats, error) {
+	campaigns, err := List(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectStats(db, userID, campaigns)
+}
+
+// GetStats returns the statistics of the campaign id
+func GetStats(db *gorm.DB, userID string, id uint) (*Stats, error) {
+	campaign, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := collectStats(db, userID, []database.Campaign{*campaign})
+	if err != nil {
+		return nil, err
+	}
+	return &stats[0], nil
+}
+
+// collectStats counts the posts and jobs of campaigns with one grouped query
+// per table
+func collectStats(db *gorm.DB, userID string, campaigns []database.Campaign) ([]Stats, error) {
+	stats := make([]Stats, len(campaigns))
+	index := make(map[uint]*Stats, len(campaigns))
+	ids := make([]uint, len(campaigns))
+	for i, campaign := range campaigns {
+		stats[i].Campaign = campaign
+		index[campaign.ID] = &stats[i]
+		ids[i] = campaign.ID
+	}
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	type row struct {
+		CampaignID uint
+		Status     string
+		Count      int64
+	}
+	var posts []row
+	if err := db.Model(&database.Post{}).
+		Select("campaign_id, status, COUNT(*) AS count").
+		Where("user_id = ? AND campaign_id IN ?", userID, ids).
+		Group("campaign_id, status").
+		Scan(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count campaign posts: %w", err)
+	}
+	for _, r := range posts {
+		s := index[r.CampaignID]
+		s.Posts += r.Count
+		switch r.Status {
+		case database.PostStatusPublished:
+			s.Published += r.Count
+		case database.PostStatusDraft, database.PostStatusInReview, database.PostStatusApproved:
+			s.Drafts += r.Count
+		}
+	}
+
+	var jobs []row
+	if err := db.Model(&database.ScheduledJob{}).
+		Select("campaign_id, status, COUNT(*) AS count").
+		Where("user_id = ? AND campaign_id IN ? AND status IN ?", userID, ids,
+			[]string{database.JobStatusPending, database.JobStatusFailed}).
+		Group("campaign_id, status").
+		Scan(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to count campaign jobs: %w", err)
+	}
+	for _, r := range jobs {
+		if r.Status == database.JobStatusPending {
+			index[r.CampaignID].Scheduled += r.Count
+		} else {
+			index[r.CampaignID].Failed += r.Count
+		}
+	}
+
+	// Providers of posts and of pending jobs that have no post yet
+	var providers []struct {
+		CampaignID uint
+		Count      int64
+	}
+	if err := db.Raw(`SELECT campaign_id, COUNT(DISTINCT provider_id) AS count FROM (
+			SELECT campaign_id, provider_id FROM posts WHERE user_id = ? AND campaign_id IN ? AND provider_id <> 0 AND deleted_at IS NULL
+			UNION
+			SELECT campaign_id, provider_id FROM scheduled_jobs WHERE user_id = ? AND campaign_id IN ? AND status = ?
+		) AS targets GROUP BY campaign_id`,
+		userID, ids, userID, ids, database.JobStatusPending).
+		Scan(&providers).Error; err != nil {
+		return nil, fmt.Errorf("failed to count campaign providers: %w", err)
+	}
+	for _, r := range providers {
+		index[r.CampaignID].Providers = r.Count
+	}
+
+	return stats, nil
+}
+
+// apply validates in and copies it onto campaign
+func apply(db *gorm.DB, campaign *database.Campaign, in Input) error {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+
+	color := strings.TrimSpace(in.Color)
+	if color == "" {
+		color = DefaultColor
+	}
+	if !colorPattern.MatchString(color) {
+		return fmt.Errorf("%w: color must be a hex color such as %s", ErrInvalid, DefaultColor)
+	}
+
+	startsOn, err := parseDate(in.StartsOn)
+	if err != nil {
+		return fmt.Errorf("%w: invalid starts_on date, use YYYY-MM-DD", ErrInvalid)
+	}
+	endsOn, err := parseDate(in.EndsOn)
+	if err != nil {
+		return fmt.Errorf("%w: invalid ends_on date, use YYYY-MM-DD", ErrInvalid)
+	}
+	if startsOn != nil && endsOn != nil && endsOn.Before(*startsOn) {
+		return fmt.Errorf("%w: ends_on is before starts_on", ErrInvalid)
+	}
+
+	var taken int64
+	if err := db.Model(&database.Campaign{}).
+		Where("user_id = ? AND name = ? AND id <> ?", campaign.UserID, name, campaign.ID).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("%w: a campaign named %q already exists", ErrInvalid, name)
+	}
+
+	campaign.Name = name
+	campaign.Color = strings.ToLower(color)
+	campaign.StartsOn = startsOn
+	campaign.EndsOn = endsOn
+	return nil
+}
+
+func parseDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
@@ -0,0 +1,103 @@
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	t.Helper()
+	manager := database.NewTestManager(t)
+	t.Cleanup(func() { manager.Close() })
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	return db
+}
+
+func TestCreate_Validates(t *testing.T) {
+	db := setup(t)
+	ctx := context.Background()
+
+	campaign, err := Create(ctx, db, "alice", Input{Name: " Launch ", StartsOn: "2026-03-01", EndsOn: "2026-03-31"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if campaign.Name != "Launch" || campaign.Color != DefaultColor || campaign.StartsOn == nil || campaign.StartsOn.Format(DateLayout) != "2026-03-01" {
+		t.Errorf("Unexpected campaign: %+v", campaign)
+	}
+
+	for name, in := range map[string]Input{
+		"no name":        {Color: "#ffffff"},
+		"taken name":     {Name: "Launch"},
+		"bad color":      {Name: "Sale", Color: "red"},
+		"bad date":       {Name: "Sale", StartsOn: "March"},
+		"ends too early": {Name: "Sale", StartsOn: "2026-03-02", EndsOn: "2026-03-01"},
+	} {
+		if _, err := Create(ctx, db, "alice", in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+
+	// Names are unique per user only, and a campaign keeps its own name
+	if _, err := Create(ctx, db, "bob", Input{Name: "Launch"}); err != nil {
+		t.Errorf("Expected another user to reuse the name: %v", err)
+	}
+	if _, err := Update(ctx, db, "alice", campaign.ID, Input{Name: "Launch", Color: "#FF0000"}); err != nil {
+		t.Errorf("Update failed: %v", err)
+	}
+	if _, err := Update(ctx, db, "bob", campaign.ID, Input{Name: "Mine"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another user's campaign, got %v", err)
+	}
+}
+
+func TestStats_CountsPostsAndJobs(t *testing.T) {
+	db := setup(t)
+	ctx := context.Background()
+	launch, _ := Create(ctx, db, "alice", Input{Name: "Launch"})
+	empty, _ := Create(ctx, db, "alice", Input{Name: "Empty"})
+
+	now := time.Now()
+	db.Create(&database.Post{Content: "a", UserID: "alice", ProviderID: 1, Status: database.PostStatusPublished, PublishedAt: &now, CampaignID: &launch.ID})
+	db.Create(&database.Post{Content: "b", UserID: "alice", ProviderID: 2, Status: database.PostStatusPublished, PublishedAt: &now, CampaignID: &launch.ID})
+	db.Create(&database.Post{Content: "c", UserID: "alice", Status: database.PostStatusDraft, CampaignID: &launch.ID})
+	db.Create(&database.Post{Content: "d", UserID: "alice", ProviderID: 1, Status: database.PostStatusPublished})
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "e", UserID: "alice", ProviderID: 3, ScheduledAt: now.Add(time.Hour), Status: database.JobStatusPending, CampaignID: &launch.ID})
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "f", UserID: "alice", ProviderID: 1, ScheduledAt: now, Status: database.JobStatusFailed, CampaignID: &launch.ID})
+
+	stats, err := AllStats(db, "alice")
+	if err != nil {
+		t.Fatalf("AllStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 campaigns, got %+v", stats)
+	}
+	got, err := GetStats(db, "alice", launch.ID)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if got.Posts != 3 || got.Published != 2 || got.Drafts != 1 || got.Scheduled != 1 || got.Failed != 1 || got.Providers != 3 {
+		t.Errorf("Unexpected stats: %+v", got)
+	}
+
+	// Deleting a campaign keeps its posts and jobs
+	if err := Delete(ctx, db, "alice", launch.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	var linked int64
+	db.Model(&database.Post{}).Where("campaign_id IS NOT NULL").Count(&linked)
+	var posts int64
+	db.Model(&database.Post{}).Count(&posts)
+	if linked != 0 || posts != 4 {
+		t.Errorf("Expected 4 posts without a campaign, got %d of %d linked", linked, posts)
+	}
+	if stats, _ := GetStats(db, "alice", empty.ID); stats == nil || stats.Posts != 0 {
+		t.Errorf("Unexpected stats for an empty campaign: %+v", stats)
+	}
+}
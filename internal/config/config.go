@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,6 +18,9 @@ type Config struct {
 	DB        DBConfig        `yaml:"db"`
 	Database  DatabaseConfig  `yaml:"database"`
 	Providers ProvidersConfig `yaml:"providers"`
+	Backup    BackupConfig    `yaml:"backup"`
+	Trash     TrashConfig     `yaml:"trash"`
+	Retention RetentionConfig `yaml:"retention"`
 }
 
 type ServerConfig struct {
@@ -23,16 +29,81 @@ type ServerConfig struct {
 	BaseURL string `yaml:"base_url"`
 }
 
+// DBConfig holds the PostgreSQL connection used when Database.Driver is "postgres"
 type DBConfig struct {
 	Host     string `yaml:"host"`
 	Port     string `yaml:"port"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
 	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
 }
 
+// DatabaseConfig selects the storage backend: "sqlite" (one file per tenant
+// in DataDir, the default) or "postgres" (one schema per tenant, see DB).
+// At most MaxOpenDBs tenant databases stay open; ones unused for IdleTimeout
+// are closed. MaxConnsPerDB limits the connections of each tenant database;
+// with Postgres, MaxConnsPerDB and then MaxOpenDBs are lowered so all tenant
+// pools together stay within MaxServerConns. BusyTimeout and ForeignKeys
+// apply to SQLite.
 type DatabaseConfig struct {
-	DataDir string `yaml:"data_dir"`
+	Driver         string        `yaml:"driver"`
+	DataDir        string        `yaml:"data_dir"`
+	MaxOpenDBs     int           `yaml:"max_open_dbs"`
+	IdleTimeout    time.Duration `yaml:"idle_timeout"`
+	BusyTimeout    time.Duration `yaml:"busy_timeout"`
+	MaxConnsPerDB  int           `yaml:"max_conns_per_db"`
+	MaxServerConns int           `yaml:"max_server_conns"`
+	ForeignKeys    bool          `yaml:"foreign_keys"`
+}
+
+// TrashConfig controls the trash of deleted posts and providers. Items are
+// purged Retention after they were deleted; a negative Retention keeps them
+// until purged by hand.
+type TrashConfig struct {
+	Retention time.Duration `yaml:"retention"`
+}
+
+// RetentionConfig sets how long finished records are kept in the tenant
+// databases. Expired records are pruned every Interval; with a rule's
+// Archive they are first appended to JSON Lines files in ArchiveDir.
+type RetentionConfig struct {
+	Interval   time.Duration `yaml:"interval"`
+	ArchiveDir string        `yaml:"archive_dir"`
+	// Jobs covers completed and failed scheduled jobs
+	Jobs RetentionRule `yaml:"jobs"`
+	// APITokens covers revoked API tokens
+	APITokens RetentionRule `yaml:"api_tokens"`
+	// WebhookDeliveries covers delivered and failed webhook deliveries
+	WebhookDeliveries RetentionRule `yaml:"webhook_deliveries"`
+}
+
+// RetentionRule keeps records for MaxAge after they were finished; zero keeps
+// them forever
+type RetentionRule struct {
+	MaxAge  time.Duration `yaml:"max_age"`
+	Archive bool          `yaml:"archive"`
+}
+
+// BackupConfig controls online backups of the SQLite databases. Backups run
+// every Interval (never when zero) into Dir, keeping the newest Retain per
+// database, and are also uploaded when S3.Bucket is set.
+type BackupConfig struct {
+	Dir      string        `yaml:"dir"`
+	Interval time.Duration `yaml:"interval"`
+	Retain   int           `yaml:"retain"`
+	S3       S3Config      `yaml:"s3"`
+}
+
+// S3Config describes an S3-compatible bucket, e.g. AWS S3 or MinIO. Objects
+// are addressed path-style: <endpoint>/<bucket>/<prefix><key>.
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Prefix    string `yaml:"prefix"`
 }
 
 type ProvidersConfig struct {
@@ -90,7 +161,7 @@ func loadFromEnv() *Config {
 		}
 	}
 
-	return &Config{
+	config := &Config{
 		Server: ServerConfig{
 			Port:    getEnv("SERVER_PORT", "8080"),
 			Host:    getEnv("SERVER_HOST", "localhost"),
@@ -102,9 +173,40 @@ func loadFromEnv() *Config {
 			User:     getEnv("DB_USER", "postgres"),
 			Password: getEnv("DB_PASSWORD", "password"),
 			Name:     getEnv("DB_NAME", "socgo"),
+			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		Database: DatabaseConfig{
-			DataDir: getEnv("DATABASE_DATA_DIR", "./data"),
+			Driver:         getEnv("DATABASE_DRIVER", "sqlite"),
+			DataDir:        getEnv("DATABASE_DATA_DIR", "./data"),
+			MaxOpenDBs:     getEnvInt("DATABASE_MAX_OPEN_DBS", 0),
+			IdleTimeout:    getEnvDuration("DATABASE_IDLE_TIMEOUT", 0),
+			BusyTimeout:    getEnvDuration("DATABASE_BUSY_TIMEOUT", 0),
+			MaxConnsPerDB:  getEnvInt("DATABASE_MAX_CONNS_PER_DB", 0),
+			MaxServerConns: getEnvInt("DATABASE_MAX_SERVER_CONNS", 0),
+			ForeignKeys:    getEnv("DATABASE_FOREIGN_KEYS", "") == "true",
+		},
+		Backup: BackupConfig{
+			Dir:      getEnv("BACKUP_DIR", ""),
+			Interval: getEnvDuration("BACKUP_INTERVAL", 0),
+			Retain:   getEnvInt("BACKUP_RETAIN", 0),
+			S3: S3Config{
+				Endpoint:  getEnv("BACKUP_S3_ENDPOINT", ""),
+				Region:    getEnv("BACKUP_S3_REGION", ""),
+				Bucket:    getEnv("BACKUP_S3_BUCKET", ""),
+				AccessKey: getEnv("BACKUP_S3_ACCESS_KEY", ""),
+				SecretKey: getEnv("BACKUP_S3_SECRET_KEY", ""),
+				Prefix:    getEnv("BACKUP_S3_PREFIX", ""),
+			},
+		},
+		Trash: TrashConfig{
+			Retention: getEnvDuration("TRASH_RETENTION", 0),
+		},
+		Retention: RetentionConfig{
+			Interval:          getEnvDuration("RETENTION_INTERVAL", 0),
+			ArchiveDir:        getEnv("RETENTION_ARCHIVE_DIR", ""),
+			Jobs:              getEnvRetentionRule("RETENTION_JOBS"),
+			APITokens:         getEnvRetentionRule("RETENTION_API_TOKENS"),
+			WebhookDeliveries: getEnvRetentionRule("RETENTION_WEBHOOK_DELIVERIES"),
 		},
 		Providers: ProvidersConfig{
 			TikTok:    []ProviderInstance{},
@@ -112,6 +214,8 @@ func loadFromEnv() *Config {
 			Facebook:  []ProviderInstance{},
 		},
 	}
+	setDefaults(config)
+	return config
 }
 
 func setDefaults(config *Config) {
@@ -127,6 +231,49 @@ func setDefaults(config *Config) {
 	if config.Database.DataDir == "" {
 		config.Database.DataDir = "./data"
 	}
+	if config.Database.Driver == "" {
+		config.Database.Driver = "sqlite"
+	}
+	if config.Database.MaxOpenDBs <= 0 {
+		config.Database.MaxOpenDBs = 100
+	}
+	if config.Database.IdleTimeout <= 0 {
+		config.Database.IdleTimeout = 10 * time.Minute
+	}
+	if config.Database.BusyTimeout <= 0 {
+		config.Database.BusyTimeout = 5 * time.Second
+	}
+	if config.Database.MaxConnsPerDB <= 0 {
+		config.Database.MaxConnsPerDB = 4
+	}
+	if config.Database.MaxServerConns <= 0 {
+		// Below Postgres' default max_connections of 100
+		config.Database.MaxServerConns = 80
+	}
+	if config.DB.SSLMode == "" {
+		config.DB.SSLMode = "disable"
+	}
+	if config.Trash.Retention == 0 {
+		config.Trash.Retention = 30 * 24 * time.Hour
+	}
+	if config.Retention.Interval <= 0 {
+		config.Retention.Interval = 24 * time.Hour
+	}
+	if config.Retention.ArchiveDir == "" {
+		config.Retention.ArchiveDir = filepath.Join(config.Database.DataDir, "archive")
+	}
+	if config.Backup.Dir == "" {
+		config.Backup.Dir = filepath.Join(config.Database.DataDir, "backups")
+	}
+	if config.Backup.Retain <= 0 {
+		config.Backup.Retain = 7
+	}
+	if config.Backup.S3.Endpoint == "" {
+		config.Backup.S3.Endpoint = "https://s3.amazonaws.com"
+	}
+	if config.Backup.S3.Region == "" {
+		config.Backup.S3.Region = "us-east-1"
+	}
 }
 
 func loadEnvFile() {
@@ -167,10 +314,58 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvRetentionRule reads a retention rule from <prefix>_MAX_AGE and
+// <prefix>_ARCHIVE
+func getEnvRetentionRule(prefix string) RetentionRule {
+	return RetentionRule{
+		MaxAge:  getEnvDuration(prefix+"_MAX_AGE", 0),
+		Archive: getEnv(prefix+"_ARCHIVE", "") == "true",
+	}
+}
+
 func (c *Config) GetServerAddr() string {
 	return fmt.Sprintf("%s:%s", c.Server.Host, c.Server.Port)
 }
 
+// DSN returns the PostgreSQL connection string in keyword/value format
+func (c DBConfig) DSN() string {
+	params := []struct{ key, value string }{
+		{"host", c.Host},
+		{"port", c.Port},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.Name},
+		{"sslmode", c.SSLMode},
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+		value := strings.ReplaceAll(p.value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `'`, `\'`)
+		parts = append(parts, fmt.Sprintf("%s='%s'", p.key, value))
+	}
+	return strings.Join(parts, " ")
+}
+
 // GetProviderConfig returns the provider instance by name and type
 func (c *Config) GetProviderConfig(providerType, name string) (*ProviderInstance, error) {
 	var instances []ProviderInstance
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -86,3 +88,87 @@ func TestLoadFromEnvWithServerBaseURLPrecedence(t *testing.T) {
 		t.Errorf("Expected BaseURL %s, got %s", expected, config.Server.BaseURL)
 	}
 }
+
+func TestDBConfigDSN(t *testing.T) {
+	cfg := DBConfig{Host: "db", Port: "5432", User: "socgo", Password: "it's secret", Name: "socgo", SSLMode: "disable"}
+
+	expected := `host='db' port='5432' user='socgo' password='it\'s secret' dbname='socgo' sslmode='disable'`
+	if dsn := cfg.DSN(); dsn != expected {
+		t.Errorf("Expected %s, got %s", expected, dsn)
+	}
+}
+
+func TestLoadFromEnvBackupSettings(t *testing.T) {
+	os.Setenv("DATABASE_DATA_DIR", "/srv/socgo")
+	os.Setenv("BACKUP_INTERVAL", "6h")
+	os.Setenv("BACKUP_S3_BUCKET", "socgo-backups")
+	defer os.Unsetenv("DATABASE_DATA_DIR")
+	defer os.Unsetenv("BACKUP_INTERVAL")
+	defer os.Unsetenv("BACKUP_S3_BUCKET")
+
+	config := loadFromEnv()
+
+	if config.Backup.Dir != "/srv/socgo/backups" {
+		t.Errorf("Expected backups next to the data directory, got %s", config.Backup.Dir)
+	}
+	if config.Backup.Interval != 6*time.Hour {
+		t.Errorf("Expected 6h interval, got %s", config.Backup.Interval)
+	}
+	if config.Backup.Retain != 7 {
+		t.Errorf("Expected default retention of 7, got %d", config.Backup.Retain)
+	}
+	if config.Backup.S3.Bucket != "socgo-backups" || config.Backup.S3.Region != "us-east-1" {
+		t.Errorf("Unexpected S3 settings: %+v", config.Backup.S3)
+	}
+}
+
+func TestLoadFromEnvDatabasePoolSettings(t *testing.T) {
+	os.Setenv("DATABASE_MAX_OPEN_DBS", "500")
+	os.Setenv("DATABASE_FOREIGN_KEYS", "true")
+	defer os.Unsetenv("DATABASE_MAX_OPEN_DBS")
+	defer os.Unsetenv("DATABASE_FOREIGN_KEYS")
+
+	config := loadFromEnv()
+
+	if config.Database.MaxOpenDBs != 500 || !config.Database.ForeignKeys {
+		t.Errorf("Unexpected pool settings: %+v", config.Database)
+	}
+	if config.Database.IdleTimeout != 10*time.Minute || config.Database.BusyTimeout != 5*time.Second || config.Database.MaxConnsPerDB != 4 {
+		t.Errorf("Expected default timeouts and connection limit, got %+v", config.Database)
+	}
+}
+
+func TestLoadFromEnvTrashRetention(t *testing.T) {
+	if config := loadFromEnv(); config.Trash.Retention != 30*24*time.Hour {
+		t.Errorf("Expected a default retention of 30 days, got %s", config.Trash.Retention)
+	}
+
+	os.Setenv("TRASH_RETENTION", "-1s")
+	defer os.Unsetenv("TRASH_RETENTION")
+	if config := loadFromEnv(); config.Trash.Retention >= 0 {
+		t.Errorf("Expected a negative retention to be kept, got %s", config.Trash.Retention)
+	}
+}
+
+func TestLoadFromEnvRetentionRules(t *testing.T) {
+	os.Setenv("RETENTION_JOBS_MAX_AGE", "2160h")
+	os.Setenv("RETENTION_JOBS_ARCHIVE", "true")
+	os.Setenv("RETENTION_API_TOKENS_MAX_AGE", "720h")
+	defer os.Unsetenv("RETENTION_JOBS_MAX_AGE")
+	defer os.Unsetenv("RETENTION_JOBS_ARCHIVE")
+	defer os.Unsetenv("RETENTION_API_TOKENS_MAX_AGE")
+
+	config := loadFromEnv()
+	if config.Retention.Jobs != (RetentionRule{MaxAge: 2160 * time.Hour, Archive: true}) {
+		t.Errorf("Unexpected jobs rule: %+v", config.Retention.Jobs)
+	}
+	if config.Retention.APITokens != (RetentionRule{MaxAge: 720 * time.Hour}) {
+		t.Errorf("Unexpected API tokens rule: %+v", config.Retention.APITokens)
+	}
+	if config.Retention.WebhookDeliveries.MaxAge != 0 {
+		t.Errorf("Expected webhook deliveries to be kept, got %+v", config.Retention.WebhookDeliveries)
+	}
+	if config.Retention.Interval != 24*time.Hour || config.Retention.ArchiveDir != filepath.Join("data", "archive") {
+		t.Errorf("Unexpected defaults: %+v", config.Retention)
+	}
+}
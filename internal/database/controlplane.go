@@ -0,0 +1,481 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The control plane is the part of the registry database that lets the
+// server find its way to tenant databases without opening them: the known
+// users, which database holds each API token, short link and link-in-bio
+// page, when each database next has work for the scheduler and since when it
+// holds items in the trash. Tenant databases keep it up to date through GORM
+// callbacks registered when they are opened.
+
+var (
+	// ErrUnknownToken is returned by LookupToken for hashes without a route
+	ErrUnknownToken = errors.New("unknown API token")
+	// ErrUnknownLink is returned by LookupLink for codes without a route
+	ErrUnknownLink = errors.New("unknown short link")
+	// ErrUnknownBio is returned by LookupBio for slugs without a route
+	ErrUnknownBio = errors.New("unknown link-in-bio page")
+	// ErrRouteConflict is returned when an API token hash or short link code
+	// is already routed to another database
+	ErrRouteConflict = errors.New("already routed to another database")
+)
+
+// Tables whose changes update the control plane
+const (
+	tokensTable     = "api_tokens"
+	jobsTable       = "scheduled_jobs"
+	deliveriesTable = "webhook_deliveries"
+	postsTable      = "posts"
+	providersTable  = "providers"
+	linksTable      = "short_links"
+	bioTable        = "bio_pages"
+)
+
+// LookupToken returns the route of an API token hash
+func (m *Manager) LookupToken(hash string) (*TokenRoute, error) {
+	registry, err := m.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var route TokenRoute
+	result := registry.Where("hash = ?", hash).Limit(1).Find(&route)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrUnknownToken
+	}
+	return &route, nil
+}
+
+// LookupLink returns the route of a short link code
+func (m *Manager) LookupLink(code string) (*LinkRoute, error) {
+	registry, err := m.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var route LinkRoute
+	result := registry.Where("code = ?", code).Limit(1).Find(&route)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrUnknownLink
+	}
+	return &route, nil
+}
+
+// LookupBio returns the route of a link-in-bio page slug
+func (m *Manager) LookupBio(slug string) (*BioRoute, error) {
+	registry, err := m.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var route BioRoute
+	result := registry.Where("slug = ?", slug).Limit(1).Find(&route)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrUnknownBio
+	}
+	return &route, nil
+}
+
+// DueTenants returns the keys of tenant databases with a job or webhook
+// delivery due at now, whether they are open or not
+func (m *Manager) DueTenants(now time.Time) ([]string, error) {
+	registry, err := m.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	err = registry.Model(&TenantSchedule{}).
+		Where("next_due_at IS NOT NULL AND next_due_at <= ?", now).
+		Order("next_due_at").
+		Pluck("tenant_key", &keys).Error
+	return keys, err
+}
+
+// RefreshSchedule recomputes when the tenant database db, stored under key,
+// next has work due
+func (m *Manager) RefreshSchedule(key string, db *gorm.DB) error {
+	registry, err := m.GetRegistryDB()
+	if err != nil {
+		return err
+	}
+	return refreshSchedule(registry, key, db)
+}
+
+// ExpiredTrash returns the keys of tenant databases holding items deleted
+// before cutoff
+func (m *Manager) ExpiredTrash(cutoff time.Time) ([]string, error) {
+	registry, err := m.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	err = registry.Model(&TenantSchedule{}).
+		Where("trash_since IS NOT NULL AND trash_since < ?", cutoff).
+		Order("trash_since").
+		Pluck("tenant_key", &keys).Error
+	return keys, err
+}
+
+// RefreshTrash records since when the tenant database db, stored under key,
+// holds items in the trash
+func (m *Manager) RefreshTrash(key string, db *gorm.DB) error {
+	registry, err := m.GetRegistryDB()
+	if err != nil {
+		return err
+	}
+	return refreshTrash(registry, key, db)
+}
+
+// SyncControlPlane rebuilds the control plane from every tenant database in
+// storage. It covers databases written before the control plane existed or
+// replaced behind its back, such as by a restore.
+func (m *Manager) SyncControlPlane() error {
+	keys, err := m.TenantKeys()
+	if err != nil {
+		return err
+	}
+
+	var problems []error
+	for _, key := range keys {
+		if err := m.SyncTenant(key); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// SyncTenant rebuilds the token, link and bio routes, schedule and trash marker of
+// one tenant database
+func (m *Manager) SyncTenant(key string) error {
+	registry, err := m.GetRegistryDB()
+	if err != nil {
+		return err
+	}
+	db, err := m.GetDB(key)
+	if err != nil {
+		return err
+	}
+	if err := errors.Join(
+		refreshSchedule(registry, key, db),
+		refreshTrash(registry, key, db),
+		syncTokenRoutes(registry, key, db),
+		syncLinkRoutes(registry, key, db),
+		syncBioRoutes(registry, key, db),
+	); err != nil {
+		return fmt.Errorf("tenant %s: %w", key, err)
+	}
+	return nil
+}
+
+// registerUser records key as a user unless it is a workspace database. The
+// caller must hold the write lock.
+func (m *Manager) registerUser(key string) error {
+	if IsWorkspaceKey(key) {
+		return nil
+	}
+	registry, err := m.registryDB()
+	if err != nil {
+		return err
+	}
+	return registry.Clauses(clause.OnConflict{DoNothing: true}).Create(&User{ID: key}).Error
+}
+
+// registerControlPlaneHooks keeps the control plane in step with changes to
+// the tokens, short links, link-in-bio pages, jobs, webhook deliveries and
+// trash of the tenant database db. The control plane is updated once a
+// single statement has committed; within a transaction it is updated before
+// the transaction commits and repaired by the next sync if it rolls back.
+func (m *Manager) registerControlPlaneHooks(key string, db *gorm.DB) error {
+	// Creating posts and providers never changes the trash, so only updates
+	// and deletes look at them. Updates of tokens and short links only record
+	// their use and never change their hashes or codes.
+	hook := func(trash, routes bool) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			if tx.Error != nil || tx.Statement.Schema == nil {
+				return
+			}
+			table := tx.Statement.Schema.Table
+			var sync func(registry, conn *gorm.DB) error
+			switch {
+			case routes && table == tokensTable:
+				sync = func(registry, conn *gorm.DB) error { return syncTokenRoutes(registry, key, conn) }
+			case routes && table == linksTable:
+				sync = func(registry, conn *gorm.DB) error { return syncLinkRoutes(registry, key, conn) }
+			case table == bioTable:
+				sync = func(registry, conn *gorm.DB) error { return syncBioRoutes(registry, key, conn) }
+			case table == jobsTable || table == deliveriesTable:
+				sync = func(registry, conn *gorm.DB) error { return refreshSchedule(registry, key, conn) }
+			case trash && (table == postsTable || table == providersTable):
+				sync = func(registry, conn *gorm.DB) error { return refreshTrash(registry, key, conn) }
+			default:
+				return
+			}
+
+			registry, err := m.GetRegistryDB()
+			if err != nil {
+				log.Printf("Error updating control plane for %s: %v", key, err)
+				return
+			}
+			// Read through the statement's connection to see its own transaction
+			if err := sync(registry, tx.Session(&gorm.Session{NewDB: true})); err != nil {
+				log.Printf("Error updating control plane for %s: %v", key, err)
+			}
+		}
+	}
+
+	// A token or short link routed to another database cannot be created
+	claim := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement.Schema == nil {
+			return
+		}
+		var model interface{}
+		var column, field string
+		switch tx.Statement.Schema.Table {
+		case tokensTable:
+			model, column, field = &TokenRoute{}, "hash", "Hash"
+		case linksTable:
+			model, column, field = &LinkRoute{}, "code", "Code"
+		default:
+			return
+		}
+
+		registry, err := m.GetRegistryDB()
+		if err != nil {
+			tx.AddError(err)
+			return
+		}
+		taken, err := takenRoutes(registry, model, column, key, fieldValues(tx.Statement, field))
+		if err == nil {
+			err = conflictError(taken)
+		}
+		if err != nil {
+			tx.AddError(err)
+		}
+	}
+
+	const committed = "gorm:commit_or_rollback_transaction"
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("socgo:claim_routes", claim),
+		callbacks.Create().After(committed).Register("socgo:control_plane", hook(false, true)),
+		callbacks.Update().After(committed).Register("socgo:control_plane", hook(true, false)),
+		callbacks.Delete().After(committed).Register("socgo:control_plane", hook(true, true)),
+	)
+}
+
+// fieldValues returns the non-empty string values of the field name of the
+// records a statement writes
+func fieldValues(stmt *gorm.Statement, name string) []string {
+	field := stmt.Schema.LookUpField(name)
+	if field == nil {
+		return nil
+	}
+
+	var values []string
+	collect := func(rv reflect.Value) {
+		if value, zero := field.ValueOf(stmt.Context, rv); !zero {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	switch rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			collect(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		collect(rv)
+	}
+	return values
+}
+
+// takenRoutes returns which of values, token hashes or link codes stored in
+// column of model, the registry routes to a database other than key
+func takenRoutes(registry *gorm.DB, model interface{}, column, key string, values []string) (map[string]bool, error) {
+	taken := make(map[string]bool)
+	for start := 0; start < len(values); start += 500 {
+		end := min(start+500, len(values))
+		var found []string
+		err := registry.Model(model).
+			Where(column+" IN ? AND tenant_key <> ?", values[start:end], key).
+			Pluck(column, &found).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range found {
+			taken[value] = true
+		}
+	}
+	return taken, nil
+}
+
+// conflictError reports the routes left with another database
+func conflictError(taken map[string]bool) error {
+	if len(taken) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d routes", ErrRouteConflict, len(taken))
+}
+
+// refreshSchedule stores the earliest pending job or webhook delivery of db
+func refreshSchedule(registry *gorm.DB, key string, db *gorm.DB) error {
+	var next *time.Time
+
+	var job ScheduledJob
+	result := db.Where("status = ?", JobStatusPending).Order("scheduled_at").Limit(1).Find(&job)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		next = &job.ScheduledAt
+	}
+
+	var delivery WebhookDelivery
+	result = db.Where("status = ?", DeliveryStatusPending).Order("next_attempt_at").Limit(1).Find(&delivery)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 && (next == nil || delivery.NextAttemptAt.Before(*next)) {
+		next = &delivery.NextAttemptAt
+	}
+
+	return registry.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"next_due_at", "updated_at"}),
+	}).Create(&TenantSchedule{TenantKey: key, NextDueAt: next, UpdatedAt: time.Now()}).Error
+}
+
+// refreshTrash stores when the oldest post or provider in the trash of db was
+// deleted
+func refreshTrash(registry *gorm.DB, key string, db *gorm.DB) error {
+	var since *time.Time
+	for _, model := range []interface{}{&Post{}, &Provider{}} {
+		var deleted []time.Time
+		err := db.Unscoped().Model(model).
+			Where("deleted_at IS NOT NULL").
+			Order("deleted_at").Limit(1).
+			Pluck("deleted_at", &deleted).Error
+		if err != nil {
+			return err
+		}
+		if len(deleted) > 0 && (since == nil || deleted[0].Before(*since)) {
+			since = &deleted[0]
+		}
+	}
+
+	return registry.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"trash_since", "updated_at"}),
+	}).Create(&TenantSchedule{TenantKey: key, TrashSince: since, UpdatedAt: time.Now()}).Error
+}
+
+// syncTokenRoutes replaces the routes of key with the live tokens of db. A
+// hash already routed to another database stays with it and is reported as
+// ErrRouteConflict.
+func syncTokenRoutes(registry *gorm.DB, key string, db *gorm.DB) error {
+	var tokens []APIToken
+	if err := db.Find(&tokens).Error; err != nil {
+		return err
+	}
+	hashes := make([]string, len(tokens))
+	for i, token := range tokens {
+		hashes[i] = token.Hash
+	}
+	taken, err := takenRoutes(registry, &TokenRoute{}, "hash", key, hashes)
+	if err != nil {
+		return err
+	}
+
+	err = registry.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_key = ?", key).Delete(&TokenRoute{}).Error; err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			route := TokenRoute{Hash: token.Hash, TenantKey: key, UserID: token.UserID, CreatedAt: token.CreatedAt}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&route).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return conflictError(taken)
+}
+
+// syncLinkRoutes replaces the routes of key with the short links of db. A
+// code already routed to another database stays with it and is reported as
+// ErrRouteConflict.
+func syncLinkRoutes(registry *gorm.DB, key string, db *gorm.DB) error {
+	var codes []string
+	if err := db.Model(&ShortLink{}).Pluck("code", &codes).Error; err != nil {
+		return err
+	}
+	taken, err := takenRoutes(registry, &LinkRoute{}, "code", key, codes)
+	if err != nil {
+		return err
+	}
+
+	err = registry.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_key = ?", key).Delete(&LinkRoute{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		routes := make([]LinkRoute, len(codes))
+		for i, code := range codes {
+			routes[i] = LinkRoute{Code: code, TenantKey: key, CreatedAt: time.Now()}
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(routes, 500).Error
+	})
+	if err != nil {
+		return err
+	}
+	return conflictError(taken)
+}
+
+// syncBioRoutes replaces the route of key with the link-in-bio page of db. A
+// slug already routed to another database stays with it.
+func syncBioRoutes(registry *gorm.DB, key string, db *gorm.DB) error {
+	var slugs []string
+	if err := db.Model(&BioPage{}).Pluck("slug", &slugs).Error; err != nil {
+		return err
+	}
+
+	return registry.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_key = ?", key).Delete(&BioRoute{}).Error; err != nil {
+			return err
+		}
+		for _, slug := range slugs {
+			route := BioRoute{Slug: slug, TenantKey: key, CreatedAt: time.Now()}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&route).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestControlPlane_TracksDueWork(t *testing.T) {
+	manager := newPoolTestManager(t, PoolOptions{MaxOpen: 1, BusyTimeout: time.Second})
+	now := time.Now()
+
+	alice, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	job := ScheduledJob{JobType: "publish_post", UserID: "alice", ScheduledAt: now.Add(-time.Minute), Status: JobStatusPending}
+	alice.Create(&job)
+	alice.Create(&ScheduledJob{JobType: "publish_post", UserID: "alice", ScheduledAt: now.Add(time.Hour), Status: JobStatusPending})
+
+	// Once drained, opening bob closes alice, but her due job is still known
+	manager.dbs["alice"].lastUsed.Store(now.Add(-2 * drainPeriod).UnixNano())
+	if _, err := manager.GetDB("bob"); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	if open := manager.GetAllUserDatabases(); open["alice"] != nil {
+		t.Fatalf("Expected alice to be evicted, open: %v", open)
+	}
+
+	due, err := manager.DueTenants(now)
+	if err != nil {
+		t.Fatalf("DueTenants failed: %v", err)
+	}
+	if len(due) != 1 || due[0] != "alice" {
+		t.Fatalf("Expected alice to have work due, got %v", due)
+	}
+
+	// Finishing the job moves the next due time to the later job
+	alice, _ = manager.GetDB("alice")
+	alice.Model(&job).Update("status", JobStatusCompleted)
+	if due, _ := manager.DueTenants(now); len(due) != 0 {
+		t.Errorf("Expected nothing due now, got %v", due)
+	}
+	if due, _ := manager.DueTenants(now.Add(2 * time.Hour)); len(due) != 1 {
+		t.Errorf("Expected alice's later job to be due, got %v", due)
+	}
+
+	registry, _ := manager.GetRegistryDB()
+	var users []User
+	registry.Order("id").Find(&users)
+	if len(users) != 2 || users[0].ID != "alice" || users[1].ID != "bob" {
+		t.Errorf("Expected alice and bob to be registered, got %+v", users)
+	}
+}
+
+func TestControlPlane_RoutesTokens(t *testing.T) {
+	manager := newPoolTestManager(t, DefaultPoolOptions())
+
+	alice, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	token := APIToken{Hash: "hash-alice", UserID: "alice"}
+	alice.Create(&token)
+
+	route, err := manager.LookupToken("hash-alice")
+	if err != nil {
+		t.Fatalf("LookupToken failed: %v", err)
+	}
+	if route.TenantKey != "alice" || route.UserID != "alice" {
+		t.Errorf("Unexpected route: %+v", route)
+	}
+
+	alice.Delete(&token)
+	if _, err := manager.LookupToken("hash-alice"); !errors.Is(err, ErrUnknownToken) {
+		t.Errorf("Expected a revoked token to lose its route, got %v", err)
+	}
+}
+
+func TestControlPlane_RejectsRoutesOfAnotherDatabase(t *testing.T) {
+	manager := newPoolTestManager(t, DefaultPoolOptions())
+	alice, _ := manager.GetDB("alice")
+	bob, _ := manager.GetDB("bob")
+	alice.Create(&APIToken{Hash: "hash-alice", UserID: "alice"})
+	alice.Create(&ShortLink{Code: "abc123", URL: "https://example.com", UserID: "alice"})
+
+	if err := bob.Create(&APIToken{Hash: "hash-alice", UserID: "bob"}).Error; !errors.Is(err, ErrRouteConflict) {
+		t.Errorf("Expected ErrRouteConflict for a token, got %v", err)
+	}
+	links := []ShortLink{{Code: "xyz789", URL: "https://example.com", UserID: "bob"}, {Code: "abc123", URL: "https://example.com", UserID: "bob"}}
+	if err := bob.Create(&links).Error; !errors.Is(err, ErrRouteConflict) {
+		t.Errorf("Expected ErrRouteConflict for a link, got %v", err)
+	}
+	var count int64
+	bob.Model(&ShortLink{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected the links to be rolled back, got %d", count)
+	}
+
+	// A database written behind the registry's back keeps its other routes
+	for _, hash := range []string{"hash-alice", "hash-bob"} {
+		if err := bob.Exec("INSERT INTO api_tokens (hash, user_id, scope, created_at) VALUES (?, ?, ?, ?)",
+			hash, "bob", TokenScopeAPI, time.Now()).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := manager.SyncTenant("bob"); !errors.Is(err, ErrRouteConflict) {
+		t.Errorf("Expected SyncTenant to report the conflict, got %v", err)
+	}
+	if route, err := manager.LookupToken("hash-alice"); err != nil || route.TenantKey != "alice" {
+		t.Errorf("Expected alice to keep her token, got %+v, %v", route, err)
+	}
+	if route, err := manager.LookupToken("hash-bob"); err != nil || route.TenantKey != "bob" {
+		t.Errorf("Expected bob's token to be routed, got %+v, %v", route, err)
+	}
+}
+
+func TestSyncControlPlane_CoversExistingDatabases(t *testing.T) {
+	manager := newPoolTestManager(t, DefaultPoolOptions())
+	if _, err := manager.GetDB("carol"); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	manager.CloseAll()
+
+	// Write to carol's file directly, as a server without the control plane
+	// would have
+	direct, err := gorm.Open(sqlite.Open(manager.GetDBPath("carol")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	direct.Create(&APIToken{Hash: "hash-carol", UserID: "carol"})
+	direct.Create(&ScheduledJob{JobType: "publish_post", UserID: "carol", ScheduledAt: time.Now().Add(-time.Minute), Status: JobStatusPending})
+	if sqlDB, err := direct.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	if _, err := manager.LookupToken("hash-carol"); !errors.Is(err, ErrUnknownToken) {
+		t.Fatalf("Expected no route before syncing, got %v", err)
+	}
+	if err := manager.SyncControlPlane(); err != nil {
+		t.Fatalf("SyncControlPlane failed: %v", err)
+	}
+	if route, err := manager.LookupToken("hash-carol"); err != nil || route.TenantKey != "carol" {
+		t.Errorf("Expected carol's token to be routed, got %+v, %v", route, err)
+	}
+	if due, _ := manager.DueTenants(time.Now()); len(due) != 1 || due[0] != "carol" {
+		t.Errorf("Expected carol to have work due, got %v", due)
+	}
+}
+
+func TestControlPlane_TracksTrash(t *testing.T) {
+	manager := newPoolTestManager(t, DefaultPoolOptions())
+	now := time.Now()
+
+	alice, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	post := Post{Content: "Hello", UserID: "alice"}
+	alice.Create(&post)
+	if expired, _ := manager.ExpiredTrash(now.Add(time.Hour)); len(expired) != 0 {
+		t.Fatalf("Expected an empty trash, got %v", expired)
+	}
+
+	alice.Delete(&post)
+	if expired, _ := manager.ExpiredTrash(now.Add(-time.Hour)); len(expired) != 0 {
+		t.Errorf("Expected nothing deleted an hour ago, got %v", expired)
+	}
+	expired, err := manager.ExpiredTrash(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ExpiredTrash failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != "alice" {
+		t.Fatalf("Expected alice's trash to expire, got %v", expired)
+	}
+
+	// Restoring the post empties the trash
+	alice.Unscoped().Model(&post).Update("deleted_at", nil)
+	if expired, _ := manager.ExpiredTrash(now.Add(time.Hour)); len(expired) != 0 {
+		t.Errorf("Expected an empty trash after restoring, got %v", expired)
+	}
+}
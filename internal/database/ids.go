@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IDList is a list of record IDs, such as the providers a post template
+// targets. It is stored as one comma separated column and serialized as a
+// JSON array.
+type IDList []uint
+
+// ParseIDList reads a comma separated list of IDs, skipping empty entries
+func ParseIDList(list string) (IDList, error) {
+	ids := IDList{}
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", part)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// String returns the IDs as a comma separated list
+func (l IDList) String() string {
+	parts := make([]string, len(l))
+	for i, id := range l {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Value stores the IDs as a comma separated list
+func (l IDList) Value() (driver.Value, error) {
+	return l.String(), nil
+}
+
+// Scan reads a comma separated list of IDs
+func (l *IDList) Scan(value interface{}) error {
+	var list string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		list = v
+	case []byte:
+		list = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into an ID list", value)
+	}
+	ids, err := ParseIDList(list)
+	if err != nil {
+		return err
+	}
+	*l = ids
+	return nil
+}
+
+// MarshalJSON writes the IDs as an array, never null
+func (l IDList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]uint(l))
+}
+
+// UnmarshalJSON accepts an array of IDs or a comma separated string
+func (l *IDList) UnmarshalJSON(data []byte) error {
+	var list string
+	if err := json.Unmarshal(data, &list); err == nil {
+		ids, err := ParseIDList(list)
+		if err != nil {
+			return err
+		}
+		*l = ids
+		return nil
+	}
+	var ids []uint
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return err
+	}
+	*l = ids
+	return nil
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The models below freeze the schema that AutoMigrate produced before SQL
+// migrations were introduced, matching tenant migration 003 and registry
+// migration 001. They are only used to baseline such databases and must not
+// change when the live models do.
+
+type legacyPost struct {
+	ID          uint   `gorm:"primaryKey"`
+	Content     string `gorm:"not null"`
+	Title       string
+	UserID      string `gorm:"not null;index"`
+	ProviderID  uint   `gorm:"index"`
+	Status      string `gorm:"default:'published';index"`
+	ReviewerID  string
+	ApprovedBy  string
+	ApprovedAt  *time.Time
+	PublishedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyPost) TableName() string { return "posts" }
+
+type legacyPostComment struct {
+	ID        uint   `gorm:"primaryKey"`
+	PostID    uint   `gorm:"not null;index"`
+	UserID    string `gorm:"not null"`
+	Body      string `gorm:"type:text;not null"`
+	CreatedAt time.Time
+}
+
+func (legacyPostComment) TableName() string { return "post_comments" }
+
+type legacyProvider struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"not null;uniqueIndex"`
+	Type      string `gorm:"not null"`
+	Config    string `gorm:"type:text"`
+	UserID    string `gorm:"not null;index"`
+	IsActive  bool   `gorm:"default:true"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyProvider) TableName() string { return "providers" }
+
+type legacyScheduledJob struct {
+	ID          uint      `gorm:"primaryKey"`
+	JobType     string    `gorm:"not null"`
+	PayloadData string    `gorm:"type:text"`
+	UserID      string    `gorm:"not null;index"`
+	ProviderID  uint      `gorm:"index"`
+	PostID      *uint     `gorm:"index"`
+	ScheduledAt time.Time `gorm:"not null;index"`
+	ExecutedAt  *time.Time
+	Status      string `gorm:"default:'pending'"`
+	ErrorMsg    string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (legacyScheduledJob) TableName() string { return "scheduled_jobs" }
+
+type legacyAPIToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	Hash      string `gorm:"not null;uniqueIndex;type:varchar(64)"`
+	UserID    string `gorm:"not null;index"`
+	CreatedAt time.Time
+	LastUsed  *time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyAPIToken) TableName() string { return "api_tokens" }
+
+type legacyAuditLog struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     string    `gorm:"not null;index"`
+	ActorID    string    `gorm:"not null;index"`
+	Action     string    `gorm:"not null;index"`
+	TargetType string    `gorm:"not null;index:idx_audit_logs_target"`
+	TargetID   uint      `gorm:"index:idx_audit_logs_target"`
+	Before     string    `gorm:"type:text"`
+	After      string    `gorm:"type:text"`
+	Source     string    `gorm:"not null"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (legacyAuditLog) TableName() string { return "audit_logs" }
+
+type legacyWebhookEndpoint struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"not null;index"`
+	URL       string `gorm:"not null"`
+	Secret    string `gorm:"not null"`
+	Events    string `gorm:"not null"`
+	IsActive  bool   `gorm:"default:true"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyWebhookEndpoint) TableName() string { return "webhook_endpoints" }
+
+type legacyWebhookDelivery struct {
+	ID            uint   `gorm:"primaryKey"`
+	EndpointID    uint   `gorm:"not null;index"`
+	Event         string `gorm:"not null"`
+	Payload       string `gorm:"type:text"`
+	Status        string `gorm:"default:'pending';index"`
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	ResponseCode  int
+	LastError     string
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (legacyWebhookDelivery) TableName() string { return "webhook_deliveries" }
+
+type legacyWorkspace struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	OwnerID   string `gorm:"not null;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (legacyWorkspace) TableName() string { return "workspaces" }
+
+type legacyWorkspaceMember struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user"`
+	UserID      string `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user;index"`
+	Role        string `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (legacyWorkspaceMember) TableName() string { return "workspace_members" }
+
+var legacyTenantModels = []interface{}{
+	&legacyPost{},
+	&legacyProvider{},
+	&legacyScheduledJob{},
+	&legacyAPIToken{},
+	&legacyPostComment{},
+	&legacyAuditLog{},
+	&legacyWebhookEndpoint{},
+	&legacyWebhookDelivery{},
+}
+
+var legacyRegistryModels = []interface{}{
+	&legacyWorkspace{},
+	&legacyWorkspaceMember{},
+}
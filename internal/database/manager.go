@@ -1,83 +1,400 @@
 package database
 
 import (
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/tkowalski/socgo/internal/config"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// RegistryDBName is the file holding data shared between tenants, such as
+// workspaces and their members
+const RegistryDBName = "_registry"
+
+// Supported storage backends
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// postgresMaxIdleConns is the most idle connections each tenant's Postgres
+// pool keeps; the open limit is PoolOptions.MaxConns
+const postgresMaxIdleConns = 2
+
+// Manager hands out one *gorm.DB per tenant. With SQLite each tenant has its
+// own file in dataDir; with Postgres each tenant has its own schema in a
+// shared database, selected through the connection's search_path. At most
+// pool.MaxOpen tenant databases stay open; see PoolOptions.
 type Manager struct {
-	dataDir string
-	dbs     map[string]*gorm.DB
-	mutex   sync.RWMutex
+	driver       string
+	dataDir      string
+	dsn          string
+	schemaPrefix string
+	dbs          map[string]*handle
+	registry     *gorm.DB
+	admin        *gorm.DB
+	schemas      map[string]bool
+	pool         PoolOptions
+	counters     poolCounters
+	janitorStop  chan struct{}
+	// skipMigrations leaves schemas as they are when databases are opened
+	skipMigrations bool
+	mutex          sync.RWMutex
 }
 
 func NewManager(dataDir string) *Manager {
 	return &Manager{
+		driver:  DriverSQLite,
 		dataDir: dataDir,
-		dbs:     make(map[string]*gorm.DB),
+		dbs:     make(map[string]*handle),
+		pool:    DefaultPoolOptions(),
+	}
+}
+
+// NewPostgresManager creates a manager storing every tenant in its own schema
+// of the database at dsn. Schema names start with schemaPrefix, which lets
+// several installations (or test runs) share a database.
+func NewPostgresManager(dsn, schemaPrefix string) *Manager {
+	return &Manager{
+		driver:       DriverPostgres,
+		dsn:          dsn,
+		schemaPrefix: schemaPrefix,
+		dbs:          make(map[string]*handle),
+		schemas:      make(map[string]bool),
+		pool:         DefaultPoolOptions(),
+	}
+}
+
+// NewManagerFromConfig creates a manager for the configured storage backend
+func NewManagerFromConfig(cfg *config.Config) (*Manager, error) {
+	var m *Manager
+	switch cfg.Database.Driver {
+	case "", DriverSQLite:
+		m = NewManager(cfg.Database.DataDir)
+	case DriverPostgres:
+		m = NewPostgresManager(cfg.DB.DSN(), "")
+		m.dataDir = cfg.Database.DataDir
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Database.Driver)
 	}
+
+	m.SetPoolOptions(PoolOptions{
+		MaxOpen:        cfg.Database.MaxOpenDBs,
+		IdleTimeout:    cfg.Database.IdleTimeout,
+		BusyTimeout:    cfg.Database.BusyTimeout,
+		MaxConns:       cfg.Database.MaxConnsPerDB,
+		MaxServerConns: cfg.Database.MaxServerConns,
+		ForeignKeys:    cfg.Database.ForeignKeys,
+	})
+	return m, nil
+}
+
+// MediaDir returns the directory holding the media files of a user or
+// workspace. Media stay on disk with either storage backend.
+func (m *Manager) MediaDir(userID string) string {
+	return filepath.Join(m.dataDir, "media", userID)
+}
+
+// Driver returns the storage backend of the manager
+func (m *Manager) Driver() string {
+	return m.driver
 }
 
+// GetDB returns the database of userID, opening it if needed. A database
+// closed by the pool is reopened transparently.
 func (m *Manager) GetDB(userID string) (*gorm.DB, error) {
+	h, err := m.getHandle(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	return h.db, nil
+}
+
+// Acquire returns the database of userID like GetDB and keeps it open until
+// release is called. Work running many queries over a long time, such as a
+// scheduler run or an export, holds its database this way.
+func (m *Manager) Acquire(userID string) (db *gorm.DB, release func(), err error) {
+	h, err := m.getHandle(userID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var once sync.Once
+	return h.db, func() {
+		once.Do(func() {
+			h.touch()
+			h.refs.Add(-1)
+		})
+	}, nil
+}
+
+// getHandle returns the open database of userID, opening it if needed, and
+// with hold takes a reference on it
+func (m *Manager) getHandle(userID string, hold bool) (*handle, error) {
+	if !IsValidKey(userID) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidKey, userID)
+	}
 	m.mutex.RLock()
-	if db, exists := m.dbs[userID]; exists {
+	if h, exists := m.dbs[userID]; exists {
+		h.touch()
+		if hold {
+			h.refs.Add(1)
+		}
 		m.mutex.RUnlock()
-		return db, nil
+		m.counters.hits.Add(1)
+		return h, nil
 	}
 	m.mutex.RUnlock()
 
-	return m.createOrOpenDB(userID)
+	return m.createOrOpenDB(userID, hold)
 }
 
-func (m *Manager) createOrOpenDB(userID string) (*gorm.DB, error) {
+func (m *Manager) createOrOpenDB(userID string, hold bool) (*handle, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	if db, exists := m.dbs[userID]; exists {
-		return db, nil
-	}
-
-	if err := os.MkdirAll(m.dataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	if h, exists := m.dbs[userID]; exists {
+		h.touch()
+		if hold {
+			h.refs.Add(1)
+		}
+		return h, nil
 	}
 
-	dbPath := filepath.Join(m.dataDir, fmt.Sprintf("%s.db", userID))
+	m.makeRoom()
 
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := m.open(userID, m.schemaName(userID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database for user %s: %w", userID, err)
 	}
 
-	if err := m.runMigrations(db); err != nil {
+	if err := m.runMigrations(db, TenantMigrations); err != nil {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
 		return nil, fmt.Errorf("failed to run migrations for user %s: %w", userID, err)
 	}
 
-	m.dbs[userID] = db
+	if !m.skipMigrations {
+		if err := m.registerControlPlaneHooks(userID, db); err != nil {
+			return nil, fmt.Errorf("failed to register control plane hooks for user %s: %w", userID, err)
+		}
+		if err := m.registerUser(userID); err != nil {
+			log.Printf("Error registering user %s: %v", userID, err)
+		}
+	}
+
+	h := newHandle(db)
+	if hold {
+		h.refs.Add(1)
+	}
+	m.dbs[userID] = h
+	m.counters.opens.Add(1)
+	m.startJanitor()
+	return h, nil
+}
+
+// runMigrations applies the pending migrations of set unless the manager was
+// told to leave schemas alone. SQLite table rebuilds would trip foreign key
+// checks, so they are switched off on the connection running the migrations.
+func (m *Manager) runMigrations(db *gorm.DB, set *MigrationSet) error {
+	if m.skipMigrations {
+		return nil
+	}
+	if m.driver == DriverPostgres || !m.pool.ForeignKeys {
+		_, err := MigrateUp(db, set)
+		return err
+	}
+
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		_, err := MigrateUp(conn, set)
+		if restoreErr := conn.Exec("PRAGMA foreign_keys = ON").Error; err == nil {
+			err = restoreErr
+		}
+		return err
+	})
+}
+
+// SetAutoMigrate controls whether databases are migrated when opened. The
+// migrate command turns it off so it can inspect and revert schemas.
+func (m *Manager) SetAutoMigrate(enabled bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.skipMigrations = !enabled
+}
+
+// WorkspaceDBKey returns the GetDB key of a workspace's shared database
+func WorkspaceDBKey(workspaceID uint) string {
+	return fmt.Sprintf("workspace_%d", workspaceID)
+}
+
+// ErrInvalidKey is returned by GetDB and Acquire for keys that cannot name a
+// tenant database
+var ErrInvalidKey = errors.New("invalid database key")
+
+// keyPattern matches the keys of user and workspace databases. Keys become
+// file names, so they may not hold path separators or start with a dot, and
+// may not start with the underscore of the registry's name.
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.@-]{0,127}$`)
+
+// IsValidKey reports whether key can name a user's or workspace's database
+func IsValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// IsWorkspaceKey reports whether the GetDB key names a workspace's database
+// rather than a user's
+func IsWorkspaceKey(key string) bool {
+	return strings.HasPrefix(key, "workspace_")
+}
+
+// GetRegistryDB returns the database shared by all tenants
+func (m *Manager) GetRegistryDB() (*gorm.DB, error) {
+	m.mutex.RLock()
+	registry := m.registry
+	m.mutex.RUnlock()
+	if registry != nil {
+		return registry, nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.registryDB()
+}
+
+// registryDB opens the registry database on first use. The caller must hold
+// the write lock.
+func (m *Manager) registryDB() (*gorm.DB, error) {
+	if m.registry != nil {
+		return m.registry, nil
+	}
+
+	db, err := m.open(RegistryDBName, m.schemaPrefix+"registry")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry database: %w", err)
+	}
+
+	if err := m.runMigrations(db, RegistryMigrations); err != nil {
+		return nil, fmt.Errorf("failed to run registry migrations: %w", err)
+	}
+
+	m.registry = db
+	return db, nil
+}
+
+// open connects to the SQLite file named after key or, with Postgres, to
+// schema, creating it if needed. The caller must hold the write lock.
+func (m *Manager) open(key, schema string) (*gorm.DB, error) {
+	if m.driver != DriverPostgres {
+		if err := os.MkdirAll(m.dataDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+		db, err := gorm.Open(sqlite.Open(m.sqliteDSN(m.GetDBPath(key), m.pool.ForeignKeys)), &gorm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		db.ConnPool = &utcPool{db.ConnPool}
+		db.Statement.ConnPool = db.ConnPool
+		if m.pool.MaxConns > 0 {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return nil, err
+			}
+			sqlDB.SetMaxOpenConns(m.pool.MaxConns)
+			sqlDB.SetMaxIdleConns(m.pool.MaxConns)
+		}
+		return db, nil
+	}
+
+	admin, err := m.adminDB()
+	if err != nil {
+		return nil, err
+	}
+	if err := admin.Exec(fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s"`, schema)).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema %s: %w", schema, err)
+	}
+	m.schemas[schema] = true
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(m.dsn, schema)), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if m.pool.MaxConns > 0 {
+		sqlDB.SetMaxOpenConns(m.pool.MaxConns)
+	}
+	sqlDB.SetMaxIdleConns(postgresMaxIdleConns)
+
 	return db, nil
 }
 
-func (m *Manager) runMigrations(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&Post{},
-		&Provider{},
-		&ScheduledJob{},
-		&APIToken{},
-	)
+var unsafeSchemaChars = regexp.MustCompile(`[^a-z0-9_]`)
+
+// schemaName maps a tenant key to its Postgres schema. Keys that are not
+// already valid identifiers get a hash suffix so distinct keys never collide.
+func (m *Manager) schemaName(key string) string {
+	name := unsafeSchemaChars.ReplaceAllString(strings.ToLower(key), "_")
+	if name != key {
+		name = fmt.Sprintf("%s_%x", name, sha256.Sum256([]byte(key)))[:len(name)+9]
+	}
+
+	schema := m.schemaPrefix + "tenant_" + name
+	if len(schema) > 63 {
+		schema = fmt.Sprintf("%s%x", schema[:54], sha256.Sum256([]byte(key)))[:63]
+	}
+	return schema
 }
 
+// withSearchPath adds search_path to a keyword/value or URL connection string
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
+
+// CloseDB closes the database of userID; RegistryDBName closes the registry
 func (m *Manager) CloseDB(userID string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	if db, exists := m.dbs[userID]; exists {
-		sqlDB, err := db.DB()
+	if userID == RegistryDBName {
+		if m.registry == nil {
+			return nil
+		}
+		sqlDB, err := m.registry.DB()
+		if err != nil {
+			return err
+		}
+		m.registry = nil
+		return sqlDB.Close()
+	}
+
+	if h, exists := m.dbs[userID]; exists {
+		sqlDB, err := h.db.DB()
 		if err != nil {
 			return err
 		}
@@ -96,8 +413,10 @@ func (m *Manager) CloseAll() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	for userID, db := range m.dbs {
-		sqlDB, err := db.DB()
+	m.stopJanitor()
+
+	for userID, h := range m.dbs {
+		sqlDB, err := h.db.DB()
 		if err != nil {
 			continue
 		}
@@ -109,6 +428,20 @@ func (m *Manager) CloseAll() error {
 		delete(m.dbs, userID)
 	}
 
+	if m.registry != nil {
+		if sqlDB, err := m.registry.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		m.registry = nil
+	}
+
+	if m.admin != nil {
+		if sqlDB, err := m.admin.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		m.admin = nil
+	}
+
 	return nil
 }
 
@@ -117,36 +450,180 @@ func (m *Manager) GetDBPath(userID string) string {
 }
 
 func (m *Manager) UserDBExists(userID string) bool {
+	if m.driver == DriverPostgres {
+		return m.schemaExists(m.schemaName(userID))
+	}
+
 	dbPath := m.GetDBPath(userID)
 	_, err := os.Stat(dbPath)
 	return err == nil
 }
 
-// GetAllUserDatabases returns all currently opened user databases
+// GetAllUserDatabases returns all currently opened user databases. Databases
+// closed by the pool are not included; use DueTenants to find work.
 func (m *Manager) GetAllUserDatabases() map[string]*gorm.DB {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
 	result := make(map[string]*gorm.DB)
-	for userID, db := range m.dbs {
-		result[userID] = db
+	for userID, h := range m.dbs {
+		result[userID] = h.db
 	}
 
 	return result
 }
 
-// NewTestManager creates a test database manager for testing
+// TenantKeys lists the GetDB keys of every tenant database in storage,
+// including ones not opened yet
+func (m *Manager) TenantKeys() ([]string, error) {
+	if m.driver == DriverPostgres {
+		m.mutex.Lock()
+		admin, err := m.adminDB()
+		m.mutex.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := m.schemaPrefix + "tenant_"
+		var schemas []string
+		if err := admin.Raw("SELECT schema_name FROM information_schema.schemata WHERE starts_with(schema_name, ?) ORDER BY schema_name", prefix).
+			Scan(&schemas).Error; err != nil {
+			return nil, err
+		}
+		// Schema names are valid keys themselves and map back to the same schema
+		keys := make([]string, 0, len(schemas))
+		for _, schema := range schemas {
+			keys = append(keys, strings.TrimPrefix(schema, prefix))
+		}
+		return keys, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(m.dataDir, "*.db"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(paths))
+	for _, path := range paths {
+		key := strings.TrimSuffix(filepath.Base(path), ".db")
+		if key != RegistryDBName {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// adminDB returns the connection used to manage schemas, opening it on first
+// use. The caller must hold the write lock.
+func (m *Manager) adminDB() (*gorm.DB, error) {
+	if m.admin == nil {
+		admin, err := gorm.Open(postgres.Open(m.dsn), &gorm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		// The admin connection only creates and lists schemas, under the
+		// manager's lock, and counts as one of MaxServerConns
+		sqlDB, err := admin.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		m.admin = admin
+	}
+	return m.admin, nil
+}
+
+func (m *Manager) schemaExists(schema string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	admin, err := m.adminDB()
+	if err != nil {
+		return false
+	}
+
+	var count int64
+	if err := admin.Raw("SELECT count(*) FROM information_schema.schemata WHERE schema_name = ?", schema).Scan(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// dropSchemas removes every schema opened by a Postgres manager
+func (m *Manager) dropSchemas() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.schemas) == 0 {
+		return nil
+	}
+	admin, err := m.adminDB()
+	if err != nil {
+		return err
+	}
+	for schema := range m.schemas {
+		if err := admin.Exec(fmt.Sprintf(`DROP SCHEMA IF EXISTS "%s" CASCADE`, schema)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestPostgresDSNEnv names the environment variable that makes NewTestManager
+// use Postgres, e.g. a database started with docker-compose.dev.yml
+const TestPostgresDSNEnv = "SOCGO_TEST_POSTGRES_DSN"
+
+var testSchemaCounter struct {
+	sync.Mutex
+	n int
+}
+
+// NewTestManager creates a test database manager for testing. When
+// SOCGO_TEST_POSTGRES_DSN is set the manager uses Postgres with schemas
+// unique to the test, which are dropped afterwards.
 func NewTestManager(t *testing.T) *Manager {
+	if dsn := os.Getenv(TestPostgresDSNEnv); dsn != "" {
+		testSchemaCounter.Lock()
+		testSchemaCounter.n++
+		prefix := fmt.Sprintf("test_%d_%d_", time.Now().Unix(), testSchemaCounter.n)
+		testSchemaCounter.Unlock()
+
+		manager := NewPostgresManager(dsn, prefix)
+		t.Cleanup(func() {
+			if err := manager.dropSchemas(); err != nil {
+				t.Errorf("Failed to drop test schemas: %v", err)
+			}
+			manager.CloseAll()
+		})
+		return manager
+	}
+
 	if err := os.MkdirAll("./data", 0755); err != nil {
 		t.Fatalf("Failed to create ./data directory: %v", err)
 	}
-	tmpDir := "./data/test_socgo_" + time.Now().Format("20060102_150405")
+	// Managers created within the same second get directories of their own
+	tmpDir, err := os.MkdirTemp("./data", "test_socgo_"+time.Now().Format("20060102_150405")+"_")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
 	t.Cleanup(func() {
 		os.RemoveAll(tmpDir)
 	})
 	return NewManager(tmpDir)
 }
 
+// NewTestDB returns the database of key from a test manager of its own,
+// which is closed when the test ends
+func NewTestDB(t *testing.T, key string) *gorm.DB {
+	t.Helper()
+	manager := NewTestManager(t)
+	t.Cleanup(func() { manager.Close() })
+	db, err := manager.GetDB(key)
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	return db
+}
+
 // Close closes all databases and cleans up resources
 func (m *Manager) Close() error {
 	return m.CloseAll()
@@ -1,8 +1,10 @@
 package database
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -64,6 +66,25 @@ func TestGetDB_ReturnsExistingDB(t *testing.T) {
 	}
 }
 
+func TestGetDB_RejectsInvalidKeys(t *testing.T) {
+	manager := NewTestManager(t)
+	defer manager.Close()
+
+	for _, key := range []string{"", "../x", "a/b", ".hidden", RegistryDBName} {
+		if _, err := manager.GetDB(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(manager.dataDir), "x.db")); !os.IsNotExist(err) {
+		t.Error("Expected no database outside the data directory")
+	}
+	for _, key := range []string{"alice", "default_user", WorkspaceDBKey(7), "bob@example.com"} {
+		if _, err := manager.GetDB(key); err != nil {
+			t.Errorf("Expected %q to be a valid key, got %v", key, err)
+		}
+	}
+}
+
 func TestDatabaseTables_CreatedSuccessfully(t *testing.T) {
 	tmpDir := "/tmp/test_socgo_" + time.Now().Format("20060102_150405")
 	defer os.RemoveAll(tmpDir)
@@ -184,3 +205,61 @@ func TestCloseDB(t *testing.T) {
 		t.Error("Database should be removed from manager after closing")
 	}
 }
+
+func TestSchemaName(t *testing.T) {
+	manager := NewPostgresManager("host=localhost", "")
+
+	if got := manager.schemaName("default_user"); got != "tenant_default_user" {
+		t.Errorf("Expected tenant_default_user, got %s", got)
+	}
+
+	// Keys that need escaping get a hash suffix to stay distinct
+	dashed, underscored := manager.schemaName("a-b"), manager.schemaName("a_b")
+	if dashed == underscored {
+		t.Errorf("Expected distinct schemas, both are %s", dashed)
+	}
+
+	long := manager.schemaName(strings.Repeat("x", 100))
+	if len(long) > 63 {
+		t.Errorf("Schema name exceeds 63 characters: %s", long)
+	}
+}
+
+func TestWithSearchPath(t *testing.T) {
+	if got := withSearchPath("host=db dbname=socgo", "tenant_a"); got != "host=db dbname=socgo search_path=tenant_a" {
+		t.Errorf("Unexpected keyword DSN: %s", got)
+	}
+	if got := withSearchPath("postgres://db/socgo?sslmode=disable", "tenant_a"); got != "postgres://db/socgo?sslmode=disable&search_path=tenant_a" {
+		t.Errorf("Unexpected URL DSN: %s", got)
+	}
+}
+
+func TestPostgresManager_IsolatesTenants(t *testing.T) {
+	if os.Getenv(TestPostgresDSNEnv) == "" {
+		t.Skipf("%s not set", TestPostgresDSNEnv)
+	}
+
+	manager := NewTestManager(t)
+
+	first, err := manager.GetDB("first_user")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	second, err := manager.GetDB("second_user")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+
+	if err := first.Create(&Post{Content: "only mine", UserID: "first_user"}).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	var count int64
+	second.Model(&Post{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected second tenant to see no posts, got %d", count)
+	}
+	if !manager.UserDBExists("first_user") || manager.UserDBExists("nobody") {
+		t.Error("UserDBExists does not reflect the tenant schemas")
+	}
+}
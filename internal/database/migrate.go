@@ -0,0 +1,381 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationsTable records which migrations have been applied to a database
+const migrationsTable = "schema_migrations"
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change read from the embedded SQL files
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// MigrationSet is a sequence of migrations for one kind of database. The SQL
+// lives in migrations/<dialect>/<dir>.
+type MigrationSet struct {
+	Name string
+	dir  string
+	// models are the GORM models the migrated schema must match
+	models []interface{}
+	// Databases created by AutoMigrate have legacyTable but no migrations
+	// table; they are brought to the schema of baseline with legacyModels and
+	// recorded as migrated up to it.
+	legacyTable  string
+	legacyModels []interface{}
+	baseline     int
+}
+
+var (
+	// TenantMigrations build the database of a user or workspace
+	TenantMigrations = &MigrationSet{
+		Name: "tenant",
+		models: []interface{}{
+			&Post{},
+			&Provider{},
+			&ScheduledJob{},
+			&APIToken{},
+			&PostComment{},
+			&AuditLog{},
+			&WebhookEndpoint{},
+			&WebhookDelivery{},
+			&Campaign{},
+			&Snippet{},
+			&PostTemplate{},
+			&Feed{},
+			&FeedItem{},
+			&ExternalCalendar{},
+			&ExternalEvent{},
+			&LinkRule{},
+			&ShortLink{},
+			&BioPage{},
+			&BioLink{},
+			&BlackoutWindow{},
+			&PublishingPause{},
+			&PostingLimit{},
+		},
+		legacyTable:  "posts",
+		legacyModels: legacyTenantModels,
+		baseline:     3,
+	}
+
+	// RegistryMigrations build the database shared by all tenants
+	RegistryMigrations = &MigrationSet{
+		Name: "registry",
+		dir:  "registry",
+		models: []interface{}{
+			&Workspace{},
+			&WorkspaceMember{},
+			&User{},
+			&TokenRoute{},
+			&LinkRoute{},
+			&BioRoute{},
+			&TenantSchedule{},
+		},
+		legacyTable:  "workspaces",
+		legacyModels: legacyRegistryModels,
+		baseline:     1,
+	}
+)
+
+// LoadMigrations reads the migrations of set for dialect ("sqlite" or
+// "postgres"), ordered by version
+func LoadMigrations(dialect string, set *MigrationSet) ([]Migration, error) {
+	dir := path.Join("migrations", dialect, set.dir)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no %s migrations for %s: %w", set.Name, dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %03d_%s needs both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration of set to db and returns how many
+// were applied
+func MigrateUp(db *gorm.DB, set *MigrationSet) (int, error) {
+	migrations, applied, err := prepareMigrations(db, set)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if _, done := applied[migration.Version]; done {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := execStatements(tx, migration.Up); err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO "+migrationsTable+" (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now()).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %03d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrateDown reverts the last steps applied migrations of set and returns
+// how many were reverted
+func MigrateDown(db *gorm.DB, set *MigrationSet, steps int) (int, error) {
+	migrations, applied, err := prepareMigrations(db, set)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := migrations[i]
+		if _, done := applied[migration.Version]; !done {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := execStatements(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Exec("DELETE FROM "+migrationsTable+" WHERE version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("reverting migration %03d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// GetMigrationStatus lists every migration of set and whether db has it
+func GetMigrationStatus(db *gorm.DB, set *MigrationSet) ([]MigrationStatus, error) {
+	migrations, applied, err := prepareMigrations(db, set)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, done := applied[migration.Version]; done {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CheckSchema reports every column and index of the models of set that is
+// missing from db, and every column of their tables that no model field maps
+// to. A nil result means the migrations and the models agree.
+func CheckSchema(db *gorm.DB, set *MigrationSet) error {
+	var problems []error
+	for _, model := range set.models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+
+		if !db.Migrator().HasTable(table) {
+			problems = append(problems, fmt.Errorf("table %s is missing", table))
+			continue
+		}
+
+		columnTypes, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			return err
+		}
+		columns := make(map[string]bool, len(columnTypes))
+		for _, columnType := range columnTypes {
+			columns[columnType.Name()] = true
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !columns[field.DBName] {
+				problems = append(problems, fmt.Errorf("column %s.%s is missing", table, field.DBName))
+			}
+			delete(columns, field.DBName)
+		}
+		for column := range columns {
+			problems = append(problems, fmt.Errorf("column %s.%s has no model field", table, column))
+		}
+
+		for _, index := range stmt.Schema.ParseIndexes() {
+			if !db.Migrator().HasIndex(table, index.Name) {
+				problems = append(problems, fmt.Errorf("index %s on %s is missing", index.Name, table))
+			}
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// prepareMigrations loads the migrations of set, creates the migrations table
+// and baselines databases created by AutoMigrate. It returns the migrations
+// and the applied versions with their time.
+func prepareMigrations(db *gorm.DB, set *MigrationSet) ([]Migration, map[int]time.Time, error) {
+	migrations, err := LoadMigrations(db.Dialector.Name(), set)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	legacy := !db.Migrator().HasTable(migrationsTable) && db.Migrator().HasTable(set.legacyTable)
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS " + migrationsTable +
+		" (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP NOT NULL)").Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s table: %w", migrationsTable, err)
+	}
+	if legacy {
+		if err := baseline(db, set, migrations); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var rows []struct {
+		Version   int
+		AppliedAt time.Time
+	}
+	if err := db.Raw("SELECT version, applied_at FROM " + migrationsTable).Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return migrations, applied, nil
+}
+
+// baseline brings a database created by AutoMigrate to the schema of the
+// baseline migration and records the migrations up to it as applied
+func baseline(db *gorm.DB, set *MigrationSet, migrations []Migration) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(set.legacyModels...); err != nil {
+			return fmt.Errorf("failed to baseline %s database: %w", set.Name, err)
+		}
+		now := time.Now()
+		for _, migration := range migrations {
+			if migration.Version > set.baseline {
+				break
+			}
+			if err := tx.Exec("INSERT INTO "+migrationsTable+" (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// execStatements runs each statement of a migration file. Lines starting with
+// "--" are comments; statements end with a semicolon. The semicolons inside
+// the BEGIN ... END body of a CREATE TRIGGER do not end it.
+func execStatements(tx *gorm.DB, script string) error {
+	var sb strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+
+	var pending string
+	for _, part := range strings.Split(sb.String(), ";") {
+		statement := strings.TrimSpace(pending + part)
+		if statement == "" {
+			continue
+		}
+		if isOpenTrigger(statement) {
+			pending = statement + ";\n"
+			continue
+		}
+		pending = ""
+		if err := tx.Exec(statement).Error; err != nil {
+			return fmt.Errorf("%w\n%s", err, statement)
+		}
+	}
+	if pending != "" {
+		return fmt.Errorf("unterminated trigger:\n%s", pending)
+	}
+	return nil
+}
+
+// isOpenTrigger reports whether statement is a CREATE TRIGGER whose body has
+// not reached its END yet
+func isOpenTrigger(statement string) bool {
+	upper := strings.ToUpper(statement)
+	if !strings.HasPrefix(upper, "CREATE TRIGGER") {
+		return false
+	}
+	return !strings.HasSuffix(upper, "END")
+}
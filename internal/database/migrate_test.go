@@ -0,0 +1,230 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	for _, dialect := range []string{DriverSQLite, DriverPostgres} {
+		for _, set := range []*MigrationSet{TenantMigrations, RegistryMigrations} {
+			migrations, err := LoadMigrations(dialect, set)
+			if err != nil {
+				t.Fatalf("LoadMigrations(%s, %s) failed: %v", dialect, set.Name, err)
+			}
+			for i, migration := range migrations {
+				if migration.Version != i+1 {
+					t.Errorf("%s %s: expected version %d, got %d", dialect, set.Name, i+1, migration.Version)
+				}
+			}
+			if len(migrations) < set.baseline {
+				t.Errorf("%s %s: baseline %d is beyond the last migration", dialect, set.Name, set.baseline)
+			}
+		}
+	}
+}
+
+func TestMigrations_MatchModels(t *testing.T) {
+	manager := NewTestManager(t)
+	defer manager.Close()
+
+	db, err := manager.GetDB("test_user_schema")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	if err := CheckSchema(db, TenantMigrations); err != nil {
+		t.Errorf("Tenant migrations do not match the models:\n%v", err)
+	}
+
+	registry, err := manager.GetRegistryDB()
+	if err != nil {
+		t.Fatalf("GetRegistryDB failed: %v", err)
+	}
+	if err := CheckSchema(registry, RegistryMigrations); err != nil {
+		t.Errorf("Registry migrations do not match the models:\n%v", err)
+	}
+}
+
+func TestMigrateDown_RevertsEverything(t *testing.T) {
+	manager := NewTestManager(t)
+	defer manager.Close()
+
+	db, err := manager.GetDB("test_user_down")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+
+	migrations, err := LoadMigrations(db.Dialector.Name(), TenantMigrations)
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+
+	count, err := MigrateDown(db, TenantMigrations, len(migrations))
+	if err != nil {
+		t.Fatalf("MigrateDown failed: %v", err)
+	}
+	if count != len(migrations) {
+		t.Errorf("Expected %d migrations reverted, got %d", len(migrations), count)
+	}
+	for _, table := range []string{"posts", "providers", "scheduled_jobs", "api_tokens", "audit_logs"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("Table %s should have been dropped", table)
+		}
+	}
+
+	if _, err := MigrateUp(db, TenantMigrations); err != nil {
+		t.Fatalf("MigrateUp after down failed: %v", err)
+	}
+	if err := CheckSchema(db, TenantMigrations); err != nil {
+		t.Errorf("Schema differs after down and up:\n%v", err)
+	}
+}
+
+func TestMigrateUp_BaselinesAutoMigratedDatabase(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+	defer manager.Close()
+	userID := "legacy_user"
+
+	// Database as created by AutoMigrate before SQL migrations existed
+	legacy, err := gorm.Open(sqlite.Open(manager.GetDBPath(userID)), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open legacy database: %v", err)
+	}
+	if err := legacy.AutoMigrate(legacyTenantModels...); err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+	if err := legacy.Create(&Provider{Name: "Main", Type: "facebook", UserID: userID}).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	sqlDB, _ := legacy.DB()
+	sqlDB.Close()
+
+	db, err := manager.GetDB(userID)
+	if err != nil {
+		t.Fatalf("GetDB failed on legacy database: %v", err)
+	}
+
+	statuses, err := GetMigrationStatus(db, TenantMigrations)
+	if err != nil {
+		t.Fatalf("GetMigrationStatus failed: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("Migration %03d_%s should be applied", status.Version, status.Name)
+		}
+	}
+	if err := CheckSchema(db, TenantMigrations); err != nil {
+		t.Errorf("Baselined schema differs from the models:\n%v", err)
+	}
+
+	var count int64
+	db.Model(&Provider{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the existing provider to survive, got %d providers", count)
+	}
+}
+
+func TestProviderName_UniquePerUser(t *testing.T) {
+	manager := NewTestManager(t)
+	defer manager.Close()
+
+	db, err := manager.GetDB("workspace_1")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+
+	if err := db.Create(&Provider{Name: "Main", Type: "facebook", UserID: "alice"}).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if err := db.Create(&Provider{Name: "Main", Type: "facebook", UserID: "bob"}).Error; err != nil {
+		t.Errorf("Another user should be able to reuse a provider name: %v", err)
+	}
+	if err := db.Create(&Provider{Name: "Main", Type: "tiktok", UserID: "alice"}).Error; err == nil {
+		t.Error("Expected duplicate provider name for the same user to fail")
+	}
+}
+
+func TestExecStatements_SkipsComments(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	script := "-- first; with a semicolon\nCREATE TABLE a (id INTEGER);\n\n-- second\nCREATE TABLE b (id INTEGER);\n"
+	if err := execStatements(db, script); err != nil {
+		t.Fatalf("execStatements failed: %v", err)
+	}
+	for _, table := range []string{"a", "b"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("Table %s was not created", table)
+		}
+	}
+}
+
+func TestExecStatements_KeepsTriggerBodies(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	script := `CREATE TABLE a (id INTEGER);
+CREATE TABLE b (id INTEGER);
+CREATE TRIGGER copy_a AFTER INSERT ON a BEGIN
+    INSERT INTO b (id) VALUES (new.id);
+    INSERT INTO b (id) VALUES (new.id + 1);
+END;
+INSERT INTO a (id) VALUES (1);
+`
+	if err := execStatements(db, script); err != nil {
+		t.Fatalf("execStatements failed: %v", err)
+	}
+	var count int64
+	db.Table("b").Count(&count)
+	if count != 2 {
+		t.Errorf("Expected the trigger to insert 2 rows, got %d", count)
+	}
+}
+
+func TestUTCTimes_RewritesLegacyOffsets(t *testing.T) {
+	manager := NewTestManager(t)
+	defer manager.Close()
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	// Written by a host two hours east of UTC, before times were bound in UTC
+	if err := db.Exec(`INSERT INTO posts (content, user_id, provider_id, status, created_at, updated_at)
+		VALUES ('legacy', 'alice', NULL, 'published', '2030-03-14 01:30:00.5+02:00', 'not a time')`).Error; err != nil {
+		t.Fatalf("Failed to insert post: %v", err)
+	}
+
+	migrations, err := LoadMigrations(DriverSQLite, TenantMigrations)
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+	for _, migration := range migrations {
+		if migration.Name == "utc_times" {
+			if err := execStatements(db, migration.Up); err != nil {
+				t.Fatalf("Migration failed: %v", err)
+			}
+		}
+	}
+
+	var row struct {
+		CreatedAt string
+		UpdatedAt string
+	}
+	db.Raw("SELECT CAST(created_at AS TEXT) AS created_at, CAST(updated_at AS TEXT) AS updated_at FROM posts").Scan(&row)
+	if row.CreatedAt != "2030-03-13 23:30:00.500+00:00" || row.UpdatedAt != "not a time" {
+		t.Errorf("Unexpected times: %+v", row)
+	}
+	var createdAt time.Time
+	db.Raw("SELECT created_at FROM posts").Scan(&createdAt)
+	if !createdAt.Equal(time.Date(2030, time.March, 13, 23, 30, 0, 500000000, time.UTC)) {
+		t.Errorf("Expected the rewritten time to read back, got %v", createdAt)
+	}
+}
@@ -0,0 +1,51 @@
+-- Create providers table
+CREATE TABLE IF NOT EXISTS providers (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL,
+    config TEXT,
+    user_id TEXT NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_providers_user_id ON providers(user_id);
+CREATE INDEX IF NOT EXISTS idx_providers_deleted_at ON providers(deleted_at);
+
+-- Create posts table
+CREATE TABLE IF NOT EXISTS posts (
+    id BIGSERIAL PRIMARY KEY,
+    content TEXT NOT NULL,
+    title TEXT,
+    user_id TEXT NOT NULL,
+    provider_id BIGINT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts(user_id);
+CREATE INDEX IF NOT EXISTS idx_posts_provider_id ON posts(provider_id);
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts(deleted_at);
+
+-- Create scheduled_jobs table
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    job_type TEXT NOT NULL,
+    payload_data TEXT,
+    user_id TEXT NOT NULL,
+    provider_id BIGINT,
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    executed_at TIMESTAMPTZ,
+    status TEXT DEFAULT 'pending',
+    error_msg TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_user_id ON scheduled_jobs(user_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_provider_id ON scheduled_jobs(provider_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_scheduled_at ON scheduled_jobs(scheduled_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_status ON scheduled_jobs(status);
@@ -0,0 +1,4 @@
+-- Restore the global unique constraint on provider names
+DROP INDEX IF EXISTS idx_providers_user_name;
+
+ALTER TABLE providers ADD CONSTRAINT providers_name_key UNIQUE (name);
@@ -0,0 +1,5 @@
+-- Provider names were unique across all users; make them unique per user
+ALTER TABLE providers DROP CONSTRAINT IF EXISTS providers_name_key;
+DROP INDEX IF EXISTS idx_providers_name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_providers_user_name ON providers(user_id, name);
@@ -0,0 +1,4 @@
+-- Full-text index over the title and content of posts. Searches must use the
+-- same expression for the index to apply.
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts
+    USING GIN (to_tsvector('simple', coalesce(title, '') || ' ' || content));
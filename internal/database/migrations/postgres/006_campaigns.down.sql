@@ -0,0 +1,9 @@
+-- Drop campaigns and the campaign and tags of posts and jobs
+DROP INDEX IF EXISTS idx_scheduled_jobs_campaign_id;
+DROP INDEX IF EXISTS idx_posts_campaign_id;
+ALTER TABLE scheduled_jobs DROP COLUMN tags;
+ALTER TABLE scheduled_jobs DROP COLUMN campaign_id;
+ALTER TABLE posts DROP COLUMN tags;
+ALTER TABLE posts DROP COLUMN campaign_id;
+
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,2 @@
+-- Drop post templates
+DROP TABLE IF EXISTS post_templates;
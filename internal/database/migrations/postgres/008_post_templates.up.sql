@@ -0,0 +1,15 @@
+-- Post templates instantiated with variables
+CREATE TABLE IF NOT EXISTS post_templates (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    content TEXT NOT NULL,
+    provider_ids TEXT DEFAULT '',
+    schedule_offset TEXT NOT NULL DEFAULT '',
+    tags TEXT DEFAULT '',
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_templates_user_id ON post_templates(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_templates_user_name ON post_templates(user_id, name);
@@ -0,0 +1,38 @@
+-- UTM rules for links in published content and short links counting clicks
+CREATE TABLE IF NOT EXISTS link_rules (
+    id BIGSERIAL PRIMARY KEY,
+    provider_id BIGINT,
+    campaign_id BIGINT,
+    utm_source TEXT,
+    utm_medium TEXT,
+    utm_campaign TEXT,
+    utm_term TEXT,
+    utm_content TEXT,
+    shorten BOOLEAN DEFAULT false,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_rules_provider_id ON link_rules(provider_id);
+CREATE INDEX IF NOT EXISTS idx_link_rules_campaign_id ON link_rules(campaign_id);
+CREATE INDEX IF NOT EXISTS idx_link_rules_user_id ON link_rules(user_id);
+
+CREATE TABLE IF NOT EXISTS short_links (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(16) NOT NULL,
+    url TEXT NOT NULL,
+    post_id BIGINT,
+    provider_id BIGINT,
+    campaign_id BIGINT,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    last_clicked_at TIMESTAMPTZ,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_short_links_code ON short_links(code);
+CREATE INDEX IF NOT EXISTS idx_short_links_post_id ON short_links(post_id);
+CREATE INDEX IF NOT EXISTS idx_short_links_provider_id ON short_links(provider_id);
+CREATE INDEX IF NOT EXISTS idx_short_links_campaign_id ON short_links(campaign_id);
+CREATE INDEX IF NOT EXISTS idx_short_links_user_id ON short_links(user_id);
@@ -0,0 +1,30 @@
+-- Public link-in-bio pages and their pinned links
+CREATE TABLE IF NOT EXISTS bio_pages (
+    id BIGSERIAL PRIMARY KEY,
+    slug VARCHAR(64) NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    color VARCHAR(7) DEFAULT '#2563eb',
+    post_count INTEGER NOT NULL DEFAULT 6,
+    enabled BOOLEAN DEFAULT true,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bio_pages_slug ON bio_pages(slug);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bio_pages_user_id ON bio_pages(user_id);
+
+CREATE TABLE IF NOT EXISTS bio_links (
+    id BIGSERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    short_link_id BIGINT,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_bio_links_short_link_id ON bio_links(short_link_id);
+CREATE INDEX IF NOT EXISTS idx_bio_links_user_id ON bio_links(user_id);
@@ -0,0 +1,3 @@
+-- Drop blackout windows and the publishing pause switch
+DROP TABLE IF EXISTS publishing_pauses;
+DROP TABLE IF EXISTS blackout_windows;
@@ -0,0 +1,2 @@
+-- Drop posting limits
+DROP TABLE IF EXISTS posting_limits;
@@ -0,0 +1,14 @@
+-- Daily caps and minimum spacing of posts per provider
+CREATE TABLE IF NOT EXISTS posting_limits (
+    id BIGSERIAL PRIMARY KEY,
+    provider_id BIGINT NOT NULL,
+    daily_cap INTEGER DEFAULT 0,
+    min_gap_minutes INTEGER DEFAULT 0,
+    timezone TEXT,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posting_limits_provider_id ON posting_limits(provider_id);
+CREATE INDEX IF NOT EXISTS idx_posting_limits_user_id ON posting_limits(user_id);
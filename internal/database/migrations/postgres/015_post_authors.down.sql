@@ -0,0 +1,2 @@
+-- Drop the author of posts
+ALTER TABLE posts DROP COLUMN author_id;
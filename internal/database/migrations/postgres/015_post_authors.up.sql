@@ -0,0 +1,2 @@
+-- Author of a post, who may not review it
+ALTER TABLE posts ADD COLUMN author_id TEXT;
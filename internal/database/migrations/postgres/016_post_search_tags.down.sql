@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_search;
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts
+    USING GIN (to_tsvector('simple', coalesce(title, '') || ' ' || content));
@@ -0,0 +1,5 @@
+-- Tags join the title and content in the full-text index. Searches must use
+-- the same expression for the index to apply.
+DROP INDEX IF EXISTS idx_posts_search;
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts
+    USING GIN (to_tsvector('simple', coalesce(title, '') || ' ' || content || ' ' || coalesce(tags, '')));
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tenant_schedules;
+DROP TABLE IF EXISTS token_routes;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,24 @@
+-- Users with a personal database
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- API token hash -> database holding the token
+CREATE TABLE IF NOT EXISTS token_routes (
+    hash VARCHAR(64) PRIMARY KEY,
+    tenant_key TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_routes_tenant_key ON token_routes(tenant_key);
+
+-- Next due job or webhook delivery per tenant database
+CREATE TABLE IF NOT EXISTS tenant_schedules (
+    tenant_key TEXT PRIMARY KEY,
+    next_due_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_schedules_next_due_at ON tenant_schedules(next_due_at);
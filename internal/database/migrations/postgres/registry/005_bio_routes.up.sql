@@ -0,0 +1,8 @@
+-- Link-in-bio page slug -> database holding the page
+CREATE TABLE IF NOT EXISTS bio_routes (
+    slug VARCHAR(64) PRIMARY KEY,
+    tenant_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_bio_routes_tenant_key ON bio_routes(tenant_key);
@@ -0,0 +1,4 @@
+-- Drop tables in reverse order due to foreign key constraints
+DROP TABLE IF EXISTS scheduled_jobs;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS providers;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- Drop tables and columns added for the review workflow, audit log and webhooks
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS post_comments;
+
+DROP INDEX IF EXISTS idx_scheduled_jobs_post_id;
+ALTER TABLE scheduled_jobs DROP COLUMN post_id;
+
+DROP INDEX IF EXISTS idx_posts_status;
+ALTER TABLE posts DROP COLUMN published_at;
+ALTER TABLE posts DROP COLUMN approved_at;
+ALTER TABLE posts DROP COLUMN approved_by;
+ALTER TABLE posts DROP COLUMN reviewer_id;
+ALTER TABLE posts DROP COLUMN status;
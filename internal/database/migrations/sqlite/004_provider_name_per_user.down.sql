@@ -0,0 +1,21 @@
+-- Restore the global unique constraint on provider names
+CREATE TABLE providers_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL,
+    config TEXT,
+    user_id TEXT NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+INSERT INTO providers_old (id, name, type, config, user_id, is_active, created_at, updated_at, deleted_at)
+SELECT id, name, type, config, user_id, is_active, created_at, updated_at, deleted_at FROM providers;
+
+DROP TABLE providers;
+ALTER TABLE providers_old RENAME TO providers;
+
+CREATE INDEX IF NOT EXISTS idx_providers_user_id ON providers(user_id);
+CREATE INDEX IF NOT EXISTS idx_providers_deleted_at ON providers(deleted_at);
@@ -0,0 +1,23 @@
+-- Provider names were unique across all users; make them unique per user.
+-- SQLite cannot drop a column constraint, so the table is rebuilt.
+CREATE TABLE providers_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    config TEXT,
+    user_id TEXT NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+INSERT INTO providers_new (id, name, type, config, user_id, is_active, created_at, updated_at, deleted_at)
+SELECT id, name, type, config, user_id, is_active, created_at, updated_at, deleted_at FROM providers;
+
+DROP TABLE providers;
+ALTER TABLE providers_new RENAME TO providers;
+
+CREATE INDEX IF NOT EXISTS idx_providers_user_id ON providers(user_id);
+CREATE INDEX IF NOT EXISTS idx_providers_deleted_at ON providers(deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_providers_user_name ON providers(user_id, name);
@@ -0,0 +1,22 @@
+-- Full-text index over the title and content of posts. FTS4 keeps only the
+-- index and reads the text from the posts table; triggers keep it in step.
+CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts4(content="posts", title, content);
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_before_update BEFORE UPDATE ON posts BEGIN
+    DELETE FROM posts_fts WHERE docid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_before_delete BEFORE DELETE ON posts BEGIN
+    DELETE FROM posts_fts WHERE docid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_update AFTER UPDATE ON posts BEGIN
+    INSERT INTO posts_fts (docid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (docid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+-- Index the posts written before this migration
+INSERT INTO posts_fts (posts_fts) VALUES ('rebuild');
@@ -0,0 +1,23 @@
+-- Campaigns group posts and jobs of a launch
+CREATE TABLE IF NOT EXISTS campaigns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    color TEXT,
+    starts_on DATETIME,
+    ends_on DATETIME,
+    user_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_user_id ON campaigns(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaigns_user_name ON campaigns(user_id, name);
+
+-- Campaign and comma separated tags of posts and jobs
+ALTER TABLE posts ADD COLUMN campaign_id INTEGER;
+ALTER TABLE posts ADD COLUMN tags TEXT DEFAULT '';
+ALTER TABLE scheduled_jobs ADD COLUMN campaign_id INTEGER;
+ALTER TABLE scheduled_jobs ADD COLUMN tags TEXT DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_posts_campaign_id ON posts(campaign_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_campaign_id ON scheduled_jobs(campaign_id);
@@ -0,0 +1,2 @@
+-- Drop snippets and hashtag groups
+DROP TABLE IF EXISTS snippets;
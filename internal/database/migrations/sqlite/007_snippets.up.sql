@@ -0,0 +1,13 @@
+-- Reusable snippets and hashtag groups of the post composer
+CREATE TABLE IF NOT EXISTS snippets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'snippet',
+    body TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippets_user_id ON snippets(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_user_name ON snippets(user_id, name);
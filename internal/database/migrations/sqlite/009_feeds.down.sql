@@ -0,0 +1,3 @@
+-- Drop feeds and their seen items
+DROP TABLE IF EXISTS feed_items;
+DROP TABLE IF EXISTS feeds;
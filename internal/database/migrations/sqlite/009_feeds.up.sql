@@ -0,0 +1,32 @@
+-- RSS and Atom feeds turned into posts, and the items already seen
+CREATE TABLE IF NOT EXISTS feeds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    mode TEXT NOT NULL DEFAULT 'draft',
+    template TEXT NOT NULL,
+    provider_ids TEXT DEFAULT '',
+    schedule_offset TEXT NOT NULL DEFAULT '',
+    tags TEXT DEFAULT '',
+    poll_interval TEXT NOT NULL DEFAULT '1h',
+    is_active BOOLEAN DEFAULT true,
+    last_polled_at DATETIME,
+    last_error TEXT,
+    user_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_feeds_user_id ON feeds(user_id);
+
+CREATE TABLE IF NOT EXISTS feed_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    feed_id INTEGER NOT NULL,
+    guid TEXT NOT NULL,
+    title TEXT,
+    link TEXT,
+    published_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feed_items_feed_guid ON feed_items(feed_id, guid);
@@ -0,0 +1,5 @@
+-- Drop external calendars and token scopes
+DROP TABLE IF EXISTS external_events;
+DROP TABLE IF EXISTS external_calendars;
+
+ALTER TABLE api_tokens DROP COLUMN scope;
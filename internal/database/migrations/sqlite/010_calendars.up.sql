@@ -0,0 +1,28 @@
+-- Calendar feed tokens and external calendars shown on the calendar
+ALTER TABLE api_tokens ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS external_calendars (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    color TEXT NOT NULL DEFAULT '',
+    last_synced_at DATETIME,
+    user_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_calendars_user_id ON external_calendars(user_id);
+
+CREATE TABLE IF NOT EXISTS external_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    calendar_id INTEGER NOT NULL,
+    uid TEXT NOT NULL,
+    summary TEXT,
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NOT NULL,
+    all_day BOOLEAN DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_events_calendar_id ON external_events(calendar_id);
+CREATE INDEX IF NOT EXISTS idx_external_events_starts_at ON external_events(starts_at);
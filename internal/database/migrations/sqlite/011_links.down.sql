@@ -0,0 +1,3 @@
+-- Drop link rules and short links
+DROP TABLE IF EXISTS short_links;
+DROP TABLE IF EXISTS link_rules;
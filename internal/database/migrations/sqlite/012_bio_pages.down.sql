@@ -0,0 +1,3 @@
+-- Drop link-in-bio pages and pinned links
+DROP TABLE IF EXISTS bio_links;
+DROP TABLE IF EXISTS bio_pages;
@@ -0,0 +1,30 @@
+-- Blackout windows, quiet hours and the publishing pause switch
+CREATE TABLE IF NOT EXISTS blackout_windows (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    provider_id INTEGER,
+    recurring BOOLEAN DEFAULT false,
+    starts_at DATETIME,
+    ends_at DATETIME,
+    start_time VARCHAR(5),
+    end_time VARCHAR(5),
+    weekdays TEXT,
+    timezone TEXT,
+    action VARCHAR(10) NOT NULL DEFAULT 'defer',
+    user_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_blackout_windows_provider_id ON blackout_windows(provider_id);
+CREATE INDEX IF NOT EXISTS idx_blackout_windows_user_id ON blackout_windows(user_id);
+
+CREATE TABLE IF NOT EXISTS publishing_pauses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reason TEXT,
+    paused_by TEXT,
+    user_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_publishing_pauses_user_id ON publishing_pauses(user_id);
@@ -0,0 +1,27 @@
+-- Tags join the title and content in the full-text index. FTS4 tables cannot
+-- gain columns, so the index and its triggers are rebuilt.
+DROP TRIGGER IF EXISTS posts_fts_after_insert;
+DROP TRIGGER IF EXISTS posts_fts_after_update;
+DROP TRIGGER IF EXISTS posts_fts_before_delete;
+DROP TRIGGER IF EXISTS posts_fts_before_update;
+DROP TABLE IF EXISTS posts_fts;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts4(content="posts", title, content, tags);
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_before_update BEFORE UPDATE ON posts BEGIN
+    DELETE FROM posts_fts WHERE docid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_before_delete BEFORE DELETE ON posts BEGIN
+    DELETE FROM posts_fts WHERE docid = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_update AFTER UPDATE ON posts BEGIN
+    INSERT INTO posts_fts (docid, title, content, tags) VALUES (new.id, new.title, new.content, new.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (docid, title, content, tags) VALUES (new.id, new.title, new.content, new.tags);
+END;
+
+INSERT INTO posts_fts (posts_fts) VALUES ('rebuild');
@@ -0,0 +1,2 @@
+-- The original offsets are not kept; times stay in UTC
+SELECT 1;
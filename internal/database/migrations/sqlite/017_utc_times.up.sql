@@ -0,0 +1,17 @@
+-- Times are bound in UTC from now on. Times written before carry the offset
+-- of the host that wrote them; rewrite those compared in ranges in UTC, so
+-- that they order with the new ones. Values SQLite cannot parse are kept.
+UPDATE posts SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) IS NOT NULL;
+UPDATE posts SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', updated_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', updated_at) IS NOT NULL;
+UPDATE posts SET published_at = strftime('%Y-%m-%d %H:%M:%f+00:00', published_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', published_at) IS NOT NULL;
+UPDATE posts SET deleted_at = strftime('%Y-%m-%d %H:%M:%f+00:00', deleted_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', deleted_at) IS NOT NULL;
+UPDATE scheduled_jobs SET scheduled_at = strftime('%Y-%m-%d %H:%M:%f+00:00', scheduled_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', scheduled_at) IS NOT NULL;
+UPDATE scheduled_jobs SET executed_at = strftime('%Y-%m-%d %H:%M:%f+00:00', executed_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', executed_at) IS NOT NULL;
+UPDATE scheduled_jobs SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) IS NOT NULL;
+UPDATE scheduled_jobs SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', updated_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', updated_at) IS NOT NULL;
+UPDATE external_events SET starts_at = strftime('%Y-%m-%d %H:%M:%f+00:00', starts_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', starts_at) IS NOT NULL;
+UPDATE external_events SET ends_at = strftime('%Y-%m-%d %H:%M:%f+00:00', ends_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', ends_at) IS NOT NULL;
+UPDATE blackout_windows SET starts_at = strftime('%Y-%m-%d %H:%M:%f+00:00', starts_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', starts_at) IS NOT NULL;
+UPDATE blackout_windows SET ends_at = strftime('%Y-%m-%d %H:%M:%f+00:00', ends_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', ends_at) IS NOT NULL;
+UPDATE webhook_deliveries SET next_attempt_at = strftime('%Y-%m-%d %H:%M:%f+00:00', next_attempt_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', next_attempt_at) IS NOT NULL;
+UPDATE webhook_deliveries SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) WHERE strftime('%Y-%m-%d %H:%M:%f+00:00', created_at) IS NOT NULL;
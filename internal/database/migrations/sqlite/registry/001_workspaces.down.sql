@@ -0,0 +1,3 @@
+-- Drop tables in reverse order due to foreign key constraints
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
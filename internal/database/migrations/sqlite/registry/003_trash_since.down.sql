@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_tenant_schedules_trash_since;
+ALTER TABLE tenant_schedules DROP COLUMN trash_since;
@@ -0,0 +1,4 @@
+-- When the oldest item in each tenant's trash was deleted
+ALTER TABLE tenant_schedules ADD COLUMN trash_since DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_tenant_schedules_trash_since ON tenant_schedules(trash_since);
@@ -0,0 +1,8 @@
+-- Short link code -> database holding the link
+CREATE TABLE IF NOT EXISTS link_routes (
+    code VARCHAR(16) PRIMARY KEY,
+    tenant_key TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_routes_tenant_key ON link_routes(tenant_key);
@@ -1,35 +1,270 @@
 package database
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Post struct {
-	ID         uint           `json:"id" gorm:"primaryKey"`
-	Content    string         `json:"content" gorm:"not null"`
-	Title      string         `json:"title"`
-	UserID     string         `json:"user_id" gorm:"not null;index"`
-	ProviderID uint           `json:"provider_id" gorm:"index"`
-	Provider   Provider       `json:"provider" gorm:"foreignKey:ProviderID"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Content     string         `json:"content" gorm:"not null"`
+	Title       string         `json:"title"`
+	UserID      string         `json:"user_id" gorm:"not null;index"`
+	ProviderID  uint           `json:"provider_id" gorm:"index"`
+	Provider    Provider       `json:"provider" gorm:"foreignKey:ProviderID"`
+	CampaignID  *uint          `json:"campaign_id,omitempty" gorm:"index"`
+	Tags        Tags           `json:"tags" gorm:"type:text;default:''"`
+	Status      string         `json:"status" gorm:"default:'published';index"`
+	AuthorID    string         `json:"author_id,omitempty"`
+	ReviewerID  string         `json:"reviewer_id,omitempty"`
+	ApprovedBy  string         `json:"approved_by,omitempty"`
+	ApprovedAt  *time.Time     `json:"approved_at,omitempty"`
+	PublishedAt *time.Time     `json:"published_at,omitempty"`
+	Comments    []PostComment  `json:"comments,omitempty" gorm:"foreignKey:PostID"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// PostComment is a review comment left on a draft post
+type PostComment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PostID    uint      `json:"post_id" gorm:"not null;index"`
+	UserID    string    `json:"user_id" gorm:"not null"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Provider is a connected social account. Names are unique per user.
 type Provider struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"not null;uniqueIndex"`
+	Name      string         `json:"name" gorm:"not null;uniqueIndex:idx_providers_user_name,priority:2"`
 	Type      string         `json:"type" gorm:"not null"`
-	Config    string         `json:"config" gorm:"type:text"`
-	UserID    string         `json:"user_id" gorm:"not null;index"`
+	Config    string         `json:"-" gorm:"type:text"` // OAuth credentials, never serialized
+	UserID    string         `json:"user_id" gorm:"not null;index;uniqueIndex:idx_providers_user_name,priority:1"`
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// Campaign groups the posts and scheduled jobs of a launch across networks.
+// StartsOn and EndsOn are optional calendar dates; Color is a CSS hex color
+// marking the campaign's entries in the calendar. Names are unique per user.
+type Campaign struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Name      string     `json:"name" gorm:"not null;uniqueIndex:idx_campaigns_user_name,priority:2"`
+	Color     string     `json:"color"`
+	StartsOn  *time.Time `json:"starts_on,omitempty"`
+	EndsOn    *time.Time `json:"ends_on,omitempty"`
+	UserID    string     `json:"user_id" gorm:"not null;index;uniqueIndex:idx_campaigns_user_name,priority:1"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Snippet is a reusable block of text for the post composer: a signature or
+// call to action, or with Kind SnippetKindHashtags a group of hashtags.
+// Bodies may contain placeholders expanded at publish time. Names are unique
+// per user.
+type Snippet struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_snippets_user_name,priority:2"`
+	Kind      string    `json:"kind" gorm:"not null;default:'snippet'"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	UserID    string    `json:"user_id" gorm:"not null;index;uniqueIndex:idx_snippets_user_name,priority:1"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PostTemplate is a reusable post. Content is a text/template rendered with
+// the variables given on instantiation; the resulting posts go to ProviderIDs
+// with Tags, scheduled ScheduleOffset (a Go duration such as "24h") after the
+// instantiation unless a time is given. Names are unique per user.
+type PostTemplate struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"not null;uniqueIndex:idx_post_templates_user_name,priority:2"`
+	Content        string    `json:"content" gorm:"type:text;not null"`
+	ProviderIDs    IDList    `json:"provider_ids" gorm:"type:text;default:''"`
+	ScheduleOffset string    `json:"schedule_offset" gorm:"not null;default:''"`
+	Tags           Tags      `json:"tags" gorm:"type:text;default:''"`
+	UserID         string    `json:"user_id" gorm:"not null;index;uniqueIndex:idx_post_templates_user_name,priority:1"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Feed is an RSS or Atom feed whose new items become posts. Each item's
+// post content is Template, a post template rendered with the item's title,
+// link and summary. With Mode FeedModeDraft the posts are drafts for review;
+// with FeedModeSchedule they are scheduled ScheduleOffset after the poll that
+// found them. The feed is polled every PollInterval by a JobTypePollFeed job.
+type Feed struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Name           string     `json:"name" gorm:"not null"`
+	URL            string     `json:"url" gorm:"not null"`
+	Mode           string     `json:"mode" gorm:"not null;default:'draft'"`
+	Template       string     `json:"template" gorm:"type:text;not null"`
+	ProviderIDs    IDList     `json:"provider_ids" gorm:"type:text;default:''"`
+	ScheduleOffset string     `json:"schedule_offset" gorm:"not null;default:''"`
+	Tags           Tags       `json:"tags" gorm:"type:text;default:''"`
+	PollInterval   string     `json:"poll_interval" gorm:"not null;default:'1h'"`
+	IsActive       bool       `json:"is_active" gorm:"default:true"`
+	LastPolledAt   *time.Time `json:"last_polled_at,omitempty"`
+	LastError      string     `json:"last_error"`
+	UserID         string     `json:"user_id" gorm:"not null;index"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// FeedItem records a feed item that has been seen, so that each item, known
+// by its GUID, becomes a post once
+type FeedItem struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	FeedID      uint       `json:"feed_id" gorm:"not null;uniqueIndex:idx_feed_items_feed_guid,priority:1"`
+	GUID        string     `json:"guid" gorm:"not null;uniqueIndex:idx_feed_items_feed_guid,priority:2"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ExternalCalendar is a calendar imported from an ICS file or URL, such as
+// holidays or product launches, whose events are shown on the calendar.
+// Calendars imported from a URL can be synced again.
+type ExternalCalendar struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Name         string     `json:"name" gorm:"not null"`
+	URL          string     `json:"url" gorm:"not null;default:''"`
+	Color        string     `json:"color" gorm:"not null;default:''"`
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	UserID       string     `json:"user_id" gorm:"not null;index"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ExternalEvent is an event of an ExternalCalendar. Recurring events are
+// stored as one row per occurrence.
+type ExternalEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CalendarID uint      `json:"calendar_id" gorm:"not null;index"`
+	UID        string    `json:"uid" gorm:"not null"`
+	Summary    string    `json:"summary"`
+	StartsAt   time.Time `json:"starts_at" gorm:"not null;index"`
+	EndsAt     time.Time `json:"ends_at" gorm:"not null"`
+	AllDay     bool      `json:"all_day" gorm:"default:false"`
+}
+
+// LinkRule configures the links found in content published to ProviderID in
+// CampaignID: the UTM parameters appended to them, and with Shorten whether
+// they are replaced with short links counting clicks. A nil ProviderID or
+// CampaignID matches any; the most specific rule applies. UTM values may use
+// the {{provider}}, {{campaign}} and {{date}} placeholders.
+type LinkRule struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ProviderID  *uint     `json:"provider_id,omitempty" gorm:"index"`
+	CampaignID  *uint     `json:"campaign_id,omitempty" gorm:"index"`
+	UTMSource   string    `json:"utm_source"`
+	UTMMedium   string    `json:"utm_medium"`
+	UTMCampaign string    `json:"utm_campaign"`
+	UTMTerm     string    `json:"utm_term"`
+	UTMContent  string    `json:"utm_content"`
+	Shorten     bool      `json:"shorten" gorm:"default:false"`
+	UserID      string    `json:"user_id" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ShortLink is a link of published content served as /l/{Code}, which
+// redirects to URL and counts the clicks of PostID on ProviderID
+type ShortLink struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Code          string     `json:"code" gorm:"type:varchar(16);not null;uniqueIndex"`
+	URL           string     `json:"url" gorm:"type:text;not null"`
+	PostID        *uint      `json:"post_id,omitempty" gorm:"index"`
+	ProviderID    uint       `json:"provider_id" gorm:"index"`
+	CampaignID    *uint      `json:"campaign_id,omitempty" gorm:"index"`
+	Clicks        int64      `json:"clicks" gorm:"not null;default:0"`
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty"`
+	UserID        string     `json:"user_id" gorm:"not null;index"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// BioPage is the public link-in-bio page of a user or workspace, served as
+// /bio/{Slug}. It lists the pinned BioLinks first, then the links of the
+// latest PostCount published posts.
+type BioPage struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Slug        string    `json:"slug" gorm:"type:varchar(64);not null;uniqueIndex"`
+	Title       string    `json:"title" gorm:"not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	Color       string    `json:"color" gorm:"type:varchar(7);default:'#2563eb'"`
+	PostCount   int       `json:"post_count" gorm:"not null;default:6"`
+	Enabled     bool      `json:"enabled"`
+	UserID      string    `json:"user_id" gorm:"not null;uniqueIndex"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// BioLink is a link pinned to the link-in-bio page. Its clicks are counted
+// by the short link ShortLinkID.
+type BioLink struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Title       string    `json:"title" gorm:"not null"`
+	URL         string    `json:"url" gorm:"type:text;not null"`
+	Position    int       `json:"position" gorm:"not null;default:0"`
+	ShortLinkID uint      `json:"short_link_id" gorm:"index"`
+	UserID      string    `json:"user_id" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// BlackoutWindow stops the scheduler from publishing to ProviderID, or to
+// every provider when nil. A one-off window runs from StartsAt to EndsAt.
+// Recurring quiet hours run from StartTime to EndTime (HH:MM in Timezone) on
+// Weekdays, past midnight when EndTime is earlier. Action decides whether due
+// jobs are deferred to the end of the window or held until it ends.
+type BlackoutWindow struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Name       string     `json:"name" gorm:"not null"`
+	ProviderID *uint      `json:"provider_id,omitempty" gorm:"index"`
+	Recurring  bool       `json:"recurring" gorm:"default:false"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	StartTime  string     `json:"start_time,omitempty" gorm:"type:varchar(5)"`
+	EndTime    string     `json:"end_time,omitempty" gorm:"type:varchar(5)"`
+	Weekdays   string     `json:"weekdays,omitempty"`
+	Timezone   string     `json:"timezone,omitempty"`
+	Action     string     `json:"action" gorm:"type:varchar(10);not null;default:'defer'"`
+	UserID     string     `json:"user_id" gorm:"not null;index"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// PublishingPause is the emergency switch of a user or workspace: while it
+// exists nothing is published
+type PublishingPause struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Reason    string    `json:"reason"`
+	PausedBy  string    `json:"paused_by"`
+	UserID    string    `json:"user_id" gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PostingLimit caps how often the scheduler publishes to ProviderID: at most
+// DailyCap posts per day in Timezone and MinGapMinutes between two posts.
+// Zero turns either limit off.
+type PostingLimit struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ProviderID    uint      `json:"provider_id" gorm:"not null;uniqueIndex"`
+	DailyCap      int       `json:"daily_cap" gorm:"default:0"`
+	MinGapMinutes int       `json:"min_gap_minutes" gorm:"default:0"`
+	Timezone      string    `json:"timezone,omitempty"`
+	UserID        string    `json:"user_id" gorm:"not null;index"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
 type ScheduledJob struct {
 	ID          uint       `json:"id" gorm:"primaryKey"`
 	JobType     string     `json:"job_type" gorm:"not null"`
@@ -37,6 +272,9 @@ type ScheduledJob struct {
 	UserID      string     `json:"user_id" gorm:"not null;index"`
 	ProviderID  uint       `json:"provider_id" gorm:"index"`
 	Provider    Provider   `json:"provider" gorm:"foreignKey:ProviderID"`
+	PostID      *uint      `json:"post_id,omitempty" gorm:"index"`
+	CampaignID  *uint      `json:"campaign_id,omitempty" gorm:"index"`
+	Tags        Tags       `json:"tags" gorm:"type:text;default:''"`
 	ScheduledAt time.Time  `json:"scheduled_at" gorm:"not null;index"`
 	ExecutedAt  *time.Time `json:"executed_at,omitempty"`
 	Status      string     `json:"status" gorm:"default:'pending'"`
@@ -45,18 +283,191 @@ type ScheduledJob struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// APIToken is a hashed API token. Scope limits what the token may access;
+// TokenScopeAPI, the empty scope, is the whole API.
 type APIToken struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	Hash      string         `json:"-" gorm:"not null;uniqueIndex;type:varchar(64)"`
 	UserID    string         `json:"user_id" gorm:"not null;index"`
+	Scope     string         `json:"scope" gorm:"not null;default:''"`
 	CreatedAt time.Time      `json:"created_at"`
 	LastUsed  *time.Time     `json:"last_used,omitempty"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// AuditLog is an append-only record of a publishing or account action.
+// Before and After hold JSON snapshots of the target.
+type AuditLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     string    `json:"user_id" gorm:"not null;index"`
+	ActorID    string    `json:"actor_id" gorm:"not null;index"`
+	Action     string    `json:"action" gorm:"not null;index"`
+	TargetType string    `json:"target_type" gorm:"not null;index:idx_audit_logs_target"`
+	TargetID   uint      `json:"target_id" gorm:"index:idx_audit_logs_target"`
+	Before     string    `json:"before,omitempty" gorm:"type:text"`
+	After      string    `json:"after,omitempty" gorm:"type:text"`
+	Source     string    `json:"source" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// ErrAuditLogImmutable is returned when an audit entry is updated or deleted
+var ErrAuditLogImmutable = errors.New("audit log entries are append-only")
+
+func (AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// WebhookEndpoint receives signed event notifications. Events is a comma
+// separated list of subscribed event names.
+type WebhookEndpoint struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    string         `json:"user_id" gorm:"not null;index"`
+	URL       string         `json:"url" gorm:"not null"`
+	Secret    string         `json:"-" gorm:"not null"`
+	Events    string         `json:"events" gorm:"not null"`
+	IsActive  bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// WebhookDelivery is a queued or attempted delivery of an event to an endpoint
+type WebhookDelivery struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	EndpointID    uint       `json:"endpoint_id" gorm:"not null;index"`
+	Event         string     `json:"event" gorm:"not null"`
+	Payload       string     `json:"payload" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"default:'pending';index"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	ResponseCode  int        `json:"response_code,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// Workspace groups users that share providers, posts and jobs. Workspaces
+// and their members live in the registry database; the shared content lives
+// in the workspace's own database (see WorkspaceDBKey).
+type Workspace struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	OwnerID   string    `json:"owner_id" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint      `json:"workspace_id" gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user"`
+	Workspace   Workspace `json:"workspace,omitempty" gorm:"foreignKey:WorkspaceID"`
+	UserID      string    `json:"user_id" gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user;index"`
+	Role        string    `json:"role" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// User is a person with a personal database, keyed by ID. Users live in the
+// registry database together with the rest of the control plane.
+type User struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenRoute maps the hash of an API token to the database holding it, so
+// requests can be authenticated without searching every tenant database
+type TokenRoute struct {
+	Hash      string    `json:"-" gorm:"primaryKey;type:varchar(64)"`
+	TenantKey string    `json:"tenant_key" gorm:"not null;index"`
+	UserID    string    `json:"user_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LinkRoute maps the code of a short link to the database holding it, so
+// clicks can be counted without searching every tenant database
+type LinkRoute struct {
+	Code      string    `json:"code" gorm:"primaryKey;type:varchar(16)"`
+	TenantKey string    `json:"tenant_key" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BioRoute maps the slug of a link-in-bio page to the database holding it
+type BioRoute struct {
+	Slug      string    `json:"slug" gorm:"primaryKey;type:varchar(64)"`
+	TenantKey string    `json:"tenant_key" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TenantSchedule records when a tenant database next has a job or webhook
+// delivery due. NextDueAt is nil when nothing is pending. TrashSince is when
+// the oldest item in its trash was deleted, nil when the trash is empty.
+type TenantSchedule struct {
+	TenantKey  string     `json:"tenant_key" gorm:"primaryKey"`
+	NextDueAt  *time.Time `json:"next_due_at" gorm:"index"`
+	TrashSince *time.Time `json:"trash_since" gorm:"index"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleViewer = "viewer"
+)
+
+// Job types. A JobTypePollFeed job polls the feed whose ID is its payload.
+const (
+	JobTypePublishPost = "publish_post"
+	JobTypePollFeed    = "poll_feed"
+)
+
+// API token scopes. A TokenScopeCalendar token only reads the ICS calendar
+// feed, so it can be shared with calendar apps.
+const (
+	TokenScopeAPI      = ""
+	TokenScopeCalendar = "calendar"
+)
+
+// Feed modes
+const (
+	FeedModeDraft    = "draft"
+	FeedModeSchedule = "schedule"
+)
+
+// What a blackout window does with jobs falling due inside it
+const (
+	BlackoutDefer = "defer"
+	BlackoutHold  = "hold"
+)
+
 const (
 	JobStatusPending   = "pending"
 	JobStatusExecuting = "executing"
 	JobStatusCompleted = "completed"
 	JobStatusFailed    = "failed"
 )
+
+// Post workflow states. Posts created by publishing directly default to published.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusInReview  = "in_review"
+	PostStatusApproved  = "approved"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+)
+
+// Snippet kinds
+const (
+	SnippetKindText     = "snippet"
+	SnippetKindHashtags = "hashtags"
+)
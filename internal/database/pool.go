@@ -0,0 +1,273 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PoolOptions bounds the tenant databases a Manager keeps open and sets up
+// their connections
+type PoolOptions struct {
+	// MaxOpen is the number of tenant databases kept open; the least recently
+	// used idle one is closed to make room. Zero means no limit.
+	MaxOpen int
+	// IdleTimeout closes databases not used for this long. Zero keeps them.
+	IdleTimeout time.Duration
+	// BusyTimeout is how long SQLite waits for a lock before failing
+	BusyTimeout time.Duration
+	// MaxConns limits the connections of each tenant database
+	MaxConns int
+	// MaxServerConns bounds the Postgres connections of all tenant pools, the
+	// registry and the admin connection together. Zero means no limit.
+	MaxServerConns int
+	// ForeignKeys enforces foreign key constraints in SQLite
+	ForeignKeys bool
+}
+
+// DefaultPoolOptions returns the options used unless configured otherwise
+func DefaultPoolOptions() PoolOptions {
+	return PoolOptions{
+		MaxOpen:     100,
+		IdleTimeout: 10 * time.Minute,
+		BusyTimeout: 5 * time.Second,
+		MaxConns:    4,
+	}
+}
+
+// fitServerConns lowers MaxConns, then MaxOpen, until the pools of MaxOpen
+// tenants and the registry plus the admin connection fit in MaxServerConns.
+// Each Postgres tenant has its own pool, so without this every open tenant
+// adds MaxConns connections to the server.
+func (o PoolOptions) fitServerConns() PoolOptions {
+	if o.MaxServerConns <= 0 {
+		return o
+	}
+	if o.MaxConns <= 0 {
+		o.MaxConns = DefaultPoolOptions().MaxConns
+	}
+	budget := max(o.MaxServerConns-1, 2)
+	if o.MaxOpen > 0 && (o.MaxOpen+1)*o.MaxConns > budget {
+		o.MaxConns = max(budget/(o.MaxOpen+1), 1)
+	}
+	if o.MaxOpen <= 0 || (o.MaxOpen+1)*o.MaxConns > budget {
+		o.MaxOpen = max(budget/o.MaxConns-1, 1)
+	}
+	return o
+}
+
+// PoolStats reports the state of a Manager's tenant databases
+type PoolStats struct {
+	Open      int    `json:"open"`
+	MaxOpen   int    `json:"max_open"`
+	Hits      uint64 `json:"hits"`
+	Opens     uint64 `json:"opens"`
+	Evictions uint64 `json:"evictions"`
+	// Pinned counts the times a database over the limit was kept open
+	// because a query was running on it
+	Pinned uint64 `json:"pinned"`
+}
+
+// drainPeriod is how long a database stays open after it was last handed
+// out or released. Callers of GetDB hold no reference, so a database handed
+// out more recently may be about to run a query.
+const drainPeriod = time.Minute
+
+// handle is an open tenant database, when it was last handed out and the
+// references held on it through Acquire
+type handle struct {
+	db       *gorm.DB
+	lastUsed atomic.Int64
+	refs     atomic.Int64
+}
+
+func newHandle(db *gorm.DB) *handle {
+	h := &handle{db: db}
+	h.touch()
+	return h
+}
+
+func (h *handle) touch() {
+	h.lastUsed.Store(time.Now().UnixNano())
+}
+
+func (h *handle) idleSince() time.Time {
+	return time.Unix(0, h.lastUsed.Load())
+}
+
+// poolCounters are updated without holding the manager's lock
+type poolCounters struct {
+	hits      atomic.Uint64
+	opens     atomic.Uint64
+	evictions atomic.Uint64
+	pinned    atomic.Uint64
+}
+
+// SetPoolOptions replaces the pool options. Pragmas and connection limits
+// apply to databases opened afterwards.
+func (m *Manager) SetPoolOptions(opts PoolOptions) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.driver == DriverPostgres {
+		fitted := opts.fitServerConns()
+		if fitted.MaxOpen != opts.MaxOpen || fitted.MaxConns != opts.MaxConns {
+			log.Printf("Postgres pool lowered to %d tenants with %d connections each to stay within %d server connections",
+				fitted.MaxOpen, fitted.MaxConns, opts.MaxServerConns)
+		}
+		opts = fitted
+	}
+	m.pool = opts
+}
+
+// Stats returns the current pool statistics
+func (m *Manager) Stats() PoolStats {
+	m.mutex.RLock()
+	open := len(m.dbs)
+	maxOpen := m.pool.MaxOpen
+	m.mutex.RUnlock()
+
+	return PoolStats{
+		Open:      open,
+		MaxOpen:   maxOpen,
+		Hits:      m.counters.hits.Load(),
+		Opens:     m.counters.opens.Load(),
+		Evictions: m.counters.evictions.Load(),
+		Pinned:    m.counters.pinned.Load(),
+	}
+}
+
+// sqliteDSN adds the connection pragmas to the path of a SQLite database
+func (m *Manager) sqliteDSN(path string, foreignKeys bool) string {
+	params := url.Values{}
+	params.Set("_journal_mode", "WAL")
+	params.Set("_synchronous", "NORMAL")
+	params.Set("_busy_timeout", fmt.Sprint(m.pool.BusyTimeout.Milliseconds()))
+	if foreignKeys {
+		params.Set("_foreign_keys", "1")
+	} else {
+		params.Set("_foreign_keys", "0")
+	}
+	return path + "?" + params.Encode()
+}
+
+// makeRoom closes least recently used databases until one more fits within
+// MaxOpen. Databases in use or not drained yet stay open, so the limit may be
+// exceeded for a while. The caller must hold the write lock.
+func (m *Manager) makeRoom() {
+	if m.pool.MaxOpen <= 0 || len(m.dbs) < m.pool.MaxOpen {
+		return
+	}
+
+	for _, key := range m.keysByLastUse() {
+		if len(m.dbs) < m.pool.MaxOpen {
+			return
+		}
+		if !m.evict(key) {
+			m.counters.pinned.Add(1)
+		}
+	}
+	if len(m.dbs) >= m.pool.MaxOpen {
+		log.Printf("Database pool over its limit of %d: %d databases are busy", m.pool.MaxOpen, len(m.dbs))
+	}
+}
+
+// evictIdle closes the databases unused for longer than IdleTimeout
+func (m *Manager) evictIdle() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.pool.IdleTimeout <= 0 {
+		return 0
+	}
+	cutoff := time.Now().Add(-m.pool.IdleTimeout)
+
+	count := 0
+	for _, key := range m.keysByLastUse() {
+		if m.dbs[key].idleSince().After(cutoff) {
+			break
+		}
+		if m.evict(key) {
+			count++
+		}
+	}
+	return count
+}
+
+// keysByLastUse lists the open tenant keys, least recently used first. The
+// caller must hold the lock.
+func (m *Manager) keysByLastUse() []string {
+	keys := make([]string, 0, len(m.dbs))
+	for key := range m.dbs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return m.dbs[keys[i]].lastUsed.Load() < m.dbs[keys[j]].lastUsed.Load()
+	})
+	return keys
+}
+
+// evict closes the database of key unless it is held, was used within the
+// drain period or a query is running on it. The scheduler finds databases
+// with due work through the control plane and reopens them. The caller must
+// hold the write lock.
+func (m *Manager) evict(key string) bool {
+	h := m.dbs[key]
+	if h.refs.Load() > 0 || time.Since(h.idleSince()) < drainPeriod {
+		return false
+	}
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return false
+	}
+	if sqlDB.Stats().InUse > 0 {
+		return false
+	}
+
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("Error closing database %s: %v", key, err)
+	}
+	delete(m.dbs, key)
+	m.counters.evictions.Add(1)
+	return true
+}
+
+// startJanitor begins closing idle databases in the background. The caller
+// must hold the write lock.
+func (m *Manager) startJanitor() {
+	if m.janitorStop != nil || m.pool.IdleTimeout <= 0 {
+		return
+	}
+
+	interval := m.pool.IdleTimeout / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	stop := make(chan struct{})
+	m.janitorStop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.evictIdle()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopJanitor stops the idle janitor. The caller must hold the write lock.
+func (m *Manager) stopJanitor() {
+	if m.janitorStop != nil {
+		close(m.janitorStop)
+		m.janitorStop = nil
+	}
+}
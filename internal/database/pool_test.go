@@ -0,0 +1,188 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func newPoolTestManager(t *testing.T, opts PoolOptions) *Manager {
+	t.Helper()
+	manager := NewManager(t.TempDir())
+	manager.SetPoolOptions(opts)
+	t.Cleanup(func() { manager.CloseAll() })
+	return manager
+}
+
+func TestGetDB_EvictsLeastRecentlyUsed(t *testing.T) {
+	manager := newPoolTestManager(t, PoolOptions{MaxOpen: 2, BusyTimeout: time.Second})
+
+	alice, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	alice.Create(&Post{Content: "kept on disk", UserID: "alice"})
+	if _, err := manager.GetDB("bob"); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	// Alice has drained; bob was handed out too recently to close
+	manager.dbs["alice"].lastUsed.Store(time.Now().Add(-2 * drainPeriod).UnixNano())
+	manager.GetDB("bob")
+
+	if _, err := manager.GetDB("carol"); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	open := manager.GetAllUserDatabases()
+	if len(open) != 2 || open["alice"] != nil || open["bob"] == nil || open["carol"] == nil {
+		t.Errorf("Expected alice to be evicted, open: %v", open)
+	}
+
+	stats := manager.Stats()
+	if stats.Open != 2 || stats.Evictions != 1 || stats.Opens != 3 || stats.Hits != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// An evicted database is reopened on demand
+	reopened, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB after eviction failed: %v", err)
+	}
+	var count int64
+	reopened.Model(&Post{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the post to survive eviction, got %d posts", count)
+	}
+}
+
+func TestGetDB_KeepsDatabasesInUse(t *testing.T) {
+	manager := newPoolTestManager(t, PoolOptions{MaxOpen: 1, BusyTimeout: time.Second})
+
+	alice, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	// An open transaction holds one of alice's connections
+	tx := alice.Begin()
+	defer tx.Rollback()
+
+	if _, err := manager.GetDB("bob"); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	open := manager.GetAllUserDatabases()
+	if open["alice"] == nil || open["bob"] == nil {
+		t.Errorf("Expected alice to stay open while in use, open: %v", open)
+	}
+	if stats := manager.Stats(); stats.Evictions != 0 || stats.Pinned != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestAcquire_HoldsDatabaseUntilReleased(t *testing.T) {
+	manager := newPoolTestManager(t, PoolOptions{MaxOpen: 1, BusyTimeout: time.Second})
+
+	alice, release, err := manager.Acquire("alice")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	// Idle between queries, but still held
+	manager.dbs["alice"].lastUsed.Store(time.Now().Add(-2 * drainPeriod).UnixNano())
+	if _, err := manager.GetDB("bob"); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	if err := alice.Create(&Post{Content: "still open", UserID: "alice"}).Error; err != nil {
+		t.Errorf("Expected alice to stay open while held: %v", err)
+	}
+
+	release()
+	release()
+	if refs := manager.dbs["alice"].refs.Load(); refs != 0 {
+		t.Errorf("Expected releasing twice to drop one reference, got %d", refs)
+	}
+	manager.dbs["alice"].lastUsed.Store(time.Now().Add(-2 * drainPeriod).UnixNano())
+	manager.dbs["bob"].lastUsed.Store(time.Now().Add(-drainPeriod).UnixNano())
+	if _, err := manager.GetDB("carol"); err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	if open := manager.GetAllUserDatabases(); open["alice"] != nil {
+		t.Errorf("Expected alice to be closed once released and drained, open: %v", open)
+	}
+}
+
+func TestEvictIdle(t *testing.T) {
+	manager := newPoolTestManager(t, PoolOptions{IdleTimeout: time.Hour, BusyTimeout: time.Second})
+
+	for _, key := range []string{"alice", "bob"} {
+		if _, err := manager.GetDB(key); err != nil {
+			t.Fatalf("GetDB failed: %v", err)
+		}
+	}
+	if evicted := manager.evictIdle(); evicted != 0 {
+		t.Errorf("Expected no idle databases, evicted %d", evicted)
+	}
+
+	manager.dbs["alice"].lastUsed.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+	if evicted := manager.evictIdle(); evicted != 1 {
+		t.Errorf("Expected alice to be evicted, evicted %d", evicted)
+	}
+	if open := manager.GetAllUserDatabases(); len(open) != 1 || open["bob"] == nil {
+		t.Errorf("Expected only bob to stay open, open: %v", open)
+	}
+}
+
+func TestOpen_AppliesPragmas(t *testing.T) {
+	for _, foreignKeys := range []bool{false, true} {
+		opts := DefaultPoolOptions()
+		opts.ForeignKeys = foreignKeys
+		manager := newPoolTestManager(t, opts)
+
+		db, err := manager.GetDB("pragmas")
+		if err != nil {
+			t.Fatalf("GetDB failed: %v", err)
+		}
+
+		var journalMode string
+		var busyTimeout, enforced int
+		db.Raw("PRAGMA journal_mode").Scan(&journalMode)
+		db.Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+		db.Raw("PRAGMA foreign_keys").Scan(&enforced)
+		if journalMode != "wal" || busyTimeout != 5000 || (enforced == 1) != foreignKeys {
+			t.Errorf("foreign keys %v: journal_mode=%s busy_timeout=%d foreign_keys=%d", foreignKeys, journalMode, busyTimeout, enforced)
+		}
+
+		sqlDB, _ := db.DB()
+		if max := sqlDB.Stats().MaxOpenConnections; max != opts.MaxConns {
+			t.Errorf("Expected %d connections per database, got %d", opts.MaxConns, max)
+		}
+	}
+}
+
+func TestSetPoolOptions_FitsPostgresServerConns(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		opts              PoolOptions
+		maxOpen, maxConns int
+	}{
+		{"within the limit", PoolOptions{MaxOpen: 10, MaxConns: 4, MaxServerConns: 80}, 10, 4},
+		{"fewer connections", PoolOptions{MaxOpen: 25, MaxConns: 4, MaxServerConns: 80}, 25, 3},
+		{"fewer tenants", PoolOptions{MaxOpen: 100, MaxConns: 4, MaxServerConns: 80}, 78, 1},
+		{"no tenant limit", PoolOptions{MaxConns: 4, MaxServerConns: 80}, 18, 4},
+		{"no server limit", PoolOptions{MaxOpen: 100, MaxConns: 4}, 100, 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := NewPostgresManager("host=localhost", "")
+			manager.SetPoolOptions(tc.opts)
+			if manager.pool.MaxOpen != tc.maxOpen || manager.pool.MaxConns != tc.maxConns {
+				t.Errorf("Expected %d tenants with %d connections, got %d with %d",
+					tc.maxOpen, tc.maxConns, manager.pool.MaxOpen, manager.pool.MaxConns)
+			}
+			if limit := tc.opts.MaxServerConns; limit > 0 && (manager.pool.MaxOpen+1)*manager.pool.MaxConns+1 > limit {
+				t.Errorf("Expected the pools to fit in %d connections", limit)
+			}
+		})
+	}
+
+	// SQLite files are not bounded by a server
+	manager := newPoolTestManager(t, PoolOptions{MaxOpen: 100, MaxConns: 4, MaxServerConns: 80})
+	if manager.pool.MaxOpen != 100 || manager.pool.MaxConns != 4 {
+		t.Errorf("Expected SQLite limits to be kept, got %+v", manager.pool)
+	}
+}
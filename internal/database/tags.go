@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tags are the free-form labels of a post or scheduled job. They are stored
+// as one comma separated column, lower case and sorted, and serialized as a
+// JSON array. JSON input may also be a comma separated string, as sent by
+// HTML forms.
+type Tags []string
+
+// ParseTags splits a comma separated list into normalized tags: trimmed,
+// lower case, without a leading '#', deduplicated and sorted
+func ParseTags(list string) Tags {
+	return NormalizeTags(strings.Split(list, ","))
+}
+
+// NormalizeTags returns tags trimmed, lower case, without a leading '#',
+// deduplicated and sorted. Empty tags are dropped.
+func NormalizeTags(tags []string) Tags {
+	seen := make(map[string]bool, len(tags))
+	result := Tags{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+		tag = strings.ReplaceAll(tag, ",", "")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// String returns the tags as a comma separated list
+func (t Tags) String() string {
+	return strings.Join(t, ",")
+}
+
+// Value stores the tags as a comma separated list
+func (t Tags) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+// Scan reads a comma separated list of tags
+func (t *Tags) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*t = Tags{}
+	case string:
+		*t = ParseTags(v)
+	case []byte:
+		*t = ParseTags(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into tags", value)
+	}
+	return nil
+}
+
+// MarshalJSON writes the tags as an array, never null
+func (t Tags) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON accepts an array of tags or a comma separated string
+func (t *Tags) UnmarshalJSON(data []byte) error {
+	var list string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*t = ParseTags(list)
+		return nil
+	}
+	var tags []string
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return err
+	}
+	*t = NormalizeTags(tags)
+	return nil
+}
+
+// TagCondition returns a condition matching rows whose tags column holds the
+// tag given as its argument by TagPattern, e.g.
+//
+//	db.Where(TagCondition("posts.tags"), TagPattern(tag))
+func TagCondition(column string) string {
+	return fmt.Sprintf(`(',' || %s || ',') LIKE ? ESCAPE '\'`, column)
+}
+
+// TagPattern returns the LIKE pattern TagCondition matches tag with
+func TagPattern(tag string) string {
+	tags := NormalizeTags([]string{tag})
+	if len(tags) == 0 {
+		return ""
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(tags[0])
+	return "%," + escaped + ",%"
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// go-sqlite3 writes times as text carrying their own offset and SQLite
+// compares them as text, so a time written with the server's local offset
+// does not order with a range bound given in UTC. utcPool binds every time
+// in UTC, which keeps stored times and query bounds comparable whatever the
+// zone of the host or of the value.
+
+// utcPool is a connection pool binding times in UTC
+type utcPool struct {
+	gorm.ConnPool
+}
+
+// utcTx is a transaction of a utcPool
+type utcTx struct {
+	*sql.Tx
+	db *sql.DB
+}
+
+func (p *utcPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.ConnPool.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (p *utcPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.ConnPool.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (p *utcPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.ConnPool.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+// BeginTx starts a transaction that binds times in UTC as well
+func (p *utcPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	sqlDB, ok := p.ConnPool.(*sql.DB)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	tx, err := sqlDB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &utcTx{Tx: tx, db: sqlDB}, nil
+}
+
+// GetDBConn returns the wrapped pool, for gorm.DB.DB
+func (p *utcPool) GetDBConn() (*sql.DB, error) {
+	if sqlDB, ok := p.ConnPool.(*sql.DB); ok {
+		return sqlDB, nil
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// GetDBConn returns the pool of the transaction, for gorm.DB.DB
+func (t *utcTx) GetDBConn() (*sql.DB, error) {
+	return t.db, nil
+}
+
+func (t *utcTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (t *utcTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (t *utcTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+// utcArgs converts the times among args to UTC
+func utcArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				args[i] = v.UTC()
+			}
+		}
+	}
+	return args
+}
@@ -0,0 +1,490 @@
+// Package feeds turns the items of RSS and Atom feeds into draft or scheduled
+// posts. Feeds are polled by scheduler jobs of type database.JobTypePollFeed;
+// items are de-duplicated by GUID.
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"github.com/tkowalski/socgo/internal/posttemplates"
+	"github.com/tkowalski/socgo/internal/providers"
+	"github.com/tkowalski/socgo/internal/webhooks"
+	"gorm.io/gorm"
+)
+
+// DefaultTemplate is the post template of feeds created without one
+const DefaultTemplate = "{{.title}} {{.link}}"
+
+// DefaultPollInterval is used for feeds created without a poll interval
+const DefaultPollInterval = time.Hour
+
+// MinPollInterval is the shortest poll interval a feed may use
+const MinPollInterval = 5 * time.Minute
+
+// maxFeedSize limits the size of a fetched feed document
+const maxFeedSize = 5 << 20
+
+var (
+	// ErrNotFound is returned when the feed does not exist
+	ErrNotFound = errors.New("feed not found")
+	// ErrInvalid wraps validation errors of an Input
+	ErrInvalid = errors.New("invalid feed")
+	// ErrInvalidFeed wraps errors reading a feed document
+	ErrInvalidFeed = errors.New("invalid feed document")
+)
+
+// variables are the item fields a feed template may use
+var variables = map[string]bool{"title": true, "link": true, "summary": true, "published": true}
+
+// Input holds the editable fields of a feed
+type Input struct {
+	Name           string          `json:"name"`
+	URL            string          `json:"url"`
+	Mode           string          `json:"mode"`
+	Template       string          `json:"template"`
+	ProviderIDs    database.IDList `json:"provider_ids"`
+	ScheduleOffset string          `json:"schedule_offset"`
+	Tags           database.Tags   `json:"tags"`
+	PollInterval   string          `json:"poll_interval"`
+	// IsActive pauses polling when false; nil keeps the current state, and
+	// new feeds are active
+	IsActive *bool `json:"is_active"`
+}
+
+// PollResult reports a poll of a feed
+type PollResult struct {
+	// Items counts the items of the feed document, New those not seen before
+	Items int `json:"items"`
+	New   int `json:"new"`
+	// Drafts and Jobs count the posts created from new items
+	Drafts int `json:"drafts"`
+	Jobs   int `json:"jobs"`
+	// Errors lists the items that could not be turned into posts
+	Errors []string `json:"errors"`
+}
+
+// List returns the feeds of userID ordered by name
+func List(db *gorm.DB, userID string) ([]database.Feed, error) {
+	var feeds []database.Feed
+	err := db.Where("user_id = ?", userID).Order("name, id").Find(&feeds).Error
+	return feeds, err
+}
+
+// Get returns the feed id of userID
+func Get(db *gorm.DB, userID string, id uint) (*database.Feed, error) {
+	var feed database.Feed
+	result := db.Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&feed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &feed, nil
+}
+
+// Create adds a feed for userID and schedules its first poll right away
+func Create(ctx context.Context, db *gorm.DB, userID string, in Input, now time.Time) (*database.Feed, error) {
+	feed := &database.Feed{UserID: userID, IsActive: true}
+	if err := apply(db, feed, in); err != nil {
+		return nil, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(feed).Error; err != nil {
+			return err
+		}
+		if err := SchedulePoll(tx, feed, now); err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionFeedCreated, TargetType: audit.TargetFeed, TargetID: feed.ID, After: feed,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// Update replaces the fields of the feed id with in. A feed that is resumed
+// is polled right away.
+func Update(ctx context.Context, db *gorm.DB, userID string, id uint, in Input, now time.Time) (*database.Feed, error) {
+	feed, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *feed
+	if err := apply(db, feed, in); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(feed).Error; err != nil {
+			return err
+		}
+		if feed.IsActive {
+			if err := SchedulePoll(tx, feed, now); err != nil {
+				return err
+			}
+		} else if err := cancelPolls(tx, feed.ID); err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionFeedUpdated, TargetType: audit.TargetFeed, TargetID: feed.ID, Before: before, After: feed,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// Delete removes the feed id with its seen items and pending polls. Posts
+// created from it are kept.
+func Delete(ctx context.Context, db *gorm.DB, userID string, id uint) error {
+	feed, err := Get(db, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := cancelPolls(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Where("feed_id = ?", id).Delete(&database.FeedItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(feed).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionFeedDeleted, TargetType: audit.TargetFeed, TargetID: id, Before: feed,
+		})
+	})
+}
+
+// SchedulePoll creates a pending poll job for feed at at, unless one is
+// already pending
+func SchedulePoll(db *gorm.DB, feed *database.Feed, at time.Time) error {
+	var pending int64
+	if err := db.Model(&database.ScheduledJob{}).
+		Where("job_type = ? AND payload_data = ? AND status = ?", database.JobTypePollFeed, pollPayload(feed.ID), database.JobStatusPending).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+	return db.Create(&database.ScheduledJob{
+		JobType:     database.JobTypePollFeed,
+		PayloadData: pollPayload(feed.ID),
+		UserID:      feed.UserID,
+		ScheduledAt: at,
+		Status:      database.JobStatusPending,
+	}).Error
+}
+
+// NextPoll returns when feed is polled next after a poll at now
+func NextPoll(feed *database.Feed, now time.Time) time.Time {
+	interval, err := time.ParseDuration(feed.PollInterval)
+	if err != nil || interval < MinPollInterval {
+		interval = DefaultPollInterval
+	}
+	return now.Add(interval)
+}
+
+// FeedID returns the ID of the feed a poll job polls
+func FeedID(job *database.ScheduledJob) (uint, error) {
+	id, err := strconv.ParseUint(job.PayloadData, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid feed ID %q", job.PayloadData)
+	}
+	return uint(id), nil
+}
+
+func pollPayload(feedID uint) string {
+	return strconv.FormatUint(uint64(feedID), 10)
+}
+
+func cancelPolls(tx *gorm.DB, feedID uint) error {
+	return tx.Where("job_type = ? AND payload_data = ? AND status = ?", database.JobTypePollFeed, pollPayload(feedID), database.JobStatusPending).
+		Delete(&database.ScheduledJob{}).Error
+}
+
+// Poll fetches the feed id with client and turns its new items into posts.
+// Items published before the feed was added are only recorded as seen, so
+// connecting a feed does not repost its archive. The outcome is stored in
+// the feed's LastPolledAt and LastError.
+func Poll(ctx context.Context, db *gorm.DB, userID string, id uint, client *http.Client, now time.Time) (*PollResult, error) {
+	feed, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := poll(ctx, db, feed, client, now)
+	feed.LastPolledAt = &now
+	feed.LastError = ""
+	if err != nil {
+		feed.LastError = err.Error()
+	} else if len(result.Errors) > 0 {
+		feed.LastError = strings.Join(result.Errors, "; ")
+	}
+	if saveErr := db.Model(feed).Select("last_polled_at", "last_error").Updates(feed).Error; saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return result, err
+}
+
+func poll(ctx context.Context, db *gorm.DB, feed *database.Feed, client *http.Client, now time.Time) (*PollResult, error) {
+	items, err := fetch(ctx, client, feed.URL)
+	if err != nil {
+		return nil, err
+	}
+	result := &PollResult{Items: len(items), Errors: []string{}}
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	guids := make([]string, len(items))
+	for i, item := range items {
+		guids[i] = item.GUID
+	}
+	var seen []string
+	if err := db.Model(&database.FeedItem{}).Where("feed_id = ? AND guid IN ?", feed.ID, guids).Pluck("guid", &seen).Error; err != nil {
+		return nil, fmt.Errorf("failed to load seen items: %w", err)
+	}
+	known := make(map[string]bool, len(seen))
+	for _, guid := range seen {
+		known[guid] = true
+	}
+
+	var targets []database.Provider
+	if len(feed.ProviderIDs) > 0 {
+		if err := db.Where("user_id = ? AND id IN ?", feed.UserID, []uint(feed.ProviderIDs)).Find(&targets).Error; err != nil {
+			return nil, fmt.Errorf("failed to load providers: %w", err)
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Feeds list the newest items first; posts are created oldest first
+		for i := len(items) - 1; i >= 0; i-- {
+			item := items[i]
+			if known[item.GUID] {
+				continue
+			}
+			known[item.GUID] = true
+			result.New++
+
+			if err := tx.Create(&database.FeedItem{
+				FeedID: feed.ID, GUID: item.GUID, Title: item.Title, Link: item.Link, PublishedAt: item.Published,
+			}).Error; err != nil {
+				return err
+			}
+			if item.Published != nil && item.Published.Before(feed.CreatedAt) {
+				continue
+			}
+			if err := createPosts(ctx, tx, feed, targets, item, now, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create posts: %w", err)
+	}
+	return result, nil
+}
+
+// createPosts turns item into a draft, or in FeedModeSchedule into a job per
+// target provider. Content a provider would reject becomes a draft instead.
+func createPosts(ctx context.Context, tx *gorm.DB, feed *database.Feed, targets []database.Provider, item Item, now time.Time, result *PollResult) error {
+	published := ""
+	if item.Published != nil {
+		published = item.Published.Format("2006-01-02")
+	}
+	content, err := posttemplates.Render(feed.Template, map[string]string{
+		"title": item.Title, "link": item.Link, "summary": item.Summary, "published": published,
+	})
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", item.GUID, err))
+		return nil
+	}
+	content = strings.TrimSpace(content)
+
+	draft := func(providerID uint) error {
+		post := database.Post{
+			Title:      item.Title,
+			Content:    content,
+			UserID:     feed.UserID,
+			ProviderID: providerID,
+			Tags:       feed.Tags,
+			Status:     database.PostStatusDraft,
+		}
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		result.Drafts++
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionPostCreated, TargetType: audit.TargetPost, TargetID: post.ID, After: post,
+		})
+	}
+
+	if feed.Mode != database.FeedModeSchedule {
+		if len(targets) == 0 {
+			return draft(0)
+		}
+		for _, provider := range targets {
+			if err := draft(provider.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	offset, _ := time.ParseDuration(feed.ScheduleOffset)
+	for _, provider := range targets {
+		err := providers.ValidateContent(providers.ProviderType(provider.Name), content)
+		if err == nil {
+			// Validated in tx, so that items of one poll count each other
+			err = limits.Validate(tx, feed.UserID, provider.ID, now.Add(offset), 0)
+			if err != nil && !errors.Is(err, limits.ErrConflict) {
+				return err
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s: %v, saved as draft", item.GUID, provider.Name, err))
+			if err := draft(provider.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		job := database.ScheduledJob{
+			JobType:     database.JobTypePublishPost,
+			PayloadData: content,
+			UserID:      feed.UserID,
+			ProviderID:  provider.ID,
+			ScheduledAt: now.Add(offset),
+			Tags:        feed.Tags,
+			Status:      database.JobStatusPending,
+		}
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+		result.Jobs++
+		if err := audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionJobScheduled, TargetType: audit.TargetJob, TargetID: job.ID, After: job,
+		}); err != nil {
+			return err
+		}
+		if err := webhooks.Emit(tx, feed.UserID, webhooks.EventJobScheduled, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch downloads and parses the feed at feedURL
+func fetch(ctx context.Context, client *http.Client, feedURL string) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch feed: %s", resp.Status)
+	}
+	return Parse(io.LimitReader(resp.Body, maxFeedSize))
+}
+
+// apply validates in and copies it onto feed
+func apply(db *gorm.DB, feed *database.Feed, in Input) error {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	feedURL := strings.TrimSpace(in.URL)
+	if parsed, err := url.Parse(feedURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an http or https URL", ErrInvalid)
+	}
+
+	mode := strings.TrimSpace(in.Mode)
+	if mode == "" {
+		mode = database.FeedModeDraft
+	}
+	if mode != database.FeedModeDraft && mode != database.FeedModeSchedule {
+		return fmt.Errorf("%w: mode must be %s or %s", ErrInvalid, database.FeedModeDraft, database.FeedModeSchedule)
+	}
+
+	template := in.Template
+	if strings.TrimSpace(template) == "" {
+		template = DefaultTemplate
+	}
+	names, err := posttemplates.Variables(template)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, strings.TrimPrefix(err.Error(), posttemplates.ErrInvalid.Error()+": "))
+	}
+	for _, name := range names {
+		if !variables[name] {
+			return fmt.Errorf("%w: unknown template variable %q, use title, link, summary or published", ErrInvalid, name)
+		}
+	}
+
+	offset := strings.TrimSpace(in.ScheduleOffset)
+	if offset != "" {
+		if duration, err := time.ParseDuration(offset); err != nil || duration < 0 {
+			return fmt.Errorf("%w: schedule_offset must be a positive duration such as 2h", ErrInvalid)
+		}
+	}
+	interval := strings.TrimSpace(in.PollInterval)
+	if interval == "" {
+		interval = DefaultPollInterval.String()
+	}
+	if duration, err := time.ParseDuration(interval); err != nil || duration < MinPollInterval {
+		return fmt.Errorf("%w: poll_interval must be a duration of at least %s", ErrInvalid, MinPollInterval)
+	}
+
+	providerIDs := database.IDList{}
+	for _, id := range in.ProviderIDs {
+		var count int64
+		if err := db.Model(&database.Provider{}).Where("user_id = ? AND id = ?", feed.UserID, id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: provider %d not found", ErrInvalid, id)
+		}
+		providerIDs = append(providerIDs, id)
+	}
+	if mode == database.FeedModeSchedule && len(providerIDs) == 0 {
+		return fmt.Errorf("%w: scheduling posts needs at least one provider", ErrInvalid)
+	}
+
+	feed.Name = name
+	feed.URL = feedURL
+	feed.Mode = mode
+	feed.Template = template
+	feed.ProviderIDs = providerIDs
+	feed.ScheduleOffset = offset
+	feed.Tags = database.NormalizeTags(in.Tags)
+	feed.PollInterval = interval
+	if in.IsActive != nil {
+		feed.IsActive = *in.IsActive
+	}
+	return nil
+}
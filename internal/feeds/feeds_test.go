@@ -0,0 +1,248 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+)
+
+// serve returns a server answering with the document body points to, so
+// tests can add items between polls
+func serve(t *testing.T, body *string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, *body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func rss(items ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Blog</title>` + strings.Join(items, "") + `</channel></rss>`
+}
+
+func rssItem(guid, title string, published time.Time) string {
+	return fmt.Sprintf(`<item><guid>%s</guid><title>%s</title><link>https://example.com/%s</link><description>&lt;p&gt;About %s&lt;/p&gt;</description><pubDate>%s</pubDate></item>`,
+		guid, title, guid, title, published.Format(time.RFC1123Z))
+}
+
+func TestParse_RSSAndAtom(t *testing.T) {
+	items, err := Parse(strings.NewReader(rss(
+		rssItem("a", "First", time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)),
+		`<item><title>No GUID</title><link>https://example.com/b</link></item>`,
+	)))
+	if err != nil {
+		t.Fatalf("Parse RSS failed: %v", err)
+	}
+	if len(items) != 2 || items[0].GUID != "a" || items[0].Summary != "About First" || items[0].Published == nil || items[0].Published.Day() != 1 {
+		t.Fatalf("Unexpected RSS items: %+v", items)
+	}
+	if items[1].GUID != "https://example.com/b" {
+		t.Errorf("Expected the link to identify an item without GUID, got %q", items[1].GUID)
+	}
+
+	items, err = Parse(strings.NewReader(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+		<entry><id>urn:1</id><title>Atom post</title><link rel="self" href="https://example.com/self"/><link href="https://example.com/atom"/>
+		<content type="html">&lt;b&gt;Bold&lt;/b&gt; text</content><updated>2026-05-02T08:00:00Z</updated></entry></feed>`))
+	if err != nil {
+		t.Fatalf("Parse Atom failed: %v", err)
+	}
+	if len(items) != 1 || items[0].GUID != "urn:1" || items[0].Link != "https://example.com/atom" || items[0].Summary != "Bold text" || items[0].Published == nil {
+		t.Fatalf("Unexpected Atom items: %+v", items)
+	}
+
+	if _, err := Parse(strings.NewReader(`<html><body/></html>`)); !errors.Is(err, ErrInvalidFeed) {
+		t.Errorf("Expected ErrInvalidFeed for HTML, got %v", err)
+	}
+}
+
+func TestPoll_CreatesDraftsOnceAndSkipsOldItems(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	now := time.Now()
+
+	body := rss(
+		rssItem("new", "Fresh post", now.Add(time.Hour)),
+		rssItem("old", "Archived post", now.Add(-24*time.Hour)),
+	)
+	server := serve(t, &body)
+
+	feed, err := Create(ctx, db, "alice", Input{Name: "Blog", URL: server.URL, Template: "{{.title}}: {{.summary}} {{.link}}"}, now)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	var polls int64
+	db.Model(&database.ScheduledJob{}).Where("job_type = ? AND payload_data = ?", database.JobTypePollFeed, fmt.Sprint(feed.ID)).Count(&polls)
+	if polls != 1 {
+		t.Errorf("Expected the first poll to be scheduled, got %d jobs", polls)
+	}
+
+	result, err := Poll(ctx, db, "alice", feed.ID, server.Client(), now)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if result.Items != 2 || result.New != 2 || result.Drafts != 1 || result.Jobs != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	var drafts []database.Post
+	db.Where("status = ?", database.PostStatusDraft).Find(&drafts)
+	if len(drafts) != 1 || drafts[0].Content != "Fresh post: About Fresh post https://example.com/new" || drafts[0].Title != "Fresh post" {
+		t.Fatalf("Unexpected drafts: %+v", drafts)
+	}
+
+	body = rss(
+		rssItem("newer", "Newer post", now.Add(2*time.Hour)),
+		rssItem("new", "Fresh post", now.Add(time.Hour)),
+	)
+	result, err = Poll(ctx, db, "alice", feed.ID, server.Client(), now)
+	if err != nil {
+		t.Fatalf("Second poll failed: %v", err)
+	}
+	if result.New != 1 || result.Drafts != 1 {
+		t.Errorf("Expected only the newer item to be new, got %+v", result)
+	}
+
+	polled, _ := Get(db, "alice", feed.ID)
+	if polled.LastPolledAt == nil || polled.LastError != "" {
+		t.Errorf("Expected a successful poll to be recorded: %+v", polled)
+	}
+}
+
+func TestPoll_SchedulesPostsPerProvider(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	now := time.Now()
+	facebook := database.Provider{Name: "facebook", Type: "facebook", UserID: "alice", IsActive: true}
+	tiktok := database.Provider{Name: "tiktok", Type: "tiktok", UserID: "alice", IsActive: true}
+	db.Create(&facebook)
+	db.Create(&tiktok)
+
+	body := rss(rssItem("long", strings.Repeat("a", 2300), now.Add(time.Hour)))
+	server := serve(t, &body)
+
+	feed, err := Create(ctx, db, "alice", Input{
+		Name: "Blog", URL: server.URL, Mode: database.FeedModeSchedule, ScheduleOffset: "2h",
+		ProviderIDs: database.IDList{facebook.ID, tiktok.ID}, Tags: database.Tags{"Blog"},
+	}, now)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	result, err := Poll(ctx, db, "alice", feed.ID, server.Client(), now)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	// TikTok rejects captions over 2200 characters, so it gets a draft
+	if result.Jobs != 1 || result.Drafts != 1 || len(result.Errors) != 1 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	var job database.ScheduledJob
+	db.Where("job_type = ?", database.JobTypePublishPost).First(&job)
+	if job.ProviderID != facebook.ID || !job.ScheduledAt.Equal(now.Add(2*time.Hour)) || job.Tags.String() != "blog" {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+	polled, _ := Get(db, "alice", feed.ID)
+	if !strings.Contains(polled.LastError, "tiktok") {
+		t.Errorf("Expected the rejected provider to be reported, got %q", polled.LastError)
+	}
+}
+
+func TestPoll_KeepsPostingLimits(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	now := time.Now()
+	facebook := database.Provider{Name: "facebook", Type: "facebook", UserID: "alice", IsActive: true}
+	db.Create(&facebook)
+	if _, err := limits.Set(ctx, db, "alice", facebook.ID, limits.Input{MinGapMinutes: 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := rss(rssItem("1", "First", now.Add(time.Hour)), rssItem("2", "Second", now.Add(time.Hour)))
+	server := serve(t, &body)
+	feed, err := Create(ctx, db, "alice", Input{
+		Name: "Blog", URL: server.URL, Mode: database.FeedModeSchedule, ScheduleOffset: "1h", ProviderIDs: database.IDList{facebook.ID},
+	}, now)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Items of one poll land at the same time, so only the first is scheduled
+	result, err := Poll(ctx, db, "alice", feed.ID, server.Client(), now)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if result.Jobs != 1 || result.Drafts != 1 || len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "posting limit") {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestPoll_RecordsFetchErrors(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	feed, err := Create(context.Background(), db, "alice", Input{Name: "Gone", URL: server.URL}, time.Now())
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := Poll(context.Background(), db, "alice", feed.ID, server.Client(), time.Now()); err == nil {
+		t.Fatal("Expected polling a missing feed to fail")
+	}
+	polled, _ := Get(db, "alice", feed.ID)
+	if !strings.Contains(polled.LastError, "404") {
+		t.Errorf("Expected the fetch error to be stored, got %q", polled.LastError)
+	}
+}
+
+func TestCreate_Validates(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+
+	for _, in := range []Input{
+		{Name: "", URL: "https://example.com/feed"},
+		{Name: "Local", URL: "file:///etc/passwd"},
+		{Name: "Blog", URL: "https://example.com/feed", Template: "{{.author}}"},
+		{Name: "Blog", URL: "https://example.com/feed", PollInterval: "1m"},
+		{Name: "Blog", URL: "https://example.com/feed", Mode: database.FeedModeSchedule},
+		{Name: "Blog", URL: "https://example.com/feed", ProviderIDs: database.IDList{42}},
+	} {
+		if _, err := Create(ctx, db, "alice", in, time.Now()); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for %+v, got %v", in, err)
+		}
+	}
+
+	feed, err := Create(ctx, db, "alice", Input{Name: "Blog", URL: "https://example.com/feed"}, time.Now())
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if feed.Template != DefaultTemplate || feed.PollInterval != "1h0m0s" || feed.Mode != database.FeedModeDraft || !feed.IsActive {
+		t.Errorf("Expected defaults, got %+v", feed)
+	}
+
+	paused := false
+	if _, err := Update(ctx, db, "alice", feed.ID, Input{Name: "Blog", URL: "https://example.com/feed", IsActive: &paused}, time.Now()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	var polls int64
+	db.Model(&database.ScheduledJob{}).Where("job_type = ? AND status = ?", database.JobTypePollFeed, database.JobStatusPending).Count(&polls)
+	if polls != 0 {
+		t.Errorf("Expected pausing to cancel pending polls, got %d", polls)
+	}
+
+	if err := Delete(ctx, db, "alice", feed.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Get(db, "alice", feed.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
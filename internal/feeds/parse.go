@@ -0,0 +1,128 @@
+package feeds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Item is an entry of an RSS or Atom feed
+type Item struct {
+	GUID      string
+	Title     string
+	Link      string
+	Summary   string
+	Published *time.Time
+}
+
+// document holds the elements of RSS 2.0 and Atom documents that Parse reads
+type document struct {
+	XMLName xml.Name
+	// RSS
+	Items []struct {
+		GUID        string `xml:"guid"`
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		PubDate     string `xml:"pubDate"`
+	} `xml:"channel>item"`
+	// Atom
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// dateLayouts are the date formats of RSS pubDate and Atom dates
+var dateLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// Parse reads the items of an RSS 2.0 or Atom document. Items without a GUID
+// are identified by their link, or else by a hash of their title.
+func Parse(r io.Reader) ([]Item, error) {
+	var doc document
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Feeds declaring a charset are read as UTF-8
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+
+	var items []Item
+	switch doc.XMLName.Local {
+	case "rss":
+		for _, entry := range doc.Items {
+			items = append(items, newItem(entry.GUID, entry.Title, entry.Link, entry.Description, entry.PubDate))
+		}
+	case "feed":
+		for _, entry := range doc.Entries {
+			link := ""
+			for _, l := range entry.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			summary := entry.Summary
+			if summary == "" {
+				summary = entry.Content
+			}
+			published := entry.Published
+			if published == "" {
+				published = entry.Updated
+			}
+			items = append(items, newItem(entry.ID, entry.Title, link, summary, published))
+		}
+	default:
+		return nil, fmt.Errorf("%w: <%s> is neither RSS nor Atom", ErrInvalidFeed, doc.XMLName.Local)
+	}
+	return items, nil
+}
+
+func newItem(guid, title, link, summary, published string) Item {
+	item := Item{
+		GUID:    strings.TrimSpace(guid),
+		Title:   strings.TrimSpace(title),
+		Link:    strings.TrimSpace(link),
+		Summary: plainText(summary),
+	}
+	if item.GUID == "" {
+		item.GUID = item.Link
+	}
+	if item.GUID == "" {
+		sum := sha256.Sum256([]byte(item.Title))
+		item.GUID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	published = strings.TrimSpace(published)
+	for _, layout := range dateLayouts {
+		if at, err := time.Parse(layout, published); err == nil {
+			item.Published = &at
+			break
+		}
+	}
+	return item
+}
+
+// plainText strips the markup of an HTML summary
+func plainText(s string) string {
+	s = html.UnescapeString(tagPattern.ReplaceAllString(s, " "))
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -6,25 +6,37 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/tkowalski/socgo/internal/audit"
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
 )
 
 type APITokenHandler struct {
 	dbManager *database.Manager
 	secretKey []byte
+	baseURL   string
 }
 
 type APITokenRequest struct {
-	// Token generation doesn't require additional parameters
+	// Scope limits the token, e.g. to database.TokenScopeCalendar; empty is
+	// the whole API
+	Scope string `json:"scope"`
 }
 
 type APITokenResponse struct {
 	Token     string    `json:"token"`
+	Scope     string    `json:"scope,omitempty"`
+	FeedURL   string    `json:"feed_url,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	Message   string    `json:"message"`
 }
@@ -38,13 +50,28 @@ func NewAPITokenHandler(dbManager *database.Manager) *APITokenHandler {
 	}
 }
 
+// SetBaseURL sets the public URL of the server used in calendar feed URLs
+func (h *APITokenHandler) SetBaseURL(baseURL string) {
+	h.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
 func (h *APITokenHandler) HandleCreateToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get user ID (currently defaults to "default_user")
+	// The request body is optional
+	var req APITokenRequest
+	if err := decodeRequest(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Scope != database.TokenScopeAPI && req.Scope != database.TokenScopeCalendar {
+		http.Error(w, "Unknown token scope", http.StatusBadRequest)
+		return
+	}
+
 	userID := h.getUserID(r)
 
 	// Generate random bytes for token
@@ -82,6 +109,7 @@ func (h *APITokenHandler) HandleCreateToken(w http.ResponseWriter, r *http.Reque
 	apiToken := database.APIToken{
 		Hash:      tokenHashString,
 		UserID:    userID,
+		Scope:     req.Scope,
 		CreatedAt: time.Now(),
 	}
 
@@ -90,20 +118,40 @@ func (h *APITokenHandler) HandleCreateToken(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "Failed to create API token", http.StatusInternalServerError)
 		return
 	}
+	if err := audit.Record(r.Context(), db, audit.Entry{
+		Action: audit.ActionTokenCreated, TargetType: audit.TargetToken, TargetID: apiToken.ID, After: apiToken,
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
 
 	// Return the token (only once)
 	response := APITokenResponse{
 		Token:     token,
+		Scope:     apiToken.Scope,
 		CreatedAt: apiToken.CreatedAt,
 		Message:   "API token created successfully. Store it securely as it won't be shown again.",
 	}
+	if apiToken.Scope == database.TokenScopeCalendar {
+		response.FeedURL = h.baseURL + "/calendar.ics?token=" + url.QueryEscape(token)
+		response.Message = "Calendar feed token created. Subscribe to the feed URL in your calendar app; it won't be shown again."
+	}
+
+	if r.Header.Get("HX-Request") == "true" && response.FeedURL != "" {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `<div class="p-3 bg-green-100 text-green-800 rounded text-sm">Subscribe to this URL in your calendar app (shown once):
+			<input type="text" readonly value="%s" onclick="this.select()" class="mt-2 w-full border rounded px-2 py-1 text-gray-800"/></div>`,
+			html.EscapeString(response.FeedURL))
+		return
+	}
 
 	h.writeJSONResponse(w, response, http.StatusCreated)
 }
 
+// getUserID returns the user owning the token; tokens are personal and are
+// kept in the user's own database even when a workspace is selected
 func (h *APITokenHandler) getUserID(r *http.Request) string {
-	// TODO: Implement proper user authentication
-	return "default_user"
+	return tenant.FromRequest(r).UserID
 }
 
 func (h *APITokenHandler) writeJSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
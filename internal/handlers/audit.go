@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+)
+
+// defaultAuditLimit caps the number of entries listed at once; exports are
+// not limited
+const defaultAuditLimit = 200
+
+type AuditHandler struct {
+	dbManager *database.Manager
+}
+
+// NewAuditHandler creates a new AuditHandler instance
+func NewAuditHandler(dbManager *database.Manager) *AuditHandler {
+	return &AuditHandler{
+		dbManager: dbManager,
+	}
+}
+
+// AuditPage renders the audit log page
+func (h *AuditHandler) AuditPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Audit log",
+		CurrentPage: "audit",
+		FlashType:   "info",
+		Content:     templates.AuditContent(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering audit page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleListAudit returns filtered audit entries as JSON or HTML table rows
+func (h *AuditHandler) HandleListAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+
+	logs, ok := h.queryAudit(w, r, filter)
+	if !ok {
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, logs, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(logs) == 0 {
+		sb.WriteString(`<tr><td colspan="6" class="py-4 text-center text-gray-500">No matching entries</td></tr>`)
+	}
+	for _, entry := range logs {
+		sb.WriteString(fmt.Sprintf(`
+			<tr class="border-b align-top">
+				<td class="py-2 pr-4 whitespace-nowrap">%s</td>
+				<td class="py-2 pr-4">%s</td>
+				<td class="py-2 pr-4 font-medium">%s</td>
+				<td class="py-2 pr-4">%s #%d</td>
+				<td class="py-2 pr-4"><span class="px-2 py-1 text-xs rounded bg-gray-100 text-gray-800">%s</span></td>
+				<td class="py-2 text-xs text-gray-600"><details><summary class="cursor-pointer">Changes</summary><pre class="whitespace-pre-wrap">Before: %s
+After: %s</pre></details></td>
+			</tr>`,
+			entry.CreatedAt.Format("2006-01-02 15:04:05"), html.EscapeString(entry.ActorID), html.EscapeString(entry.Action),
+			html.EscapeString(entry.TargetType), entry.TargetID, html.EscapeString(entry.Source),
+			html.EscapeString(entry.Before), html.EscapeString(entry.After)))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleExportAudit writes the filtered audit entries as CSV
+func (h *AuditHandler) HandleExportAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logs, ok := h.queryAudit(w, r, filter)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.csv"`, time.Now().Format("20060102")))
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "created_at", "actor_id", "action", "target_type", "target_id", "source", "before", "after"}); err != nil {
+		log.Printf("Error writing audit export: %v", err)
+		return
+	}
+	for _, entry := range logs {
+		if err := writer.Write([]string{
+			strconv.FormatUint(uint64(entry.ID), 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			entry.ActorID,
+			entry.Action,
+			entry.TargetType,
+			strconv.FormatUint(uint64(entry.TargetID), 10),
+			entry.Source,
+			entry.Before,
+			entry.After,
+		}); err != nil {
+			log.Printf("Error writing audit export: %v", err)
+			return
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("Error writing audit export: %v", err)
+	}
+}
+
+func (h *AuditHandler) queryAudit(w http.ResponseWriter, r *http.Request, filter audit.Filter) ([]database.AuditLog, bool) {
+	db, err := h.dbManager.GetDB(tenant.FromRequest(r).DBKey())
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	logs, err := audit.Query(db, filter)
+	if err != nil {
+		log.Printf("Error querying audit log: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return logs, true
+}
+
+// parseAuditFilter reads filters from the query string. Dates use the
+// calendar's YYYY-MM-DD format and "to" is inclusive.
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+	filter := audit.Filter{
+		ActorID:    strings.TrimSpace(q.Get("actor_id")),
+		Action:     strings.TrimSpace(q.Get("action")),
+		TargetType: strings.TrimSpace(q.Get("target_type")),
+		Source:     strings.TrimSpace(q.Get("source")),
+	}
+
+	if value := q.Get("target_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid target_id")
+		}
+		filter.TargetID = uint(id)
+	}
+	if value := q.Get("from"); value != "" {
+		from, err := time.ParseInLocation(calendarDateLayout, value, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date, use YYYY-MM-DD")
+		}
+		filter.From = from
+	}
+	if value := q.Get("to"); value != "" {
+		to, err := time.ParseInLocation(calendarDateLayout, value, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date, use YYYY-MM-DD")
+		}
+		filter.To = to.AddDate(0, 0, 1)
+	}
+	if value := q.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return filter, fmt.Errorf("invalid limit")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
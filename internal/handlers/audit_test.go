@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+)
+
+func TestAuditHandler_RecordsDraftActionsAndExports(t *testing.T) {
+	handler, dbManager := newCalendarTestHandler(t)
+	auditHandler := NewAuditHandler(dbManager)
+
+	// Create a draft as if through an API token
+	req := httptest.NewRequest("POST", "/api/drafts", strings.NewReader(`{"content":"Audited"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(tenant.WithContext(req.Context(), tenant.Context{
+		UserID: "default_user", Role: database.WorkspaceRoleOwner, Source: tenant.SourceAPI,
+	}))
+	rr := httptest.NewRecorder()
+	handler.HandleCreateDraft(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected draft to be created, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/audit/logs?source=api_token&action=post.created", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	auditHandler.HandleListAudit(rr, req)
+
+	var logs []database.AuditLog
+	if err := json.Unmarshal(rr.Body.Bytes(), &logs); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(logs) != 1 || logs[0].TargetType != audit.TargetPost || logs[0].ActorID != "default_user" {
+		t.Fatalf("Unexpected audit entries: %+v", logs)
+	}
+
+	req = httptest.NewRequest("GET", "/audit/export.csv", nil)
+	rr = httptest.NewRecorder()
+	auditHandler.HandleExportAudit(rr, req)
+
+	if ct := rr.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Expected text/csv, got %s", ct)
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 2 || records[0][3] != "action" || records[1][3] != audit.ActionPostCreated || records[1][6] != tenant.SourceAPI {
+		t.Errorf("Unexpected CSV export: %v", records)
+	}
+
+	req = httptest.NewRequest("GET", "/audit/logs?from=yesterday", nil)
+	rr = httptest.NewRecorder()
+	auditHandler.HandleListAudit(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid date, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/backup"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+)
+
+type BackupHandler struct {
+	dbManager *database.Manager
+}
+
+// NewBackupHandler creates a new BackupHandler instance
+func NewBackupHandler(dbManager *database.Manager) *BackupHandler {
+	return &BackupHandler{
+		dbManager: dbManager,
+	}
+}
+
+// HandleExportDatabase downloads a point-in-time copy of the current user's or
+// workspace's database. The copy includes provider credentials, so the route
+// is limited to owners.
+func (h *BackupHandler) HandleExportDatabase(w http.ResponseWriter, r *http.Request) {
+	if h.dbManager.Driver() != database.DriverSQLite {
+		http.Error(w, backup.ErrUnsupportedDriver.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	key := tenant.FromRequest(r).DBKey()
+	db, release, err := h.dbManager.Acquire(key)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer release()
+
+	tmpDir, err := os.MkdirTemp("", "socgo-export-")
+	if err != nil {
+		log.Printf("Error creating export directory: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "export.db")
+	if err := backup.Snapshot(db, path); err != nil {
+		log.Printf("Error exporting database %s: %v", key, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error opening database export: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="socgo-%s.db"`, time.Now().Format("20060102-150405")))
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Error writing database export: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/backup"
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestBackupHandler_ExportsDatabaseSnapshot(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+	if dbManager.Driver() != database.DriverSQLite {
+		t.Skip("database export requires the sqlite driver")
+	}
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	db.Create(&database.Post{Content: "Exported", UserID: "default_user"})
+
+	rr := httptest.NewRecorder()
+	NewBackupHandler(dbManager).HandleExportDatabase(rr, httptest.NewRequest("GET", "/backups/export.db", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "export.db")
+	if err := os.WriteFile(path, rr.Body.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed to save export: %v", err)
+	}
+	if err := backup.Verify(path); err != nil {
+		t.Errorf("Export is not a valid database: %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/links"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+type BioHandler struct {
+	dbManager *database.Manager
+	baseURL   string
+}
+
+// NewBioHandler creates a new BioHandler instance
+func NewBioHandler(dbManager *database.Manager) *BioHandler {
+	return &BioHandler{dbManager: dbManager}
+}
+
+// SetBaseURL sets the public URL of the server shown with link-in-bio pages
+func (h *BioHandler) SetBaseURL(baseURL string) {
+	h.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// HandlePage renders the public link-in-bio page {slug}. Pages are public,
+// so the tenant is found through the control plane.
+func (h *BioHandler) HandlePage(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	route, err := h.dbManager.LookupBio(slug)
+	if err != nil {
+		if !errors.Is(err, database.ErrUnknownBio) {
+			log.Printf("Error looking up link-in-bio page: %v", err)
+		}
+		http.NotFound(w, r)
+		return
+	}
+	db, err := h.dbManager.GetDB(route.TenantKey)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := links.FindBioPage(db, slug)
+	if err != nil {
+		if !errors.Is(err, links.ErrBioNotFound) {
+			log.Printf("Error loading link-in-bio page: %v", err)
+		}
+		http.NotFound(w, r)
+		return
+	}
+	entries, err := links.BioEntries(db, page)
+	if err != nil {
+		log.Printf("Error loading link-in-bio entries: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := templates.BioPageData{Title: page.Title, Description: page.Description, Color: page.Color}
+	for _, entry := range entries {
+		data.Entries = append(data.Entries, templates.BioPageEntry{Title: entry.Title, Subtitle: entry.Subtitle, URL: entry.URL})
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := templates.BioPage(data).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering link-in-bio page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetBio returns the link-in-bio page settings as JSON, or as the HTML
+// form editing them
+func (h *BioHandler) HandleGetBio(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := links.GetBioPage(db, userID)
+	if err != nil && !errors.Is(err, links.ErrBioNotFound) {
+		h.writeError(w, err)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		if page == nil {
+			h.writeError(w, err)
+			return
+		}
+		writeJSON(w, page, http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, h.renderBioForm(page))
+}
+
+// HandleSaveBio creates or updates the link-in-bio page from a JSON or form
+// request
+func (h *BioHandler) HandleSaveBio(w http.ResponseWriter, r *http.Request) {
+	var in links.BioInput
+	if err := decodeBioInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	key := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(key)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	registry, err := h.dbManager.GetRegistryDB()
+	if err != nil {
+		log.Printf("Error getting registry database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := links.SaveBioPage(r.Context(), db, registry, key, in)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "bio-changed")
+	writeJSON(w, page, http.StatusOK)
+}
+
+// HandleListLinks lists the pinned links as JSON or as HTML list items
+func (h *BioHandler) HandleListLinks(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	pinned, err := links.ListBioLinks(db, userID)
+	if err != nil {
+		log.Printf("Error listing pinned links: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, pinned, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(pinned) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No pinned links yet; the page lists your latest posts only.</li>`)
+	}
+	for _, link := range pinned {
+		fmt.Fprintf(&sb, `
+		<li class="py-3 border-b">
+			<div class="flex justify-between items-center">
+				<span class="font-medium">%d. %s</span>
+				<button hx-delete="/links/bio/links/%d" hx-swap="none" hx-confirm="Unpin this link?" class="text-red-600 text-sm">Unpin</button>
+			</div>
+			<div class="text-sm text-gray-700 break-all">%s</div>
+		</li>`, link.Position, html.EscapeString(link.Title), link.ID, html.EscapeString(link.URL))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleGetLink returns one pinned link as JSON
+func (h *BioHandler) HandleGetLink(w http.ResponseWriter, r *http.Request) {
+	h.withLinkID(w, r, func(db *gorm.DB, userID string, id uint) {
+		pinned, err := links.GetBioLink(db, userID, id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		writeJSON(w, pinned, http.StatusOK)
+	})
+}
+
+// HandleCreateLink pins a link from a JSON or form request
+func (h *BioHandler) HandleCreateLink(w http.ResponseWriter, r *http.Request) {
+	var in links.BioLinkInput
+	if err := decodeBioLinkInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	pinned, err := links.CreateBioLink(r.Context(), db, userID, in)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "bio-links-changed")
+	writeJSON(w, pinned, http.StatusCreated)
+}
+
+// HandleUpdateLink replaces the fields of a pinned link
+func (h *BioHandler) HandleUpdateLink(w http.ResponseWriter, r *http.Request) {
+	var in links.BioLinkInput
+	if err := decodeBioLinkInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withLinkID(w, r, func(db *gorm.DB, userID string, id uint) {
+		pinned, err := links.UpdateBioLink(r.Context(), db, userID, id, in)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "bio-links-changed")
+		writeJSON(w, pinned, http.StatusOK)
+	})
+}
+
+// HandleDeleteLink unpins a link
+func (h *BioHandler) HandleDeleteLink(w http.ResponseWriter, r *http.Request) {
+	h.withLinkID(w, r, func(db *gorm.DB, userID string, id uint) {
+		if err := links.DeleteBioLink(r.Context(), db, userID, id); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "bio-links-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// withLinkID parses the {id} route variable and runs fn with the tenant's
+// database
+func (h *BioHandler) withLinkID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, id uint)) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid pinned link ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(id))
+}
+
+// writeError maps link-in-bio errors to HTTP status codes
+func (h *BioHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, links.ErrBioNotFound):
+		http.Error(w, "Link-in-bio page not found", http.StatusNotFound)
+	case errors.Is(err, links.ErrBioLinkNotFound):
+		http.Error(w, "Pinned link not found", http.StatusNotFound)
+	case errors.Is(err, links.ErrInvalidBio):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, links.ErrBioSlugTaken):
+		http.Error(w, "Slug is taken", http.StatusConflict)
+	default:
+		log.Printf("Error updating link-in-bio page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// decodeBioInput decodes link-in-bio settings from JSON or a form, where
+// enabled is a checkbox
+func decodeBioInput(r *http.Request, in *links.BioInput) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return decodeRequest(r, in)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	in.Slug = r.Form.Get("slug")
+	in.Title = r.Form.Get("title")
+	in.Description = r.Form.Get("description")
+	in.Color = r.Form.Get("color")
+	if value := r.Form.Get("post_count"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		in.PostCount = &count
+	}
+	enabled := r.Form.Get("enabled") == "on" || r.Form.Get("enabled") == "true"
+	in.Enabled = &enabled
+	return nil
+}
+
+// decodeBioLinkInput decodes a pinned link from JSON or a form
+func decodeBioLinkInput(r *http.Request, in *links.BioLinkInput) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return decodeRequest(r, in)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	in.Title = r.Form.Get("title")
+	in.URL = r.Form.Get("url")
+	if value := r.Form.Get("position"); value != "" {
+		position, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		in.Position = position
+	}
+	return nil
+}
+
+func (h *BioHandler) renderBioForm(page *database.BioPage) string {
+	if page == nil {
+		page = &database.BioPage{Color: "#2563eb", PostCount: 6, Enabled: true}
+	}
+	public := ""
+	if page.ID != 0 {
+		href := h.baseURL + links.BioPathPrefix + page.Slug
+		public = fmt.Sprintf(`<p class="text-sm mb-3">Public page: <a href="%s" target="_blank" class="text-blue-600 break-all">%s</a></p>`,
+			html.EscapeString(href), html.EscapeString(href))
+	}
+	checked := ""
+	if page.Enabled {
+		checked = " checked"
+	}
+
+	return fmt.Sprintf(`%s
+		<form hx-put="/links/bio" hx-swap="none" class="space-y-3">
+			<input type="text" name="slug" value="%s" placeholder="Slug, e.g. my-brand" required class="border rounded px-3 py-2 w-full"/>
+			<input type="text" name="title" value="%s" placeholder="Title" required class="border rounded px-3 py-2 w-full"/>
+			<textarea name="description" rows="2" placeholder="Description" class="border rounded px-3 py-2 w-full">%s</textarea>
+			<div class="flex items-center gap-4">
+				<label class="text-sm">Color <input type="color" name="color" value="%s"/></label>
+				<label class="text-sm">Latest posts <input type="number" name="post_count" value="%d" min="0" max="20" class="border rounded px-2 py-1 w-20"/></label>
+				<label class="text-sm flex items-center space-x-1"><input type="checkbox" name="enabled"%s/><span>Published</span></label>
+			</div>
+			<button type="submit" class="bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded">Save page</button>
+		</form>`,
+		public, html.EscapeString(page.Slug), html.EscapeString(page.Title), html.EscapeString(page.Description),
+		html.EscapeString(page.Color), page.PostCount, checked)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/links"
+	"github.com/tkowalski/socgo/internal/tenant"
+)
+
+func TestBioHandler_PublicPage(t *testing.T) {
+	_, dbManager := newCalendarTestHandler(t)
+	handler := NewBioHandler(dbManager)
+
+	req := httptest.NewRequest("PUT", "/api/bio", strings.NewReader(`{"slug":"acme","title":"Acme <Shop>"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleSaveBio(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/links/bio/links", strings.NewReader("title=Store&url=https%3A%2F%2Fstore.example.com&position=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	handler.HandleCreateLink(rr, req)
+	if rr.Code != http.StatusCreated || rr.Header().Get("HX-Trigger") != "bio-links-changed" {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(links.BioPathPrefix+"{slug}", handler.HandlePage).Methods("GET")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/bio/acme", nil))
+	body := rr.Body.String()
+	if rr.Code != http.StatusOK || !strings.Contains(body, "Acme &lt;Shop&gt;") || !strings.Contains(body, `href="/l/`) || !strings.Contains(body, "Store") ||
+		!strings.Contains(body, "background-color: #2563eb") {
+		t.Fatalf("Expected the public page, got %d:\n%s", rr.Code, body)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/bio/missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown slug, got %d", rr.Code)
+	}
+
+	// Slugs are unique across users and workspaces
+	req = httptest.NewRequest("PUT", "/api/bio", strings.NewReader(`{"slug":"acme","title":"Other"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(tenant.WithContext(req.Context(), tenant.Context{UserID: "bob", Role: database.WorkspaceRoleOwner}))
+	rr = httptest.NewRecorder()
+	handler.HandleSaveBio(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a taken slug, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/blackouts"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+type BlackoutHandler struct {
+	dbManager *database.Manager
+}
+
+// NewBlackoutHandler creates a new BlackoutHandler instance
+func NewBlackoutHandler(dbManager *database.Manager) *BlackoutHandler {
+	return &BlackoutHandler{dbManager: dbManager}
+}
+
+// BlackoutsPage renders the blackout windows page
+func (h *BlackoutHandler) BlackoutsPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Blackouts",
+		CurrentPage: "blackouts",
+		FlashType:   "info",
+		Content:     templates.BlackoutsContent(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering blackouts page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleListWindows lists the blackout windows as JSON or as HTML list items
+func (h *BlackoutHandler) HandleListWindows(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	windows, err := blackouts.List(db, userID)
+	if err != nil {
+		log.Printf("Error listing blackout windows: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, windows, http.StatusOK)
+		return
+	}
+
+	names, err := providerNames(db, userID)
+	if err != nil {
+		log.Printf("Error loading providers: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var sb strings.Builder
+	if len(windows) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No blackout windows yet.</li>`)
+	}
+	for _, window := range windows {
+		sb.WriteString(renderBlackoutWindow(window, names))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleGetWindow returns one blackout window as JSON
+func (h *BlackoutHandler) HandleGetWindow(w http.ResponseWriter, r *http.Request) {
+	h.withWindowID(w, r, func(db *gorm.DB, userID string, id uint) {
+		window, err := blackouts.Get(db, userID, id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		writeJSON(w, window, http.StatusOK)
+	})
+}
+
+// HandleCreateWindow creates a blackout window from a JSON or form request
+func (h *BlackoutHandler) HandleCreateWindow(w http.ResponseWriter, r *http.Request) {
+	var in blackouts.Input
+	if err := decodeBlackoutInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	window, err := blackouts.Create(r.Context(), db, userID, in)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "blackouts-changed")
+	writeJSON(w, window, http.StatusCreated)
+}
+
+// HandleUpdateWindow replaces the fields of a blackout window
+func (h *BlackoutHandler) HandleUpdateWindow(w http.ResponseWriter, r *http.Request) {
+	var in blackouts.Input
+	if err := decodeBlackoutInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withWindowID(w, r, func(db *gorm.DB, userID string, id uint) {
+		window, err := blackouts.Update(r.Context(), db, userID, id, in)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "blackouts-changed")
+		writeJSON(w, window, http.StatusOK)
+	})
+}
+
+// HandleDeleteWindow deletes a blackout window
+func (h *BlackoutHandler) HandleDeleteWindow(w http.ResponseWriter, r *http.Request) {
+	h.withWindowID(w, r, func(db *gorm.DB, userID string, id uint) {
+		if err := blackouts.Delete(r.Context(), db, userID, id); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "blackouts-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// HandleStatus returns whether publishing is paused and the windows in force,
+// as JSON or as the HTML pause switch
+func (h *BlackoutHandler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	status, err := blackouts.GetStatus(db, userID, time.Now())
+	if err != nil {
+		log.Printf("Error loading publishing status: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, status, http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, renderPublishingStatus(status))
+}
+
+// HandlePause pauses all publishing, with an optional reason
+func (h *BlackoutHandler) HandlePause(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is fine
+	if err := decodeRequest(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	pause, err := blackouts.Pause(r.Context(), db, userID, req.Reason)
+	if err != nil {
+		log.Printf("Error pausing publishing: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "publishing-changed")
+	writeJSON(w, pause, http.StatusOK)
+}
+
+// HandleResume lifts the publishing pause
+func (h *BlackoutHandler) HandleResume(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := blackouts.Resume(r.Context(), db, userID); err != nil {
+		log.Printf("Error resuming publishing: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "publishing-changed")
+	w.WriteHeader(http.StatusOK)
+}
+
+// withWindowID parses the {id} route variable and runs fn with the tenant's
+// database
+func (h *BlackoutHandler) withWindowID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, id uint)) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid blackout window ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(id))
+}
+
+// writeError maps blackout errors to HTTP status codes
+func (h *BlackoutHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, blackouts.ErrNotFound):
+		http.Error(w, "Blackout window not found", http.StatusNotFound)
+	case errors.Is(err, blackouts.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error updating blackout window: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// decodeBlackoutInput decodes a blackout window from JSON or a form, where
+// recurring is a checkbox and weekdays may repeat
+func decodeBlackoutInput(r *http.Request, in *blackouts.Input) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return decodeRequest(r, in)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	in.Name = r.Form.Get("name")
+	if value := r.Form.Get("provider_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		providerID := uint(id)
+		in.ProviderID = &providerID
+	}
+	in.Recurring = r.Form.Get("recurring") == "on" || r.Form.Get("recurring") == "true"
+	in.StartsAt = r.Form.Get("starts_at")
+	in.EndsAt = r.Form.Get("ends_at")
+	in.StartTime = r.Form.Get("start_time")
+	in.EndTime = r.Form.Get("end_time")
+	in.Weekdays = r.Form["weekdays"]
+	in.Timezone = r.Form.Get("timezone")
+	in.Action = r.Form.Get("action")
+	return nil
+}
+
+// providerNames returns the names of the providers of userID keyed by ID
+func providerNames(db *gorm.DB, userID string) (map[uint]string, error) {
+	var providers []database.Provider
+	if err := db.Where("user_id = ?", userID).Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(providers))
+	for _, provider := range providers {
+		names[provider.ID] = provider.Name
+	}
+	return names, nil
+}
+
+func renderBlackoutWindow(window database.BlackoutWindow, names map[uint]string) string {
+	scope := "All providers"
+	if window.ProviderID != nil {
+		scope = names[*window.ProviderID]
+		if scope == "" {
+			scope = fmt.Sprintf("provider %d", *window.ProviderID)
+		}
+	}
+
+	when := ""
+	if window.Recurring {
+		days := "every day"
+		if window.Weekdays != "" {
+			days = strings.ReplaceAll(window.Weekdays, ",", ", ")
+		}
+		when = fmt.Sprintf("%s–%s %s, %s", window.StartTime, window.EndTime, window.Timezone, days)
+	} else if window.StartsAt != nil && window.EndsAt != nil {
+		when = window.StartsAt.Format("2006-01-02 15:04") + " – " + window.EndsAt.Format("2006-01-02 15:04 MST")
+	}
+
+	return fmt.Sprintf(`
+		<li class="py-3 border-b">
+			<div class="flex justify-between items-center">
+				<span class="font-medium">%s · %s <span class="text-xs px-2 py-0.5 rounded bg-gray-100 text-gray-800">%s</span></span>
+				<button hx-delete="/blackouts/windows/%d" hx-swap="none" hx-confirm="Delete this blackout window?" class="text-red-600 text-sm">Delete</button>
+			</div>
+			<div class="text-sm text-gray-700">%s</div>
+		</li>`,
+		html.EscapeString(window.Name), html.EscapeString(scope), html.EscapeString(window.Action), window.ID, html.EscapeString(when))
+}
+
+func renderPublishingStatus(status *blackouts.Status) string {
+	if status.Paused != nil {
+		reason := ""
+		if status.Paused.Reason != "" {
+			reason = ": " + html.EscapeString(status.Paused.Reason)
+		}
+		return fmt.Sprintf(`
+		<div class="flex justify-between items-center p-4 rounded-lg bg-red-100 text-red-800">
+			<span><strong>Publishing is paused</strong> since %s%s. Due posts wait until it resumes.</span>
+			<button hx-post="/blackouts/resume" hx-swap="none" class="bg-green-600 hover:bg-green-700 text-white font-bold py-2 px-4 rounded">Resume publishing</button>
+		</div>`, status.Paused.CreatedAt.Format("2006-01-02 15:04"), reason)
+	}
+
+	var active []string
+	for _, window := range status.Active {
+		active = append(active, fmt.Sprintf("%s until %s", html.EscapeString(window.Name), window.Until.Format("2006-01-02 15:04 MST")))
+	}
+	message := "Publishing is running."
+	if len(active) > 0 {
+		message = "Blackout in force: " + strings.Join(active, "; ") + "."
+	}
+	return fmt.Sprintf(`
+		<div class="flex justify-between items-center p-4 rounded-lg bg-gray-50">
+			<span>%s</span>
+			<form hx-post="/blackouts/pause" hx-swap="none" hx-confirm="Pause all publishing?" class="flex gap-2">
+				<input type="text" name="reason" placeholder="Reason" class="border rounded px-3 py-2"/>
+				<button type="submit" class="bg-red-600 hover:bg-red-700 text-white font-bold py-2 px-4 rounded">Pause all publishing</button>
+			</form>
+		</div>`, message)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/blackouts"
+)
+
+func TestBlackoutHandler_PauseStopsPublishing(t *testing.T) {
+	postHandler, dbManager := newCalendarTestHandler(t)
+	handler := NewBlackoutHandler(dbManager)
+
+	req := httptest.NewRequest("POST", "/api/blackouts", strings.NewReader(
+		"name=Nights&recurring=on&start_time=22%3A00&end_time=07%3A00&weekdays=sat&weekdays=sun&action=hold"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.HandleCreateWindow(rr, req)
+	if rr.Code != http.StatusCreated || !strings.Contains(rr.Body.String(), `"weekdays":"sat,sun"`) {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/publishing/pause", nil)
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandlePause(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("HX-Trigger") != "publishing-changed" {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/posts", strings.NewReader(`{"provider_id":1,"content":"Now"}`))
+	rr = httptest.NewRecorder()
+	postHandler.HandlePost(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while paused, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/publishing", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandleStatus(rr, req)
+	var status blackouts.Status
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to unmarshal status: %v", err)
+	}
+	if status.Paused == nil || status.Windows != 1 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.HandleResume(rr, httptest.NewRequest("POST", "/api/publishing/resume", nil))
+	req = httptest.NewRequest("GET", "/blackouts/status", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleStatus(rr, req)
+	if !strings.Contains(rr.Body.String(), "Pause all publishing") {
+		t.Errorf("Expected the pause switch after resuming, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/bulkimport"
+)
+
+// maxBulkImportSize limits the size of uploaded CSV and JSON files
+const maxBulkImportSize = 5 << 20
+
+// HandleImportPosts schedules the posts of a CSV or JSON file, uploaded as the
+// file field of a multipart form or sent as the request body. The format is
+// taken from the format parameter, the file extension or the content type.
+// With dry_run=true the rows are only validated.
+func (h *PostHandler) HandleImportPosts(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkImportSize)
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	contentType := r.Header.Get("Content-Type")
+	var upload io.Reader = r.Body
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		upload = file
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(path.Ext(header.Filename)), ".")
+		}
+	} else if format == "" {
+		switch {
+		case strings.HasPrefix(contentType, "application/json"):
+			format = bulkimport.FormatJSON
+		case strings.HasPrefix(contentType, "text/csv"):
+			format = bulkimport.FormatCSV
+		}
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true" || r.FormValue("dry_run") == "true"
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := bulkimport.Parse(format, upload)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := bulkimport.Import(r.Context(), db, userID, rows, dryRun, time.Now())
+	if err != nil && !errors.Is(err, bulkimport.ErrInvalid) {
+		log.Printf("Error importing posts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusCreated
+	switch {
+	case err != nil:
+		status = http.StatusBadRequest
+	case dryRun:
+		status = http.StatusOK
+	default:
+		w.Header().Set("HX-Trigger", "calendar-changed")
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, result, status)
+		return
+	}
+
+	// htmx only swaps successful responses, so errors are reported with 200
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, renderImportResult(result))
+}
+
+func renderImportResult(result *bulkimport.Result) string {
+	if len(result.Errors) > 0 {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf(`<div class="text-red-600 mb-2">Nothing was scheduled: %d errors in %d rows.</div>
+			<table class="min-w-full text-sm"><thead><tr class="text-left"><th class="pr-4">Line</th><th class="pr-4">Field</th><th>Error</th></tr></thead><tbody>`,
+			len(result.Errors), result.Rows))
+		for _, e := range result.Errors {
+			sb.WriteString(fmt.Sprintf(`<tr><td class="pr-4">%d</td><td class="pr-4">%s</td><td>%s</td></tr>`,
+				e.Line, html.EscapeString(e.Field), html.EscapeString(e.Message)))
+		}
+		sb.WriteString(`</tbody></table>`)
+		return sb.String()
+	}
+	if result.DryRun {
+		return fmt.Sprintf(`<div class="text-blue-700">All %d rows are valid and would schedule %d posts.</div>`, result.Rows, result.Posts)
+	}
+	return fmt.Sprintf(`<div class="text-green-700">Scheduled %d posts from %d rows.</div>`, result.Posts, result.Rows)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/bulkimport"
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestHandleImportPosts_UploadAndJSON(t *testing.T) {
+	handler, dbManager := newCalendarTestHandler(t)
+	db, _ := dbManager.GetDB("default_user")
+	db.Create(&database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true})
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Format("2006-01-02 15:04")
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "month.csv")
+	part.Write([]byte("content,providers,schedule_at\nFirst,facebook," + tomorrow + "\nSecond,tiktok," + tomorrow + "\n"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/posts/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+	handler.HandleImportPosts(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Nothing was scheduled") || !strings.Contains(rr.Body.String(), "&#34;tiktok&#34;") {
+		t.Errorf("Expected the unknown provider to be reported, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/posts/import", strings.NewReader(`[{"content": "First", "providers": ["facebook"], "schedule_at": "`+tomorrow+`"}]`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandleImportPosts(rr, req)
+	var result bulkimport.Result
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil || rr.Code != http.StatusCreated || len(result.Jobs) != 1 {
+		t.Errorf("Expected one job to be scheduled, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,785 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/calendars"
+	"github.com/tkowalski/socgo/internal/campaigns"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"gorm.io/gorm"
+)
+
+const (
+	calendarViewMonth  = "month"
+	calendarViewWeek   = "week"
+	calendarViewAgenda = "agenda"
+
+	calendarDateLayout = "2006-01-02"
+
+	// agendaDays is how far ahead the agenda view looks
+	agendaDays = 30
+)
+
+// CalendarEntry is a single item shown on the calendar: a published post, a
+// scheduled job that has not completed yet or an event of an external
+// calendar, whose ProviderName is the calendar's name
+type CalendarEntry struct {
+	Kind          string        `json:"kind"`
+	ID            uint          `json:"id"`
+	Content       string        `json:"content"`
+	ProviderID    uint          `json:"provider_id"`
+	ProviderName  string        `json:"provider_name"`
+	CampaignID    *uint         `json:"campaign_id,omitempty"`
+	Tags          database.Tags `json:"tags"`
+	Color         string        `json:"color,omitempty"` // of the campaign
+	Status        string        `json:"status"`
+	At            time.Time     `json:"at"`
+	AllDay        bool          `json:"all_day,omitempty"`
+	Reschedulable bool          `json:"reschedulable"`
+	Conflict      string        `json:"conflict,omitempty"` // the posting limit a job breaks
+}
+
+// calendarFilter narrows the calendar to a campaign or tag
+type calendarFilter struct {
+	CampaignID uint
+	Tag        string
+}
+
+const (
+	calendarEntryPost  = "post"
+	calendarEntryJob   = "job"
+	calendarEntryEvent = "event"
+)
+
+type CalendarDayDetail struct {
+	Date    string          `json:"date"`
+	Entries []CalendarEntry `json:"entries"`
+}
+
+type CalendarRangeResponse struct {
+	View  string              `json:"view"`
+	From  string              `json:"from"`
+	To    string              `json:"to"`
+	Days  []CalendarDayDetail `json:"days"`
+	Total int                 `json:"total"`
+}
+
+type RescheduleRequest struct {
+	ScheduledAt string `json:"scheduled_at"` // RFC3339
+	Date        string `json:"date"`         // YYYY-MM-DD, keeps the original time of day
+}
+
+// loadCalendarEntries returns all posts and not yet completed jobs in [from, to)
+// matching filter. Dates are bucketed in Go rather than SQL so the query stays
+// portable across database dialects.
+func loadCalendarEntries(db *gorm.DB, userID string, from, to time.Time, filter calendarFilter) ([]CalendarEntry, error) {
+	// Posts that went through review carry their publish time separately
+	var posts []database.Post
+	if err := filter.apply(db.Preload("Provider"), "posts").
+		Where("user_id = ? AND status = ?", userID, database.PostStatusPublished).
+		Where("(published_at >= ? AND published_at < ?) OR (published_at IS NULL AND created_at >= ? AND created_at < ?)",
+			from, to, from, to).
+		Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+	}
+
+	// Completed jobs already have a matching post record
+	var jobs []database.ScheduledJob
+	if err := filter.apply(db.Preload("Provider"), "scheduled_jobs").
+		Where("user_id = ? AND job_type = ? AND scheduled_at >= ? AND scheduled_at < ? AND status <> ?",
+			userID, database.JobTypePublishPost, from, to, database.JobStatusCompleted).
+		Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch scheduled jobs: %w", err)
+	}
+
+	entries := make([]CalendarEntry, 0, len(posts)+len(jobs))
+	for _, post := range posts {
+		publishedAt := post.CreatedAt
+		if post.PublishedAt != nil {
+			publishedAt = *post.PublishedAt
+		}
+		entries = append(entries, CalendarEntry{
+			Kind:         calendarEntryPost,
+			ID:           post.ID,
+			Content:      post.Content,
+			ProviderID:   post.ProviderID,
+			ProviderName: post.Provider.Name,
+			CampaignID:   post.CampaignID,
+			Tags:         post.Tags,
+			Status:       "published",
+			At:           publishedAt.UTC(),
+		})
+	}
+	conflicts, err := limits.Conflicts(db, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check posting limits: %w", err)
+	}
+	for _, job := range jobs {
+		entries = append(entries, CalendarEntry{
+			Kind:          calendarEntryJob,
+			ID:            job.ID,
+			Content:       job.PayloadData,
+			ProviderID:    job.ProviderID,
+			ProviderName:  job.Provider.Name,
+			CampaignID:    job.CampaignID,
+			Tags:          job.Tags,
+			Status:        job.Status,
+			At:            job.ScheduledAt.UTC(),
+			Reschedulable: job.Status == database.JobStatusPending,
+			Conflict:      conflicts[job.ID],
+		})
+	}
+
+	if err := colorCalendarEntries(db, userID, entries); err != nil {
+		return nil, err
+	}
+
+	// External events belong to no campaign, so filters hide them
+	if filter == (calendarFilter{}) {
+		events, err := loadExternalEntries(db, userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, events...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.Before(entries[j].At)
+	})
+	return entries, nil
+}
+
+// loadExternalEntries returns the events of external calendars in [from, to).
+// All-day events get an entry on each of their days.
+func loadExternalEntries(db *gorm.DB, userID string, from, to time.Time) ([]CalendarEntry, error) {
+	events, err := calendars.Events(db, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CalendarEntry
+	for _, event := range events {
+		entry := CalendarEntry{
+			Kind:         calendarEntryEvent,
+			ID:           event.ID,
+			Content:      event.Summary,
+			ProviderName: event.CalendarName,
+			Tags:         database.Tags{},
+			Color:        event.Color,
+			Status:       calendarEntryEvent,
+			At:           event.StartsAt.UTC(),
+			AllDay:       event.AllDay,
+		}
+		if !event.AllDay {
+			entries = append(entries, entry)
+			continue
+		}
+		for day := event.StartsAt.UTC(); day.Before(event.EndsAt) && day.Before(to); day = day.AddDate(0, 0, 1) {
+			if day.Before(from) {
+				continue
+			}
+			entry.At = day
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// countPosts counts the entries that are posts or jobs
+func countPosts(entries []CalendarEntry) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.Kind != calendarEntryEvent {
+			count++
+		}
+	}
+	return count
+}
+
+// countConflicts counts the jobs breaking a posting limit
+func countConflicts(entries []CalendarEntry) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.Conflict != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// colorCalendarEntries sets the color of entries that belong to a campaign
+func colorCalendarEntries(db *gorm.DB, userID string, entries []CalendarEntry) error {
+	colors := map[uint]string{}
+	for i, entry := range entries {
+		if entry.CampaignID == nil {
+			continue
+		}
+		if len(colors) == 0 {
+			list, err := campaigns.List(db, userID)
+			if err != nil {
+				return fmt.Errorf("failed to fetch campaigns: %w", err)
+			}
+			for _, campaign := range list {
+				colors[campaign.ID] = campaign.Color
+			}
+		}
+		entries[i].Color = colors[*entry.CampaignID]
+	}
+	return nil
+}
+
+// parseCalendarFilter reads the campaign_id and tag filters of the calendar
+func parseCalendarFilter(r *http.Request) (calendarFilter, error) {
+	q := r.URL.Query()
+	filter := calendarFilter{Tag: strings.TrimSpace(q.Get("tag"))}
+	if value := q.Get("campaign_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid campaign_id")
+		}
+		filter.CampaignID = uint(id)
+	}
+	return filter, nil
+}
+
+// apply adds the filter conditions on table to query
+func (f calendarFilter) apply(query *gorm.DB, table string) *gorm.DB {
+	if f.CampaignID != 0 {
+		query = query.Where(table+".campaign_id = ?", f.CampaignID)
+	}
+	if pattern := database.TagPattern(f.Tag); pattern != "" {
+		query = query.Where(database.TagCondition(table+".tags"), pattern)
+	}
+	return query
+}
+
+// query returns the filter as query parameters to append to calendar links
+func (f calendarFilter) query() string {
+	values := url.Values{}
+	if f.CampaignID != 0 {
+		values.Set("campaign_id", strconv.FormatUint(uint64(f.CampaignID), 10))
+	}
+	if f.Tag != "" {
+		values.Set("tag", f.Tag)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "&" + html.EscapeString(values.Encode())
+}
+
+// groupEntriesByDay buckets entries into consecutive days starting at from
+func groupEntriesByDay(entries []CalendarEntry, from time.Time, days int) []CalendarDayDetail {
+	result := make([]CalendarDayDetail, days)
+	index := make(map[string]int, days)
+	for i := 0; i < days; i++ {
+		date := from.AddDate(0, 0, i).Format(calendarDateLayout)
+		result[i] = CalendarDayDetail{Date: date, Entries: []CalendarEntry{}}
+		index[date] = i
+	}
+
+	for _, entry := range entries {
+		if i, ok := index[entry.At.Format(calendarDateLayout)]; ok {
+			result[i].Entries = append(result[i].Entries, entry)
+		}
+	}
+
+	return result
+}
+
+func (h *PostHandler) HandleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.URL.Query().Get("view") {
+	case calendarViewWeek:
+		h.handleCalendarWeek(w, r)
+		return
+	case calendarViewAgenda:
+		h.handleCalendarAgenda(w, r)
+		return
+	}
+
+	// Get year and month parameters
+	now := time.Now()
+	year := now.Year()
+	month := int(now.Month())
+
+	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
+		if y, err := strconv.Atoi(yearStr); err == nil {
+			year = y
+		}
+	}
+	if monthStr := r.URL.Query().Get("month"); monthStr != "" {
+		if m, err := strconv.Atoi(monthStr); err == nil && m >= 1 && m <= 12 {
+			month = m
+		}
+	}
+
+	filter, err := parseCalendarFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	startOfMonth := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	startOfNextMonth := startOfMonth.AddDate(0, 1, 0)
+	daysInMonth := startOfNextMonth.AddDate(0, 0, -1).Day()
+
+	entries, err := loadCalendarEntries(db, userID, startOfMonth, startOfNextMonth, filter)
+	if err != nil {
+		log.Printf("Error loading calendar entries: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	details := groupEntriesByDay(entries, startOfMonth, daysInMonth)
+	days := make([]CalendarDay, daysInMonth)
+	for i, detail := range details {
+		count := countPosts(detail.Entries)
+		days[i] = CalendarDay{
+			Day:       i + 1,
+			HasPosts:  count > 0,
+			PostCount: count,
+			Conflicts: countConflicts(detail.Entries),
+		}
+	}
+
+	// Check if request wants JSON (API) or HTML (HTMX)
+	if r.Header.Get("Accept") == "application/json" {
+		response := CalendarResponse{
+			Year:  year,
+			Month: month,
+			Days:  days,
+		}
+		h.writeJSONResponse(w, response, http.StatusOK)
+		return
+	}
+
+	// Return HTML calendar grid for HTMX
+	var htmlBuilder strings.Builder
+	htmlBuilder.WriteString(`<div class="grid grid-cols-7 gap-1 text-center">`)
+
+	// Days of week header
+	daysOfWeek := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+	for _, day := range daysOfWeek {
+		htmlBuilder.WriteString(fmt.Sprintf(`<div class="font-semibold text-gray-600 p-2">%s</div>`, day))
+	}
+
+	// Add empty cells for days before month starts
+	for i := 0; i < int(startOfMonth.Weekday()); i++ {
+		htmlBuilder.WriteString(`<div class="p-2"></div>`)
+	}
+
+	for i, day := range days {
+		dayClass := "calendar-day p-2 border border-gray-200 hover:bg-gray-100 cursor-pointer min-h-[4rem]"
+		if day.HasPosts {
+			dayClass = "calendar-day p-2 border border-blue-500 bg-blue-100 text-blue-800 font-semibold hover:bg-blue-200 cursor-pointer min-h-[4rem]"
+		}
+
+		postCountText := ""
+		if day.PostCount > 0 {
+			postCountText = fmt.Sprintf(`<br><span class="text-xs">(%d posts)</span>`, day.PostCount)
+		}
+		if day.Conflicts > 0 {
+			postCountText += fmt.Sprintf(`<br><span class="text-xs text-red-700">%d over limit</span>`, day.Conflicts)
+		}
+
+		date := details[i].Date
+		htmlBuilder.WriteString(fmt.Sprintf(`
+			<div class="%s" data-date="%s" hx-get="/posts/calendar/day?date=%s%s" hx-target="#calendar-day-detail">
+				%d%s%s
+			</div>
+		`, dayClass, date, date, filter.query(), day.Day, postCountText, renderEntryChips(details[i].Entries)))
+	}
+
+	htmlBuilder.WriteString(`</div>`)
+
+	w.Header().Set("Content-Type", "text/html")
+	if _, err := w.Write([]byte(htmlBuilder.String())); err != nil {
+		log.Printf("Error writing calendar response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleCalendarWeek renders the seven days starting on the Sunday of the requested date
+func (h *PostHandler) handleCalendarWeek(w http.ResponseWriter, r *http.Request) {
+	date, err := parseCalendarDate(r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	from := date.AddDate(0, 0, -int(date.Weekday()))
+
+	h.writeCalendarRange(w, r, calendarViewWeek, from, 7)
+}
+
+// handleCalendarAgenda renders upcoming entries starting at the requested date
+func (h *PostHandler) handleCalendarAgenda(w http.ResponseWriter, r *http.Request) {
+	from, err := parseCalendarDate(r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	h.writeCalendarRange(w, r, calendarViewAgenda, from, agendaDays)
+}
+
+// HandleCalendarDay lists posts and jobs for a single day
+func (h *PostHandler) HandleCalendarDay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	date, err := parseCalendarDate(r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseCalendarFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := loadCalendarEntries(db, userID, date, date.AddDate(0, 0, 1), filter)
+	if err != nil {
+		log.Printf("Error loading calendar entries: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	detail := CalendarDayDetail{Date: date.Format(calendarDateLayout), Entries: entries}
+
+	if r.Header.Get("Accept") == "application/json" {
+		h.writeJSONResponse(w, detail, http.StatusOK)
+		return
+	}
+
+	var htmlBuilder strings.Builder
+	htmlBuilder.WriteString(fmt.Sprintf(`<div class="space-y-3"><h3 class="text-lg font-semibold">%s</h3>`, date.Format("Monday, January 2, 2006")))
+	if len(entries) == 0 {
+		htmlBuilder.WriteString(`<p class="text-gray-500">Nothing planned for this day.</p>`)
+	}
+	for _, entry := range entries {
+		htmlBuilder.WriteString(renderEntryRow(entry))
+	}
+	htmlBuilder.WriteString(`</div>`)
+
+	w.Header().Set("Content-Type", "text/html")
+	if _, err := w.Write([]byte(htmlBuilder.String())); err != nil {
+		log.Printf("Error writing calendar day response: %v", err)
+	}
+}
+
+// HandleRescheduleJob moves a pending scheduled job to a new time
+func (h *PostHandler) HandleRescheduleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RescheduleRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid form data", http.StatusBadRequest)
+			return
+		}
+		req.ScheduledAt = r.FormValue("scheduled_at")
+		req.Date = r.FormValue("date")
+	}
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var job database.ScheduledJob
+	if err := db.Where("user_id = ?", userID).First(&job, jobID).Error; err != nil {
+		http.Error(w, "Scheduled job not found", http.StatusNotFound)
+		return
+	}
+	if job.Status != database.JobStatusPending {
+		http.Error(w, "Only pending jobs can be rescheduled", http.StatusConflict)
+		return
+	}
+
+	scheduledAt, err := resolveRescheduleTime(job.ScheduledAt, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if scheduledAt.Before(time.Now()) {
+		http.Error(w, "scheduled_at must be in the future", http.StatusBadRequest)
+		return
+	}
+	if job.JobType == database.JobTypePublishPost && !h.checkPostingLimit(w, db, userID, job.ProviderID, scheduledAt, job.ID) {
+		return
+	}
+
+	// The scheduler may claim the job meanwhile, so only a job still pending
+	// is moved
+	before := job
+	job.ScheduledAt = scheduledAt
+	job.UpdatedAt = time.Now()
+	result := db.Model(&database.ScheduledJob{}).
+		Where("id = ? AND status = ?", job.ID, database.JobStatusPending).
+		Updates(map[string]interface{}{"scheduled_at": job.ScheduledAt, "updated_at": job.UpdatedAt})
+	if result.Error != nil {
+		log.Printf("Error rescheduling job %d: %v", job.ID, result.Error)
+		http.Error(w, "Failed to reschedule post", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Only pending jobs can be rescheduled", http.StatusConflict)
+		return
+	}
+	if err := audit.Record(r.Context(), db, audit.Entry{
+		Action: audit.ActionJobRescheduled, TargetType: audit.TargetJob, TargetID: job.ID, Before: before, After: job,
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	w.Header().Set("HX-Trigger", "calendar-changed")
+	h.writeJSONResponse(w, PostResponse{
+		ID:         job.ID,
+		Status:     job.Status,
+		ProviderID: job.ProviderID,
+		Content:    job.PayloadData,
+		CreatedAt:  job.CreatedAt,
+		Message:    "Post rescheduled for " + scheduledAt.Format(time.RFC3339),
+	}, http.StatusOK)
+}
+
+func (h *PostHandler) writeCalendarRange(w http.ResponseWriter, r *http.Request, view string, from time.Time, days int) {
+	filter, err := parseCalendarFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	to := from.AddDate(0, 0, days)
+	entries, err := loadCalendarEntries(db, userID, from, to, filter)
+	if err != nil {
+		log.Printf("Error loading calendar entries: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	details := groupEntriesByDay(entries, from, days)
+
+	if r.Header.Get("Accept") == "application/json" {
+		h.writeJSONResponse(w, CalendarRangeResponse{
+			View:  view,
+			From:  from.Format(calendarDateLayout),
+			To:    to.Format(calendarDateLayout),
+			Days:  details,
+			Total: len(entries),
+		}, http.StatusOK)
+		return
+	}
+
+	var htmlBuilder strings.Builder
+	if view == calendarViewWeek {
+		htmlBuilder.WriteString(`<div class="grid grid-cols-7 gap-1">`)
+		for _, day := range details {
+			date, _ := time.Parse(calendarDateLayout, day.Date)
+			htmlBuilder.WriteString(fmt.Sprintf(`
+				<div class="calendar-day border border-gray-200 p-2 min-h-[10rem]" data-date="%s">
+					<div class="font-semibold text-gray-600 text-center mb-2 cursor-pointer" hx-get="/posts/calendar/day?date=%s%s" hx-target="#calendar-day-detail">%s</div>
+					%s
+				</div>`, day.Date, day.Date, filter.query(), date.Format("Mon 02"), renderEntryChips(day.Entries)))
+		}
+		htmlBuilder.WriteString(`</div>`)
+	} else {
+		htmlBuilder.WriteString(`<div class="space-y-4">`)
+		for _, day := range details {
+			if len(day.Entries) == 0 {
+				continue
+			}
+			date, _ := time.Parse(calendarDateLayout, day.Date)
+			htmlBuilder.WriteString(fmt.Sprintf(`<div><h3 class="font-semibold text-gray-700 mb-2">%s</h3><div class="space-y-2">`, date.Format("Monday, January 2")))
+			for _, entry := range day.Entries {
+				htmlBuilder.WriteString(renderEntryRow(entry))
+			}
+			htmlBuilder.WriteString(`</div></div>`)
+		}
+		if len(entries) == 0 {
+			htmlBuilder.WriteString(`<p class="text-gray-500">No upcoming posts.</p>`)
+		}
+		htmlBuilder.WriteString(`</div>`)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if _, err := w.Write([]byte(htmlBuilder.String())); err != nil {
+		log.Printf("Error writing calendar response: %v", err)
+	}
+}
+
+// renderEntryChips renders compact entries for calendar cells; pending jobs are draggable
+func renderEntryChips(entries []CalendarEntry) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.Kind == calendarEntryEvent {
+			b.WriteString(fmt.Sprintf(
+				`<div class="mt-1 truncate rounded bg-gray-100 px-1 text-xs font-normal text-gray-700" title="%s"%s>%s</div>`,
+				html.EscapeString(entry.ProviderName), campaignStyle(entry), html.EscapeString(entry.Content)))
+			continue
+		}
+		if !entry.Reschedulable {
+			continue
+		}
+		chipClass, title := "bg-yellow-100 text-yellow-800", entry.Content
+		if entry.Conflict != "" {
+			chipClass, title = "bg-red-100 text-red-800", entry.Conflict+": "+entry.Content
+		}
+		b.WriteString(fmt.Sprintf(
+			`<div class="calendar-job mt-1 truncate rounded %s px-1 text-xs font-normal cursor-move" draggable="true" data-job-id="%d" title="%s"%s>%s %s</div>`,
+			chipClass, entry.ID, html.EscapeString(title), campaignStyle(entry), entry.At.Format("15:04"), html.EscapeString(entry.ProviderName)))
+	}
+	return b.String()
+}
+
+func renderEntryRow(entry CalendarEntry) string {
+	if entry.Kind == calendarEntryEvent {
+		at := entry.At.Format("15:04")
+		if entry.AllDay {
+			at = "all day"
+		}
+		return fmt.Sprintf(`
+		<div class="border rounded-lg p-3 bg-gray-50"%s>
+			<div class="flex justify-between items-start mb-1">
+				<span class="px-2 py-1 text-xs rounded bg-gray-200 text-gray-700">%s</span>
+				<span class="text-sm text-gray-500">%s</span>
+			</div>
+			<p class="text-gray-800">%s</p>
+		</div>`,
+			campaignStyle(entry), html.EscapeString(entry.ProviderName), at, html.EscapeString(entry.Content))
+	}
+
+	statusClass := "bg-green-100 text-green-800"
+	switch entry.Status {
+	case database.JobStatusPending, database.JobStatusExecuting:
+		statusClass = "bg-yellow-100 text-yellow-800"
+	case database.JobStatusFailed:
+		statusClass = "bg-red-100 text-red-800"
+	}
+
+	return fmt.Sprintf(`
+		<div class="border rounded-lg p-3 bg-white"%s>
+			<div class="flex justify-between items-start mb-1">
+				<span class="px-2 py-1 text-xs rounded %s">%s</span>
+				<span class="text-sm text-gray-500">%s</span>
+			</div>
+			<p class="text-gray-800">%s</p>
+			<div class="mt-1 text-xs text-gray-500">Provider: %s%s</div>%s
+		</div>`,
+		campaignStyle(entry), statusClass, entry.Status, entry.At.Format("15:04"), html.EscapeString(entry.Content), html.EscapeString(entry.ProviderName), renderTags(entry.Tags), renderConflict(entry))
+}
+
+// renderConflict flags a job breaking a posting limit
+func renderConflict(entry CalendarEntry) string {
+	if entry.Conflict == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+			<div class="mt-2 inline-block rounded bg-red-100 px-2 py-1 text-xs text-red-800">Conflict: %s</div>`, html.EscapeString(entry.Conflict))
+}
+
+// campaignStyle marks entries of a campaign, or of an external calendar,
+// with its color
+func campaignStyle(entry CalendarEntry) string {
+	if entry.Color == "" {
+		return ""
+	}
+	return fmt.Sprintf(` style="border-left: 4px solid %s"`, html.EscapeString(entry.Color))
+}
+
+// parseCalendarDate parses a YYYY-MM-DD date, defaulting to today (UTC)
+func parseCalendarDate(value string) (time.Time, error) {
+	if value == "" {
+		now := time.Now().UTC()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Parse(calendarDateLayout, value)
+}
+
+// resolveRescheduleTime computes the new schedule time from either an explicit
+// timestamp or a target date that keeps the job's original time of day
+func resolveRescheduleTime(current time.Time, req RescheduleRequest) (time.Time, error) {
+	if req.ScheduledAt != "" {
+		scheduledAt, err := time.Parse(time.RFC3339, req.ScheduledAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid scheduled_at format. Use ISO8601 format")
+		}
+		return scheduledAt, nil
+	}
+
+	if req.Date == "" {
+		return time.Time{}, fmt.Errorf("scheduled_at or date is required")
+	}
+
+	date, err := time.Parse(calendarDateLayout, req.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date format. Use YYYY-MM-DD")
+	}
+
+	current = current.UTC()
+	return time.Date(date.Year(), date.Month(), date.Day(),
+		current.Hour(), current.Minute(), current.Second(), 0, time.UTC), nil
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/config"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/oauth"
+	"github.com/tkowalski/socgo/internal/providers"
+)
+
+func newCalendarTestHandler(t *testing.T) (*PostHandler, *database.Manager) {
+	dbManager := database.NewTestManager(t)
+	t.Cleanup(func() { dbManager.Close() })
+
+	oauthService := oauth.NewService(dbManager, &config.Config{})
+	providerService := providers.NewProviderService(dbManager, oauthService)
+	return NewPostHandler(dbManager, providerService), dbManager
+}
+
+func TestHandleCalendar_CountsPostsAndJobsOnSQLite(t *testing.T) {
+	handler, dbManager := newCalendarTestHandler(t)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	day := time.Date(2030, time.March, 14, 10, 0, 0, 0, time.UTC)
+	if err := db.Create(&database.Post{Content: "published", UserID: "default_user", ProviderID: provider.ID, CreatedAt: day}).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	if err := db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "scheduled", UserID: "default_user",
+		ProviderID: provider.ID, ScheduledAt: day.Add(2 * time.Hour), Status: database.JobStatusPending}).Error; err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/posts/calendar?year=2030&month=3", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleCalendar(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response CalendarResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Days) != 31 {
+		t.Fatalf("Expected 31 days, got %d", len(response.Days))
+	}
+	if got := response.Days[13].PostCount; got != 2 {
+		t.Errorf("Expected 2 entries on March 14, got %d", got)
+	}
+	if response.Days[0].HasPosts {
+		t.Error("Expected March 1 to be empty")
+	}
+
+	// Day detail lists both entries
+	req = httptest.NewRequest("GET", "/posts/calendar/day?date=2030-03-14", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandleCalendarDay(rr, req)
+
+	var detail CalendarDayDetail
+	if err := json.Unmarshal(rr.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("Failed to unmarshal day detail: %v", err)
+	}
+	if len(detail.Entries) != 2 {
+		t.Fatalf("Expected 2 entries in day detail, got %d", len(detail.Entries))
+	}
+	if detail.Entries[0].Kind != calendarEntryPost || !detail.Entries[1].Reschedulable {
+		t.Errorf("Unexpected day detail entries: %+v", detail.Entries)
+	}
+
+	// Week view covers the Sunday-based week containing the date
+	req = httptest.NewRequest("GET", "/posts/calendar?view=week&date=2030-03-14", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandleCalendar(rr, req)
+
+	var week CalendarRangeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &week); err != nil {
+		t.Fatalf("Failed to unmarshal week view: %v", err)
+	}
+	if week.From != "2030-03-10" || len(week.Days) != 7 || week.Total != 2 {
+		t.Errorf("Unexpected week view: from=%s days=%d total=%d", week.From, len(week.Days), week.Total)
+	}
+}
+
+func TestHandleCalendarDay_OnNonUTCHost(t *testing.T) {
+	// Times written with the host's offset are bucketed by their UTC day
+	local := time.Local
+	time.Local = time.FixedZone("UTC+2", 2*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	handler, dbManager := newCalendarTestHandler(t)
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	// 23:30 and 22:30 UTC on March 13
+	publishedAt := time.Date(2030, time.March, 14, 1, 30, 0, 0, time.Local)
+	if err := db.Create(&database.Post{Content: "late", UserID: "default_user", ProviderID: provider.ID,
+		Status: database.PostStatusPublished, PublishedAt: &publishedAt}).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	if err := db.Create(&database.ScheduledJob{JobType: database.JobTypePublishPost, PayloadData: "later", UserID: "default_user",
+		ProviderID: provider.ID, ScheduledAt: publishedAt.Add(-time.Hour), Status: database.JobStatusPending}).Error; err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	for date, want := range map[string]int{"2030-03-13": 2, "2030-03-14": 0} {
+		req := httptest.NewRequest("GET", "/posts/calendar/day?date="+date, nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+		handler.HandleCalendarDay(rr, req)
+
+		var detail CalendarDayDetail
+		if err := json.Unmarshal(rr.Body.Bytes(), &detail); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(detail.Entries) != want {
+			t.Errorf("Expected %d entries on %s, got %+v", want, date, detail.Entries)
+		}
+	}
+}
+
+func TestHandleRescheduleJob(t *testing.T) {
+	handler, dbManager := newCalendarTestHandler(t)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+
+	original := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Second)
+	pending := database.ScheduledJob{JobType: "publish_post", PayloadData: "pending", UserID: "default_user",
+		ScheduledAt: original, Status: database.JobStatusPending}
+	failed := database.ScheduledJob{JobType: "publish_post", PayloadData: "failed", UserID: "default_user",
+		ScheduledAt: original, Status: database.JobStatusFailed}
+	if err := db.Create(&pending).Error; err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	if err := db.Create(&failed).Error; err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/posts/jobs/{id}/reschedule", handler.HandleRescheduleJob).Methods("POST")
+
+	target := original.AddDate(0, 0, 3).Format(calendarDateLayout)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/posts/jobs/%d/reschedule", pending.ID), strings.NewReader("date="+target))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var updated database.ScheduledJob
+	if err := db.First(&updated, pending.ID).Error; err != nil {
+		t.Fatalf("Failed to reload job: %v", err)
+	}
+	if !updated.ScheduledAt.Equal(original.AddDate(0, 0, 3)) {
+		t.Errorf("Expected job moved to %v, got %v", original.AddDate(0, 0, 3), updated.ScheduledAt)
+	}
+
+	// Non-pending jobs cannot be moved
+	req = httptest.NewRequest("POST", fmt.Sprintf("/posts/jobs/%d/reschedule", failed.ID), strings.NewReader("date="+target))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for failed job, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/calendars"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/ical"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// maxCalendarUploadSize limits the size of uploaded ICS files
+const maxCalendarUploadSize = 5 << 20
+
+type CalendarHandler struct {
+	dbManager *database.Manager
+	client    *http.Client
+}
+
+// NewCalendarHandler creates a new CalendarHandler instance
+func NewCalendarHandler(dbManager *database.Manager) *CalendarHandler {
+	return &CalendarHandler{
+		dbManager: dbManager,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// HandleFeed serves the schedule of the owner of a calendar feed token as an
+// ICS feed. Calendar apps cannot send headers, so the token is the token
+// query parameter; only tokens with the calendar scope are accepted.
+func (h *CalendarHandler) HandleFeed(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Token is required", http.StatusUnauthorized)
+		return
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+
+	route, err := h.dbManager.LookupToken(hash)
+	if err != nil {
+		if !errors.Is(err, database.ErrUnknownToken) {
+			log.Printf("Error looking up token: %v", err)
+		}
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+	db, err := h.dbManager.GetDB(route.TenantKey)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	var apiToken database.APIToken
+	if err := db.Where("hash = ? AND scope = ?", hash, database.TokenScopeCalendar).First(&apiToken).Error; err != nil || apiToken.UserID != route.TenantKey {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	events, err := calendars.Schedule(db, route.TenantKey, now.Add(-calendars.FeedPast), now.Add(calendars.FeedFuture))
+	if err != nil {
+		log.Printf("Error building calendar feed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	apiToken.LastUsed = &now
+	if err := db.Save(&apiToken).Error; err != nil {
+		log.Printf("Error updating token last_used: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="socgo.ics"`)
+	if err := ical.Write(w, "SocGo posts", events, now); err != nil {
+		log.Printf("Error writing calendar feed: %v", err)
+	}
+}
+
+// HandleListCalendars lists the external calendars as JSON or as HTML list
+// items
+func (h *CalendarHandler) HandleListCalendars(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	list, err := calendars.List(db, userID)
+	if err != nil {
+		log.Printf("Error listing calendars: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, list, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(list) == 0 {
+		sb.WriteString(`<li class="text-gray-500 text-sm">No calendars imported.</li>`)
+	}
+	for _, calendar := range list {
+		sync := ""
+		if calendar.URL != "" {
+			sync = fmt.Sprintf(`<button hx-post="/calendars/%d/sync" hx-swap="none" class="text-blue-600">Sync</button>`, calendar.ID)
+		}
+		sb.WriteString(fmt.Sprintf(`
+			<li class="flex justify-between items-center py-1 text-sm">
+				<span><span class="inline-block w-3 h-3 rounded-full mr-1" style="background: %s"></span>%s</span>
+				<span class="space-x-2">%s<button hx-delete="/calendars/%d" hx-swap="none" hx-confirm="Remove this calendar?" class="text-red-600">Remove</button></span>
+			</li>`,
+			html.EscapeString(calendar.Color), html.EscapeString(calendar.Name), sync, calendar.ID))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleImportCalendar imports an external calendar from the file field of a
+// multipart form, or from the url of a JSON or form request
+func (h *CalendarHandler) HandleImportCalendar(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCalendarUploadSize)
+
+	var in calendars.ImportInput
+	var body io.Reader
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxCalendarUploadSize); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		in = calendars.ImportInput{Name: r.FormValue("name"), URL: r.FormValue("url"), Color: r.FormValue("color")}
+		if file, _, err := r.FormFile("file"); err == nil {
+			defer file.Close()
+			body = file
+			// An uploaded file is not synced from a URL
+			in.URL = ""
+		}
+	} else if err := decodeRequest(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	calendar, err := calendars.Import(r.Context(), db, userID, in, body, h.client, time.Now())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "calendars-changed, calendar-changed")
+	writeJSON(w, calendar, http.StatusCreated)
+}
+
+// HandleSyncCalendar fetches the events of a calendar from its URL again
+func (h *CalendarHandler) HandleSyncCalendar(w http.ResponseWriter, r *http.Request) {
+	h.withCalendarID(w, r, func(db *gorm.DB, userID string, id uint) {
+		calendar, err := calendars.Sync(r.Context(), db, userID, id, h.client, time.Now())
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "calendars-changed, calendar-changed")
+		writeJSON(w, calendar, http.StatusOK)
+	})
+}
+
+// HandleDeleteCalendar removes an external calendar and its events
+func (h *CalendarHandler) HandleDeleteCalendar(w http.ResponseWriter, r *http.Request) {
+	h.withCalendarID(w, r, func(db *gorm.DB, userID string, id uint) {
+		if err := calendars.Delete(r.Context(), db, userID, id); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "calendars-changed, calendar-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// withCalendarID parses the {id} route variable and runs fn with the
+// tenant's database
+func (h *CalendarHandler) withCalendarID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, id uint)) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid calendar ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(id))
+}
+
+// writeError maps calendar errors to HTTP status codes
+func (h *CalendarHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, calendars.ErrNotFound):
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+	case errors.Is(err, calendars.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, calendars.ErrFetch):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		log.Printf("Error updating calendar: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/middleware"
+)
+
+func createTestToken(t *testing.T, handler *APITokenHandler, body string) APITokenResponse {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api-tokens", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleCreateToken(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response APITokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal token response: %v", err)
+	}
+	return response
+}
+
+func TestCalendarHandler_HandleFeed(t *testing.T) {
+	_, dbManager := newCalendarTestHandler(t)
+	tokenHandler := NewAPITokenHandler(dbManager)
+	tokenHandler.SetBaseURL("https://socgo.example/")
+	handler := NewCalendarHandler(dbManager)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	job := database.ScheduledJob{JobType: database.JobTypePublishPost, PayloadData: "Spring launch", UserID: "default_user",
+		ScheduledAt: time.Now().Add(24 * time.Hour), Status: database.JobStatusPending}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	calendarToken := createTestToken(t, tokenHandler, `{"scope":"calendar"}`)
+	if calendarToken.Scope != database.TokenScopeCalendar ||
+		calendarToken.FeedURL != "https://socgo.example/calendar.ics?token="+url.QueryEscape(calendarToken.Token) {
+		t.Fatalf("Unexpected calendar token: %+v", calendarToken)
+	}
+
+	req := httptest.NewRequest("GET", "/calendar.ics?token="+url.QueryEscape(calendarToken.Token), nil)
+	rr := httptest.NewRecorder()
+	handler.HandleFeed(rr, req)
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("Expected an ICS feed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if body := rr.Body.String(); !strings.Contains(body, fmt.Sprintf("UID:job-%d@socgo", job.ID)) || !strings.Contains(body, "Spring launch") {
+		t.Errorf("Expected the job in the feed:\n%s", body)
+	}
+
+	// A full API token does not open the feed, and a feed token does not open the API
+	apiToken := createTestToken(t, tokenHandler, `{}`)
+	req = httptest.NewRequest("GET", "/calendar.ics?token="+url.QueryEscape(apiToken.Token), nil)
+	rr = httptest.NewRecorder()
+	handler.HandleFeed(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an API token, got %d", rr.Code)
+	}
+
+	protected := middleware.NewAuthMiddleware(dbManager).APIAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req = httptest.NewRequest("GET", "/api/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+calendarToken.Token)
+	rr = httptest.NewRecorder()
+	protected.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a calendar token on the API, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api-tokens", bytes.NewBufferString(`{"scope":"admin"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	tokenHandler.HandleCreateToken(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown scope, got %d", rr.Code)
+	}
+}
+
+func TestCalendarHandler_ImportOverlaysEvents(t *testing.T) {
+	postHandler, dbManager := newCalendarTestHandler(t)
+	handler := NewCalendarHandler(dbManager)
+
+	ics := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:easter\r\nSUMMARY:Easter Monday\r\n" +
+		"DTSTART;VALUE=DATE:20300422\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ics)
+	}))
+	defer server.Close()
+	handler.client = server.Client()
+
+	body, _ := json.Marshal(map[string]string{"name": "Holidays", "url": server.URL})
+	req := httptest.NewRequest("POST", "/api/calendars", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleImportCalendar(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/posts/calendar/day?date=2030-04-22", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	postHandler.HandleCalendarDay(rr, req)
+
+	var detail CalendarDayDetail
+	if err := json.Unmarshal(rr.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("Failed to unmarshal day detail: %v", err)
+	}
+	if len(detail.Entries) != 1 || detail.Entries[0].Kind != calendarEntryEvent || detail.Entries[0].Content != "Easter Monday" || !detail.Entries[0].AllDay {
+		t.Fatalf("Expected the imported event in the day detail, got %+v", detail.Entries)
+	}
+
+	// Events are shown but not counted as posts
+	req = httptest.NewRequest("GET", "/posts/calendar?year=2030&month=4", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	postHandler.HandleCalendar(rr, req)
+
+	var month CalendarResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &month); err != nil {
+		t.Fatalf("Failed to unmarshal month view: %v", err)
+	}
+	if month.Days[21].PostCount != 0 {
+		t.Errorf("Expected events not to count as posts, got %d", month.Days[21].PostCount)
+	}
+
+	req = httptest.NewRequest("POST", "/api/calendars", strings.NewReader(`{"name":"Local","url":"file:///etc/hosts"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandleImportCalendar(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a file URL, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/campaigns"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+type CampaignHandler struct {
+	dbManager *database.Manager
+}
+
+// NewCampaignHandler creates a new CampaignHandler instance
+func NewCampaignHandler(dbManager *database.Manager) *CampaignHandler {
+	return &CampaignHandler{
+		dbManager: dbManager,
+	}
+}
+
+// CampaignsPage renders the campaign management page
+func (h *CampaignHandler) CampaignsPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Campaigns",
+		CurrentPage: "campaigns",
+		FlashType:   "info",
+		Content:     templates.CampaignsContent(campaigns.DefaultColor),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering campaigns page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCampaignOptions renders the campaigns as <option> elements for the
+// campaign selects of the post and filter forms
+func (h *CampaignHandler) HandleCampaignOptions(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	list, err := campaigns.List(db, userID)
+	if err != nil {
+		log.Printf("Error listing campaigns: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	selected, _ := strconv.ParseUint(r.URL.Query().Get("campaign_id"), 10, 32)
+	var sb strings.Builder
+	sb.WriteString(`<option value="">No campaign</option>`)
+	for _, campaign := range list {
+		attr := ""
+		if uint(selected) == campaign.ID {
+			attr = " selected"
+		}
+		sb.WriteString(fmt.Sprintf(`<option value="%d"%s>%s</option>`, campaign.ID, attr, html.EscapeString(campaign.Name)))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleCreateCampaign creates a campaign from a JSON or form request
+func (h *CampaignHandler) HandleCreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var in campaigns.Input
+	if err := decodeRequest(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	campaign, err := campaigns.Create(r.Context(), db, userID, in)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "campaigns-changed")
+	writeJSON(w, campaign, http.StatusCreated)
+}
+
+// HandleUpdateCampaign replaces the name, color and dates of a campaign
+func (h *CampaignHandler) HandleUpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	var in campaigns.Input
+	if err := decodeRequest(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withCampaignID(w, r, func(db *gorm.DB, userID string, id uint) {
+		campaign, err := campaigns.Update(r.Context(), db, userID, id, in)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "campaigns-changed")
+		writeJSON(w, campaign, http.StatusOK)
+	})
+}
+
+// HandleDeleteCampaign deletes a campaign; its posts and jobs are kept
+func (h *CampaignHandler) HandleDeleteCampaign(w http.ResponseWriter, r *http.Request) {
+	h.withCampaignID(w, r, func(db *gorm.DB, userID string, id uint) {
+		if err := campaigns.Delete(r.Context(), db, userID, id); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "campaigns-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// HandleCampaignStats lists every campaign with its post and job counts as
+// JSON or HTML
+func (h *CampaignHandler) HandleCampaignStats(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := campaigns.AllStats(db, userID)
+	if err != nil {
+		log.Printf("Error counting campaign stats: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, stats, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(stats) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No campaigns yet.</li>`)
+	}
+	for _, s := range stats {
+		sb.WriteString(renderCampaignItem(s))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleCampaignStat returns the post and job counts of one campaign
+func (h *CampaignHandler) HandleCampaignStat(w http.ResponseWriter, r *http.Request) {
+	h.withCampaignID(w, r, func(db *gorm.DB, userID string, id uint) {
+		stats, err := campaigns.GetStats(db, userID, id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+
+		if r.Header.Get("Accept") == "application/json" {
+			writeJSON(w, stats, http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, renderCampaignItem(*stats))
+	})
+}
+
+// withCampaignID parses the {id} route variable and runs fn with the
+// tenant's database
+func (h *CampaignHandler) withCampaignID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, id uint)) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(id))
+}
+
+// writeError maps campaign errors to HTTP status codes
+func (h *CampaignHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, campaigns.ErrNotFound):
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+	case errors.Is(err, campaigns.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error updating campaign: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// resolveCampaign checks that the campaign id exists and returns it as the
+// optional campaign of a post or job; zero means no campaign
+func resolveCampaign(db *gorm.DB, userID string, id uint) (*uint, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	campaign, err := campaigns.Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign.ID, nil
+}
+
+func renderCampaignItem(s campaigns.Stats) string {
+	dates := "No dates"
+	if s.StartsOn != nil || s.EndsOn != nil {
+		dates = formatCampaignDate(s.StartsOn) + " – " + formatCampaignDate(s.EndsOn)
+	}
+	return fmt.Sprintf(`
+		<li class="py-3 border-b">
+			<div class="flex justify-between items-center">
+				<span class="font-medium"><span class="inline-block w-3 h-3 rounded-full mr-2" style="background-color: %s"></span>%s</span>
+				<span class="flex space-x-3 text-sm">
+					<button hx-delete="/campaigns/%d" hx-swap="none" hx-confirm="Delete this campaign? Its posts are kept." class="text-red-600">Delete</button>
+				</span>
+			</div>
+			<div class="text-xs text-gray-500">%s · %d posts · %d drafts · %d scheduled · %d published · %d failed · %d providers</div>
+		</li>`,
+		html.EscapeString(s.Color), html.EscapeString(s.Name), s.ID, dates,
+		s.Posts, s.Drafts, s.Scheduled, s.Published, s.Failed, s.Providers)
+}
+
+func formatCampaignDate(date *time.Time) string {
+	if date == nil {
+		return "…"
+	}
+	return date.Format(campaigns.DateLayout)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/campaigns"
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestCampaignHandler_DraftsStatsAndFilters(t *testing.T) {
+	postHandler, dbManager := newCalendarTestHandler(t)
+	handler := NewCampaignHandler(dbManager)
+
+	form := func(action http.HandlerFunc, path string, values url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		action(rr, req)
+		return rr
+	}
+
+	rr := form(handler.HandleCreateCampaign, "/campaigns", url.Values{"name": {"Launch"}, "color": {"#ff0000"}, "starts_on": {"2026-03-01"}})
+	if rr.Code != http.StatusCreated || rr.Header().Get("HX-Trigger") != "campaigns-changed" {
+		t.Fatalf("Expected the campaign to be created, got %d %s", rr.Code, rr.Body.String())
+	}
+	var campaign database.Campaign
+	if err := json.Unmarshal(rr.Body.Bytes(), &campaign); err != nil || campaign.ID == 0 {
+		t.Fatalf("Invalid campaign response: %v", err)
+	}
+	if rr := form(handler.HandleCreateCampaign, "/campaigns", url.Values{"name": {"Launch"}}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a duplicate name to be rejected, got %d", rr.Code)
+	}
+
+	// Drafts take a campaign and comma separated tags from forms
+	rr = form(postHandler.HandleCreateDraft, "/posts/drafts", url.Values{"content": {"Teaser"}, "campaign_id": {"1"}, "tags": {"Launch, #Video"}})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected the draft to be created, got %d %s", rr.Code, rr.Body.String())
+	}
+	var draft database.Post
+	json.Unmarshal(rr.Body.Bytes(), &draft)
+	if draft.CampaignID == nil || *draft.CampaignID != campaign.ID || draft.Tags.String() != "launch,video" {
+		t.Errorf("Unexpected draft: %+v", draft)
+	}
+	if rr := form(postHandler.HandleCreateDraft, "/posts/drafts", url.Values{"content": {"Lost"}, "campaign_id": {"99"}}); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown campaign to return 404, got %d", rr.Code)
+	}
+	if rr := form(postHandler.HandleCreateDraft, "/posts/drafts", url.Values{"content": {"Plain"}, "campaign_id": {""}}); rr.Code != http.StatusCreated {
+		t.Errorf("Expected an empty campaign select to be ignored, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/api/stats/campaigns/1", nil)
+	req.Header.Set("Accept", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr = httptest.NewRecorder()
+	handler.HandleCampaignStat(rr, req)
+	var stats campaigns.Stats
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil || stats.Name != "Launch" || stats.Posts != 1 || stats.Drafts != 1 {
+		t.Errorf("Unexpected stats: %d %s", rr.Code, rr.Body.String())
+	}
+
+	// The calendar shows only entries with the tag, in the campaign color
+	db, _ := dbManager.GetDB("default_user")
+	date := time.Now().Add(48 * time.Hour).UTC()
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "Tagged", UserID: "default_user", ProviderID: 1, ScheduledAt: date, Status: database.JobStatusPending, CampaignID: &campaign.ID, Tags: database.Tags{"video"}})
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "Untagged", UserID: "default_user", ProviderID: 1, ScheduledAt: date, Status: database.JobStatusPending})
+
+	req = httptest.NewRequest("GET", "/posts/calendar/day?date="+date.Format(calendarDateLayout)+"&tag=video", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	postHandler.HandleCalendarDay(rr, req)
+	var day CalendarDayDetail
+	if err := json.Unmarshal(rr.Body.Bytes(), &day); err != nil {
+		t.Fatalf("Invalid calendar response: %v", err)
+	}
+	if len(day.Entries) != 1 || day.Entries[0].Content != "Tagged" || day.Entries[0].Color != "#ff0000" {
+		t.Errorf("Unexpected calendar entries: %+v", day.Entries)
+	}
+}
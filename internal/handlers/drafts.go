@@ -0,0 +1,621 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/campaigns"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"github.com/tkowalski/socgo/internal/webhooks"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DraftRequest is used to create or edit a draft post
+type DraftRequest struct {
+	Title      string        `json:"title"`
+	Content    string        `json:"content"`
+	ProviderID uint          `json:"provider_id"`
+	ReviewerID string        `json:"reviewer_id"`
+	CampaignID uint          `json:"campaign_id"`
+	Tags       database.Tags `json:"tags"` // left unchanged on update when omitted
+}
+
+// DraftActionRequest carries the optional fields of workflow actions
+type DraftActionRequest struct {
+	ReviewerID string `json:"reviewer_id"`
+	Comment    string `json:"comment"`
+	ScheduleAt string `json:"schedule_at"` // ISO8601 format, used by schedule
+}
+
+// draftTransitions lists the statuses a post may move to from each state
+var draftTransitions = map[string][]string{
+	database.PostStatusDraft:    {database.PostStatusInReview},
+	database.PostStatusInReview: {database.PostStatusApproved, database.PostStatusDraft},
+	database.PostStatusApproved: {database.PostStatusScheduled},
+}
+
+var (
+	errDraftNotFound = errors.New("draft not found")
+	errSelfReview    = errors.New("the author of a post cannot review it")
+)
+
+func canTransitionPost(from, to string) bool {
+	for _, allowed := range draftTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleCreateDraft creates a new post in draft state
+func (h *PostHandler) HandleCreateDraft(w http.ResponseWriter, r *http.Request) {
+	var req DraftRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "content is required", http.StatusBadRequest)
+		return
+	}
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if req.ProviderID != 0 {
+		var provider database.Provider
+		if err := db.First(&provider, req.ProviderID).Error; err != nil {
+			http.Error(w, "Provider not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	campaignID, err := resolveCampaign(db, userID, req.CampaignID)
+	if err != nil {
+		if errors.Is(err, campaigns.ErrNotFound) {
+			http.Error(w, "Campaign not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error loading campaign: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	post := database.Post{
+		Title:      strings.TrimSpace(req.Title),
+		Content:    strings.TrimSpace(req.Content),
+		UserID:     userID,
+		ProviderID: req.ProviderID,
+		CampaignID: campaignID,
+		Tags:       database.NormalizeTags(req.Tags),
+		Status:     database.PostStatusDraft,
+		AuthorID:   h.getActorID(r),
+		ReviewerID: strings.TrimSpace(req.ReviewerID),
+	}
+	if post.ReviewerID != "" && post.ReviewerID == post.AuthorID {
+		http.Error(w, errSelfReview.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := db.Create(&post).Error; err != nil {
+		log.Printf("Error creating draft: %v", err)
+		http.Error(w, "Failed to create draft", http.StatusInternalServerError)
+		return
+	}
+	if err := audit.Record(r.Context(), db, audit.Entry{
+		Action: audit.ActionPostCreated, TargetType: audit.TargetPost, TargetID: post.ID, After: post,
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	w.Header().Set("HX-Trigger", "drafts-changed")
+	h.writeJSONResponse(w, post, http.StatusCreated)
+}
+
+// HandleUpdateDraft edits the content of a post that is still a draft
+func (h *PostHandler) HandleUpdateDraft(w http.ResponseWriter, r *http.Request) {
+	var req DraftRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withDraft(w, r, audit.ActionPostUpdated, func(db *gorm.DB, post *database.Post) (int, error) {
+		if post.Status != database.PostStatusDraft {
+			return http.StatusConflict, fmt.Errorf("only drafts can be edited")
+		}
+		if strings.TrimSpace(req.Content) != "" {
+			post.Content = strings.TrimSpace(req.Content)
+		}
+		if req.Title != "" {
+			post.Title = strings.TrimSpace(req.Title)
+		}
+		if req.ProviderID != 0 {
+			var provider database.Provider
+			if err := db.First(&provider, req.ProviderID).Error; err != nil {
+				return http.StatusNotFound, fmt.Errorf("provider not found")
+			}
+			post.ProviderID = req.ProviderID
+		}
+		if req.ReviewerID != "" {
+			post.ReviewerID = strings.TrimSpace(req.ReviewerID)
+			if post.ReviewerID == post.AuthorID {
+				return http.StatusBadRequest, errSelfReview
+			}
+		}
+		if req.CampaignID != 0 {
+			campaignID, err := resolveCampaign(db, post.UserID, req.CampaignID)
+			if errors.Is(err, campaigns.ErrNotFound) {
+				return http.StatusNotFound, err
+			} else if err != nil {
+				return http.StatusInternalServerError, err
+			}
+			post.CampaignID = campaignID
+		}
+		if req.Tags != nil {
+			post.Tags = database.NormalizeTags(req.Tags)
+		}
+		return http.StatusOK, db.Omit(clause.Associations).Save(post).Error
+	})
+}
+
+// HandleListDrafts lists posts that have not been published yet
+func (h *PostHandler) HandleListDrafts(w http.ResponseWriter, r *http.Request) {
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	statuses := []string{database.PostStatusDraft, database.PostStatusInReview, database.PostStatusApproved, database.PostStatusScheduled}
+	if status := r.URL.Query().Get("status"); status != "" {
+		statuses = []string{status}
+	}
+
+	query := db.Preload("Provider").Preload("Comments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("user_id = ? AND status IN ?", userID, statuses)
+	if reviewer := r.URL.Query().Get("reviewer_id"); reviewer != "" {
+		query = query.Where("reviewer_id = ?", reviewer)
+	}
+
+	var drafts []database.Post
+	if err := query.Order("updated_at DESC").Find(&drafts).Error; err != nil {
+		log.Printf("Error fetching drafts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		h.writeJSONResponse(w, drafts, http.StatusOK)
+		return
+	}
+
+	var htmlBuilder strings.Builder
+	htmlBuilder.WriteString(`<div class="space-y-4">`)
+	if len(drafts) == 0 {
+		htmlBuilder.WriteString(`<p class="text-gray-500">No drafts yet.</p>`)
+	}
+	for _, draft := range drafts {
+		htmlBuilder.WriteString(renderDraftCard(draft))
+	}
+	htmlBuilder.WriteString(`</div>`)
+
+	w.Header().Set("Content-Type", "text/html")
+	if _, err := w.Write([]byte(htmlBuilder.String())); err != nil {
+		log.Printf("Error writing drafts response: %v", err)
+	}
+}
+
+// HandleGetDraft returns a single draft with its comments
+func (h *PostHandler) HandleGetDraft(w http.ResponseWriter, r *http.Request) {
+	h.withDraft(w, r, "", func(db *gorm.DB, post *database.Post) (int, error) {
+		return http.StatusOK, nil
+	})
+}
+
+// HandleSubmitDraft sends a draft to its reviewer
+func (h *PostHandler) HandleSubmitDraft(w http.ResponseWriter, r *http.Request) {
+	var req DraftActionRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withDraft(w, r, audit.ActionPostSubmitted, func(db *gorm.DB, post *database.Post) (int, error) {
+		if !canTransitionPost(post.Status, database.PostStatusInReview) {
+			return http.StatusConflict, fmt.Errorf("cannot submit a post in %s state", post.Status)
+		}
+		if req.ReviewerID != "" {
+			post.ReviewerID = strings.TrimSpace(req.ReviewerID)
+		}
+		if post.ReviewerID == "" {
+			return http.StatusBadRequest, fmt.Errorf("reviewer_id is required to submit a post for review")
+		}
+		if post.ReviewerID == post.AuthorID {
+			return http.StatusBadRequest, errSelfReview
+		}
+		post.Status = database.PostStatusInReview
+		return http.StatusOK, db.Omit(clause.Associations).Save(post).Error
+	})
+}
+
+// HandleApproveDraft approves a post that is in review
+func (h *PostHandler) HandleApproveDraft(w http.ResponseWriter, r *http.Request) {
+	var req DraftActionRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actorID := h.getActorID(r)
+	h.withDraft(w, r, audit.ActionPostApproved, func(db *gorm.DB, post *database.Post) (int, error) {
+		if !canTransitionPost(post.Status, database.PostStatusApproved) {
+			return http.StatusConflict, fmt.Errorf("cannot approve a post in %s state", post.Status)
+		}
+		// Posts submitted before reviewers were required may still lack one
+		if post.ReviewerID == "" {
+			return http.StatusConflict, fmt.Errorf("assign a reviewer before approving this post")
+		}
+		if post.ReviewerID != actorID {
+			return http.StatusForbidden, fmt.Errorf("only the assigned reviewer can approve this post")
+		}
+		if post.AuthorID == actorID {
+			return http.StatusForbidden, errSelfReview
+		}
+
+		now := time.Now()
+		post.Status = database.PostStatusApproved
+		post.ApprovedBy = actorID
+		post.ApprovedAt = &now
+
+		return http.StatusOK, db.Transaction(func(tx *gorm.DB) error {
+			if err := addDraftComment(tx, post, actorID, req.Comment); err != nil {
+				return err
+			}
+			return tx.Omit(clause.Associations).Save(post).Error
+		})
+	})
+}
+
+// HandleRejectDraft sends a post in review back to draft with an optional comment
+func (h *PostHandler) HandleRejectDraft(w http.ResponseWriter, r *http.Request) {
+	var req DraftActionRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.Comment == "" {
+		// hx-prompt sends the reviewer's answer as a header
+		req.Comment = r.Header.Get("HX-Prompt")
+	}
+
+	actorID := h.getActorID(r)
+	h.withDraft(w, r, audit.ActionPostRejected, func(db *gorm.DB, post *database.Post) (int, error) {
+		if post.Status != database.PostStatusInReview {
+			return http.StatusConflict, fmt.Errorf("cannot reject a post in %s state", post.Status)
+		}
+		// Posts submitted before reviewers were required may still lack one
+		if post.ReviewerID == "" {
+			return http.StatusConflict, fmt.Errorf("assign a reviewer before rejecting this post")
+		}
+		if post.ReviewerID != actorID {
+			return http.StatusForbidden, fmt.Errorf("only the assigned reviewer can reject this post")
+		}
+
+		post.Status = database.PostStatusDraft
+		post.ApprovedBy = ""
+		post.ApprovedAt = nil
+
+		return http.StatusOK, db.Transaction(func(tx *gorm.DB) error {
+			if err := addDraftComment(tx, post, actorID, req.Comment); err != nil {
+				return err
+			}
+			return tx.Omit(clause.Associations).Save(post).Error
+		})
+	})
+}
+
+// HandleCommentDraft adds a review comment to a post
+func (h *PostHandler) HandleCommentDraft(w http.ResponseWriter, r *http.Request) {
+	var req DraftActionRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Comment) == "" {
+		http.Error(w, "comment is required", http.StatusBadRequest)
+		return
+	}
+
+	actorID := h.getActorID(r)
+	h.withDraft(w, r, audit.ActionPostCommented, func(db *gorm.DB, post *database.Post) (int, error) {
+		return http.StatusCreated, addDraftComment(db, post, actorID, req.Comment)
+	})
+}
+
+// HandleScheduleDraft creates a publish job for an approved post
+func (h *PostHandler) HandleScheduleDraft(w http.ResponseWriter, r *http.Request) {
+	var req DraftActionRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	scheduledAt, err := time.Parse(time.RFC3339, req.ScheduleAt)
+	if err != nil {
+		// HTML datetime-local inputs have no seconds or zone
+		if scheduledAt, err = time.Parse("2006-01-02T15:04", req.ScheduleAt); err != nil {
+			http.Error(w, "Invalid schedule_at format. Use ISO8601 format", http.StatusBadRequest)
+			return
+		}
+	}
+	if scheduledAt.Before(time.Now()) {
+		http.Error(w, "scheduled_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	userID := h.getUserID(r)
+	h.withDraft(w, r, audit.ActionPostScheduled, func(db *gorm.DB, post *database.Post) (int, error) {
+		if !canTransitionPost(post.Status, database.PostStatusScheduled) {
+			return http.StatusConflict, fmt.Errorf("only approved posts can be scheduled")
+		}
+		if post.ProviderID == 0 {
+			return http.StatusBadRequest, fmt.Errorf("post has no provider")
+		}
+
+		var provider database.Provider
+		if err := db.First(&provider, post.ProviderID).Error; err != nil {
+			return http.StatusNotFound, fmt.Errorf("provider not found")
+		}
+		isConfigured, err := h.providerService.IsProviderConfigured(userID, provider.Name)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if !isConfigured {
+			return http.StatusBadRequest, fmt.Errorf("provider not configured")
+		}
+		if err := limits.Validate(db, userID, post.ProviderID, scheduledAt, 0); err != nil {
+			if errors.Is(err, limits.ErrConflict) {
+				return http.StatusConflict, err
+			}
+			return http.StatusInternalServerError, err
+		}
+
+		postID := post.ID
+		job := database.ScheduledJob{
+			JobType:     "publish_post",
+			PayloadData: post.Content,
+			UserID:      userID,
+			ProviderID:  post.ProviderID,
+			PostID:      &postID,
+			CampaignID:  post.CampaignID,
+			Tags:        post.Tags,
+			ScheduledAt: scheduledAt,
+			Status:      database.JobStatusPending,
+		}
+		post.Status = database.PostStatusScheduled
+
+		return http.StatusOK, db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&job).Error; err != nil {
+				return err
+			}
+			if err := audit.Record(r.Context(), tx, audit.Entry{
+				Action: audit.ActionJobScheduled, TargetType: audit.TargetJob, TargetID: job.ID, After: job,
+			}); err != nil {
+				return err
+			}
+			if err := webhooks.Emit(tx, userID, webhooks.EventJobScheduled, job); err != nil {
+				return err
+			}
+			return tx.Omit(clause.Associations).Save(post).Error
+		})
+	})
+}
+
+// withDraft loads the post from the {id} route variable, runs fn and writes
+// the updated post (or fn's error) as the response. A non-empty action is
+// recorded in the audit log once fn succeeds.
+func (h *PostHandler) withDraft(w http.ResponseWriter, r *http.Request, action string, fn func(db *gorm.DB, post *database.Post) (int, error)) {
+	postID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	post, err := loadDraft(db, userID, uint(postID))
+	if err != nil {
+		if errors.Is(err, errDraftNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error loading post %d: %v", postID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	before := *post
+	status, err := fn(db, post)
+	if err != nil {
+		if status >= http.StatusInternalServerError {
+			log.Printf("Error updating post %d: %v", post.ID, err)
+			http.Error(w, "Internal server error", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Reload so comments and provider reflect the change
+	if post, err = loadDraft(db, userID, post.ID); err != nil {
+		log.Printf("Error reloading post %d: %v", postID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if action != "" {
+		if err := audit.Record(r.Context(), db, audit.Entry{
+			Action: action, TargetType: audit.TargetPost, TargetID: post.ID, Before: before, After: post,
+		}); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("HX-Trigger", "drafts-changed")
+	}
+	h.writeJSONResponse(w, post, status)
+}
+
+func loadDraft(db *gorm.DB, userID string, postID uint) (*database.Post, error) {
+	var post database.Post
+	err := db.Preload("Provider").Preload("Comments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("user_id = ?", userID).First(&post, postID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errDraftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func addDraftComment(db *gorm.DB, post *database.Post, userID, body string) error {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil
+	}
+	return db.Create(&database.PostComment{
+		PostID: post.ID,
+		UserID: userID,
+		Body:   body,
+	}).Error
+}
+
+// userIDFields are form fields holding user IDs, which stay strings even when
+// they look numeric
+var userIDFields = map[string]bool{"reviewer_id": true, "user_id": true}
+
+// decodeRequest fills v from a JSON body or, for HTML forms, from form values
+// keyed by the struct's json tags
+func decodeRequest(r *http.Request, v interface{}) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return json.NewDecoder(r.Body).Decode(v)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{}, len(r.Form))
+	for key := range r.Form {
+		value := r.Form.Get(key)
+		if value == "" && strings.HasSuffix(key, "_id") && !userIDFields[key] {
+			// An empty select means no ID
+			continue
+		}
+		if n, err := strconv.ParseUint(value, 10, 32); err == nil && strings.HasSuffix(key, "_id") && !userIDFields[key] {
+			values[key] = n
+			continue
+		}
+		values[key] = value
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func renderDraftCard(post database.Post) string {
+	statusClass := "bg-gray-100 text-gray-800"
+	switch post.Status {
+	case database.PostStatusInReview:
+		statusClass = "bg-yellow-100 text-yellow-800"
+	case database.PostStatusApproved:
+		statusClass = "bg-green-100 text-green-800"
+	case database.PostStatusScheduled:
+		statusClass = "bg-blue-100 text-blue-800"
+	}
+
+	var actions string
+	switch post.Status {
+	case database.PostStatusDraft:
+		actions = fmt.Sprintf(`
+			<form hx-post="/posts/drafts/%d/submit" hx-swap="none" class="flex space-x-2">
+				<input type="text" name="reviewer_id" value="%s" placeholder="Reviewer" class="border rounded px-2 py-1 text-sm">
+				<button class="bg-blue-500 hover:bg-blue-700 text-white text-sm py-1 px-3 rounded">Submit for review</button>
+			</form>`, post.ID, html.EscapeString(post.ReviewerID))
+	case database.PostStatusInReview:
+		actions = fmt.Sprintf(`
+			<div class="flex space-x-2">
+				<button hx-post="/posts/drafts/%d/approve" hx-swap="none" class="bg-green-500 hover:bg-green-700 text-white text-sm py-1 px-3 rounded">Approve</button>
+				<button hx-post="/posts/drafts/%d/reject" hx-swap="none" hx-prompt="Reason for rejection" class="bg-red-500 hover:bg-red-700 text-white text-sm py-1 px-3 rounded">Reject</button>
+			</div>`, post.ID, post.ID)
+	case database.PostStatusApproved:
+		actions = fmt.Sprintf(`
+			<form hx-post="/posts/drafts/%d/schedule" hx-swap="none" class="flex space-x-2">
+				<input type="datetime-local" name="schedule_at" required class="border rounded px-2 py-1 text-sm">
+				<button class="bg-blue-500 hover:bg-blue-700 text-white text-sm py-1 px-3 rounded">Schedule</button>
+			</form>`, post.ID)
+	}
+
+	var comments strings.Builder
+	for _, comment := range post.Comments {
+		comments.WriteString(fmt.Sprintf(`<li><span class="font-medium">%s:</span> %s</li>`,
+			html.EscapeString(comment.UserID), html.EscapeString(comment.Body)))
+	}
+
+	reviewer := "unassigned"
+	if post.ReviewerID != "" {
+		reviewer = post.ReviewerID
+	}
+
+	return fmt.Sprintf(`
+		<div class="border rounded-lg p-4 bg-white space-y-3">
+			<div class="flex justify-between items-start">
+				<span class="px-2 py-1 text-xs rounded %s">%s</span>
+				<span class="text-xs text-gray-500">Reviewer: %s · Provider: %s</span>
+			</div>
+			<p class="text-gray-800">%s</p>
+			<ul class="text-sm text-gray-600 space-y-1">%s</ul>
+			<form hx-post="/posts/drafts/%d/comments" hx-swap="none" class="flex space-x-2">
+				<input type="text" name="comment" placeholder="Add a comment" class="flex-1 border rounded px-2 py-1 text-sm">
+				<button class="bg-gray-500 hover:bg-gray-700 text-white text-sm py-1 px-3 rounded">Comment</button>
+			</form>
+			%s
+		</div>`,
+		statusClass, post.Status, html.EscapeString(reviewer), html.EscapeString(post.Provider.Name),
+		html.EscapeString(post.Content), comments.String(), post.ID, actions)
+}
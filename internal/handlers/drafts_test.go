@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"github.com/tkowalski/socgo/internal/tenant"
+)
+
+func newDraftsRouter(handler *PostHandler) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/posts/drafts", handler.HandleCreateDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}", handler.HandleUpdateDraft).Methods("PUT")
+	r.HandleFunc("/posts/drafts/{id}/submit", handler.HandleSubmitDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}/approve", handler.HandleApproveDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}/reject", handler.HandleRejectDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}/comments", handler.HandleCommentDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}/schedule", handler.HandleScheduleDraft).Methods("POST")
+	return r
+}
+
+func doDraftRequest(t *testing.T, router http.Handler, path string, body interface{}) (*httptest.ResponseRecorder, database.Post) {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var post database.Post
+	if rr.Code < 300 {
+		if err := json.Unmarshal(rr.Body.Bytes(), &post); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+	}
+	return rr, post
+}
+
+// asMember runs router as userID, a member of workspace 1 with role
+func asMember(router http.Handler, userID, role string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tenant.WithContext(r.Context(), tenant.Context{UserID: userID, WorkspaceID: 1, Role: role})
+		router.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func TestDraftWorkflow(t *testing.T) {
+	handler, dbManager := newCalendarTestHandler(t)
+	router := asMember(newDraftsRouter(handler), "alice", database.WorkspaceRoleEditor)
+	client := asMember(newDraftsRouter(handler), "client", database.WorkspaceRoleEditor)
+
+	db, err := dbManager.GetDB(database.WorkspaceDBKey(1))
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: database.WorkspaceDBKey(1), IsActive: true}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	rr, draft := doDraftRequest(t, router, "/posts/drafts", DraftRequest{Content: "Launch day!", ProviderID: provider.ID})
+	if rr.Code != http.StatusCreated || draft.Status != database.PostStatusDraft || draft.AuthorID != "alice" {
+		t.Fatalf("Expected draft to be created, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// Drafts can only move to providers that exist
+	req := httptest.NewRequest("PUT", fmt.Sprintf("/posts/drafts/%d", draft.ID), strings.NewReader(`{"provider_id":999}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 when moving a draft to an unknown provider, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// Drafts cannot be scheduled before approval
+	scheduleAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	rr, _ = doDraftRequest(t, router, fmt.Sprintf("/posts/drafts/%d/schedule", draft.ID), DraftActionRequest{ScheduleAt: scheduleAt})
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 when scheduling an unapproved draft, got %d", rr.Code)
+	}
+
+	// Review needs a reviewer other than the author
+	for _, reviewer := range []string{"", "alice"} {
+		rr, _ = doDraftRequest(t, router, fmt.Sprintf("/posts/drafts/%d/submit", draft.ID), DraftActionRequest{ReviewerID: reviewer})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 when submitting to reviewer %q, got %d", reviewer, rr.Code)
+		}
+	}
+	rr, submitted := doDraftRequest(t, router, fmt.Sprintf("/posts/drafts/%d/submit", draft.ID), DraftActionRequest{ReviewerID: "client"})
+	if rr.Code != http.StatusOK || submitted.Status != database.PostStatusInReview {
+		t.Fatalf("Expected post in review, got %d %s", rr.Code, rr.Body.String())
+	}
+	rr, _ = doDraftRequest(t, router, fmt.Sprintf("/posts/drafts/%d/approve", draft.ID), DraftActionRequest{})
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when the author approves, got %d", rr.Code)
+	}
+
+	// The reviewer rejects with a comment, the author resubmits
+	rr, rejected := doDraftRequest(t, client, fmt.Sprintf("/posts/drafts/%d/reject", draft.ID), DraftActionRequest{Comment: "Add a link"})
+	if rr.Code != http.StatusOK || rejected.Status != database.PostStatusDraft || len(rejected.Comments) != 1 {
+		t.Fatalf("Expected rejected draft with comment, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	doDraftRequest(t, router, fmt.Sprintf("/posts/drafts/%d/submit", draft.ID), DraftActionRequest{})
+	rr, approved := doDraftRequest(t, client, fmt.Sprintf("/posts/drafts/%d/approve", draft.ID), DraftActionRequest{})
+	if rr.Code != http.StatusOK || approved.Status != database.PostStatusApproved || approved.ApprovedBy != "client" {
+		t.Fatalf("Expected approved post, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// Posts submitted without a reviewer cannot be approved by anyone
+	if err := db.Create(&database.Post{Content: "Legacy", UserID: database.WorkspaceDBKey(1), ProviderID: provider.ID,
+		Status: database.PostStatusInReview}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var legacy database.Post
+	db.Where("content = ?", "Legacy").First(&legacy)
+	for _, action := range []string{"approve", "reject"} {
+		rr, _ = doDraftRequest(t, client, fmt.Sprintf("/posts/drafts/%d/%s", legacy.ID, action), DraftActionRequest{})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for %s without a reviewer, got %d", action, rr.Code)
+		}
+	}
+
+	// Approved posts keep the posting limits
+	key := database.WorkspaceDBKey(1)
+	if _, err := limits.Set(context.Background(), db, key, provider.ID, limits.Input{DailyCap: 1}); err != nil {
+		t.Fatal(err)
+	}
+	crowding := database.ScheduledJob{JobType: database.JobTypePublishPost, UserID: key, ProviderID: provider.ID,
+		ScheduledAt: time.Now().Add(time.Hour), Status: database.JobStatusPending}
+	db.Create(&crowding)
+	rr, _ = doDraftRequest(t, router, fmt.Sprintf("/posts/drafts/%d/schedule", draft.ID), DraftActionRequest{ScheduleAt: scheduleAt})
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 over the daily cap, got %d %s", rr.Code, rr.Body.String())
+	}
+	db.Delete(&crowding)
+
+	rr, scheduled := doDraftRequest(t, router, fmt.Sprintf("/posts/drafts/%d/schedule", draft.ID), DraftActionRequest{ScheduleAt: scheduleAt})
+	if rr.Code != http.StatusOK || scheduled.Status != database.PostStatusScheduled {
+		t.Fatalf("Expected scheduled post, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	var job database.ScheduledJob
+	if err := db.Where("post_id = ?", draft.ID).First(&job).Error; err != nil {
+		t.Fatalf("Expected a job linked to the post: %v", err)
+	}
+	if job.Status != database.JobStatusPending || job.PayloadData != "Launch day!" {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/feeds"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+type FeedHandler struct {
+	dbManager *database.Manager
+	client    *http.Client
+}
+
+// NewFeedHandler creates a new FeedHandler instance
+func NewFeedHandler(dbManager *database.Manager) *FeedHandler {
+	return &FeedHandler{
+		dbManager: dbManager,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// FeedsPage renders the feed page
+func (h *FeedHandler) FeedsPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Feeds",
+		CurrentPage: "feeds",
+		FlashType:   "info",
+		Content:     templates.FeedsContent(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering feeds page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleListFeeds lists the feeds as JSON or as HTML list items
+func (h *FeedHandler) HandleListFeeds(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	list, err := feeds.List(db, userID)
+	if err != nil {
+		log.Printf("Error listing feeds: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, list, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(list) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No feeds yet.</li>`)
+	}
+	for _, feed := range list {
+		sb.WriteString(renderFeedItem(feed))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleGetFeed returns one feed as JSON
+func (h *FeedHandler) HandleGetFeed(w http.ResponseWriter, r *http.Request) {
+	h.withFeedID(w, r, func(db *gorm.DB, userID string, id uint) {
+		feed, err := feeds.Get(db, userID, id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		writeJSON(w, feed, http.StatusOK)
+	})
+}
+
+// HandleCreateFeed creates a feed from a JSON or form request; it is polled
+// by the scheduler right away
+func (h *FeedHandler) HandleCreateFeed(w http.ResponseWriter, r *http.Request) {
+	var in feeds.Input
+	if err := decodeFeedInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if !mayScheduleFeed(r, in) {
+		http.Error(w, "Only owners can schedule feed items without review", http.StatusForbidden)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	feed, err := feeds.Create(r.Context(), db, userID, in, time.Now())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "feeds-changed")
+	writeJSON(w, feed, http.StatusCreated)
+}
+
+// HandleUpdateFeed replaces the fields of a feed
+func (h *FeedHandler) HandleUpdateFeed(w http.ResponseWriter, r *http.Request) {
+	var in feeds.Input
+	if err := decodeFeedInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if !mayScheduleFeed(r, in) {
+		http.Error(w, "Only owners can schedule feed items without review", http.StatusForbidden)
+		return
+	}
+
+	h.withFeedID(w, r, func(db *gorm.DB, userID string, id uint) {
+		feed, err := feeds.Update(r.Context(), db, userID, id, in, time.Now())
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "feeds-changed")
+		writeJSON(w, feed, http.StatusOK)
+	})
+}
+
+// HandleDeleteFeed deletes a feed; posts created from it are kept
+func (h *FeedHandler) HandleDeleteFeed(w http.ResponseWriter, r *http.Request) {
+	h.withFeedID(w, r, func(db *gorm.DB, userID string, id uint) {
+		if err := feeds.Delete(r.Context(), db, userID, id); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "feeds-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// HandlePollFeed polls a feed now instead of waiting for the scheduler
+func (h *FeedHandler) HandlePollFeed(w http.ResponseWriter, r *http.Request) {
+	h.withFeedID(w, r, func(db *gorm.DB, userID string, id uint) {
+		result, err := feeds.Poll(r.Context(), db, userID, id, h.client, time.Now())
+		if errors.Is(err, feeds.ErrNotFound) {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "feeds-changed, calendar-changed")
+
+		if r.Header.Get("Accept") == "application/json" {
+			if err != nil {
+				// The error is stored on the feed
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, result, http.StatusOK)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		if err != nil {
+			fmt.Fprintf(w, `<span class="text-red-600">%s</span>`, html.EscapeString(err.Error()))
+			return
+		}
+		fmt.Fprintf(w, `<span class="text-green-700">%d new items: %d drafts, %d scheduled posts.</span>`, result.New, result.Drafts, result.Jobs)
+	})
+}
+
+// withFeedID parses the {id} route variable and runs fn with the tenant's
+// database
+func (h *FeedHandler) withFeedID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, id uint)) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid feed ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(id))
+}
+
+// writeError maps feed errors to HTTP status codes
+func (h *FeedHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, feeds.ErrNotFound):
+		http.Error(w, "Feed not found", http.StatusNotFound)
+	case errors.Is(err, feeds.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error updating feed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// decodeFeedInput decodes a feed from JSON or a form, where a multiple
+// select may send several provider_ids and is_active is a form boolean
+func decodeFeedInput(r *http.Request, in *feeds.Input) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return decodeRequest(r, in)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	var active *bool
+	if value := r.Form.Get("is_active"); value != "" {
+		b := value == "on" || value == "true"
+		active = &b
+		r.Form.Del("is_active")
+	}
+	providerIDs := r.Form["provider_ids"]
+	if err := decodeRequest(r, in); err != nil {
+		return err
+	}
+	ids, err := database.ParseIDList(strings.Join(providerIDs, ","))
+	if err != nil {
+		return err
+	}
+	in.ProviderIDs = ids
+	in.IsActive = active
+	return nil
+}
+
+func renderFeedItem(feed database.Feed) string {
+	status := `<span class="text-xs px-2 py-0.5 rounded bg-green-100 text-green-800">active</span>`
+	toggle, toggleLabel := "false", "Pause"
+	if !feed.IsActive {
+		status = `<span class="text-xs px-2 py-0.5 rounded bg-gray-100 text-gray-800">paused</span>`
+		toggle, toggleLabel = "true", "Resume"
+	}
+
+	polled := "never"
+	if feed.LastPolledAt != nil {
+		polled = feed.LastPolledAt.Format("2006-01-02 15:04")
+	}
+	lastError := ""
+	if feed.LastError != "" {
+		lastError = fmt.Sprintf(`<div class="text-xs text-red-600">%s</div>`, html.EscapeString(feed.LastError))
+	}
+
+	// Pausing resends the feed's fields, since updates replace them all
+	hidden := func(name, value string) string {
+		return fmt.Sprintf(`<input type="hidden" name="%s" value="%s"/>`, name, html.EscapeString(value))
+	}
+	fields := hidden("name", feed.Name) + hidden("url", feed.URL) + hidden("mode", feed.Mode) +
+		hidden("template", feed.Template) + hidden("provider_ids", feed.ProviderIDs.String()) +
+		hidden("schedule_offset", feed.ScheduleOffset) + hidden("tags", feed.Tags.String()) +
+		hidden("poll_interval", feed.PollInterval) + hidden("is_active", toggle)
+
+	return fmt.Sprintf(`
+		<li class="py-3 border-b">
+			<div class="flex justify-between items-center">
+				<span class="font-medium">%s %s</span>
+				<div class="flex space-x-2 text-sm">
+					<button hx-post="/feeds/%d/poll" hx-target="#feed-result-%d" class="text-blue-600">Poll now</button>
+					<form hx-put="/feeds/%d" hx-swap="none">%s<button type="submit" class="text-gray-600">%s</button></form>
+					<button hx-delete="/feeds/%d" hx-swap="none" hx-confirm="Delete this feed?" class="text-red-600">Delete</button>
+				</div>
+			</div>
+			<div class="text-sm text-gray-700 break-all">%s</div>
+			<div class="text-xs text-gray-500">%s · providers %s · every %s · last polled %s</div>
+			%s
+			<div id="feed-result-%d" class="text-sm"></div>
+		</li>`,
+		html.EscapeString(feed.Name), status,
+		feed.ID, feed.ID, feed.ID, fields, toggleLabel, feed.ID,
+		html.EscapeString(feed.URL),
+		html.EscapeString(feedModeLabel(feed)), html.EscapeString(feed.ProviderIDs.String()), html.EscapeString(feed.PollInterval), polled,
+		lastError, feed.ID)
+}
+
+func feedModeLabel(feed database.Feed) string {
+	if feed.Mode != database.FeedModeSchedule {
+		return "drafts"
+	}
+	if feed.ScheduleOffset == "" {
+		return "published right away"
+	}
+	return "published after " + feed.ScheduleOffset
+}
+
+// mayScheduleFeed reports whether the request may save in. Feeds in schedule
+// mode publish without review, which like direct posting is for owners.
+func mayScheduleFeed(r *http.Request, in feeds.Input) bool {
+	return in.Mode != database.FeedModeSchedule || tenant.FromRequest(r).Can(database.WorkspaceRoleOwner)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+)
+
+func TestFeedHandler_CreatePauseAndPoll(t *testing.T) {
+	_, dbManager := newCalendarTestHandler(t)
+	handler := NewFeedHandler(dbManager)
+	db, _ := dbManager.GetDB("default_user")
+	db.Create(&database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<rss version="2.0"><channel><item><guid>1</guid><title>Hello</title><link>https://example.com/1</link><pubDate>%s</pubDate></item></channel></rss>`,
+			time.Now().Add(time.Hour).Format(time.RFC1123Z))
+	}))
+	defer server.Close()
+	handler.client = server.Client()
+
+	form := func(action http.HandlerFunc, method, path string, values url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		rr := httptest.NewRecorder()
+		action(rr, req)
+		return rr
+	}
+
+	values := url.Values{"name": {"Blog"}, "url": {server.URL}, "mode": {"schedule"}, "provider_ids": {"", "1"}, "schedule_offset": {"1h"}}
+
+	// Scheduling without review is for owners
+	editor := func(w http.ResponseWriter, r *http.Request) {
+		ctx := tenant.WithContext(r.Context(), tenant.Context{UserID: "default_user", Role: database.WorkspaceRoleEditor})
+		handler.HandleCreateFeed(w, r.WithContext(ctx))
+	}
+	if rr := form(editor, "POST", "/feeds", values); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for an editor's schedule feed, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr := form(handler.HandleCreateFeed, "POST", "/feeds", values)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected the feed to be created, got %d %s", rr.Code, rr.Body.String())
+	}
+	var feed database.Feed
+	if err := json.Unmarshal(rr.Body.Bytes(), &feed); err != nil || len(feed.ProviderIDs) != 1 || !feed.IsActive {
+		t.Fatalf("Unexpected feed: %v %s", err, rr.Body.String())
+	}
+
+	rr = form(handler.HandlePollFeed, "POST", "/feeds/1/poll", url.Values{})
+	if !strings.Contains(rr.Body.String(), "1 new items") || !strings.Contains(rr.Header().Get("HX-Trigger"), "calendar-changed") {
+		t.Errorf("Unexpected poll response: %s", rr.Body.String())
+	}
+
+	values.Set("is_active", "false")
+	values["provider_ids"] = []string{"1"}
+	rr = form(handler.HandleUpdateFeed, "PUT", "/feeds/1", values)
+	if err := json.Unmarshal(rr.Body.Bytes(), &feed); err != nil || feed.IsActive {
+		t.Fatalf("Expected the feed to be paused, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handler.HandleListFeeds(rr, httptest.NewRequest("GET", "/feeds/list", nil))
+	if !strings.Contains(rr.Body.String(), "paused") || !strings.Contains(rr.Body.String(), "Resume") {
+		t.Errorf("Expected the paused feed to be listed: %s", rr.Body.String())
+	}
+}
@@ -3,7 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
@@ -11,15 +11,26 @@ import (
 	"strings"
 	"time"
 
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/blackouts"
+	"github.com/tkowalski/socgo/internal/campaigns"
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"github.com/tkowalski/socgo/internal/links"
 	"github.com/tkowalski/socgo/internal/providers"
+	"github.com/tkowalski/socgo/internal/snippets"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/webhooks"
+	"gorm.io/gorm"
 )
 
 // Request/Response structs for POST /posts endpoint
 type PostRequest struct {
-	ProviderID uint   `json:"provider_id"`
-	Content    string `json:"content"`
-	ScheduleAt string `json:"schedule_at"` // ISO8601 format or "now"
+	ProviderID uint          `json:"provider_id"`
+	Content    string        `json:"content"`
+	ScheduleAt string        `json:"schedule_at"` // ISO8601 format or "now"
+	CampaignID uint          `json:"campaign_id"`
+	Tags       database.Tags `json:"tags"`
 }
 
 type PostResponse struct {
@@ -31,26 +42,11 @@ type PostResponse struct {
 	Message    string    `json:"message,omitempty"`
 }
 
-type HistoryPost struct {
-	ID          uint      `json:"id"`
-	Content     string    `json:"content"`
-	ProviderID  uint      `json:"provider_id"`
-	Provider    database.Provider `json:"provider"`
-	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	Status      string    `json:"status"`
-}
-
-type HistoryResponse struct {
-	Posts []HistoryPost `json:"posts"`
-	Page  int           `json:"page"`
-	Total int64         `json:"total"`
-}
-
 type CalendarDay struct {
 	Day      int  `json:"day"`
 	HasPosts bool `json:"has_posts"`
 	PostCount int `json:"post_count"`
+	Conflicts int `json:"conflicts,omitempty"` // jobs breaking a posting limit
 }
 
 type CalendarResponse struct {
@@ -98,7 +94,6 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 		req.ScheduleAt = "now"
 	}
 
-	// Get user ID (currently defaults to "default_user")
 	userID := h.getUserID(r)
 
 	// Get database instance for user
@@ -109,6 +104,21 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The pause switch stops immediate posts too; blackout windows only apply
+	// to the scheduler
+	if req.ScheduleAt == "now" {
+		pause, err := blackouts.GetPause(db, userID)
+		if err != nil {
+			log.Printf("Error checking publishing pause: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if pause != nil {
+			http.Error(w, "Publishing is paused", http.StatusConflict)
+			return
+		}
+	}
+
 	// Validate provider exists and is configured
 	var provider database.Provider
 	if err := db.First(&provider, req.ProviderID).Error; err != nil {
@@ -116,6 +126,18 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	campaignID, err := resolveCampaign(db, userID, req.CampaignID)
+	if err != nil {
+		if errors.Is(err, campaigns.ErrNotFound) {
+			http.Error(w, "Campaign not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error loading campaign: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	tags := database.NormalizeTags(req.Tags)
+
 	// Check if provider is configured
 	isConfigured, err := h.providerService.IsProviderConfigured(userID, provider.Name)
 	if err != nil {
@@ -128,25 +150,48 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	vars, err := snippets.PostVars(db, userID, campaignID, tags)
+	if err != nil {
+		log.Printf("Error loading placeholder values: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	pub := &links.Publication{ProviderID: provider.ID, CampaignID: campaignID}
+	ctx := links.WithPublication(snippets.WithVars(context.Background(), vars), pub)
 
 	// Handle immediate or scheduled posting
 	if req.ScheduleAt == "now" {
+		if !h.checkPostingLimit(w, db, userID, provider.ID, time.Now(), 0) {
+			return
+		}
+
 		// Immediate posting
 		postID, err := h.providerService.PublishContent(ctx, userID, provider.Name, req.Content)
 		if err != nil {
 			log.Printf("Error publishing content: %v", err)
+			if err := webhooks.Emit(db, userID, webhooks.EventPostFailed, map[string]interface{}{
+				"provider_id": req.ProviderID,
+				"content":     req.Content,
+				"error":       err.Error(),
+			}); err != nil {
+				log.Printf("Warning: %v", err)
+			}
 			http.Error(w, "Failed to publish content", http.StatusInternalServerError)
 			return
 		}
 
 		// Save post to database
+		now := time.Now()
 		post := database.Post{
-			Content:    req.Content,
-			UserID:     userID,
-			ProviderID: req.ProviderID,
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
+			Content:     req.Content,
+			UserID:      userID,
+			ProviderID:  req.ProviderID,
+			CampaignID:  campaignID,
+			Tags:        tags,
+			Status:      database.PostStatusPublished,
+			PublishedAt: &now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
 		}
 
 		if err := db.Create(&post).Error; err != nil {
@@ -154,6 +199,20 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Failed to save post", http.StatusInternalServerError)
 			return
 		}
+		if err := links.Attach(db, pub, post.ID); err != nil {
+			log.Printf("Warning: failed to attach short links to post %d: %v", post.ID, err)
+		}
+		if err := links.LinkPost(db, &post); err != nil {
+			log.Printf("Warning: failed to link post %d for the link-in-bio page: %v", post.ID, err)
+		}
+		if err := audit.Record(r.Context(), db, audit.Entry{
+			Action: audit.ActionPostPublished, TargetType: audit.TargetPost, TargetID: post.ID, After: post,
+		}); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		if err := webhooks.Emit(db, userID, webhooks.EventPostPublished, post); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 
 		// Return success response
 		response := PostResponse{
@@ -178,6 +237,9 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "scheduled_at must be in the future", http.StatusBadRequest)
 			return
 		}
+		if !h.checkPostingLimit(w, db, userID, provider.ID, scheduledAt, 0) {
+			return
+		}
 
 		// Create scheduled job
 		job := database.ScheduledJob{
@@ -186,6 +248,8 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 			UserID:      userID,
 			ProviderID:  req.ProviderID,
 			ScheduledAt: scheduledAt,
+			CampaignID:  campaignID,
+			Tags:        tags,
 			Status:      "pending",
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
@@ -196,6 +260,14 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Failed to schedule post", http.StatusInternalServerError)
 			return
 		}
+		if err := audit.Record(r.Context(), db, audit.Entry{
+			Action: audit.ActionJobScheduled, TargetType: audit.TargetJob, TargetID: job.ID, After: job,
+		}); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		if err := webhooks.Emit(db, userID, webhooks.EventJobScheduled, job); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 
 		// Return success response
 		response := PostResponse{
@@ -211,302 +283,32 @@ func (h *PostHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *PostHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Get page parameter (default to 1)
-	pageStr := r.URL.Query().Get("page")
-	page := 1
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+// checkPostingLimit writes a conflict and returns false when a post to
+// providerID at at would break its posting limit. excludeJobID is the job
+// being rescheduled, if any.
+func (h *PostHandler) checkPostingLimit(w http.ResponseWriter, db *gorm.DB, userID string, providerID uint, at time.Time, excludeJobID uint) bool {
+	err := limits.Validate(db, userID, providerID, at, excludeJobID)
+	if errors.Is(err, limits.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return false
 	}
-
-	userID := h.getUserID(r)
-	db, err := h.dbManager.GetDB(userID)
 	if err != nil {
-		log.Printf("Error getting database: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	const pageSize = 20
-	offset := (page - 1) * pageSize
-
-	// Get total count
-	var total int64
-	if err := db.Model(&database.Post{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
-		log.Printf("Error counting posts: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Get posts with pagination and include scheduled jobs
-	var posts []database.Post
-	if err := db.Preload("Provider").Where("user_id = ?", userID).
-		Order("created_at DESC").
-		Limit(pageSize).Offset(offset).
-		Find(&posts).Error; err != nil {
-		log.Printf("Error fetching posts: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Get scheduled jobs
-	var scheduledJobs []database.ScheduledJob
-	if err := db.Preload("Provider").Where("user_id = ?", userID).
-		Order("scheduled_at DESC").
-		Limit(pageSize).Offset(offset).
-		Find(&scheduledJobs).Error; err != nil {
-		log.Printf("Error fetching scheduled jobs: %v", err)
+		log.Printf("Error checking posting limit: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Convert to history posts
-	var historyPosts []HistoryPost
-	
-	// Add published posts
-	for _, post := range posts {
-		historyPosts = append(historyPosts, HistoryPost{
-			ID:         post.ID,
-			Content:    post.Content,
-			ProviderID: post.ProviderID,
-			Provider:   post.Provider,
-			CreatedAt:  post.CreatedAt,
-			Status:     "published",
-		})
-	}
-
-	// Add scheduled posts
-	for _, job := range scheduledJobs {
-		historyPosts = append(historyPosts, HistoryPost{
-			ID:          job.ID,
-			Content:     job.PayloadData,
-			ProviderID:  job.ProviderID,
-			Provider:    job.Provider,
-			ScheduledAt: &job.ScheduledAt,
-			CreatedAt:   job.CreatedAt,
-			Status:      job.Status,
-		})
-	}
-
-	// Check if request wants JSON (API) or HTML (HTMX)
-	if r.Header.Get("Accept") == "application/json" {
-		response := HistoryResponse{
-			Posts: historyPosts,
-			Page:  page,
-			Total: total,
-		}
-		h.writeJSONResponse(w, response, http.StatusOK)
-		return
-	}
-
-	// Return HTML for HTMX
-	var htmlBuilder strings.Builder
-	htmlBuilder.WriteString(`<div class="space-y-4">`)
-	
-	for _, post := range historyPosts {
-		statusClass := "bg-green-100 text-green-800"
-		if post.Status == "pending" {
-			statusClass = "bg-yellow-100 text-yellow-800"
-		} else if post.Status == "failed" {
-			statusClass = "bg-red-100 text-red-800"
-		}
-
-		scheduledText := ""
-		if post.ScheduledAt != nil {
-			scheduledText = fmt.Sprintf(" (scheduled for %s)", post.ScheduledAt.Format("Jan 02, 15:04"))
-		}
-
-		htmlBuilder.WriteString(fmt.Sprintf(`
-			<div class="border rounded-lg p-4 bg-white">
-				<div class="flex justify-between items-start mb-2">
-					<span class="px-2 py-1 text-xs rounded %s">%s</span>
-					<span class="text-sm text-gray-500">%s%s</span>
-				</div>
-				<p class="text-gray-800">%s</p>
-				<div class="mt-2 text-xs text-gray-500">
-					Provider: %s
-				</div>
-			</div>
-		`, statusClass, post.Status, post.CreatedAt.Format("Jan 02, 15:04"), scheduledText, post.Content, post.Provider.Name))
-	}
-	
-	htmlBuilder.WriteString(`</div>`)
-	
-	w.Header().Set("Content-Type", "text/html")
-	if _, err := w.Write([]byte(htmlBuilder.String())); err != nil {
-		log.Printf("Error writing history response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		return false
 	}
+	return true
 }
 
-func (h *PostHandler) HandleCalendar(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Get year and month parameters
-	yearStr := r.URL.Query().Get("year")
-	monthStr := r.URL.Query().Get("month")
-	
-	now := time.Now()
-	year := now.Year()
-	month := int(now.Month())
-
-	if yearStr != "" {
-		if y, err := strconv.Atoi(yearStr); err == nil {
-			year = y
-		}
-	}
-	if monthStr != "" {
-		if m, err := strconv.Atoi(monthStr); err == nil && m >= 1 && m <= 12 {
-			month = m
-		}
-	}
-
-	userID := h.getUserID(r)
-	db, err := h.dbManager.GetDB(userID)
-	if err != nil {
-		log.Printf("Error getting database: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Calculate month boundaries
-	startOfMonth := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Second)
-
-	// Get posts count by day for published posts
-	var postCounts []struct {
-		Day   int
-		Count int
-	}
-	
-	if err := db.Model(&database.Post{}).
-		Select("EXTRACT(DAY FROM created_at) as day, COUNT(*) as count").
-		Where("user_id = ? AND created_at >= ? AND created_at <= ?", userID, startOfMonth, endOfMonth).
-		Group("EXTRACT(DAY FROM created_at)").
-		Scan(&postCounts).Error; err != nil {
-		log.Printf("Error fetching post counts: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Get scheduled posts count by day
-	var scheduledCounts []struct {
-		Day   int
-		Count int
-	}
-	
-	if err := db.Model(&database.ScheduledJob{}).
-		Select("EXTRACT(DAY FROM scheduled_at) as day, COUNT(*) as count").
-		Where("user_id = ? AND scheduled_at >= ? AND scheduled_at <= ?", userID, startOfMonth, endOfMonth).
-		Group("EXTRACT(DAY FROM scheduled_at)").
-		Scan(&scheduledCounts).Error; err != nil {
-		log.Printf("Error fetching scheduled counts: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Create calendar days
-	daysInMonth := endOfMonth.Day()
-	days := make([]CalendarDay, daysInMonth)
-	
-	// Initialize all days
-	for i := 0; i < daysInMonth; i++ {
-		days[i] = CalendarDay{
-			Day:       i + 1,
-			HasPosts:  false,
-			PostCount: 0,
-		}
-	}
-
-	// Add post counts
-	for _, pc := range postCounts {
-		if pc.Day > 0 && pc.Day <= daysInMonth {
-			days[pc.Day-1].PostCount += pc.Count
-			days[pc.Day-1].HasPosts = true
-		}
-	}
-
-	// Add scheduled counts
-	for _, sc := range scheduledCounts {
-		if sc.Day > 0 && sc.Day <= daysInMonth {
-			days[sc.Day-1].PostCount += sc.Count
-			days[sc.Day-1].HasPosts = true
-		}
-	}
-
-	// Check if request wants JSON (API) or HTML (HTMX)
-	if r.Header.Get("Accept") == "application/json" {
-		response := CalendarResponse{
-			Year:  year,
-			Month: month,
-			Days:  days,
-		}
-		h.writeJSONResponse(w, response, http.StatusOK)
-		return
-	}
-
-	// Return HTML calendar grid for HTMX
-	var htmlBuilder strings.Builder
-	htmlBuilder.WriteString(`<div class="grid grid-cols-7 gap-1 text-center">`)
-	
-	// Days of week header
-	daysOfWeek := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
-	for _, day := range daysOfWeek {
-		htmlBuilder.WriteString(fmt.Sprintf(`<div class="font-semibold text-gray-600 p-2">%s</div>`, day))
-	}
-
-	// Get first day of month to calculate starting position
-	firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	startingWeekday := int(firstDay.Weekday())
-
-	// Add empty cells for days before month starts
-	for i := 0; i < startingWeekday; i++ {
-		htmlBuilder.WriteString(`<div class="p-2"></div>`)
-	}
-
-	// Add calendar days
-	for _, day := range days {
-		dayClass := "p-2 border border-gray-200 hover:bg-gray-100"
-		if day.HasPosts {
-			dayClass = "p-2 border border-blue-500 bg-blue-100 text-blue-800 font-semibold hover:bg-blue-200"
-		}
-
-		postCountText := ""
-		if day.PostCount > 0 {
-			postCountText = fmt.Sprintf(`<br><span class="text-xs">(%d posts)</span>`, day.PostCount)
-		}
-
-		htmlBuilder.WriteString(fmt.Sprintf(`
-			<div class="%s">
-				%d%s
-			</div>
-		`, dayClass, day.Day, postCountText))
-	}
-	
-	htmlBuilder.WriteString(`</div>`)
-	
-	w.Header().Set("Content-Type", "text/html")
-	if _, err := w.Write([]byte(htmlBuilder.String())); err != nil {
-		log.Printf("Error writing calendar response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+// getUserID returns the tenant whose database the request works on: the
+// selected workspace or the user's personal space
+func (h *PostHandler) getUserID(r *http.Request) string {
+	return tenant.FromRequest(r).DBKey()
 }
 
-func (h *PostHandler) getUserID(r *http.Request) string {
-	// TODO: Implement proper user authentication
-	return "default_user"
+// getActorID returns the user performing the request, regardless of workspace
+func (h *PostHandler) getActorID(r *http.Request) string {
+	return tenant.FromRequest(r).UserID
 }
 
 func (h *PostHandler) writeJSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
@@ -623,21 +425,37 @@ var calendarTemplate = `
             <!-- History Section -->
             <div class="bg-white rounded-lg shadow-md p-6">
                 <h2 class="text-2xl font-bold text-gray-800 mb-4">Recent Posts</h2>
+                <form id="history-search" class="mb-4 grid grid-cols-2 gap-2"
+                      hx-get="/posts/search"
+                      hx-trigger="input delay:300ms, change, submit"
+                      hx-target="#history-list">
+                    <input type="search" name="q" placeholder="Search posts..." class="col-span-2 border rounded px-3 py-2">
+                    <select name="status" class="border rounded px-3 py-2">
+                        <option value="">Any status</option>
+                        <option value="published">Published</option>
+                        <option value="draft">Draft</option>
+                        <option value="in_review">In review</option>
+                        <option value="approved">Approved</option>
+                        <option value="scheduled">Scheduled</option>
+                        <option value="failed">Failed</option>
+                    </select>
+                    <select name="provider_id" hx-get="/api/providers/options" hx-trigger="load" hx-target="this" class="border rounded px-3 py-2">
+                        <option value="">Any provider</option>
+                    </select>
+                    <select name="campaign_id" hx-get="/campaigns/options" hx-trigger="load" hx-target="this" class="border rounded px-3 py-2">
+                        <option value="">Any campaign</option>
+                    </select>
+                    <input type="text" name="tag" placeholder="Tag" class="border rounded px-3 py-2">
+                    <input type="date" name="from" class="border rounded px-3 py-2">
+                    <input type="date" name="to" class="border rounded px-3 py-2">
+                </form>
+                <style>#history-list mark { background-color: #fef08a; }</style>
                 <div id="history-list" 
                      hx-get="/posts/history" 
                      hx-trigger="load"
                      hx-target="this">
                     Loading history...
                 </div>
-                <div class="mt-4 text-center">
-                    <button 
-                        hx-get="/posts/history?page=2" 
-                        hx-target="#history-list"
-                        class="bg-gray-500 hover:bg-gray-700 text-white font-bold py-2 px-4 rounded"
-                    >
-                        Load More
-                    </button>
-                </div>
             </div>
         </div>
     </div>
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/timeline"
+)
+
+// historyPageSize is the number of timeline items returned per page
+const historyPageSize = 20
+
+// HandleHistory returns the post timeline: one item per post, whether it is
+// a draft, scheduled, published or failed. Pages continue from the
+// next_cursor of the previous one; HTMX pages end with a button loading the
+// next.
+func (h *PostHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseTimelineFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := timeline.Query(db, userID, filter)
+	if errors.Is(err, timeline.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching timeline: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Check if request wants JSON (API) or HTML (HTMX)
+	if r.Header.Get("Accept") == "application/json" {
+		h.writeJSONResponse(w, page, http.StatusOK)
+		return
+	}
+
+	// Later pages replace the button that loaded them inside the list
+	var sb strings.Builder
+	if filter.Cursor == "" {
+		sb.WriteString(`<div class="space-y-4">`)
+		sb.WriteString(fmt.Sprintf(`<p class="text-sm text-gray-500">%d posts</p>`, page.Total))
+	}
+	for _, item := range page.Items {
+		statusClass := "bg-gray-100 text-gray-800"
+		switch item.Status {
+		case database.PostStatusPublished:
+			statusClass = "bg-green-100 text-green-800"
+		case database.PostStatusScheduled, timeline.StatusPublishing:
+			statusClass = "bg-yellow-100 text-yellow-800"
+		case timeline.StatusFailed:
+			statusClass = "bg-red-100 text-red-800"
+		}
+
+		when := item.At.Format("Jan 02, 15:04")
+		if item.ScheduledAt != nil && item.PublishedAt == nil {
+			when = "scheduled for " + item.ScheduledAt.Format("Jan 02, 15:04")
+		}
+		deleteButton := ""
+		if item.Kind == timeline.KindPost {
+			deleteButton = fmt.Sprintf(`<button hx-delete="/posts/%d" hx-target="closest .border" hx-swap="delete" hx-confirm="Move this post to the trash?" class="ml-2 text-red-600 text-xs">Delete</button>`, item.ID)
+		}
+		errorText := ""
+		if item.Error != "" {
+			errorText = fmt.Sprintf(`<p class="mt-1 text-xs text-red-700">%s</p>`, html.EscapeString(item.Error))
+		}
+
+		sb.WriteString(fmt.Sprintf(`
+			<div class="border rounded-lg p-4 bg-white">
+				<div class="flex justify-between items-start mb-2">
+					<span class="px-2 py-1 text-xs rounded %s">%s</span>
+					<span class="text-sm text-gray-500">%s%s</span>
+				</div>
+				<p class="text-gray-800">%s</p>
+				%s
+				<div class="mt-2 text-xs text-gray-500">
+					Provider: %s%s
+				</div>
+			</div>
+		`, statusClass, html.EscapeString(item.Status), when, deleteButton, html.EscapeString(item.Content), errorText, html.EscapeString(item.Provider), renderTags(item.Tags)))
+	}
+	if page.NextCursor != "" {
+		next := r.URL.Query()
+		next.Set("cursor", page.NextCursor)
+		sb.WriteString(fmt.Sprintf(`
+			<button hx-get="/posts/history?%s" hx-target="this" hx-swap="outerHTML"
+				class="bg-gray-500 hover:bg-gray-700 text-white font-bold py-2 px-4 rounded">Load More</button>`,
+			html.EscapeString(next.Encode())))
+	}
+	if filter.Cursor == "" {
+		sb.WriteString(`</div>`)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// renderTags lists tags as "#tag" labels after the provider of an item
+func renderTags(tags database.Tags) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	labels := make([]string, len(tags))
+	for i, tag := range tags {
+		labels[i] = "#" + html.EscapeString(tag)
+	}
+	return " · " + strings.Join(labels, " ")
+}
+
+// parseTimelineFilter reads the timeline filters, sort and cursor from the
+// query string. Dates use the calendar's YYYY-MM-DD format and "to" is
+// inclusive. sort names the field to order by, prefixed with "-" for newest
+// first; the default is "-at".
+func parseTimelineFilter(r *http.Request) (timeline.Filter, error) {
+	q := r.URL.Query()
+	filter := timeline.Filter{
+		Status: strings.TrimSpace(q.Get("status")),
+		Tag:    strings.TrimSpace(q.Get("tag")),
+		Cursor: q.Get("cursor"),
+		Limit:  historyPageSize,
+	}
+
+	if value := q.Get("provider_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid provider_id")
+		}
+		filter.ProviderID = uint(id)
+	}
+	if value := q.Get("campaign_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid campaign_id")
+		}
+		filter.CampaignID = uint(id)
+	}
+	if value := q.Get("from"); value != "" {
+		from, err := time.ParseInLocation(calendarDateLayout, value, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date, use YYYY-MM-DD")
+		}
+		filter.From = from
+	}
+	if value := q.Get("to"); value != "" {
+		to, err := time.ParseInLocation(calendarDateLayout, value, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date, use YYYY-MM-DD")
+		}
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	if value := q.Get("sort"); value != "" {
+		field := strings.TrimPrefix(value, "-")
+		if field != timeline.SortAt && field != timeline.SortCreatedAt {
+			return filter, fmt.Errorf("invalid sort, use at, -at, created_at or -created_at")
+		}
+		filter.SortBy = field
+		filter.Ascending = field == value
+	}
+	if value := q.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 100 {
+			return filter, fmt.Errorf("invalid limit, use 1 to 100")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/timeline"
+)
+
+func TestHandleHistory_PaginatesTimeline(t *testing.T) {
+	handler, dbManager := newCalendarTestHandler(t)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true}
+	db.Create(&provider)
+	for i := 0; i < 25; i++ {
+		db.Create(&database.Post{Content: "Published <b>post</b>", UserID: "default_user", ProviderID: provider.ID, Status: database.PostStatusPublished})
+	}
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "Upcoming", UserID: "default_user", ProviderID: provider.ID,
+		ScheduledAt: time.Now().Add(time.Hour), Status: database.JobStatusPending})
+
+	get := func(path string) timeline.Page {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+		handler.HandleHistory(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d %s", path, rr.Code, rr.Body.String())
+		}
+		var page timeline.Page
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		return page
+	}
+
+	first := get("/posts/history")
+	if first.Total != 26 || len(first.Items) != historyPageSize || first.NextCursor == "" {
+		t.Fatalf("Unexpected first page: total %d, %d items, cursor %q", first.Total, len(first.Items), first.NextCursor)
+	}
+	if first.Items[0].Kind != timeline.KindJob || first.Items[0].Status != database.PostStatusScheduled {
+		t.Errorf("Expected the scheduled job first, got %+v", first.Items[0])
+	}
+	second := get("/posts/history?cursor=" + first.NextCursor)
+	if second.Total != 26 || len(second.Items) != 6 || second.NextCursor != "" {
+		t.Errorf("Unexpected second page: total %d, %d items, cursor %q", second.Total, len(second.Items), second.NextCursor)
+	}
+
+	if page := get("/posts/history?status=scheduled&sort=created_at"); page.Total != 1 {
+		t.Errorf("Expected 1 scheduled item, got %d", page.Total)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.HandleHistory(rr, httptest.NewRequest("GET", "/posts/history", nil))
+	body := rr.Body.String()
+	if !strings.Contains(body, "26 posts") || !strings.Contains(body, "&lt;b&gt;post&lt;/b&gt;") || !strings.Contains(body, "cursor=") {
+		t.Errorf("Unexpected HTML: %s", body)
+	}
+
+	for _, path := range []string{"/posts/history?cursor=nope", "/posts/history?sort=title", "/posts/history?from=soon"} {
+		rr := httptest.NewRecorder()
+		handler.HandleHistory(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, rr.Code)
+		}
+	}
+}
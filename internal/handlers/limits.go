@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"gorm.io/gorm"
+)
+
+type LimitHandler struct {
+	dbManager *database.Manager
+}
+
+// NewLimitHandler creates a new LimitHandler instance
+func NewLimitHandler(dbManager *database.Manager) *LimitHandler {
+	return &LimitHandler{dbManager: dbManager}
+}
+
+// limitRequest is a posting limit and, when the route does not name it, its
+// provider
+type limitRequest struct {
+	limits.Input
+	ProviderID uint `json:"provider_id"`
+}
+
+// HandleListLimits lists the posting limits as JSON or as HTML list items
+func (h *LimitHandler) HandleListLimits(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	list, err := limits.List(db, userID)
+	if err != nil {
+		log.Printf("Error listing posting limits: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, list, http.StatusOK)
+		return
+	}
+
+	names, err := providerNames(db, userID)
+	if err != nil {
+		log.Printf("Error loading providers: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var sb strings.Builder
+	if len(list) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No posting limits yet.</li>`)
+	}
+	for _, limit := range list {
+		sb.WriteString(renderPostingLimit(limit, names))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleGetLimit returns the posting limit of a provider as JSON
+func (h *LimitHandler) HandleGetLimit(w http.ResponseWriter, r *http.Request) {
+	h.withProviderID(w, r, func(db *gorm.DB, userID string, providerID uint) {
+		limit, err := limits.Get(db, userID, providerID)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		writeJSON(w, limit, http.StatusOK)
+	})
+}
+
+// HandleSetLimit creates or replaces the posting limit of a provider, named
+// by the route or by provider_id in a JSON or form request
+func (h *LimitHandler) HandleSetLimit(w http.ResponseWriter, r *http.Request) {
+	var req limitRequest
+	if err := decodeLimitRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if id, ok := mux.Vars(r)["provider_id"]; ok {
+		providerID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+			return
+		}
+		req.ProviderID = uint(providerID)
+	}
+	if req.ProviderID == 0 {
+		http.Error(w, "provider_id is required", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	limit, err := limits.Set(r.Context(), db, userID, req.ProviderID, req.Input)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "limits-changed")
+	writeJSON(w, limit, http.StatusOK)
+}
+
+// HandleDeleteLimit removes the posting limit of a provider
+func (h *LimitHandler) HandleDeleteLimit(w http.ResponseWriter, r *http.Request) {
+	h.withProviderID(w, r, func(db *gorm.DB, userID string, providerID uint) {
+		if err := limits.Delete(r.Context(), db, userID, providerID); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "limits-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// withProviderID parses the {provider_id} route variable and runs fn with the
+// tenant's database
+func (h *LimitHandler) withProviderID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, providerID uint)) {
+	providerID, err := strconv.ParseUint(mux.Vars(r)["provider_id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(providerID))
+}
+
+// writeError maps posting limit errors to HTTP status codes
+func (h *LimitHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, limits.ErrNotFound):
+		http.Error(w, "Posting limit not found", http.StatusNotFound)
+	case errors.Is(err, limits.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error updating posting limit: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// decodeLimitRequest decodes a posting limit from JSON or a form, where the
+// numbers arrive as text and empty fields mean no limit
+func decodeLimitRequest(r *http.Request, req *limitRequest) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return decodeRequest(r, req)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	numbers := map[string]*int{"daily_cap": &req.DailyCap, "min_gap_minutes": &req.MinGapMinutes}
+	for field, target := range numbers {
+		if value := strings.TrimSpace(r.Form.Get(field)); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			*target = n
+		}
+	}
+	if value := r.Form.Get("provider_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		req.ProviderID = uint(id)
+	}
+	req.Timezone = r.Form.Get("timezone")
+	return nil
+}
+
+func renderPostingLimit(limit database.PostingLimit, names map[uint]string) string {
+	name := names[limit.ProviderID]
+	if name == "" {
+		name = fmt.Sprintf("provider %d", limit.ProviderID)
+	}
+
+	var rules []string
+	if limit.DailyCap > 0 {
+		rules = append(rules, fmt.Sprintf("at most %d posts a day (%s)", limit.DailyCap, limit.Timezone))
+	}
+	if limit.MinGapMinutes > 0 {
+		rules = append(rules, fmt.Sprintf("%d minutes between posts", limit.MinGapMinutes))
+	}
+
+	return fmt.Sprintf(`
+		<li class="py-3 border-b">
+			<div class="flex justify-between items-center">
+				<span class="font-medium">%s</span>
+				<button hx-delete="/blackouts/limits/%d" hx-swap="none" hx-confirm="Remove this posting limit?" class="text-red-600 text-sm">Remove</button>
+			</div>
+			<div class="text-sm text-gray-700">%s</div>
+		</li>`,
+		html.EscapeString(name), limit.ProviderID, html.EscapeString(strings.Join(rules, ", ")))
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestLimitHandler_RejectsAndFlagsCrowdedPosts(t *testing.T) {
+	postHandler, dbManager := newCalendarTestHandler(t)
+	handler := NewLimitHandler(dbManager)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/blackouts/limits", strings.NewReader(fmt.Sprintf("provider_id=%d&daily_cap=&min_gap_minutes=30", provider.ID)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.HandleSetLimit(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("HX-Trigger") != "limits-changed" {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	day := time.Date(2030, time.March, 14, 0, 0, 0, 0, time.UTC)
+	schedule := func(at time.Time) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"provider_id":%d,"content":"Hello","schedule_at":%q}`, provider.ID, at.Format(time.RFC3339))
+		rr := httptest.NewRecorder()
+		postHandler.HandlePost(rr, httptest.NewRequest("POST", "/api/posts", strings.NewReader(body)))
+		return rr
+	}
+	if rr := schedule(day.Add(10 * time.Hour)); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := schedule(day.Add(10*time.Hour + 10*time.Minute)); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 within the gap, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = schedule(day.Add(12 * time.Hour))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var second PostResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &second); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/posts/jobs/{id}/reschedule", postHandler.HandleRescheduleJob).Methods("PATCH")
+	req = httptest.NewRequest("PATCH", fmt.Sprintf("/posts/jobs/%d/reschedule", second.ID),
+		strings.NewReader(fmt.Sprintf(`{"scheduled_at":%q}`, day.Add(10*time.Hour+20*time.Minute).Format(time.RFC3339))))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 when rescheduling into the gap, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Jobs created before the limit was tightened show up as conflicts
+	req = httptest.NewRequest("PUT", fmt.Sprintf("/api/limits/%d", provider.ID), strings.NewReader(`{"min_gap_minutes":180}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"provider_id": fmt.Sprint(provider.ID)})
+	rr = httptest.NewRecorder()
+	handler.HandleSetLimit(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/posts/calendar?view=agenda&date=2030-03-14", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	postHandler.HandleCalendar(rr, req)
+	var calendar CalendarRangeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &calendar); err != nil {
+		t.Fatalf("Failed to unmarshal calendar: %v", err)
+	}
+	entries := calendar.Days[0].Entries
+	if len(entries) != 2 || entries[0].Conflict != "" || entries[1].Conflict != "less than 180 minutes after another post" {
+		t.Errorf("Expected the later job to conflict, got %+v", entries)
+	}
+
+	req = httptest.NewRequest("GET", "/posts/calendar?view=agenda&date=2030-03-14", nil)
+	rr = httptest.NewRecorder()
+	postHandler.HandleCalendar(rr, req)
+	if !strings.Contains(rr.Body.String(), "Conflict: less than 180 minutes after another post") {
+		t.Errorf("Expected the conflict in the agenda, got %s", rr.Body.String())
+	}
+}
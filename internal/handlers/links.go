@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/links"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+type LinkHandler struct {
+	dbManager *database.Manager
+}
+
+// NewLinkHandler creates a new LinkHandler instance
+func NewLinkHandler(dbManager *database.Manager) *LinkHandler {
+	return &LinkHandler{dbManager: dbManager}
+}
+
+// LinksPage renders the link rules page
+func (h *LinkHandler) LinksPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Links",
+		CurrentPage: "links",
+		FlashType:   "info",
+		Content:     templates.LinksContent(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering links page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleRedirect counts a click of the short link {code} and redirects to
+// its target. Short links are public, so the tenant is found through the
+// control plane.
+func (h *LinkHandler) HandleRedirect(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	route, err := h.dbManager.LookupLink(code)
+	if err != nil {
+		if !errors.Is(err, database.ErrUnknownLink) {
+			log.Printf("Error looking up short link: %v", err)
+		}
+		http.NotFound(w, r)
+		return
+	}
+	db, err := h.dbManager.GetDB(route.TenantKey)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	target, err := links.Click(db, code, time.Now())
+	if err != nil {
+		if errors.Is(err, links.ErrUnknownLink) {
+			http.NotFound(w, r)
+			return
+		}
+		log.Printf("Error counting click: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// HandleListRules lists the link rules as JSON or as HTML list items
+func (h *LinkHandler) HandleListRules(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	rules, err := links.ListRules(db, userID)
+	if err != nil {
+		log.Printf("Error listing link rules: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, rules, http.StatusOK)
+		return
+	}
+
+	names, err := ruleTargetNames(db, userID)
+	if err != nil {
+		log.Printf("Error loading rule targets: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var sb strings.Builder
+	if len(rules) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No link rules yet; links are published unchanged.</li>`)
+	}
+	for _, rule := range rules {
+		sb.WriteString(renderLinkRule(rule, names))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleGetRule returns one link rule as JSON
+func (h *LinkHandler) HandleGetRule(w http.ResponseWriter, r *http.Request) {
+	h.withRuleID(w, r, func(db *gorm.DB, userID string, id uint) {
+		rule, err := links.GetRule(db, userID, id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		writeJSON(w, rule, http.StatusOK)
+	})
+}
+
+// HandleCreateRule creates a link rule from a JSON or form request
+func (h *LinkHandler) HandleCreateRule(w http.ResponseWriter, r *http.Request) {
+	var in links.RuleInput
+	if err := decodeLinkRuleInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	rule, err := links.CreateRule(r.Context(), db, userID, in)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "link-rules-changed")
+	writeJSON(w, rule, http.StatusCreated)
+}
+
+// HandleUpdateRule replaces the fields of a link rule
+func (h *LinkHandler) HandleUpdateRule(w http.ResponseWriter, r *http.Request) {
+	var in links.RuleInput
+	if err := decodeLinkRuleInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withRuleID(w, r, func(db *gorm.DB, userID string, id uint) {
+		rule, err := links.UpdateRule(r.Context(), db, userID, id, in)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "link-rules-changed")
+		writeJSON(w, rule, http.StatusOK)
+	})
+}
+
+// HandleDeleteRule deletes a link rule
+func (h *LinkHandler) HandleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	h.withRuleID(w, r, func(db *gorm.DB, userID string, id uint) {
+		if err := links.DeleteRule(r.Context(), db, userID, id); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "link-rules-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// HandleLinkStats returns the clicks of short links per provider and for the
+// most clicked posts, as JSON or as an HTML summary for the dashboard
+func (h *LinkHandler) HandleLinkStats(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := links.GetStats(db, userID)
+	if err != nil {
+		log.Printf("Error loading link stats: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, stats, http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, renderLinkStats(stats))
+}
+
+// HandlePostLinks returns the short links of a post with their clicks
+func (h *LinkHandler) HandlePostLinks(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	list, err := links.PostLinks(db, userID, uint(id))
+	if err != nil {
+		log.Printf("Error listing short links: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, list, http.StatusOK)
+}
+
+// withRuleID parses the {id} route variable and runs fn with the tenant's
+// database
+func (h *LinkHandler) withRuleID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, id uint)) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid link rule ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(id))
+}
+
+// writeError maps link rule errors to HTTP status codes
+func (h *LinkHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, links.ErrNotFound):
+		http.Error(w, "Link rule not found", http.StatusNotFound)
+	case errors.Is(err, links.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error updating link rule: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// decodeLinkRuleInput decodes a link rule from JSON or a form, where shorten
+// is a checkbox
+func decodeLinkRuleInput(r *http.Request, in *links.RuleInput) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return decodeRequest(r, in)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	value := r.Form.Get("shorten")
+	r.Form.Del("shorten")
+	if err := decodeRequest(r, in); err != nil {
+		return err
+	}
+	in.Shorten = value == "on" || value == "true"
+	return nil
+}
+
+// ruleTargetNames returns the names of the providers and campaigns rules may
+// apply to, keyed by ID
+func ruleTargetNames(db *gorm.DB, userID string) (map[string]map[uint]string, error) {
+	var providers []database.Provider
+	if err := db.Where("user_id = ?", userID).Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	var campaigns []database.Campaign
+	if err := db.Where("user_id = ?", userID).Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+
+	names := map[string]map[uint]string{"provider": {}, "campaign": {}}
+	for _, provider := range providers {
+		names["provider"][provider.ID] = provider.Name
+	}
+	for _, campaign := range campaigns {
+		names["campaign"][campaign.ID] = campaign.Name
+	}
+	return names, nil
+}
+
+func renderLinkRule(rule database.LinkRule, names map[string]map[uint]string) string {
+	scope := func(kind string, id *uint) string {
+		if id == nil {
+			return "any " + kind
+		}
+		if name, ok := names[kind][*id]; ok {
+			return name
+		}
+		return fmt.Sprintf("%s %d", kind, *id)
+	}
+
+	var params []string
+	for _, p := range []struct{ name, value string }{
+		{"utm_source", rule.UTMSource}, {"utm_medium", rule.UTMMedium}, {"utm_campaign", rule.UTMCampaign},
+		{"utm_term", rule.UTMTerm}, {"utm_content", rule.UTMContent},
+	} {
+		if p.value != "" {
+			params = append(params, p.name+"="+p.value)
+		}
+	}
+	if len(params) == 0 {
+		params = append(params, "no UTM parameters")
+	}
+	shorten := ""
+	if rule.Shorten {
+		shorten = `<span class="text-xs px-2 py-0.5 rounded bg-blue-100 text-blue-800">short links</span>`
+	}
+
+	return fmt.Sprintf(`
+		<li class="py-3 border-b">
+			<div class="flex justify-between items-center">
+				<span class="font-medium">%s · %s %s</span>
+				<button hx-delete="/links/rules/%d" hx-swap="none" hx-confirm="Delete this rule?" class="text-red-600 text-sm">Delete</button>
+			</div>
+			<div class="text-sm text-gray-700 break-all">%s</div>
+		</li>`,
+		html.EscapeString(scope("provider", rule.ProviderID)), html.EscapeString(scope("campaign", rule.CampaignID)), shorten,
+		rule.ID, html.EscapeString(strings.Join(params, " & ")))
+}
+
+func renderLinkStats(stats *links.Stats) string {
+	if stats.Links == 0 {
+		return `<p class="text-gray-500">No short links yet. Add a link rule that shortens links to count clicks.</p>`
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<p class="mb-3"><span class="text-2xl font-bold">%d</span> clicks on %d short links</p>`, stats.Clicks, stats.Links)
+	sb.WriteString(`<div class="grid grid-cols-1 md:grid-cols-2 gap-6"><div><h3 class="font-semibold mb-1">By provider</h3><ul class="text-sm">`)
+	for _, p := range stats.Providers {
+		name := p.ProviderName
+		switch {
+		case p.ProviderID == 0:
+			name = "Link in bio"
+		case name == "":
+			name = fmt.Sprintf("provider %d", p.ProviderID)
+		}
+		fmt.Fprintf(&sb, `<li class="flex justify-between py-1 border-b"><span>%s</span><span>%d</span></li>`, html.EscapeString(name), p.Clicks)
+	}
+	sb.WriteString(`</ul></div><div><h3 class="font-semibold mb-1">Top posts</h3><ul class="text-sm">`)
+	if len(stats.Posts) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No published posts with short links.</li>`)
+	}
+	for _, p := range stats.Posts {
+		fmt.Fprintf(&sb, `<li class="flex justify-between py-1 border-b"><span class="truncate mr-2">[%s] %s</span><span>%d</span></li>`,
+			html.EscapeString(p.ProviderName), html.EscapeString(p.Content), p.Clicks)
+	}
+	sb.WriteString(`</ul></div></div>`)
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/links"
+)
+
+func TestLinkHandler_RulesRedirectAndStats(t *testing.T) {
+	_, dbManager := newCalendarTestHandler(t)
+	handler := NewLinkHandler(dbManager)
+
+	req := httptest.NewRequest("POST", "/api/links/rules", strings.NewReader(`{"utm_source":"{{provider}}","shorten":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleCreateRule(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/links/rules", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandleCreateRule(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty rule, got %d", rr.Code)
+	}
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	link := database.ShortLink{Code: "abc12345", URL: "https://example.com/?utm_source=facebook", ProviderID: 1, UserID: "default_user"}
+	if err := db.Create(&link).Error; err != nil {
+		t.Fatalf("Failed to create short link: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc(links.PathPrefix+"{code}", handler.HandleRedirect).Methods("GET")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/l/abc12345", nil))
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != link.URL {
+		t.Fatalf("Expected a redirect to %s, got %d %q", link.URL, rr.Code, rr.Header().Get("Location"))
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/l/missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown code, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/stats/links", nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandleLinkStats(rr, req)
+
+	var stats links.Stats
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to unmarshal stats: %v", err)
+	}
+	if stats.Links != 1 || stats.Clicks != 1 {
+		t.Errorf("Expected one click on one link, got %+v", stats)
+	}
+}
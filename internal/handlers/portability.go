@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/portability"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+)
+
+// maxImportSize limits the size of uploaded archives
+const maxImportSize = 100 << 20
+
+type PortabilityHandler struct {
+	dbManager *database.Manager
+}
+
+// NewPortabilityHandler creates a new PortabilityHandler instance
+func NewPortabilityHandler(dbManager *database.Manager) *PortabilityHandler {
+	return &PortabilityHandler{
+		dbManager: dbManager,
+	}
+}
+
+// AccountPage renders the export and import page
+func (h *PortabilityHandler) AccountPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Account data",
+		CurrentPage: "account",
+		FlashType:   "info",
+		Content:     templates.AccountContent(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering account page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleExport downloads the current user's or workspace's data as a zip
+// archive. Credentials and secrets are only included with ?secrets=true.
+func (h *PortabilityHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	key := tenant.FromRequest(r).DBKey()
+	db, release, err := h.dbManager.Acquire(key)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer release()
+
+	opts := portability.ExportOptions{
+		IncludeSecrets: r.URL.Query().Get("secrets") == "true",
+		MediaDir:       h.dbManager.MediaDir(key),
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="socgo-export-%s.zip"`, time.Now().Format("20060102-150405")))
+	manifest, err := portability.Export(r.Context(), w, db, key, h.dbManager.Driver(), opts)
+	if err != nil {
+		// Headers are already sent; the client gets a truncated archive
+		log.Printf("Error exporting %s: %v", key, err)
+		return
+	}
+
+	if err := audit.Record(r.Context(), db, audit.Entry{
+		Action: audit.ActionAccountExported, TargetType: audit.TargetAccount, After: manifest,
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// HandleImport merges an uploaded archive into the current user's or
+// workspace's data. The archive is sent as the "archive" field of a multipart
+// form or as the raw request body.
+func (h *PortabilityHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var upload io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("archive")
+		if err != nil {
+			http.Error(w, "Missing archive file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		upload = file
+	}
+
+	tmp, err := os.CreateTemp("", "socgo-import-*.zip")
+	if err != nil {
+		log.Printf("Error creating import file: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, upload)
+	if err != nil {
+		http.Error(w, "Archive too large or unreadable", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	key := tenant.FromRequest(r).DBKey()
+	db, release, err := h.dbManager.Acquire(key)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer release()
+
+	registry, err := h.dbManager.GetRegistryDB()
+	if err != nil {
+		log.Printf("Error getting registry database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	opts := portability.ImportOptions{
+		SkipPendingJobs: r.URL.Query().Get("skip_pending_jobs") == "true" || r.FormValue("skip_pending_jobs") == "true",
+		MediaDir:        h.dbManager.MediaDir(key),
+		Registry:        registry,
+	}
+	manifest, report, err := portability.Import(r.Context(), tmp, size, db, key, opts)
+	if err != nil {
+		if errors.Is(err, portability.ErrInvalidArchive) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error importing into %s: %v", key, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := audit.Record(r.Context(), db, audit.Entry{
+		Action: audit.ActionAccountImported, TargetType: audit.TargetAccount, Before: manifest, After: report,
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, report, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<div class="p-4 bg-green-100 text-green-800 rounded-lg">
+		Imported from %s: %d new providers (%d matched), %d posts, %d jobs, %d tokens, %d webhooks, %d media files; %d records skipped.`,
+		html.EscapeString(manifest.Source), report.ProvidersCreated, report.ProvidersMatched, report.Posts, report.Jobs,
+		report.Tokens, report.Webhooks, report.Media, report.Skipped))
+	if len(report.Warnings) > 0 {
+		sb.WriteString(`<ul class="mt-2 text-sm list-disc ml-5">`)
+		for _, warning := range report.Warnings {
+			sb.WriteString(fmt.Sprintf(`<li>%s</li>`, html.EscapeString(warning)))
+		}
+		sb.WriteString(`</ul>`)
+	}
+	sb.WriteString(`</div>`)
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/portability"
+)
+
+func TestPortabilityHandler_ExportAndImport(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+	handler := NewPortabilityHandler(dbManager)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	provider := database.Provider{Name: "main", Type: "mastodon", Config: "{}", UserID: "default_user", IsActive: true}
+	db.Create(&provider)
+	db.Create(&database.Post{Content: "Exported", UserID: "default_user", ProviderID: provider.ID, Status: database.PostStatusPublished})
+
+	rr := httptest.NewRecorder()
+	handler.HandleExport(rr, httptest.NewRequest("GET", "/api/export", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("Expected a zip archive, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	archive := rr.Body.Bytes()
+
+	var count int64
+	db.Model(&database.AuditLog{}).Where("action = ?", "account.exported").Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the export to be audited, got %d entries", count)
+	}
+
+	// Importing the archive back into the same account only matches records
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("archive", "export.zip")
+	part.Write(archive)
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	handler.HandleImport(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	var report portability.ImportReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid report: %v", err)
+	}
+	if report.ProvidersMatched != 1 || report.Posts != 0 || report.Skipped != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestPortabilityHandler_RejectsInvalidArchive(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	rr := httptest.NewRecorder()
+	NewPortabilityHandler(dbManager).HandleImport(rr, httptest.NewRequest("POST", "/api/import", bytes.NewReader([]byte("not a zip"))))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/posttemplates"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+// templateVarPrefix prefixes the form fields holding template variables
+const templateVarPrefix = "var_"
+
+type PostTemplateHandler struct {
+	dbManager *database.Manager
+}
+
+// NewPostTemplateHandler creates a new PostTemplateHandler instance
+func NewPostTemplateHandler(dbManager *database.Manager) *PostTemplateHandler {
+	return &PostTemplateHandler{
+		dbManager: dbManager,
+	}
+}
+
+// PostTemplateDetails is a template with the variables its content uses
+type PostTemplateDetails struct {
+	database.PostTemplate
+	Variables []string `json:"variables"`
+}
+
+// ScheduledTemplate is the response to scheduling a template
+type ScheduledTemplate struct {
+	Preview *posttemplates.Preview  `json:"preview"`
+	Jobs    []database.ScheduledJob `json:"jobs"`
+}
+
+// PostTemplatesPage renders the post template page
+func (h *PostTemplateHandler) PostTemplatesPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Templates",
+		CurrentPage: "templates",
+		FlashType:   "info",
+		Content:     templates.PostTemplatesContent(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering templates page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleListPostTemplates lists the templates with their variables as JSON,
+// or as HTML cards with a form to instantiate each
+func (h *PostTemplateHandler) HandleListPostTemplates(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	list, err := posttemplates.List(db, userID)
+	if err != nil {
+		log.Printf("Error listing post templates: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	details := make([]PostTemplateDetails, len(list))
+	for i, tpl := range list {
+		details[i] = templateDetails(tpl)
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, details, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(details) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No templates yet.</li>`)
+	}
+	for _, d := range details {
+		sb.WriteString(renderPostTemplateCard(d))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleGetPostTemplate returns one template with its variables as JSON
+func (h *PostTemplateHandler) HandleGetPostTemplate(w http.ResponseWriter, r *http.Request) {
+	h.withPostTemplateID(w, r, func(db *gorm.DB, userID string, id uint) {
+		tpl, err := posttemplates.Get(db, userID, id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		writeJSON(w, templateDetails(*tpl), http.StatusOK)
+	})
+}
+
+// HandleCreatePostTemplate creates a template from a JSON or form request
+func (h *PostTemplateHandler) HandleCreatePostTemplate(w http.ResponseWriter, r *http.Request) {
+	var in posttemplates.Input
+	if err := decodePostTemplateInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tpl, err := posttemplates.Create(r.Context(), db, userID, in)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "templates-changed")
+	writeJSON(w, templateDetails(*tpl), http.StatusCreated)
+}
+
+// HandleUpdatePostTemplate replaces the fields of a template
+func (h *PostTemplateHandler) HandleUpdatePostTemplate(w http.ResponseWriter, r *http.Request) {
+	var in posttemplates.Input
+	if err := decodePostTemplateInput(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withPostTemplateID(w, r, func(db *gorm.DB, userID string, id uint) {
+		tpl, err := posttemplates.Update(r.Context(), db, userID, id, in)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "templates-changed")
+		writeJSON(w, templateDetails(*tpl), http.StatusOK)
+	})
+}
+
+// HandleDeletePostTemplate deletes a template
+func (h *PostTemplateHandler) HandleDeletePostTemplate(w http.ResponseWriter, r *http.Request) {
+	h.withPostTemplateID(w, r, func(db *gorm.DB, userID string, id uint) {
+		if err := posttemplates.Delete(r.Context(), db, userID, id); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "templates-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// HandlePreviewPostTemplate renders a template with the request's variables
+// and validates it for each provider without scheduling anything
+func (h *PostTemplateHandler) HandlePreviewPostTemplate(w http.ResponseWriter, r *http.Request) {
+	var req posttemplates.Request
+	if err := decodePostTemplateRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withPostTemplateID(w, r, func(db *gorm.DB, userID string, id uint) {
+		preview, err := posttemplates.Instantiate(db, userID, id, req, time.Now())
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+
+		if r.Header.Get("Accept") == "application/json" {
+			writeJSON(w, preview, http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, renderTemplatePreview(preview, ""))
+	})
+}
+
+// HandleSchedulePostTemplate instantiates a template and schedules a post for
+// each provider. Nothing is scheduled when the preview has errors; JSON
+// clients then get the preview with status 400.
+func (h *PostTemplateHandler) HandleSchedulePostTemplate(w http.ResponseWriter, r *http.Request) {
+	var req posttemplates.Request
+	if err := decodePostTemplateRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withPostTemplateID(w, r, func(db *gorm.DB, userID string, id uint) {
+		preview, jobs, err := posttemplates.Schedule(r.Context(), db, userID, id, req, time.Now())
+		if err != nil && preview == nil {
+			h.writeError(w, err)
+			return
+		}
+
+		if r.Header.Get("Accept") == "application/json" {
+			status := http.StatusCreated
+			if err != nil {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, ScheduledTemplate{Preview: preview, Jobs: jobs}, status)
+			return
+		}
+
+		message := ""
+		if err == nil {
+			message = fmt.Sprintf("Scheduled %d posts for %s.", len(jobs), preview.ScheduleAt.Format("2006-01-02 15:04"))
+			w.Header().Set("HX-Trigger", "calendar-changed")
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, renderTemplatePreview(preview, message))
+	})
+}
+
+// withPostTemplateID parses the {id} route variable and runs fn with the
+// tenant's database
+func (h *PostTemplateHandler) withPostTemplateID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, id uint)) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(id))
+}
+
+// writeError maps template errors to HTTP status codes
+func (h *PostTemplateHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, posttemplates.ErrNotFound):
+		http.Error(w, "Template not found", http.StatusNotFound)
+	case errors.Is(err, posttemplates.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error updating post template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// decodePostTemplateInput decodes a template from JSON or a form, where a
+// multiple select may send several provider_ids
+func decodePostTemplateInput(r *http.Request, in *posttemplates.Input) error {
+	if err := decodeRequest(r, in); err != nil {
+		return err
+	}
+	if r.Form != nil && len(r.Form["provider_ids"]) > 1 {
+		ids, err := database.ParseIDList(strings.Join(r.Form["provider_ids"], ","))
+		if err != nil {
+			return err
+		}
+		in.ProviderIDs = ids
+	}
+	return nil
+}
+
+// decodePostTemplateRequest decodes an instantiation from JSON or a form,
+// where variables are sent as var_<name> fields
+func decodePostTemplateRequest(r *http.Request, req *posttemplates.Request) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return json.NewDecoder(r.Body).Decode(req)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	req.Variables = map[string]string{}
+	for key := range r.Form {
+		if name, ok := strings.CutPrefix(key, templateVarPrefix); ok {
+			req.Variables[name] = r.Form.Get(key)
+		}
+	}
+	ids, err := database.ParseIDList(strings.Join(r.Form["provider_ids"], ","))
+	if err != nil {
+		return err
+	}
+	req.ProviderIDs = ids
+	req.ScheduleAt = r.Form.Get("schedule_at")
+	return nil
+}
+
+func templateDetails(tpl database.PostTemplate) PostTemplateDetails {
+	// Stored templates always parse
+	variables, _ := posttemplates.Variables(tpl.Content)
+	if variables == nil {
+		variables = []string{}
+	}
+	return PostTemplateDetails{PostTemplate: tpl, Variables: variables}
+}
+
+func renderPostTemplateCard(d PostTemplateDetails) string {
+	var fields strings.Builder
+	for _, name := range d.Variables {
+		fields.WriteString(fmt.Sprintf(`
+					<label class="block text-sm">%s <input type="text" name="%s%s" class="w-full border rounded px-2 py-1"/></label>`,
+			html.EscapeString(name), templateVarPrefix, html.EscapeString(name)))
+	}
+
+	offset := "now"
+	if d.ScheduleOffset != "" {
+		offset = "in " + d.ScheduleOffset
+	}
+	return fmt.Sprintf(`
+		<li class="py-3 border-b">
+			<div class="flex justify-between items-center">
+				<span class="font-medium">%s</span>
+				<button hx-delete="/templates/%d" hx-swap="none" hx-confirm="Delete this template?" class="text-sm text-red-600">Delete</button>
+			</div>
+			<div class="text-sm text-gray-700 whitespace-pre-wrap">%s</div>
+			<div class="text-xs text-gray-500">Providers %s · scheduled %s · tags %s</div>
+			<form hx-post="/templates/%d/preview" hx-target="#template-preview-%d" class="mt-2 space-y-2">%s
+				<label class="block text-sm">Schedule at (optional) <input type="datetime-local" name="schedule_at" class="border rounded px-2 py-1"/></label>
+				<div class="flex space-x-2">
+					<button type="submit" class="bg-gray-200 hover:bg-gray-300 py-1 px-3 rounded text-sm">Preview</button>
+					<button type="button" hx-post="/templates/%d/schedule" hx-include="closest form" hx-target="#template-preview-%d" class="bg-blue-500 hover:bg-blue-700 text-white py-1 px-3 rounded text-sm">Schedule</button>
+				</div>
+			</form>
+			<div id="template-preview-%d" class="mt-2"></div>
+		</li>`,
+		html.EscapeString(d.Name), d.ID, html.EscapeString(d.Content),
+		html.EscapeString(d.ProviderIDs.String()), html.EscapeString(offset), html.EscapeString(d.Tags.String()),
+		d.ID, d.ID, fields.String(), d.ID, d.ID, d.ID)
+}
+
+func renderTemplatePreview(preview *posttemplates.Preview, message string) string {
+	var sb strings.Builder
+	if message != "" {
+		sb.WriteString(fmt.Sprintf(`<div class="text-green-700 text-sm mb-2">%s</div>`, html.EscapeString(message)))
+	}
+	for _, problem := range preview.Errors {
+		sb.WriteString(fmt.Sprintf(`<div class="text-red-600 text-sm">%s</div>`, html.EscapeString(problem)))
+	}
+	for _, target := range preview.Targets {
+		if target.Content == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(`
+			<div class="border rounded p-2 mt-2 text-sm">
+				<div class="text-xs text-gray-500">%s · %s</div>
+				<div class="whitespace-pre-wrap">%s</div>
+			</div>`,
+			html.EscapeString(target.Provider), preview.ScheduleAt.Format("2006-01-02 15:04"), html.EscapeString(target.Content)))
+	}
+	return sb.String()
+}
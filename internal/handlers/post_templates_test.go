@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestPostTemplateHandler_PreviewAndSchedule(t *testing.T) {
+	_, dbManager := newCalendarTestHandler(t)
+	handler := NewPostTemplateHandler(dbManager)
+	db, _ := dbManager.GetDB("default_user")
+	db.Create(&database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true})
+
+	// A multiple select sends one provider_ids field per provider
+	values := url.Values{"name": {"Launch"}, "content": {"<b>{{.product}}</b> out now"}, "provider_ids": {"", "1"}, "schedule_offset": {"1h"}}
+	req := httptest.NewRequest("POST", "/templates", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.HandleCreatePostTemplate(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected the template to be created, got %d %s", rr.Code, rr.Body.String())
+	}
+	var created PostTemplateDetails
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || len(created.ProviderIDs) != 1 || len(created.Variables) != 1 || created.Variables[0] != "product" {
+		t.Fatalf("Unexpected template: %v %s", err, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handler.HandleListPostTemplates(rr, httptest.NewRequest("GET", "/templates/list", nil))
+	if !strings.Contains(rr.Body.String(), `name="var_product"`) {
+		t.Errorf("Expected a field for the product variable: %s", rr.Body.String())
+	}
+
+	form := func(action http.HandlerFunc, path string, values url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		rr := httptest.NewRecorder()
+		action(rr, req)
+		return rr
+	}
+
+	rr = form(handler.HandlePreviewPostTemplate, "/templates/1/preview", url.Values{"var_product": {"Socgo"}})
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "&lt;b&gt;Socgo&lt;/b&gt; out now") {
+		t.Errorf("Unexpected preview: %d %s", rr.Code, rr.Body.String())
+	}
+	rr = form(handler.HandleSchedulePostTemplate, "/templates/1/schedule", url.Values{})
+	if !strings.Contains(rr.Body.String(), "product") || rr.Header().Get("HX-Trigger") != "" {
+		t.Errorf("Expected the missing variable to be reported: %s", rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/templates/1/schedule", strings.NewReader(`{"variables": {"product": "Socgo"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr = httptest.NewRecorder()
+	handler.HandleSchedulePostTemplate(rr, req)
+	var scheduled ScheduledTemplate
+	if err := json.Unmarshal(rr.Body.Bytes(), &scheduled); err != nil || rr.Code != http.StatusCreated || len(scheduled.Jobs) != 1 {
+		t.Errorf("Expected one job to be scheduled, got %d %s", rr.Code, rr.Body.String())
+	}
+}
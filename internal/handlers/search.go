@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/search"
+)
+
+// searchPageSize is the number of matches returned per page
+const searchPageSize = 20
+
+// SearchResponse is a page of post search results
+type SearchResponse struct {
+	Query   string          `json:"query"`
+	Results []search.Result `json:"results"`
+	Page    int             `json:"page"`
+	Total   int64           `json:"total"`
+}
+
+// HandleSearch finds posts by the words of their title and content. The HTML
+// version falls back to the history list when the query is empty, so it can
+// back the search box above it.
+func (h *PostHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	query, page, err := parseSearchQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wantsJSON := r.Header.Get("Accept") == "application/json"
+	if len(search.Terms(query.Text)) == 0 {
+		if wantsJSON {
+			http.Error(w, "q is required", http.StatusBadRequest)
+			return
+		}
+		h.HandleHistory(w, r)
+		return
+	}
+
+	userID := h.getUserID(r)
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	results, total, err := search.Posts(db, userID, query)
+	if err != nil {
+		log.Printf("Error searching posts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []search.Result{}
+	}
+
+	if wantsJSON {
+		h.writeJSONResponse(w, SearchResponse{Query: query.Text, Results: results, Page: page, Total: total}, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<div class="space-y-4">`)
+	sb.WriteString(fmt.Sprintf(`<p class="text-sm text-gray-500">%d matching posts</p>`, total))
+	for _, result := range results {
+		title := ""
+		if result.Title != "" {
+			title = fmt.Sprintf(`<h3 class="font-semibold text-gray-900">%s</h3>`, html.EscapeString(result.Title))
+		}
+		sb.WriteString(fmt.Sprintf(`
+			<div class="border rounded-lg p-4 bg-white">
+				<div class="flex justify-between items-start mb-2">
+					<span class="px-2 py-1 text-xs rounded bg-gray-100 text-gray-800">%s</span>
+					<span class="text-sm text-gray-500">%s</span>
+				</div>
+				%s
+				<p class="text-gray-800">%s</p>
+				<div class="mt-2 text-xs text-gray-500">
+					Provider: %s%s
+				</div>
+			</div>
+		`, html.EscapeString(result.Status), result.CreatedAt.Format("Jan 02, 15:04"), title, result.Snippet, html.EscapeString(result.Provider), renderTags(result.Tags)))
+	}
+	if int64(page*searchPageSize) < total {
+		next := r.URL.Query()
+		next.Set("page", strconv.Itoa(page+1))
+		sb.WriteString(fmt.Sprintf(`<button hx-get="/posts/search?%s" hx-target="#history-list" class="bg-gray-500 hover:bg-gray-700 text-white font-bold py-2 px-4 rounded">More results</button>`,
+			html.EscapeString(next.Encode())))
+	}
+	sb.WriteString(`</div>`)
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// parseSearchQuery reads the search text, filters and page from the query
+// string. Dates use the calendar's YYYY-MM-DD format and "to" is inclusive.
+func parseSearchQuery(r *http.Request) (search.Query, int, error) {
+	q := r.URL.Query()
+	query := search.Query{
+		Text:   strings.TrimSpace(q.Get("q")),
+		Status: strings.TrimSpace(q.Get("status")),
+		Tag:    strings.TrimSpace(q.Get("tag")),
+		Limit:  searchPageSize,
+	}
+
+	if value := q.Get("provider_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return query, 0, fmt.Errorf("invalid provider_id")
+		}
+		query.ProviderID = uint(id)
+	}
+	if value := q.Get("campaign_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return query, 0, fmt.Errorf("invalid campaign_id")
+		}
+		query.CampaignID = uint(id)
+	}
+	if value := q.Get("from"); value != "" {
+		from, err := time.ParseInLocation(calendarDateLayout, value, time.Local)
+		if err != nil {
+			return query, 0, fmt.Errorf("invalid from date, use YYYY-MM-DD")
+		}
+		query.From = from
+	}
+	if value := q.Get("to"); value != "" {
+		to, err := time.ParseInLocation(calendarDateLayout, value, time.Local)
+		if err != nil {
+			return query, 0, fmt.Errorf("invalid to date, use YYYY-MM-DD")
+		}
+		query.To = to.AddDate(0, 0, 1)
+	}
+
+	page := 1
+	if value := q.Get("page"); value != "" {
+		p, err := strconv.Atoi(value)
+		if err != nil || p < 1 {
+			return query, 0, fmt.Errorf("invalid page")
+		}
+		page = p
+	}
+	query.Offset = (page - 1) * searchPageSize
+
+	return query, page, nil
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestHandleSearch(t *testing.T) {
+	handler, dbManager := newCalendarTestHandler(t)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true}
+	db.Create(&provider)
+	db.Create(&database.Post{Content: "Summer sale starts today", UserID: "default_user", ProviderID: provider.ID, Status: database.PostStatusPublished})
+	db.Create(&database.Post{Content: "Winter is coming", UserID: "default_user", ProviderID: provider.ID, Status: database.PostStatusPublished})
+
+	req := httptest.NewRequest("GET", "/api/posts/search?q=sale&status=published&from=2000-01-01", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	handler.HandleSearch(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	var response SearchResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if response.Total != 1 || response.Results[0].Snippet != "Summer <mark>sale</mark> starts today" {
+		t.Errorf("Unexpected results: %+v", response)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.HandleSearch(rr, httptest.NewRequest("GET", "/posts/search?q=winter", nil))
+	if !strings.Contains(rr.Body.String(), "<mark>Winter</mark> is coming") || strings.Contains(rr.Body.String(), "Summer") {
+		t.Errorf("Unexpected HTML results: %s", rr.Body.String())
+	}
+
+	for _, path := range []string{"/api/posts/search", "/api/posts/search?q=sale&from=yesterday"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+		handler.HandleSearch(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, rr.Code)
+		}
+	}
+}
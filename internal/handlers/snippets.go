@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/snippets"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+type SnippetHandler struct {
+	dbManager *database.Manager
+}
+
+// NewSnippetHandler creates a new SnippetHandler instance
+func NewSnippetHandler(dbManager *database.Manager) *SnippetHandler {
+	return &SnippetHandler{
+		dbManager: dbManager,
+	}
+}
+
+// SnippetsPage renders the snippet library page
+func (h *SnippetHandler) SnippetsPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Snippets",
+		CurrentPage: "snippets",
+		FlashType:   "info",
+		Content:     templates.SnippetsContent(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering snippets page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleListSnippets lists the snippets and hashtag groups as JSON or HTML,
+// optionally only those of the kind query parameter
+func (h *SnippetHandler) HandleListSnippets(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.list(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, list, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(list) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No snippets yet.</li>`)
+	}
+	for _, snippet := range list {
+		sb.WriteString(fmt.Sprintf(`
+			<li class="py-3 border-b">
+				<div class="flex justify-between items-center">
+					<span class="font-medium">%s <span class="text-xs text-gray-500">%s</span></span>
+					<button hx-delete="/snippets/%d" hx-swap="none" hx-confirm="Delete this snippet?" class="text-sm text-red-600">Delete</button>
+				</div>
+				<div class="text-sm text-gray-700 whitespace-pre-wrap">%s</div>
+			</li>`,
+			html.EscapeString(snippet.Name), html.EscapeString(snippet.Kind), snippet.ID, html.EscapeString(snippet.Body)))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleSnippetOptions renders the snippets as buttons that insert their body
+// into the post composer
+func (h *SnippetHandler) HandleSnippetOptions(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.list(w, r)
+	if !ok {
+		return
+	}
+
+	var sb strings.Builder
+	if len(list) == 0 {
+		sb.WriteString(`<span class="text-sm text-gray-500">No snippets yet, add some on the <a href="/snippets" class="underline">Snippets</a> page.</span>`)
+	}
+	for _, snippet := range list {
+		style := "bg-gray-100"
+		if snippet.Kind == database.SnippetKindHashtags {
+			style = "bg-blue-100"
+		}
+		sb.WriteString(fmt.Sprintf(`<button type="button" class="%s rounded px-2 py-1 text-sm" title="%s" data-body="%s" onclick="insertSnippet(this.dataset.body)">%s</button>`,
+			style, html.EscapeString(snippet.Body), html.EscapeString(snippet.Body), html.EscapeString(snippet.Name)))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleGetSnippet returns one snippet as JSON
+func (h *SnippetHandler) HandleGetSnippet(w http.ResponseWriter, r *http.Request) {
+	h.withSnippetID(w, r, func(db *gorm.DB, userID string, id uint) {
+		snippet, err := snippets.Get(db, userID, id)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		writeJSON(w, snippet, http.StatusOK)
+	})
+}
+
+// HandleCreateSnippet creates a snippet or hashtag group from a JSON or form
+// request
+func (h *SnippetHandler) HandleCreateSnippet(w http.ResponseWriter, r *http.Request) {
+	var in snippets.Input
+	if err := decodeRequest(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	snippet, err := snippets.Create(r.Context(), db, userID, in)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "snippets-changed")
+	writeJSON(w, snippet, http.StatusCreated)
+}
+
+// HandleUpdateSnippet replaces the name, kind and body of a snippet
+func (h *SnippetHandler) HandleUpdateSnippet(w http.ResponseWriter, r *http.Request) {
+	var in snippets.Input
+	if err := decodeRequest(r, &in); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	h.withSnippetID(w, r, func(db *gorm.DB, userID string, id uint) {
+		snippet, err := snippets.Update(r.Context(), db, userID, id, in)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "snippets-changed")
+		writeJSON(w, snippet, http.StatusOK)
+	})
+}
+
+// HandleDeleteSnippet deletes a snippet
+func (h *SnippetHandler) HandleDeleteSnippet(w http.ResponseWriter, r *http.Request) {
+	h.withSnippetID(w, r, func(db *gorm.DB, userID string, id uint) {
+		if err := snippets.Delete(r.Context(), db, userID, id); err != nil {
+			h.writeError(w, err)
+			return
+		}
+		w.Header().Set("HX-Trigger", "snippets-changed")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// list returns the tenant's snippets of the requested kind, writing an error
+// response when it fails
+func (h *SnippetHandler) list(w http.ResponseWriter, r *http.Request) ([]database.Snippet, bool) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	list, err := snippets.List(db, userID, r.URL.Query().Get("kind"))
+	if err != nil {
+		log.Printf("Error listing snippets: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return list, true
+}
+
+// withSnippetID parses the {id} route variable and runs fn with the tenant's
+// database
+func (h *SnippetHandler) withSnippetID(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, userID string, id uint)) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid snippet ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, userID, uint(id))
+}
+
+// writeError maps snippet errors to HTTP status codes
+func (h *SnippetHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, snippets.ErrNotFound):
+		http.Error(w, "Snippet not found", http.StatusNotFound)
+	case errors.Is(err, snippets.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error updating snippet: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
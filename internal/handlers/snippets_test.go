@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestSnippetHandler_CRUDAndOptions(t *testing.T) {
+	_, dbManager := newCalendarTestHandler(t)
+	handler := NewSnippetHandler(dbManager)
+
+	values := url.Values{"name": {"Footer"}, "body": {`Read <more> at {{campaign}}`}}
+	req := httptest.NewRequest("POST", "/snippets", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.HandleCreateSnippet(rr, req)
+	if rr.Code != http.StatusCreated || rr.Header().Get("HX-Trigger") != "snippets-changed" {
+		t.Fatalf("Expected the snippet to be created, got %d %s", rr.Code, rr.Body.String())
+	}
+	var snippet database.Snippet
+	if err := json.Unmarshal(rr.Body.Bytes(), &snippet); err != nil || snippet.ID == 0 || snippet.Kind != database.SnippetKindText {
+		t.Fatalf("Invalid snippet response: %v %+v", err, snippet)
+	}
+
+	req = httptest.NewRequest("PUT", "/api/snippets/1", strings.NewReader(`{"name":"Tags","kind":"hashtags","body":"a b"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr = httptest.NewRecorder()
+	handler.HandleUpdateSnippet(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"body":"#a #b"`) {
+		t.Errorf("Expected the snippet to become a hashtag group, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// The composer gets escaped insert buttons
+	rr = httptest.NewRecorder()
+	handler.HandleSnippetOptions(rr, httptest.NewRequest("GET", "/snippets/options?kind=hashtags", nil))
+	if !strings.Contains(rr.Body.String(), `data-body="#a #b"`) || !strings.Contains(rr.Body.String(), ">Tags</button>") {
+		t.Errorf("Unexpected snippet options: %s", rr.Body.String())
+	}
+
+	req = mux.SetURLVars(httptest.NewRequest("DELETE", "/snippets/1", nil), map[string]string{"id": "1"})
+	rr = httptest.NewRecorder()
+	handler.HandleDeleteSnippet(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the snippet to be deleted, got %d", rr.Code)
+	}
+	req = mux.SetURLVars(httptest.NewRequest("GET", "/api/snippets/1", nil), map[string]string{"id": "1"})
+	rr = httptest.NewRecorder()
+	handler.HandleGetSnippet(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected a deleted snippet to return 404, got %d", rr.Code)
+	}
+}
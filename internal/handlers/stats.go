@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+type StatsHandler struct {
+	dbManager *database.Manager
+}
+
+// NewStatsHandler creates a new StatsHandler instance
+func NewStatsHandler(dbManager *database.Manager) *StatsHandler {
+	return &StatsHandler{
+		dbManager: dbManager,
+	}
+}
+
+// HandleDatabaseStats reports how many tenant databases are open and how the
+// pool has been used since start
+func (h *StatsHandler) HandleDatabaseStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.dbManager.Stats(), http.StatusOK)
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/trash"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+// trashKinds maps the kinds used in trash URLs to trash item kinds
+var trashKinds = map[string]string{
+	"posts":     trash.KindPost,
+	"providers": trash.KindProvider,
+}
+
+type TrashHandler struct {
+	dbManager *database.Manager
+	retention time.Duration
+}
+
+// NewTrashHandler creates a new TrashHandler instance. retention is how long
+// items stay in the trash; zero or negative keeps them.
+func NewTrashHandler(dbManager *database.Manager, retention time.Duration) *TrashHandler {
+	return &TrashHandler{
+		dbManager: dbManager,
+		retention: retention,
+	}
+}
+
+// TrashPage renders the trash page
+func (h *TrashHandler) TrashPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Trash",
+		CurrentPage: "trash",
+		FlashType:   "info",
+		Content:     templates.TrashContent(h.retentionText()),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering trash page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleListTrash lists the deleted posts and providers as JSON or HTML
+func (h *TrashHandler) HandleListTrash(w http.ResponseWriter, r *http.Request) {
+	db, err := h.dbManager.GetDB(tenant.FromRequest(r).DBKey())
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	items, err := trash.List(db, h.retention)
+	if err != nil {
+		log.Printf("Error listing trash: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, items, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(items) == 0 {
+		sb.WriteString(`<li class="text-gray-500">The trash is empty.</li>`)
+	}
+	for _, item := range items {
+		kind := item.Kind + "s"
+		purge := ""
+		if item.PurgeAt != nil {
+			purge = ", purged " + item.PurgeAt.Format("Jan 02, 15:04")
+		}
+		sb.WriteString(fmt.Sprintf(`
+			<li class="py-3 border-b">
+				<div class="flex justify-between items-center">
+					<span><span class="px-2 py-1 text-xs rounded bg-gray-100 text-gray-800">%s</span> <span class="font-medium">%s</span></span>
+					<span class="flex space-x-3">
+						<button hx-post="/trash/%s/%d/restore" hx-target="closest li" hx-swap="delete" class="text-blue-600 text-sm">Restore</button>
+						<button hx-delete="/trash/%s/%d" hx-target="closest li" hx-swap="delete" hx-confirm="Delete permanently? This cannot be undone." class="text-red-600 text-sm">Delete permanently</button>
+					</span>
+				</div>
+				<div class="text-xs text-gray-500">%s · deleted %s%s</div>
+			</li>`,
+			item.Kind, html.EscapeString(item.Name), kind, item.ID, kind, item.ID,
+			html.EscapeString(item.Detail), item.DeletedAt.Format("Jan 02, 15:04"), purge))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleDelete moves a post or provider into the trash
+func (h *TrashHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	h.withItem(w, r, func(db *gorm.DB, key, kind string, id uint) error {
+		return trash.Delete(r.Context(), db, key, kind, id)
+	})
+}
+
+// HandleRestore takes a post or provider out of the trash
+func (h *TrashHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	h.withItem(w, r, func(db *gorm.DB, key, kind string, id uint) error {
+		return trash.Restore(r.Context(), db, key, kind, id)
+	})
+}
+
+// HandlePurge permanently deletes a post or provider from the trash
+func (h *TrashHandler) HandlePurge(w http.ResponseWriter, r *http.Request) {
+	h.withItem(w, r, func(db *gorm.DB, key, kind string, id uint) error {
+		return trash.Purge(r.Context(), db, key, kind, id)
+	})
+}
+
+// withItem resolves the item of the request and runs action on it. Providers
+// are managed by workspace owners only.
+func (h *TrashHandler) withItem(w http.ResponseWriter, r *http.Request, action func(db *gorm.DB, key, kind string, id uint) error) {
+	vars := mux.Vars(r)
+	kind, ok := trashKinds[vars["kind"]]
+	if !ok {
+		http.Error(w, "Unknown kind of item", http.StatusNotFound)
+		return
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ident := tenant.FromRequest(r)
+	if kind == trash.KindProvider && !ident.Can(database.WorkspaceRoleOwner) {
+		http.Error(w, "Only workspace owners can manage providers", http.StatusForbidden)
+		return
+	}
+
+	key := ident.DBKey()
+	db, err := h.dbManager.GetDB(key)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = action(db, key, kind, uint(id))
+	switch {
+	case errors.Is(err, trash.ErrNotFound):
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return
+	case errors.Is(err, trash.ErrProviderInUse):
+		http.Error(w, "Provider has pending jobs; cancel or reschedule them first", http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Error updating trash: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "trash-changed")
+	w.WriteHeader(http.StatusOK)
+}
+
+// retentionText describes when items in the trash are purged
+func (h *TrashHandler) retentionText() string {
+	if h.retention <= 0 {
+		return "Items stay in the trash until you delete them permanently."
+	}
+	days := int(h.retention.Hours() / 24)
+	if days >= 1 {
+		return fmt.Sprintf("Items are deleted permanently %d days after they were moved to the trash.", days)
+	}
+	return fmt.Sprintf("Items are deleted permanently %s after they were moved to the trash.", h.retention)
+}
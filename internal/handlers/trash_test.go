@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/trash"
+)
+
+func TestTrashHandler_DeleteRestorePurge(t *testing.T) {
+	_, dbManager := newCalendarTestHandler(t)
+	handler := NewTrashHandler(dbManager, 30*24*time.Hour)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true}
+	db.Create(&provider)
+	post := database.Post{Content: "Old <b>news</b>", UserID: "default_user", ProviderID: provider.ID}
+	db.Create(&post)
+
+	call := func(action http.HandlerFunc, method, kind, id string, role string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/trash/"+kind+"/"+id, nil)
+		req = mux.SetURLVars(req, map[string]string{"kind": kind, "id": id})
+		req = req.WithContext(tenant.WithContext(req.Context(), tenant.Context{UserID: "default_user", Role: role}))
+		rr := httptest.NewRecorder()
+		action(rr, req)
+		return rr
+	}
+	list := func() []trash.Item {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/trash", nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+		handler.HandleListTrash(rr, req)
+		var items []trash.Item
+		if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		return items
+	}
+
+	if rr := call(handler.HandleDelete, "DELETE", "posts", "1", database.WorkspaceRoleEditor); rr.Code != http.StatusOK || rr.Header().Get("HX-Trigger") != "trash-changed" {
+		t.Fatalf("Expected the post to be deleted, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := call(handler.HandleDelete, "DELETE", "providers", "1", database.WorkspaceRoleEditor); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected editors not to delete providers, got %d", rr.Code)
+	}
+	if rr := call(handler.HandleDelete, "DELETE", "providers", "1", database.WorkspaceRoleOwner); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the provider to be deleted, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := call(handler.HandleDelete, "DELETE", "comments", "1", database.WorkspaceRoleOwner); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown kinds to be rejected, got %d", rr.Code)
+	}
+
+	items := list()
+	if len(items) != 2 || items[0].PurgeAt == nil {
+		t.Fatalf("Expected 2 items in the trash, got %+v", items)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.HandleListTrash(rr, httptest.NewRequest("GET", "/trash/items", nil))
+	if body := rr.Body.String(); !strings.Contains(body, "Old &lt;b&gt;news&lt;/b&gt;") || !strings.Contains(body, `hx-post="/trash/providers/1/restore"`) {
+		t.Errorf("Unexpected HTML: %s", body)
+	}
+
+	if rr := call(handler.HandleRestore, "POST", "posts", "1", database.WorkspaceRoleEditor); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the post to be restored, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := call(handler.HandleRestore, "POST", "posts", "1", database.WorkspaceRoleEditor); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected restoring a live post to return 404, got %d", rr.Code)
+	}
+	if rr := call(handler.HandlePurge, "DELETE", "providers", "1", database.WorkspaceRoleOwner); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the provider to be purged, got %d %s", rr.Code, rr.Body.String())
+	}
+	if items := list(); len(items) != 0 {
+		t.Errorf("Expected an empty trash, got %+v", items)
+	}
+}
+
+func TestTrashHandler_RefusesProviderWithPendingJobs(t *testing.T) {
+	_, dbManager := newCalendarTestHandler(t)
+	handler := NewTrashHandler(dbManager, 0)
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "default_user", IsActive: true}
+	db.Create(&provider)
+	db.Create(&database.ScheduledJob{JobType: "publish_post", UserID: "default_user", ProviderID: provider.ID,
+		ScheduledAt: time.Now().Add(time.Hour), Status: database.JobStatusPending})
+
+	req := mux.SetURLVars(httptest.NewRequest("DELETE", "/providers/1", nil), map[string]string{"kind": "providers", "id": "1"})
+	rr := httptest.NewRecorder()
+	handler.HandleDelete(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/tkowalski/socgo/internal/database"
 	"github.com/tkowalski/socgo/internal/providers"
+	"github.com/tkowalski/socgo/internal/tenant"
 	"github.com/tkowalski/socgo/web/templates"
 )
 
@@ -259,6 +261,8 @@ func (h *WebHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 	content := strings.TrimSpace(r.FormValue("content"))
 	scheduleType := r.FormValue("schedule_type")
 	scheduleAt := r.FormValue("schedule_at")
+	campaignID, _ := strconv.ParseUint(r.FormValue("campaign_id"), 10, 32)
+	tags := r.FormValue("tags")
 
 	// Basic validation
 	if providerID == "" {
@@ -277,7 +281,9 @@ func (h *WebHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 	if err := json.Unmarshal([]byte(fmt.Sprintf(`{
 		"provider_id": %s,
 		"content": %q,
-		"schedule_at": %q
+		"schedule_at": %q,
+		"campaign_id": %d,
+		"tags": %q
 	}`, providerID, content, func() string {
 		if scheduleType == "scheduled" && scheduleAt != "" {
 			// Convert HTML datetime-local format to RFC3339
@@ -286,7 +292,7 @@ func (h *WebHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		return "now"
-	}())), &req); err != nil {
+	}(), campaignID, tags)), &req); err != nil {
 		h.setFlashMessage(w, "Invalid request format", "error")
 		http.Error(w, "Invalid request format", http.StatusBadRequest)
 		return
@@ -302,7 +308,9 @@ func (h *WebHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 	reqJSON := strings.NewReader(fmt.Sprintf(`{
 		"provider_id": %s,
 		"content": %q,
-		"schedule_at": %q
+		"schedule_at": %q,
+		"campaign_id": %d,
+		"tags": %q
 	}`, providerID, content, func() string {
 		if scheduleType == "scheduled" && scheduleAt != "" {
 			if t, err := time.Parse("2006-01-02T15:04", scheduleAt); err == nil {
@@ -310,7 +318,7 @@ func (h *WebHandler) HandlePost(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		return "now"
-	}()))
+	}(), campaignID, tags))
 
 	newReq := r.Clone(r.Context())
 	newReq.Body = io.NopCloser(reqJSON)
@@ -402,7 +410,7 @@ func (h *WebHandler) HandlePublishedCount(w http.ResponseWriter, r *http.Request
 	}
 
 	var count int64
-	db.Model(&database.Post{}).Where("user_id = ?", userID).Count(&count)
+	db.Model(&database.Post{}).Where("user_id = ? AND status = ?", userID, database.PostStatusPublished).Count(&count)
 	if _, err := w.Write([]byte(fmt.Sprintf("%d", count))); err != nil {
 		log.Printf("Error writing published count: %v", err)
 	}
@@ -419,7 +427,7 @@ func (h *WebHandler) HandleScheduledCount(w http.ResponseWriter, r *http.Request
 	}
 
 	var count int64
-	db.Model(&database.ScheduledJob{}).Where("user_id = ? AND status = ?", userID, "pending").Count(&count)
+	db.Model(&database.ScheduledJob{}).Where("user_id = ? AND job_type = ? AND status = ?", userID, database.JobTypePublishPost, "pending").Count(&count)
 	if _, err := w.Write([]byte(fmt.Sprintf("%d", count))); err != nil {
 		log.Printf("Error writing scheduled count: %v", err)
 	}
@@ -440,8 +448,8 @@ func (h *WebHandler) HandleMonthlyCount(w http.ResponseWriter, r *http.Request)
 	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Second)
 
 	var count int64
-	db.Model(&database.Post{}).Where("user_id = ? AND created_at >= ? AND created_at <= ?",
-		userID, startOfMonth, endOfMonth).Count(&count)
+	db.Model(&database.Post{}).Where("user_id = ? AND status = ? AND created_at >= ? AND created_at <= ?",
+		userID, database.PostStatusPublished, startOfMonth, endOfMonth).Count(&count)
 	if _, err := w.Write([]byte(fmt.Sprintf("%d", count))); err != nil {
 		log.Printf("Error writing monthly count: %v", err)
 	}
@@ -484,6 +492,5 @@ func (h *WebHandler) HandleProvidersOptions(w http.ResponseWriter, r *http.Reque
 }
 
 func (h *WebHandler) getUserID(r *http.Request) string {
-	// TODO: Implement proper user authentication
-	return "default_user"
+	return tenant.FromRequest(r).DBKey()
 }
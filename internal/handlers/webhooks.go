@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/webhooks"
+	"github.com/tkowalski/socgo/web/templates"
+	"gorm.io/gorm"
+)
+
+// WebhookRequest is used to register a webhook endpoint
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookResponse is returned once on creation and includes the signing secret
+type WebhookResponse struct {
+	database.WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+type WebhookHandler struct {
+	dbManager *database.Manager
+}
+
+// NewWebhookHandler creates a new WebhookHandler instance
+func NewWebhookHandler(dbManager *database.Manager) *WebhookHandler {
+	return &WebhookHandler{
+		dbManager: dbManager,
+	}
+}
+
+// WebhooksPage renders the webhook management page
+func (h *WebhookHandler) WebhooksPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Webhooks",
+		CurrentPage: "webhooks",
+		FlashType:   "info",
+		Content:     templates.WebhooksContent(webhooks.Events),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering webhooks page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleListWebhooks lists the registered endpoints
+func (h *WebhookHandler) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var endpoints []database.WebhookEndpoint
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&endpoints).Error; err != nil {
+		log.Printf("Error fetching webhook endpoints: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, endpoints, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	if len(endpoints) == 0 {
+		sb.WriteString(`<li class="text-gray-500">No webhook endpoints yet.</li>`)
+	}
+	for _, endpoint := range endpoints {
+		sb.WriteString(fmt.Sprintf(`
+			<li class="py-3 border-b">
+				<div class="flex justify-between items-center">
+					<span class="font-medium break-all">%s</span>
+					<span class="flex space-x-3">
+						<button hx-get="/webhooks/endpoints/%d/deliveries" hx-target="#webhook-deliveries" class="text-blue-600 text-sm">Deliveries</button>
+						<button hx-delete="/webhooks/endpoints/%d" hx-target="closest li" hx-swap="delete" hx-confirm="Remove this endpoint?" class="text-red-600 text-sm">Remove</button>
+					</span>
+				</div>
+				<div class="text-xs text-gray-500">%s</div>
+			</li>`, html.EscapeString(endpoint.URL), endpoint.ID, endpoint.ID, html.EscapeString(endpoint.Events)))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleCreateWebhook registers a new endpoint and returns its secret once
+func (h *WebhookHandler) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid form data", http.StatusBadRequest)
+			return
+		}
+		req.URL = r.FormValue("url")
+		req.Events = r.Form["events"]
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	endpoint, err := webhooks.CreateEndpoint(db, userID, req.URL, req.Events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Trigger", "webhooks-changed")
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<div class="p-4 bg-green-100 text-green-800 rounded-lg">Endpoint created. Signing secret (shown once): <code class="break-all">%s</code></div>`,
+			html.EscapeString(endpoint.Secret))
+		return
+	}
+
+	writeJSON(w, WebhookResponse{WebhookEndpoint: *endpoint, Secret: endpoint.Secret}, http.StatusCreated)
+}
+
+// HandleDeleteWebhook removes an endpoint; its pending deliveries fail
+func (h *WebhookHandler) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	h.withEndpoint(w, r, func(db *gorm.DB, endpoint *database.WebhookEndpoint) {
+		if err := db.Delete(endpoint).Error; err != nil {
+			log.Printf("Error deleting webhook endpoint %d: %v", endpoint.ID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// HandleListDeliveries shows the delivery log of an endpoint
+func (h *WebhookHandler) HandleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	h.withEndpoint(w, r, func(db *gorm.DB, endpoint *database.WebhookEndpoint) {
+		var deliveries []database.WebhookDelivery
+		if err := db.Where("endpoint_id = ?", endpoint.ID).Order("created_at DESC, id DESC").Limit(100).Find(&deliveries).Error; err != nil {
+			log.Printf("Error fetching webhook deliveries: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if r.Header.Get("Accept") == "application/json" {
+			writeJSON(w, deliveries, http.StatusOK)
+			return
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf(`<p class="text-sm text-gray-600 mb-2 break-all">%s</p><ul>`, html.EscapeString(endpoint.URL)))
+		if len(deliveries) == 0 {
+			sb.WriteString(`<li class="text-gray-500">No deliveries yet.</li>`)
+		}
+		for _, delivery := range deliveries {
+			statusClass := "bg-yellow-100 text-yellow-800"
+			switch delivery.Status {
+			case database.DeliveryStatusDelivered:
+				statusClass = "bg-green-100 text-green-800"
+			case database.DeliveryStatusFailed:
+				statusClass = "bg-red-100 text-red-800"
+			}
+			sb.WriteString(fmt.Sprintf(`
+				<li class="py-2 border-b text-sm">
+					<div class="flex justify-between">
+						<span class="font-medium">%s</span>
+						<span class="px-2 py-1 text-xs rounded %s">%s</span>
+					</div>
+					<div class="text-xs text-gray-500">%s · attempts: %d · response: %d %s</div>
+				</li>`,
+				html.EscapeString(delivery.Event), statusClass, delivery.Status,
+				delivery.CreatedAt.Format("2006-01-02 15:04:05"), delivery.Attempts, delivery.ResponseCode,
+				html.EscapeString(delivery.LastError)))
+		}
+		sb.WriteString(`</ul>`)
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, sb.String())
+	})
+}
+
+// withEndpoint loads the endpoint from the {id} route variable and runs fn
+func (h *WebhookHandler) withEndpoint(w http.ResponseWriter, r *http.Request, fn func(db *gorm.DB, endpoint *database.WebhookEndpoint)) {
+	endpointID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid endpoint ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := tenant.FromRequest(r).DBKey()
+	db, err := h.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Error getting database: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var endpoint database.WebhookEndpoint
+	if err := db.Where("user_id = ?", userID).First(&endpoint, endpointID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Webhook endpoint not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error loading webhook endpoint %d: %v", endpointID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fn(db, &endpoint)
+}
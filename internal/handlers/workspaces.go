@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/middleware"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/workspace"
+	"github.com/tkowalski/socgo/web/templates"
+)
+
+// WorkspaceRequest is used to create a workspace
+type WorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// MemberRequest is used to add a member or change their role
+type MemberRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// SwitchWorkspaceRequest selects the workspace used by the web UI; zero
+// selects the personal space
+type SwitchWorkspaceRequest struct {
+	WorkspaceID uint `json:"workspace_id"`
+}
+
+type WorkspaceHandler struct {
+	workspaces *workspace.Service
+}
+
+// NewWorkspaceHandler creates a new WorkspaceHandler instance
+func NewWorkspaceHandler(workspaces *workspace.Service) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaces: workspaces,
+	}
+}
+
+// WorkspacesPage renders the workspace management page
+func (h *WorkspaceHandler) WorkspacesPage(w http.ResponseWriter, r *http.Request) {
+	layoutData := templates.LayoutData{
+		Title:       "Workspaces",
+		CurrentPage: "workspaces",
+		FlashType:   "info",
+		Content:     templates.WorkspacesContent(),
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := templates.Layout(layoutData).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering workspaces page: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleListWorkspaces lists the workspaces the current user belongs to
+func (h *WorkspaceHandler) HandleListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	ident := tenant.FromRequest(r)
+	memberships, err := h.workspaces.ListForUser(ident.UserID)
+	if err != nil {
+		log.Printf("Error listing workspaces: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, memberships, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(renderWorkspaceRow(0, "Personal", database.WorkspaceRoleOwner, ident.WorkspaceID == 0))
+	for _, m := range memberships {
+		sb.WriteString(renderWorkspaceRow(m.WorkspaceID, m.Workspace.Name, m.Role, ident.WorkspaceID == m.WorkspaceID))
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleCreateWorkspace creates a workspace owned by the current user
+func (h *WorkspaceHandler) HandleCreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var req WorkspaceRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	ws, err := h.workspaces.Create(tenant.FromRequest(r).UserID, req.Name)
+	if err != nil {
+		log.Printf("Error creating workspace: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "workspaces-changed")
+	writeJSON(w, ws, http.StatusCreated)
+}
+
+// HandleSwitchWorkspace remembers the selected workspace in a cookie
+func (h *WorkspaceHandler) HandleSwitchWorkspace(w http.ResponseWriter, r *http.Request) {
+	var req SwitchWorkspaceRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     middleware.WorkspaceCookie,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if req.WorkspaceID == 0 {
+		cookie.MaxAge = -1
+	} else {
+		if _, err := h.workspaces.Role(req.WorkspaceID, tenant.FromRequest(r).UserID); err != nil {
+			h.writeServiceError(w, err)
+			return
+		}
+		cookie.Value = strconv.FormatUint(uint64(req.WorkspaceID), 10)
+	}
+
+	http.SetCookie(w, cookie)
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListMembers lists the members of a workspace the user belongs to
+func (h *WorkspaceHandler) HandleListMembers(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := h.authorize(w, r, database.WorkspaceRoleViewer)
+	if !ok {
+		return
+	}
+
+	members, err := h.workspaces.Members(workspaceID)
+	if err != nil {
+		log.Printf("Error listing workspace members: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		writeJSON(w, members, http.StatusOK)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`
+		<form hx-post="/workspaces/%[1]d/members" hx-swap="none" hx-on::after-request="htmx.ajax('GET', '/workspaces/%[1]d/members', '#workspace-members')" class="flex space-x-2 mb-4">
+			<input type="text" name="user_id" required placeholder="User ID" class="flex-1 border rounded px-2 py-1 text-sm">
+			<select name="role" class="border rounded px-2 py-1 text-sm">
+				<option value="viewer">Viewer</option>
+				<option value="editor">Editor</option>
+				<option value="owner">Owner</option>
+			</select>
+			<button class="bg-blue-500 hover:bg-blue-700 text-white text-sm py-1 px-3 rounded">Add</button>
+		</form>
+		<ul>`, workspaceID))
+	for _, m := range members {
+		sb.WriteString(fmt.Sprintf(`
+			<li class="flex justify-between items-center py-2 border-b">
+				<span>%s</span>
+				<span class="flex items-center space-x-2">
+					<span class="px-2 py-1 text-xs rounded bg-gray-100 text-gray-800">%s</span>
+					<button hx-delete="/workspaces/%d/members/%s" hx-target="closest li" hx-swap="delete" hx-confirm="Remove this member?" class="text-red-600 text-sm">Remove</button>
+				</span>
+			</li>`, html.EscapeString(m.UserID), m.Role, workspaceID, html.EscapeString(m.UserID)))
+	}
+	sb.WriteString(`</ul>`)
+
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, sb.String())
+}
+
+// HandleSetMember adds a member to a workspace or changes their role
+func (h *WorkspaceHandler) HandleSetMember(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := h.authorize(w, r, database.WorkspaceRoleOwner)
+	if !ok {
+		return
+	}
+
+	var req MemberRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if userID := mux.Vars(r)["user"]; userID != "" {
+		req.UserID = userID
+	}
+
+	member, err := h.workspaces.SetMember(workspaceID, req.UserID, req.Role)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, member, http.StatusOK)
+}
+
+// HandleRemoveMember removes a member from a workspace
+func (h *WorkspaceHandler) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := h.authorize(w, r, database.WorkspaceRoleOwner)
+	if !ok {
+		return
+	}
+
+	if err := h.workspaces.RemoveMember(workspaceID, mux.Vars(r)["user"]); err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+
+	// 200 rather than 204 so htmx removes the member row
+	w.WriteHeader(http.StatusOK)
+}
+
+// authorize parses the {id} route variable and checks that the current user
+// has at least role in that workspace
+func (h *WorkspaceHandler) authorize(w http.ResponseWriter, r *http.Request, role string) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	ident := tenant.FromRequest(r)
+	ident.WorkspaceID = uint(id)
+	ident.Role, err = h.workspaces.Role(ident.WorkspaceID, ident.UserID)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return 0, false
+	}
+	if !ident.Can(role) {
+		http.Error(w, fmt.Sprintf("This action requires the %s role", role), http.StatusForbidden)
+		return 0, false
+	}
+
+	return ident.WorkspaceID, true
+}
+
+func (h *WorkspaceHandler) writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, workspace.ErrNotMember):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, workspace.ErrInvalidRole), errors.Is(err, workspace.ErrLastOwner):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Workspace error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func renderWorkspaceRow(id uint, name, role string, current bool) string {
+	action := fmt.Sprintf(`<button hx-post="/workspaces/switch" hx-vals='{"workspace_id": "%d"}' hx-swap="none" class="bg-blue-500 hover:bg-blue-700 text-white text-sm py-1 px-3 rounded">Switch</button>`, id)
+	if current {
+		action = `<span class="text-sm text-green-700 font-semibold">Current</span>`
+	}
+
+	members := ""
+	if id != 0 {
+		members = fmt.Sprintf(`<button hx-get="/workspaces/%d/members" hx-target="#workspace-members" class="text-blue-600 text-sm">Members</button>`, id)
+	}
+
+	return fmt.Sprintf(`
+		<li class="flex justify-between items-center py-2 border-b">
+			<span><span class="font-medium">%s</span> <span class="text-xs text-gray-500">%s</span></span>
+			<span class="flex items-center space-x-3">%s %s</span>
+		</li>`, html.EscapeString(name), role, members, action)
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -0,0 +1,436 @@
+// Package ical reads and writes the subset of iCalendar (RFC 5545) that
+// socgo needs: VEVENTs with a start, an end or duration, a summary and
+// simple recurrence rules.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid wraps errors reading a calendar
+var ErrInvalid = errors.New("invalid calendar")
+
+// MaxOccurrences limits how many occurrences a recurring event expands to
+const MaxOccurrences = 500
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	utcLayout      = "20060102T150405Z"
+	// lineLength is the longest content line, in octets, before folding
+	lineLength = 75
+)
+
+// Event is a calendar event. AllDay events start and end at midnight UTC of
+// their dates; End is exclusive.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Status      string
+	Categories  []string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+// Write encodes events as a calendar named name. stamp is the DTSTAMP of
+// every event.
+func Write(w io.Writer, name string, events []Event, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//socgo//calendar//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escape(name))
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(e.UID))
+		line("DTSTAMP", stamp.UTC().Format(utcLayout))
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE", e.Start.Format(dateLayout))
+			line("DTEND;VALUE=DATE", e.End.Format(dateLayout))
+		} else {
+			line("DTSTART", e.Start.UTC().Format(utcLayout))
+			line("DTEND", e.End.UTC().Format(utcLayout))
+		}
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escape(e.Location))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		if e.Status != "" {
+			line("STATUS", e.Status)
+		}
+		if len(e.Categories) > 0 {
+			categories := make([]string, len(e.Categories))
+			for i, c := range e.Categories {
+				categories[i] = escape(c)
+			}
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeLine writes a content line folded at lineLength octets without
+// splitting UTF-8 sequences
+func writeLine(w *bufio.Writer, s string) {
+	limit := lineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space
+		limit = lineLength - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+// property is a parsed content line
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the events of a calendar. Recurring events are expanded into
+// their occurrences, at most MaxOccurrences each and none after until.
+// Cancelled events are skipped.
+func Parse(r io.Reader, until time.Time) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	var events []Event
+	var current []property
+	inCalendar, inEvent := false, false
+	depth := 0
+	for _, l := range lines {
+		p, ok := parseLine(l)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCALENDAR"):
+			inCalendar = true
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT") && inCalendar && !inEvent:
+			inEvent, current = true, nil
+		case p.name == "BEGIN" && inEvent:
+			// Nested components such as VALARM
+			depth++
+		case p.name == "END" && inEvent && depth > 0:
+			depth--
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT") && inEvent:
+			inEvent = false
+			occurrences, err := newEvents(current, until)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, occurrences...)
+		case inEvent && depth == 0:
+			current = append(current, p)
+		}
+	}
+	if !inCalendar {
+		return nil, fmt.Errorf("%w: no VCALENDAR found", ErrInvalid)
+	}
+	return events, nil
+}
+
+// unfold joins folded content lines
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		l := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if l != "" {
+			lines = append(lines, strings.TrimPrefix(l, "\ufeff"))
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits NAME;PARAM=VALUE:value, honouring quoted parameters
+func parseLine(l string) (property, bool) {
+	colon, quoted := -1, false
+	for i, c := range l {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, false
+	}
+	head := strings.Split(l[:colon], ";")
+	p := property{name: strings.ToUpper(head[0]), params: map[string]string{}, value: l[colon+1:]}
+	for _, param := range head[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return p, true
+}
+
+// newEvents builds the occurrences of the VEVENT with properties props
+func newEvents(props []property, until time.Time) ([]Event, error) {
+	var e Event
+	var end *time.Time
+	var duration time.Duration
+	var rule string
+	hasStart := false
+	for _, p := range props {
+		switch p.name {
+		case "UID":
+			e.UID = unescape(p.value)
+		case "SUMMARY":
+			e.Summary = unescape(p.value)
+		case "DESCRIPTION":
+			e.Description = unescape(p.value)
+		case "LOCATION":
+			e.Location = unescape(p.value)
+		case "URL":
+			e.URL = p.value
+		case "STATUS":
+			e.Status = strings.ToUpper(p.value)
+		case "CATEGORIES":
+			for _, c := range strings.Split(p.value, ",") {
+				if c = strings.TrimSpace(unescape(c)); c != "" {
+					e.Categories = append(e.Categories, c)
+				}
+			}
+		case "DTSTART":
+			at, allDay, err := parseTime(p)
+			if err != nil {
+				return nil, err
+			}
+			e.Start, e.AllDay, hasStart = at, allDay, true
+		case "DTEND":
+			at, _, err := parseTime(p)
+			if err != nil {
+				return nil, err
+			}
+			end = &at
+		case "DURATION":
+			d, err := parseDuration(p.value)
+			if err != nil {
+				return nil, err
+			}
+			duration = d
+		case "RRULE":
+			rule = p.value
+		}
+	}
+	if !hasStart {
+		return nil, fmt.Errorf("%w: event %q has no DTSTART", ErrInvalid, e.UID)
+	}
+	if e.Status == "CANCELLED" {
+		return nil, nil
+	}
+
+	switch {
+	case end != nil:
+		duration = end.Sub(e.Start)
+	case duration == 0 && e.AllDay:
+		duration = 24 * time.Hour
+	}
+	if duration < 0 {
+		duration = 0
+	}
+	e.End = e.Start.Add(duration)
+	if e.UID == "" {
+		e.UID = e.Start.Format(utcLayout) + "-" + e.Summary
+	}
+	if rule == "" {
+		return []Event{e}, nil
+	}
+	return expand(e, rule, duration, until)
+}
+
+// parseTime reads a DATE or DATE-TIME value. Times with a TZID are read in
+// that zone and floating times in UTC.
+func parseTime(p property) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+	if p.params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		at, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: invalid date %q", ErrInvalid, value)
+		}
+		return at, true, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		at, err := time.Parse(utcLayout, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: invalid time %q", ErrInvalid, value)
+		}
+		return at, false, nil
+	}
+	location := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			location = loc
+		}
+	}
+	at, err := time.ParseInLocation(dateTimeLayout, value, location)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: invalid time %q", ErrInvalid, value)
+	}
+	return at.UTC(), false, nil
+}
+
+// parseDuration reads a DURATION value such as P1D or PT1H30M
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if s == value || s == "" {
+		return 0, fmt.Errorf("%w: invalid duration %q", ErrInvalid, value)
+	}
+	var d time.Duration
+	inTime := false
+	number := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T':
+			inTime = true
+		default:
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("%w: invalid duration %q", ErrInvalid, value)
+			}
+			number = ""
+			switch {
+			case c == 'W':
+				d += time.Duration(n) * 7 * 24 * time.Hour
+			case c == 'D':
+				d += time.Duration(n) * 24 * time.Hour
+			case c == 'H' && inTime:
+				d += time.Duration(n) * time.Hour
+			case c == 'M' && inTime:
+				d += time.Duration(n) * time.Minute
+			case c == 'S' && inTime:
+				d += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("%w: invalid duration %q", ErrInvalid, value)
+			}
+		}
+	}
+	return d, nil
+}
+
+// expand returns the occurrences of e following rule, which supports FREQ,
+// INTERVAL, COUNT and UNTIL. Occurrences get the UID of e with their start
+// date appended.
+func expand(e Event, rule string, duration time.Duration, until time.Time) ([]Event, error) {
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			parts[strings.ToUpper(key)] = value
+		}
+	}
+
+	step := func(t time.Time, n int) time.Time {
+		switch parts["FREQ"] {
+		case "DAILY":
+			return t.AddDate(0, 0, n)
+		case "WEEKLY":
+			return t.AddDate(0, 0, 7*n)
+		case "MONTHLY":
+			return t.AddDate(0, n, 0)
+		default:
+			return t.AddDate(n, 0, 0)
+		}
+	}
+	switch parts["FREQ"] {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		// Unsupported rules keep the first occurrence
+		return []Event{e}, nil
+	}
+
+	interval := 1
+	if value, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: invalid INTERVAL %q", ErrInvalid, value)
+		}
+		interval = n
+	}
+	count := MaxOccurrences
+	if value, ok := parts["COUNT"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: invalid COUNT %q", ErrInvalid, value)
+		}
+		count = min(n, MaxOccurrences)
+	}
+	if value, ok := parts["UNTIL"]; ok {
+		last, _, err := parseTime(property{value: value, params: map[string]string{}})
+		if err != nil {
+			return nil, err
+		}
+		if last.Before(until) {
+			until = last
+		}
+	}
+
+	var events []Event
+	for i := 0; len(events) < count; i++ {
+		start := step(e.Start, i*interval)
+		if start.After(until) {
+			break
+		}
+		occurrence := e
+		occurrence.UID = e.UID + "/" + start.Format(dateLayout)
+		occurrence.Start = start
+		occurrence.End = start.Add(duration)
+		events = append(events, occurrence)
+	}
+	return events, nil
+}
@@ -0,0 +1,98 @@
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite_FoldsAndEscapes(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	events := []Event{{
+		UID:         "job-1@socgo",
+		Summary:     "Launch; day, one",
+		Description: strings.Repeat("ż", 60) + "\nsecond line",
+		Categories:  []string{"launch", "promo"},
+		Status:      "TENTATIVE",
+		Start:       start,
+		End:         start.Add(15 * time.Minute),
+	}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "SocGo posts", events, start); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"BEGIN:VCALENDAR\r\n", "DTSTART:20260302T093000Z\r\n", `SUMMARY:Launch\; day\, one`, "CATEGORIES:launch,promo\r\n", "END:VCALENDAR\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > lineLength {
+			t.Errorf("Line longer than %d octets: %q", lineLength, line)
+		}
+	}
+
+	parsed, err := Parse(strings.NewReader(out), start.AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(parsed) != 1 || parsed[0].Summary != events[0].Summary || parsed[0].Description != events[0].Description || !parsed[0].End.Equal(events[0].End) {
+		t.Errorf("Round trip changed the event: %+v", parsed)
+	}
+}
+
+func TestParse_DatesDurationsAndRecurrence(t *testing.T) {
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:new-year",
+		"SUMMARY:New Year",
+		"DTSTART;VALUE=DATE:20260101",
+		"RRULE:FREQ=YEARLY;COUNT=3",
+		"BEGIN:VALARM",
+		"SUMMARY:Not the event",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:meeting",
+		"SUMMARY:Launch",
+		" meeting",
+		"DTSTART;TZID=Europe/Warsaw:20260615T100000",
+		"DURATION:PT1H30M",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:cancelled",
+		"DTSTART:20260701T100000Z",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := Parse(strings.NewReader(calendar), time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	// The third New Year falls after until
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %+v", events)
+	}
+	if !events[0].AllDay || events[0].Summary != "New Year" || events[1].UID != "new-year/20270101" || events[1].End.Sub(events[1].Start) != 24*time.Hour {
+		t.Errorf("Unexpected recurring events: %+v", events[:2])
+	}
+	meeting := events[2]
+	if meeting.Summary != "Launchmeeting" || !meeting.Start.Equal(time.Date(2026, 6, 15, 8, 0, 0, 0, time.UTC)) || meeting.End.Sub(meeting.Start) != 90*time.Minute {
+		t.Errorf("Unexpected meeting: %+v", meeting)
+	}
+
+	if _, err := Parse(strings.NewReader("BEGIN:VEVENT\r\nEND:VEVENT"), time.Now()); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid without VCALENDAR, got %v", err)
+	}
+	if _, err := Parse(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\nEND:VEVENT\r\nEND:VCALENDAR"), time.Now()); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for an event without DTSTART, got %v", err)
+	}
+}
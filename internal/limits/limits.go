@@ -0,0 +1,328 @@
+// Package limits keeps providers from being flooded: a daily cap of posts
+// and a minimum gap between two posts per connected provider.
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when the provider has no posting limit
+	ErrNotFound = errors.New("posting limit not found")
+	// ErrInvalid wraps validation errors of an Input
+	ErrInvalid = errors.New("invalid posting limit")
+	// ErrConflict wraps the reason a post would break a posting limit
+	ErrConflict = errors.New("posting limit exceeded")
+)
+
+// Input holds the editable fields of a posting limit
+type Input struct {
+	DailyCap      int    `json:"daily_cap"`
+	MinGapMinutes int    `json:"min_gap_minutes"`
+	Timezone      string `json:"timezone"`
+}
+
+// Decision is what to do with a job falling due
+type Decision struct {
+	// Blocked is set when publishing now would break the limit; the job is
+	// then moved to Until
+	Blocked bool
+	Until   time.Time
+	Reason  string
+}
+
+// Rules are the posting limits of a user or workspace, loaded once per
+// scheduler run
+type Rules struct {
+	limits map[uint]database.PostingLimit
+}
+
+// slot is a post published, or a job due to publish, at At. JobID is zero
+// for posts.
+type slot struct {
+	JobID uint
+	At    time.Time
+}
+
+// List returns the posting limits of userID
+func List(db *gorm.DB, userID string) ([]database.PostingLimit, error) {
+	var limits []database.PostingLimit
+	err := db.Where("user_id = ?", userID).Order("provider_id").Find(&limits).Error
+	return limits, err
+}
+
+// Get returns the posting limit of the provider providerID of userID
+func Get(db *gorm.DB, userID string, providerID uint) (*database.PostingLimit, error) {
+	var limit database.PostingLimit
+	result := db.Where("user_id = ? AND provider_id = ?", userID, providerID).Limit(1).Find(&limit)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &limit, nil
+}
+
+// Set creates or replaces the posting limit of the provider providerID.
+// Jobs already scheduled are not moved; the scheduler defers them when they
+// fall due and the calendar shows them as conflicts.
+func Set(ctx context.Context, db *gorm.DB, userID string, providerID uint, in Input) (*database.PostingLimit, error) {
+	var provider database.Provider
+	result := db.Where("user_id = ? AND id = ?", userID, providerID).Limit(1).Find(&provider)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: provider not found", ErrInvalid)
+	}
+	if in.DailyCap < 0 || in.MinGapMinutes < 0 {
+		return nil, fmt.Errorf("%w: daily_cap and min_gap_minutes must not be negative", ErrInvalid)
+	}
+	if in.DailyCap == 0 && in.MinGapMinutes == 0 {
+		return nil, fmt.Errorf("%w: set daily_cap or min_gap_minutes", ErrInvalid)
+	}
+	timezone := strings.TrimSpace(in.Timezone)
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %s", ErrInvalid, timezone)
+	}
+
+	limit, err := Get(db, userID, providerID)
+	if errors.Is(err, ErrNotFound) {
+		limit, err = &database.PostingLimit{ProviderID: providerID, UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	before := *limit
+	limit.DailyCap = in.DailyCap
+	limit.MinGapMinutes = in.MinGapMinutes
+	limit.Timezone = timezone
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(limit).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionLimitUpdated, TargetType: audit.TargetLimit, TargetID: limit.ID, Before: before, After: limit,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return limit, nil
+}
+
+// Delete removes the posting limit of the provider providerID
+func Delete(ctx context.Context, db *gorm.DB, userID string, providerID uint) error {
+	limit, err := Get(db, userID, providerID)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(limit).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionLimitDeleted, TargetType: audit.TargetLimit, TargetID: limit.ID, Before: limit,
+		})
+	})
+}
+
+// Validate checks that a post to providerID at at keeps the posting limit,
+// counting published posts and the jobs not yet run. excludeJobID is the job
+// being rescheduled, if any. The error wraps ErrConflict when the limit
+// would be broken.
+func Validate(db *gorm.DB, userID string, providerID uint, at time.Time, excludeJobID uint) error {
+	limit, err := Get(db, userID, providerID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dayStart, dayEnd := day(*limit, at)
+	gap := time.Duration(limit.MinGapMinutes) * time.Minute
+	slots, err := loadSlots(db, userID, providerID, earliest(dayStart, at.Add(-gap)), latest(dayEnd, at.Add(gap)), true)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, s := range slots {
+		if s.JobID != 0 && s.JobID == excludeJobID {
+			continue
+		}
+		if !s.At.Before(dayStart) && s.At.Before(dayEnd) {
+			count++
+		}
+		if gap > 0 && s.At.After(at.Add(-gap)) && s.At.Before(at.Add(gap)) {
+			return fmt.Errorf("%w: another post is within %d minutes", ErrConflict, limit.MinGapMinutes)
+		}
+	}
+	if limit.DailyCap > 0 && count >= limit.DailyCap {
+		return fmt.Errorf("%w: the daily cap of %d posts on %s is reached", ErrConflict, limit.DailyCap, dayStart.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// Load returns the posting limits of userID
+func Load(db *gorm.DB, userID string) (*Rules, error) {
+	limits, err := List(db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load posting limits: %w", err)
+	}
+	rules := &Rules{limits: make(map[uint]database.PostingLimit, len(limits))}
+	for _, limit := range limits {
+		rules.limits[limit.ProviderID] = limit
+	}
+	return rules, nil
+}
+
+// Check decides whether a job publishing to providerID may run at now. Only
+// published posts count: a job over the daily cap is moved to the next day,
+// one too close to the last post to when the gap has passed.
+func (r *Rules) Check(db *gorm.DB, userID string, providerID uint, now time.Time) (Decision, error) {
+	limit, ok := r.limits[providerID]
+	if !ok {
+		return Decision{}, nil
+	}
+
+	dayStart, dayEnd := day(limit, now)
+	gap := time.Duration(limit.MinGapMinutes) * time.Minute
+	slots, err := loadSlots(db, userID, providerID, earliest(dayStart, now.Add(-gap)), dayEnd, false)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	var decision Decision
+	count := 0
+	for _, s := range slots {
+		if !s.At.Before(dayStart) {
+			count++
+		}
+		if gap > 0 && s.At.Add(gap).After(now) && s.At.Add(gap).After(decision.Until) {
+			decision = Decision{Blocked: true, Until: s.At.Add(gap),
+				Reason: fmt.Sprintf("minimum gap of %d minutes", limit.MinGapMinutes)}
+		}
+	}
+	if limit.DailyCap > 0 && count >= limit.DailyCap {
+		decision = Decision{Blocked: true, Until: latest(dayEnd, decision.Until),
+			Reason: fmt.Sprintf("daily cap of %d posts", limit.DailyCap)}
+	}
+	return decision, nil
+}
+
+// Conflicts returns the jobs of userID due in [from, to) that break a posting
+// limit, with the reason. Posts count in time order, so of two jobs too
+// close together the later one conflicts, as do the jobs of a day past its
+// cap.
+func Conflicts(db *gorm.DB, userID string, from, to time.Time) (map[uint]string, error) {
+	limits, err := List(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := make(map[uint]string)
+	for _, limit := range limits {
+		gap := time.Duration(limit.MinGapMinutes) * time.Minute
+		// Days and gaps reach across the edges of the range
+		slots, err := loadSlots(db, userID, limit.ProviderID, from.Add(-24*time.Hour-gap), to.Add(24*time.Hour), true)
+		if err != nil {
+			return nil, err
+		}
+
+		perDay := make(map[time.Time]int)
+		for i, s := range slots {
+			dayStart, _ := day(limit, s.At)
+			perDay[dayStart]++
+			if s.JobID == 0 || s.At.Before(from) || !s.At.Before(to) {
+				continue
+			}
+			switch {
+			case limit.DailyCap > 0 && perDay[dayStart] > limit.DailyCap:
+				conflicts[s.JobID] = fmt.Sprintf("over the daily cap of %d posts", limit.DailyCap)
+			case gap > 0 && i > 0 && s.At.Sub(slots[i-1].At) < gap:
+				conflicts[s.JobID] = fmt.Sprintf("less than %d minutes after another post", limit.MinGapMinutes)
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// loadSlots returns the posts published to providerID in [from, to) and,
+// with jobs, the publish jobs not yet run, in time order
+func loadSlots(db *gorm.DB, userID string, providerID uint, from, to time.Time, jobs bool) ([]slot, error) {
+	var posts []database.Post
+	if err := db.Select("id", "published_at").
+		Where("user_id = ? AND provider_id = ? AND status = ? AND published_at >= ? AND published_at < ?",
+			userID, providerID, database.PostStatusPublished, from, to).
+		Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+	}
+	slots := make([]slot, 0, len(posts))
+	for _, post := range posts {
+		slots = append(slots, slot{At: *post.PublishedAt})
+	}
+
+	if jobs {
+		var pending []database.ScheduledJob
+		if err := db.Select("id", "scheduled_at").
+			Where("user_id = ? AND provider_id = ? AND job_type = ? AND status IN ? AND scheduled_at >= ? AND scheduled_at < ?",
+				userID, providerID, database.JobTypePublishPost,
+				[]string{database.JobStatusPending, database.JobStatusExecuting}, from, to).
+			Find(&pending).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch scheduled jobs: %w", err)
+		}
+		for _, job := range pending {
+			slots = append(slots, slot{JobID: job.ID, At: job.ScheduledAt})
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if !slots[i].At.Equal(slots[j].At) {
+			return slots[i].At.Before(slots[j].At)
+		}
+		return slots[i].JobID < slots[j].JobID
+	})
+	return slots, nil
+}
+
+// day returns the start and end of the day of at in the timezone of limit
+func day(limit database.PostingLimit, at time.Time) (time.Time, time.Time) {
+	loc, err := time.LoadLocation(limit.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := at.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
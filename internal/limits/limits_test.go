@@ -0,0 +1,116 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestSet_Validates(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "alice"}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, in := range []Input{
+		{},
+		{DailyCap: -1},
+		{DailyCap: 3, MinGapMinutes: -5},
+		{DailyCap: 3, Timezone: "Mars/Olympus"},
+	} {
+		if _, err := Set(ctx, db, "alice", provider.ID, in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for %+v, got %v", in, err)
+		}
+	}
+	if _, err := Set(ctx, db, "alice", 42, Input{DailyCap: 3}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for an unknown provider, got %v", err)
+	}
+
+	limit, err := Set(ctx, db, "alice", provider.ID, Input{DailyCap: 3})
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	again, err := Set(ctx, db, "alice", provider.ID, Input{MinGapMinutes: 30, Timezone: "Europe/Warsaw"})
+	if err != nil || again.ID != limit.ID || again.DailyCap != 0 || again.MinGapMinutes != 30 {
+		t.Errorf("Expected setting twice to replace the limit, got %+v, %v", again, err)
+	}
+	if limit.Timezone != "UTC" {
+		t.Errorf("Expected UTC by default, got %q", limit.Timezone)
+	}
+
+	if err := Delete(ctx, db, "alice", provider.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Get(db, "alice", provider.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestValidateAndConflicts(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	facebook := database.Provider{Name: "facebook", Type: "facebook", UserID: "alice"}
+	tiktok := database.Provider{Name: "tiktok", Type: "tiktok", UserID: "alice"}
+	db.Create(&facebook)
+	db.Create(&tiktok)
+	if _, err := Set(ctx, db, "alice", facebook.ID, Input{DailyCap: 3, MinGapMinutes: 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2030, time.March, 14, 0, 0, 0, 0, time.UTC)
+	publishedAt := day.Add(8 * time.Hour)
+	if err := db.Create(&database.Post{Content: "Morning", UserID: "alice", ProviderID: facebook.ID,
+		Status: database.PostStatusPublished, PublishedAt: &publishedAt}).Error; err != nil {
+		t.Fatal(err)
+	}
+	jobs := []database.ScheduledJob{
+		{ScheduledAt: day.Add(8*time.Hour + 30*time.Minute)}, // too close to the post
+		{ScheduledAt: day.Add(12 * time.Hour)},               // third of the day
+		{ScheduledAt: day.Add(18 * time.Hour)},               // over the cap
+		{ScheduledAt: day.Add(32 * time.Hour)},               // the next day
+	}
+	for i := range jobs {
+		jobs[i].JobType = database.JobTypePublishPost
+		jobs[i].UserID = "alice"
+		jobs[i].ProviderID = facebook.ID
+		jobs[i].Status = database.JobStatusPending
+	}
+	if err := db.Create(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	conflicts, err := Conflicts(db, "alice", day, day.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Conflicts failed: %v", err)
+	}
+	if len(conflicts) != 2 || conflicts[jobs[0].ID] != "less than 60 minutes after another post" ||
+		conflicts[jobs[2].ID] != "over the daily cap of 3 posts" {
+		t.Errorf("Unexpected conflicts: %v", conflicts)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		providerID uint
+		at         time.Time
+		exclude    uint
+		conflict   bool
+	}{
+		{"within the gap", facebook.ID, day.Add(32*time.Hour + 30*time.Minute), 0, true},
+		{"over the cap", facebook.ID, day.Add(22 * time.Hour), 0, true},
+		{"free day", facebook.ID, day.Add(52 * time.Hour), 0, false},
+		{"moving a job by less than the gap", facebook.ID, day.Add(32*time.Hour + 30*time.Minute), jobs[3].ID, false},
+		{"provider without a limit", tiktok.ID, day.Add(8 * time.Hour), 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(db, "alice", tc.providerID, tc.at, tc.exclude)
+			if tc.conflict != errors.Is(err, ErrConflict) || (!tc.conflict && err != nil) {
+				t.Errorf("Validate = %v, want conflict %v", err, tc.conflict)
+			}
+		})
+	}
+}
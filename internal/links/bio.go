@@ -0,0 +1,442 @@
+package links
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// BioPathPrefix is the path link-in-bio pages are served under
+const BioPathPrefix = "/bio/"
+
+const (
+	// defaultBioPosts is the number of recent posts of a new page
+	defaultBioPosts = 6
+	// maxBioPosts caps the number of recent posts of a page
+	maxBioPosts = 20
+	// bioTitleLength is the number of characters of post titles on a page
+	bioTitleLength = 80
+)
+
+var (
+	// ErrBioNotFound is returned when the link-in-bio page does not exist
+	ErrBioNotFound = errors.New("link-in-bio page not found")
+	// ErrBioLinkNotFound is returned when the pinned link does not exist
+	ErrBioLinkNotFound = errors.New("pinned link not found")
+	// ErrInvalidBio wraps validation errors of a BioInput or BioLinkInput
+	ErrInvalidBio = errors.New("invalid link-in-bio page")
+	// ErrBioSlugTaken is returned when another user or workspace serves a
+	// page under the slug
+	ErrBioSlugTaken = errors.New("slug is taken")
+)
+
+var (
+	slugPattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,63}$`)
+	colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// BioInput holds the editable fields of a link-in-bio page. A nil PostCount
+// or Enabled keeps the current value.
+type BioInput struct {
+	Slug        string `json:"slug"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       string `json:"color"`
+	PostCount   *int   `json:"post_count"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// BioLinkInput holds the editable fields of a pinned link
+type BioLinkInput struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+}
+
+// BioEntry is a link shown on a link-in-bio page. URL is the path of the
+// short link counting its clicks.
+type BioEntry struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	URL      string `json:"url"`
+	Pinned   bool   `json:"pinned"`
+}
+
+// GetBioPage returns the link-in-bio page of userID
+func GetBioPage(db *gorm.DB, userID string) (*database.BioPage, error) {
+	var page database.BioPage
+	result := db.Where("user_id = ?", userID).Limit(1).Find(&page)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBioNotFound
+	}
+	return &page, nil
+}
+
+// FindBioPage returns the enabled link-in-bio page served as slug
+func FindBioPage(db *gorm.DB, slug string) (*database.BioPage, error) {
+	var page database.BioPage
+	result := db.Where("slug = ? AND enabled = ?", slug, true).Limit(1).Find(&page)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBioNotFound
+	}
+	return &page, nil
+}
+
+// SaveBioPage creates or updates the link-in-bio page of userID. Slugs are
+// unique across databases, which only the control plane in registry sees.
+// The recent posts shown on the page get their short links here, so that
+// serving the page never writes.
+func SaveBioPage(ctx context.Context, db, registry *gorm.DB, userID string, in BioInput) (*database.BioPage, error) {
+	page, err := GetBioPage(db, userID)
+	if errors.Is(err, ErrBioNotFound) {
+		page = &database.BioPage{UserID: userID, Color: "#2563eb", PostCount: defaultBioPosts, Enabled: true}
+	} else if err != nil {
+		return nil, err
+	}
+	before := *page
+
+	slug := strings.ToLower(strings.TrimSpace(in.Slug))
+	if !slugPattern.MatchString(slug) {
+		return nil, fmt.Errorf("%w: slug must be 2-64 lowercase letters, digits or dashes", ErrInvalidBio)
+	}
+	title := strings.TrimSpace(in.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidBio)
+	}
+	if in.Color != "" && !colorPattern.MatchString(in.Color) {
+		return nil, fmt.Errorf("%w: color must look like #2563eb", ErrInvalidBio)
+	}
+	if in.PostCount != nil && (*in.PostCount < 0 || *in.PostCount > maxBioPosts) {
+		return nil, fmt.Errorf("%w: post_count must be between 0 and %d", ErrInvalidBio, maxBioPosts)
+	}
+
+	var routed int64
+	if err := registry.Model(&database.BioRoute{}).Where("slug = ? AND tenant_key <> ?", slug, userID).Count(&routed).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up slug: %w", err)
+	}
+	if routed > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBioSlugTaken, slug)
+	}
+
+	page.Slug = slug
+	page.Title = title
+	page.Description = strings.TrimSpace(in.Description)
+	if in.Color != "" {
+		page.Color = strings.ToLower(in.Color)
+	}
+	if in.PostCount != nil {
+		page.PostCount = *in.PostCount
+	}
+	if in.Enabled != nil {
+		page.Enabled = *in.Enabled
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&database.BioPage{}).Where("slug = ? AND user_id <> ?", slug, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %s", ErrBioSlugTaken, slug)
+		}
+		if err := tx.Save(page).Error; err != nil {
+			return err
+		}
+		if err := linkRecentPosts(tx, page); err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionBioPageUpdated, TargetType: audit.TargetBioPage, TargetID: page.ID, Before: before, After: page,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ListBioLinks returns the pinned links of userID in page order
+func ListBioLinks(db *gorm.DB, userID string) ([]database.BioLink, error) {
+	var pinned []database.BioLink
+	err := db.Where("user_id = ?", userID).Order("position, id").Find(&pinned).Error
+	return pinned, err
+}
+
+// GetBioLink returns the pinned link id of userID
+func GetBioLink(db *gorm.DB, userID string, id uint) (*database.BioLink, error) {
+	var pinned database.BioLink
+	result := db.Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&pinned)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBioLinkNotFound
+	}
+	return &pinned, nil
+}
+
+// CreateBioLink pins a link to the link-in-bio page of userID, with a short
+// link counting its clicks
+func CreateBioLink(ctx context.Context, db *gorm.DB, userID string, in BioLinkInput) (*database.BioLink, error) {
+	pinned := &database.BioLink{UserID: userID}
+	if err := applyBioLink(pinned, in); err != nil {
+		return nil, err
+	}
+	code, err := newCode()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		short := database.ShortLink{Code: code, URL: pinned.URL, UserID: userID}
+		if err := tx.Create(&short).Error; err != nil {
+			return err
+		}
+		pinned.ShortLinkID = short.ID
+		if err := tx.Create(pinned).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionBioLinkCreated, TargetType: audit.TargetBioLink, TargetID: pinned.ID, After: pinned,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pinned, nil
+}
+
+// UpdateBioLink replaces the fields of the pinned link id with in. Its short
+// link keeps its code and clicks and redirects to the new URL.
+func UpdateBioLink(ctx context.Context, db *gorm.DB, userID string, id uint, in BioLinkInput) (*database.BioLink, error) {
+	pinned, err := GetBioLink(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *pinned
+	if err := applyBioLink(pinned, in); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(pinned).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&database.ShortLink{}).Where("id = ?", pinned.ShortLinkID).Update("url", pinned.URL).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionBioLinkUpdated, TargetType: audit.TargetBioLink, TargetID: pinned.ID, Before: before, After: pinned,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pinned, nil
+}
+
+// DeleteBioLink unpins the link id and deletes its short link
+func DeleteBioLink(ctx context.Context, db *gorm.DB, userID string, id uint) error {
+	pinned, err := GetBioLink(db, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(pinned).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", pinned.ShortLinkID).Delete(&database.ShortLink{}).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionBioLinkDeleted, TargetType: audit.TargetBioLink, TargetID: id, Before: pinned,
+		})
+	})
+}
+
+// BioEntries returns the links shown on page: the pinned links, then the
+// first short link of each of the latest page.PostCount published posts with
+// one. It only reads, as it serves the public page.
+func BioEntries(db *gorm.DB, page *database.BioPage) ([]BioEntry, error) {
+	entries := []BioEntry{}
+
+	var pinned []struct {
+		Title string
+		Code  string
+	}
+	err := db.Table("bio_links").
+		Select("bio_links.title, short_links.code").
+		Joins("JOIN short_links ON short_links.id = bio_links.short_link_id").
+		Where("bio_links.user_id = ?", page.UserID).
+		Order("bio_links.position, bio_links.id").
+		Scan(&pinned).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pinned links: %w", err)
+	}
+	for _, link := range pinned {
+		entries = append(entries, BioEntry{Title: link.Title, URL: PathPrefix + link.Code, Pinned: true})
+	}
+
+	posts, err := recentPosts(db.Preload("Provider"), page)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		code, err := postCode(db, &post)
+		if err != nil {
+			return nil, err
+		}
+		if code == "" {
+			continue
+		}
+
+		published := post.CreatedAt
+		if post.PublishedAt != nil {
+			published = *post.PublishedAt
+		}
+		subtitle := published.Format("Jan 2, 2006")
+		if post.Provider.Name != "" {
+			subtitle = post.Provider.Name + " · " + subtitle
+		}
+		entries = append(entries, BioEntry{Title: postTitle(&post), Subtitle: subtitle, URL: PathPrefix + code})
+	}
+	return entries, nil
+}
+
+// LinkPost gives the published post a short link for the first link of its
+// content when it shows on the enabled link-in-bio page of its owner and was
+// published without one
+func LinkPost(db *gorm.DB, post *database.Post) error {
+	page, err := GetBioPage(db, post.UserID)
+	if errors.Is(err, ErrBioNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !page.Enabled || page.PostCount == 0 {
+		return nil
+	}
+	return linkPost(db, post)
+}
+
+// linkRecentPosts gives the posts shown on the enabled page a short link each
+func linkRecentPosts(db *gorm.DB, page *database.BioPage) error {
+	if !page.Enabled {
+		return nil
+	}
+	posts, err := recentPosts(db, page)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		if err := linkPost(db, &posts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recentPosts returns the latest page.PostCount published posts of the owner
+// of page that contain a link
+func recentPosts(db *gorm.DB, page *database.BioPage) ([]database.Post, error) {
+	if page.PostCount == 0 {
+		return nil, nil
+	}
+	var posts []database.Post
+	err := db.Where("user_id = ? AND status = ? AND content LIKE ?", page.UserID, database.PostStatusPublished, "%http%").
+		Order("COALESCE(published_at, created_at) DESC, id DESC").
+		Limit(page.PostCount).
+		Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load published posts: %w", err)
+	}
+	return posts, nil
+}
+
+// postCode returns the code of the first short link of post, or "" when it
+// has none
+func postCode(db *gorm.DB, post *database.Post) (string, error) {
+	var short database.ShortLink
+	result := db.Where("post_id = ?", post.ID).Order("id").Limit(1).Find(&short)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", nil
+	}
+	return short.Code, nil
+}
+
+// linkPost creates a short link for the first link of the content of post
+// unless it has one already or no links
+func linkPost(db *gorm.DB, post *database.Post) error {
+	code, err := postCode(db, post)
+	if err != nil || code != "" {
+		return err
+	}
+
+	match := urlPattern.FindString(post.Content)
+	if match == "" {
+		return nil
+	}
+	link, _ := trimLink(match)
+	code, err = newCode()
+	if err != nil {
+		return err
+	}
+	postID := post.ID
+	short := database.ShortLink{
+		Code: code, URL: link, PostID: &postID, ProviderID: post.ProviderID, CampaignID: post.CampaignID,
+		UserID: post.UserID, CreatedAt: time.Now(),
+	}
+	if err := db.Create(&short).Error; err != nil {
+		return fmt.Errorf("failed to save short link: %w", err)
+	}
+	return nil
+}
+
+// postTitle returns the title of post, or the start of its content without
+// links
+func postTitle(post *database.Post) string {
+	if title := strings.TrimSpace(post.Title); title != "" {
+		return title
+	}
+	title := strings.Join(strings.Fields(urlPattern.ReplaceAllString(post.Content, "")), " ")
+	if utf8.RuneCountInString(title) > bioTitleLength {
+		title = strings.TrimSpace(string([]rune(title)[:bioTitleLength])) + "…"
+	}
+	return title
+}
+
+// applyBioLink validates in and copies it onto pinned
+func applyBioLink(pinned *database.BioLink, in BioLinkInput) error {
+	title := strings.TrimSpace(in.Title)
+	if title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidBio)
+	}
+	target := strings.TrimSpace(in.URL)
+	if !urlPattern.MatchString(target) || urlPattern.FindString(target) != target {
+		return fmt.Errorf("%w: url must be an http or https link", ErrInvalidBio)
+	}
+
+	pinned.Title = title
+	pinned.URL = target
+	pinned.Position = in.Position
+	return nil
+}
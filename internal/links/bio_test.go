@@ -0,0 +1,167 @@
+package links
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// setupBio returns the database of alice and the registry
+func setupBio(t *testing.T) (*gorm.DB, *gorm.DB) {
+	t.Helper()
+	manager := database.NewTestManager(t)
+	t.Cleanup(func() { manager.Close() })
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	registry, err := manager.GetRegistryDB()
+	if err != nil {
+		t.Fatalf("GetRegistryDB failed: %v", err)
+	}
+	return db, registry
+}
+
+func TestBioPage_EntriesAndPinnedLinks(t *testing.T) {
+	db, registry := setupBio(t)
+	ctx := context.Background()
+
+	if _, err := GetBioPage(db, "alice"); !errors.Is(err, ErrBioNotFound) {
+		t.Fatalf("Expected ErrBioNotFound, got %v", err)
+	}
+
+	provider := database.Provider{Name: "instagram", Type: "instagram", UserID: "alice", IsActive: true}
+	db.Create(&provider)
+	older := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+	for _, post := range []database.Post{
+		{Content: "Old news https://example.com/old", PublishedAt: &older},
+		{Content: "Without a link", PublishedAt: &newer},
+		{Content: "Spring sale!\nhttps://example.com/sale.", PublishedAt: &newer},
+		{Content: "Draft https://example.com/draft", Status: database.PostStatusDraft},
+	} {
+		post.UserID = "alice"
+		post.ProviderID = provider.ID
+		if post.Status == "" {
+			post.Status = database.PostStatusPublished
+		}
+		db.Create(&post)
+	}
+
+	// Posts published before the page get their short links when it is saved
+	count := 2
+	page, err := SaveBioPage(ctx, db, registry, "alice", BioInput{Slug: " Alice-Shop ", Title: "Alice's shop", PostCount: &count})
+	if err != nil {
+		t.Fatalf("SaveBioPage failed: %v", err)
+	}
+	if page.Slug != "alice-shop" || page.Color != "#2563eb" || !page.Enabled {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	pinned, err := CreateBioLink(ctx, db, "alice", BioLinkInput{Title: "Shop", URL: "https://shop.example.com"})
+	if err != nil {
+		t.Fatalf("CreateBioLink failed: %v", err)
+	}
+	if _, err := UpdateBioLink(ctx, db, "alice", pinned.ID, BioLinkInput{Title: "Store", URL: "https://store.example.com"}); err != nil {
+		t.Fatalf("UpdateBioLink failed: %v", err)
+	}
+
+	var before int64
+	db.Model(&database.ShortLink{}).Count(&before)
+	entries, err := BioEntries(db, page)
+	if err != nil {
+		t.Fatalf("BioEntries failed: %v", err)
+	}
+	if len(entries) != 3 || !entries[0].Pinned || entries[0].Title != "Store" ||
+		entries[1].Title != "Spring sale!" || entries[1].Subtitle != "instagram · May 2, 2026" || entries[2].Title != "Old news" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	var after int64
+	db.Model(&database.ShortLink{}).Count(&after)
+	if after != before {
+		t.Errorf("Expected serving the page not to create short links, got %d, had %d", after, before)
+	}
+
+	// Clicks of pinned links and posts are counted by short links
+	for i, want := range []string{"https://store.example.com", "https://example.com/sale"} {
+		target, err := Click(db, entries[i].URL[len(PathPrefix):], time.Now())
+		if err != nil || target != want {
+			t.Errorf("Click returned %q, %v; want %q", target, err, want)
+		}
+	}
+
+	// Posts published later get theirs when they are published
+	latest := newer.Add(24 * time.Hour)
+	post := database.Post{Content: "New arrivals https://example.com/new", UserID: "alice", ProviderID: provider.ID,
+		Status: database.PostStatusPublished, PublishedAt: &latest}
+	db.Create(&post)
+	if err := LinkPost(db, &post); err != nil {
+		t.Fatalf("LinkPost failed: %v", err)
+	}
+	if err := LinkPost(db, &post); err != nil {
+		t.Fatalf("LinkPost failed: %v", err)
+	}
+	var linked int64
+	db.Model(&database.ShortLink{}).Where("post_id = ?", post.ID).Count(&linked)
+	again, err := BioEntries(db, page)
+	if err != nil || linked != 1 || len(again) != 3 || again[1].Title != "New arrivals" || again[2].URL != entries[1].URL {
+		t.Errorf("Expected the new post to be linked once and listed first, got %d links, %+v, %v", linked, again, err)
+	}
+
+	if err := DeleteBioLink(ctx, db, "alice", pinned.ID); err != nil {
+		t.Fatalf("DeleteBioLink failed: %v", err)
+	}
+	var links int64
+	db.Model(&database.ShortLink{}).Where("id = ?", pinned.ShortLinkID).Count(&links)
+	if links != 0 {
+		t.Error("Expected the short link of an unpinned link to be deleted")
+	}
+}
+
+func TestBioPage_Validate(t *testing.T) {
+	db, registry := setupBio(t)
+	ctx := context.Background()
+	tooMany := maxBioPosts + 1
+
+	for _, in := range []BioInput{
+		{Slug: "a", Title: "Short slug"},
+		{Slug: "with space", Title: "Bad slug"},
+		{Slug: "shop"},
+		{Slug: "shop", Title: "Shop", Color: "red"},
+		{Slug: "shop", Title: "Shop", PostCount: &tooMany},
+	} {
+		if _, err := SaveBioPage(ctx, db, registry, "alice", in); !errors.Is(err, ErrInvalidBio) {
+			t.Errorf("Expected ErrInvalidBio for %+v, got %v", in, err)
+		}
+	}
+
+	for _, in := range []BioLinkInput{
+		{URL: "https://example.com"},
+		{Title: "Local", URL: "file:///etc/passwd"},
+		{Title: "Two", URL: "https://a.example.com https://b.example.com"},
+	} {
+		if _, err := CreateBioLink(ctx, db, "alice", in); !errors.Is(err, ErrInvalidBio) {
+			t.Errorf("Expected ErrInvalidBio for %+v, got %v", in, err)
+		}
+	}
+
+	disabled := false
+	if _, err := SaveBioPage(ctx, db, registry, "alice", BioInput{Slug: "shop", Title: "Shop", Enabled: &disabled}); err != nil {
+		t.Fatalf("SaveBioPage failed: %v", err)
+	}
+	if _, err := FindBioPage(db, "shop"); !errors.Is(err, ErrBioNotFound) {
+		t.Errorf("Expected a disabled page not to be found, got %v", err)
+	}
+
+	// Slugs routed to another database are taken
+	if err := registry.Create(&database.BioRoute{Slug: "acme", TenantKey: "bob"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveBioPage(ctx, db, registry, "alice", BioInput{Slug: "acme", Title: "Acme"}); !errors.Is(err, ErrBioSlugTaken) {
+		t.Errorf("Expected ErrBioSlugTaken, got %v", err)
+	}
+}
@@ -0,0 +1,476 @@
+// Package links tags the links of published content with UTM parameters,
+// replaces them with short links served by SocGo and counts their clicks.
+package links
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/snippets"
+	"gorm.io/gorm"
+)
+
+// PathPrefix is the path short links are served under
+const PathPrefix = "/l/"
+
+const (
+	// codeLength is the number of characters of a short link code
+	codeLength = 8
+	// codeAlphabet are the characters of short link codes
+	codeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// topPosts is the number of posts in Stats
+	topPosts = 10
+)
+
+var (
+	// ErrNotFound is returned when the link rule does not exist
+	ErrNotFound = errors.New("link rule not found")
+	// ErrUnknownLink is returned by Click for codes without a short link
+	ErrUnknownLink = errors.New("short link not found")
+	// ErrInvalid wraps validation errors of a RuleInput
+	ErrInvalid = errors.New("invalid link rule")
+)
+
+// urlPattern matches the http and https links of content
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// RuleInput holds the editable fields of a link rule
+type RuleInput struct {
+	ProviderID  *uint  `json:"provider_id"`
+	CampaignID  *uint  `json:"campaign_id"`
+	UTMSource   string `json:"utm_source"`
+	UTMMedium   string `json:"utm_medium"`
+	UTMCampaign string `json:"utm_campaign"`
+	UTMTerm     string `json:"utm_term"`
+	UTMContent  string `json:"utm_content"`
+	Shorten     bool   `json:"shorten"`
+}
+
+// Publication is content being published to ProviderID in CampaignID. The
+// short links created for it are collected in Links, so that they can be
+// attached to its post once that is saved.
+type Publication struct {
+	ProviderID uint
+	CampaignID *uint
+	Links      []database.ShortLink
+}
+
+// ProviderClicks counts the short links and clicks of a provider
+type ProviderClicks struct {
+	ProviderID   uint   `json:"provider_id"`
+	ProviderName string `json:"provider_name"`
+	Links        int64  `json:"links"`
+	Clicks       int64  `json:"clicks"`
+}
+
+// PostClicks counts the short links and clicks of a post
+type PostClicks struct {
+	PostID       uint   `json:"post_id"`
+	Content      string `json:"content"`
+	ProviderName string `json:"provider_name"`
+	Links        int64  `json:"links"`
+	Clicks       int64  `json:"clicks"`
+}
+
+// Stats are the clicks of the short links of a user, in total, per provider
+// and for the most clicked posts
+type Stats struct {
+	Links     int64            `json:"links"`
+	Clicks    int64            `json:"clicks"`
+	Providers []ProviderClicks `json:"providers"`
+	Posts     []PostClicks     `json:"posts"`
+}
+
+type publicationKey struct{}
+
+// WithPublication returns a context whose published content has its links
+// rewritten for pub
+func WithPublication(ctx context.Context, pub *Publication) context.Context {
+	return context.WithValue(ctx, publicationKey{}, pub)
+}
+
+// PublicationFromContext returns the publication set by WithPublication, or
+// nil
+func PublicationFromContext(ctx context.Context) *Publication {
+	pub, _ := ctx.Value(publicationKey{}).(*Publication)
+	return pub
+}
+
+// ListRules returns the link rules of userID, the defaults first
+func ListRules(db *gorm.DB, userID string) ([]database.LinkRule, error) {
+	var rules []database.LinkRule
+	err := db.Where("user_id = ?", userID).
+		Order("provider_id IS NOT NULL, campaign_id IS NOT NULL, provider_id, campaign_id, id").
+		Find(&rules).Error
+	return rules, err
+}
+
+// GetRule returns the link rule id of userID
+func GetRule(db *gorm.DB, userID string, id uint) (*database.LinkRule, error) {
+	var rule database.LinkRule
+	result := db.Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&rule)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &rule, nil
+}
+
+// CreateRule adds a link rule for userID
+func CreateRule(ctx context.Context, db *gorm.DB, userID string, in RuleInput) (*database.LinkRule, error) {
+	rule := &database.LinkRule{UserID: userID}
+	if err := apply(db, rule, in); err != nil {
+		return nil, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionLinkRuleCreated, TargetType: audit.TargetLinkRule, TargetID: rule.ID, After: rule,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces the fields of the link rule id with in
+func UpdateRule(ctx context.Context, db *gorm.DB, userID string, id uint, in RuleInput) (*database.LinkRule, error) {
+	rule, err := GetRule(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *rule
+	if err := apply(db, rule, in); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(rule).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionLinkRuleUpdated, TargetType: audit.TargetLinkRule, TargetID: rule.ID, Before: before, After: rule,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule removes the link rule id. Short links already published keep
+// working.
+func DeleteRule(ctx context.Context, db *gorm.DB, userID string, id uint) error {
+	rule, err := GetRule(db, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(rule).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionLinkRuleDeleted, TargetType: audit.TargetLinkRule, TargetID: id, Before: rule,
+		})
+	})
+}
+
+// MatchRule returns the most specific link rule of userID for content
+// published to providerID in campaignID, or nil when none applies. A rule
+// for the provider and campaign beats one for the campaign, which beats one
+// for the provider, which beats a default rule.
+func MatchRule(db *gorm.DB, userID string, providerID uint, campaignID *uint) (*database.LinkRule, error) {
+	query := db.Where("user_id = ? AND (provider_id IS NULL OR provider_id = ?)", userID, providerID)
+	if campaignID != nil {
+		query = query.Where("campaign_id IS NULL OR campaign_id = ?", *campaignID)
+	} else {
+		query = query.Where("campaign_id IS NULL")
+	}
+
+	var rule database.LinkRule
+	result := query.Order("campaign_id IS NULL, provider_id IS NULL, id").Limit(1).Find(&rule)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load link rules: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &rule, nil
+}
+
+// Rewrite tags the links of content with the UTM parameters of the rule
+// matching pub, and when the rule shortens links replaces them with short
+// links under baseURL. The short links are saved and added to pub.Links.
+// Without a baseURL short links could not be opened, so links are only
+// tagged.
+// UTM values are expanded with vars; parameters a link already has are kept.
+// Content without a matching rule is returned unchanged.
+func Rewrite(db *gorm.DB, userID, content string, pub *Publication, baseURL string, vars snippets.Vars, now time.Time) (string, error) {
+	rule, err := MatchRule(db, userID, pub.ProviderID, pub.CampaignID)
+	if err != nil || rule == nil {
+		return content, err
+	}
+
+	params := make(url.Values)
+	for name, value := range map[string]string{
+		"utm_source": rule.UTMSource, "utm_medium": rule.UTMMedium, "utm_campaign": rule.UTMCampaign,
+		"utm_term": rule.UTMTerm, "utm_content": rule.UTMContent,
+	} {
+		if value == "" {
+			continue
+		}
+		if value, err = snippets.Expand(db, userID, value, now, vars); err != nil {
+			return "", err
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			params.Set(name, value)
+		}
+	}
+
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	var created []database.ShortLink
+	rewritten := urlPattern.ReplaceAllStringFunc(content, func(match string) string {
+		link, trailing := trimLink(match)
+		if err != nil || (baseURL != "" && strings.HasPrefix(link, baseURL+PathPrefix)) {
+			return match
+		}
+		target := tag(link, params)
+		if !rule.Shorten || baseURL == "" {
+			return target + trailing
+		}
+
+		var code string
+		if code, err = newCode(); err != nil {
+			return match
+		}
+		created = append(created, database.ShortLink{
+			Code: code, URL: target, ProviderID: pub.ProviderID, CampaignID: pub.CampaignID, UserID: userID, CreatedAt: now,
+		})
+		return baseURL + PathPrefix + code + trailing
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(created) > 0 {
+		if err := db.Create(&created).Error; err != nil {
+			return "", fmt.Errorf("failed to save short links: %w", err)
+		}
+		pub.Links = append(pub.Links, created...)
+	}
+	return rewritten, nil
+}
+
+// Discard deletes the short links of pub, whose content was not published
+func Discard(db *gorm.DB, pub *Publication) error {
+	if len(pub.Links) == 0 {
+		return nil
+	}
+	if err := db.Where("id IN ?", linkIDs(pub)).Delete(&database.ShortLink{}).Error; err != nil {
+		return err
+	}
+	pub.Links = nil
+	return nil
+}
+
+// Attach links the short links of pub to the post postID
+func Attach(db *gorm.DB, pub *Publication, postID uint) error {
+	if len(pub.Links) == 0 {
+		return nil
+	}
+	return db.Model(&database.ShortLink{}).Where("id IN ?", linkIDs(pub)).Update("post_id", postID).Error
+}
+
+// Click counts a click of the short link code and returns its target
+func Click(db *gorm.DB, code string, now time.Time) (string, error) {
+	var link database.ShortLink
+	result := db.Where("code = ?", code).Limit(1).Find(&link)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrUnknownLink
+	}
+
+	if err := db.Model(&link).UpdateColumns(map[string]interface{}{
+		"clicks":          gorm.Expr("clicks + 1"),
+		"last_clicked_at": now,
+	}).Error; err != nil {
+		return "", fmt.Errorf("failed to count click: %w", err)
+	}
+	return link.URL, nil
+}
+
+// PostLinks returns the short links of the post postID
+func PostLinks(db *gorm.DB, userID string, postID uint) ([]database.ShortLink, error) {
+	var links []database.ShortLink
+	err := db.Where("user_id = ? AND post_id = ?", userID, postID).Order("id").Find(&links).Error
+	return links, err
+}
+
+// GetStats returns the clicks of the short links of userID
+func GetStats(db *gorm.DB, userID string) (*Stats, error) {
+	var totals struct {
+		Links  int64
+		Clicks int64
+	}
+	if err := db.Model(&database.ShortLink{}).
+		Select("COUNT(*) AS links, COALESCE(SUM(clicks), 0) AS clicks").
+		Where("user_id = ?", userID).
+		Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %w", err)
+	}
+	stats := &Stats{Links: totals.Links, Clicks: totals.Clicks, Providers: []ProviderClicks{}, Posts: []PostClicks{}}
+
+	if err := db.Table("short_links").
+		Select("short_links.provider_id, COALESCE(providers.name, '') AS provider_name, COUNT(*) AS links, SUM(short_links.clicks) AS clicks").
+		Joins("LEFT JOIN providers ON providers.id = short_links.provider_id").
+		Where("short_links.user_id = ?", userID).
+		Group("short_links.provider_id, providers.name").
+		Order("clicks DESC, short_links.provider_id").
+		Scan(&stats.Providers).Error; err != nil {
+		return nil, fmt.Errorf("failed to count clicks per provider: %w", err)
+	}
+
+	if err := db.Table("short_links").
+		Select("short_links.post_id, posts.content, COALESCE(providers.name, '') AS provider_name, COUNT(*) AS links, SUM(short_links.clicks) AS clicks").
+		Joins("JOIN posts ON posts.id = short_links.post_id AND posts.deleted_at IS NULL").
+		Joins("LEFT JOIN providers ON providers.id = posts.provider_id").
+		Where("short_links.user_id = ?", userID).
+		Group("short_links.post_id, posts.content, providers.name").
+		Order("clicks DESC, short_links.post_id DESC").
+		Limit(topPosts).
+		Scan(&stats.Posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count clicks per post: %w", err)
+	}
+	return stats, nil
+}
+
+// apply validates in and copies it onto rule
+func apply(db *gorm.DB, rule *database.LinkRule, in RuleInput) error {
+	if in.ProviderID != nil {
+		var provider database.Provider
+		result := db.Where("user_id = ? AND id = ?", rule.UserID, *in.ProviderID).Limit(1).Find(&provider)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: provider not found", ErrInvalid)
+		}
+	}
+	if in.CampaignID != nil {
+		var campaign database.Campaign
+		result := db.Where("user_id = ? AND id = ?", rule.UserID, *in.CampaignID).Limit(1).Find(&campaign)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: campaign not found", ErrInvalid)
+		}
+	}
+
+	utm := []*string{&in.UTMSource, &in.UTMMedium, &in.UTMCampaign, &in.UTMTerm, &in.UTMContent}
+	empty := true
+	for _, value := range utm {
+		*value = strings.TrimSpace(*value)
+		empty = empty && *value == ""
+	}
+	if empty && !in.Shorten {
+		return fmt.Errorf("%w: set a UTM parameter or shorten links", ErrInvalid)
+	}
+
+	query := db.Model(&database.LinkRule{}).Where("user_id = ? AND id <> ?", rule.UserID, rule.ID)
+	if in.ProviderID != nil {
+		query = query.Where("provider_id = ?", *in.ProviderID)
+	} else {
+		query = query.Where("provider_id IS NULL")
+	}
+	if in.CampaignID != nil {
+		query = query.Where("campaign_id = ?", *in.CampaignID)
+	} else {
+		query = query.Where("campaign_id IS NULL")
+	}
+	var taken int64
+	if err := query.Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("%w: a rule for this provider and campaign already exists", ErrInvalid)
+	}
+
+	rule.ProviderID = in.ProviderID
+	rule.CampaignID = in.CampaignID
+	rule.UTMSource = in.UTMSource
+	rule.UTMMedium = in.UTMMedium
+	rule.UTMCampaign = in.UTMCampaign
+	rule.UTMTerm = in.UTMTerm
+	rule.UTMContent = in.UTMContent
+	rule.Shorten = in.Shorten
+	return nil
+}
+
+// trimLink splits the punctuation ending a sentence off a matched link
+func trimLink(match string) (link, trailing string) {
+	link = strings.TrimRight(match, ".,;:!?")
+	// Keep a closing parenthesis only when the link opened one
+	for strings.HasSuffix(link, ")") && strings.Count(link, "(") < strings.Count(link, ")") {
+		link = strings.TrimRight(strings.TrimSuffix(link, ")"), ".,;:!?")
+	}
+	return link, match[len(link):]
+}
+
+// tag adds params to the query of link, keeping parameters it already has
+func tag(link string, params url.Values) string {
+	if len(params) == 0 {
+		return link
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	query := parsed.Query()
+	for name := range params {
+		if query.Get(name) == "" {
+			query.Set(name, params.Get(name))
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func newCode() (string, error) {
+	code := make([]byte, codeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate short link code: %w", err)
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func linkIDs(pub *Publication) []uint {
+	ids := make([]uint, len(pub.Links))
+	for i, link := range pub.Links {
+		ids[i] = link.ID
+	}
+	return ids
+}
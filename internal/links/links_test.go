@@ -0,0 +1,171 @@
+package links
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/snippets"
+)
+
+func TestRewrite_TagsAndShortensLinks(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	now := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: "alice", IsActive: true}
+	db.Create(&provider)
+	campaign := database.Campaign{Name: "Spring", UserID: "alice"}
+	db.Create(&campaign)
+
+	if _, err := CreateRule(ctx, db, "alice", RuleInput{UTMSource: "{{provider}}", UTMMedium: "social"}); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if _, err := CreateRule(ctx, db, "alice", RuleInput{CampaignID: &campaign.ID, UTMCampaign: "{{campaign}}-{{date}}", Shorten: true}); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+
+	// Without a campaign the default rule only tags links
+	pub := &Publication{ProviderID: provider.ID}
+	vars := snippets.Vars{snippets.VarProvider: "facebook", snippets.VarCampaign: ""}
+	content, err := Rewrite(db, "alice", "Read https://example.com/post?ref=x&utm_medium=email.", pub, "https://socgo.example", vars, now)
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if content != "Read https://example.com/post?ref=x&utm_medium=email&utm_source=facebook." || len(pub.Links) != 0 {
+		t.Errorf("Unexpected tagged content %q, links %+v", content, pub.Links)
+	}
+
+	// The campaign rule is more specific and shortens links
+	pub = &Publication{ProviderID: provider.ID, CampaignID: &campaign.ID}
+	vars[snippets.VarCampaign] = "Spring"
+	content, err = Rewrite(db, "alice", "New (https://example.com/a) and https://example.com/b!", pub, "https://socgo.example/", vars, now)
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if len(pub.Links) != 2 {
+		t.Fatalf("Expected 2 short links, got %+v", pub.Links)
+	}
+	want := "New (https://socgo.example/l/" + pub.Links[0].Code + ") and https://socgo.example/l/" + pub.Links[1].Code + "!"
+	if content != want || pub.Links[0].URL != "https://example.com/a?utm_campaign=Spring-2026-05-04" || pub.Links[0].CampaignID == nil {
+		t.Errorf("Unexpected shortened content %q, links %+v", content, pub.Links)
+	}
+
+	// Short links are not shortened again
+	again, err := Rewrite(db, "alice", content, &Publication{ProviderID: provider.ID, CampaignID: &campaign.ID}, "https://socgo.example", vars, now)
+	if err != nil || again != content {
+		t.Errorf("Expected short links to be kept, got %q, %v", again, err)
+	}
+
+	post := database.Post{Content: content, UserID: "alice", ProviderID: provider.ID, Status: database.PostStatusPublished}
+	db.Create(&post)
+	if err := Attach(db, pub, post.ID); err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+
+	target, err := Click(db, pub.Links[0].Code, now)
+	if err != nil || target != pub.Links[0].URL {
+		t.Fatalf("Click returned %q, %v", target, err)
+	}
+	Click(db, pub.Links[0].Code, now)
+	Click(db, pub.Links[1].Code, now)
+	if _, err := Click(db, "missing", now); !errors.Is(err, ErrUnknownLink) {
+		t.Errorf("Expected ErrUnknownLink, got %v", err)
+	}
+
+	stats, err := GetStats(db, "alice")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.Links != 2 || stats.Clicks != 3 || len(stats.Providers) != 1 || stats.Providers[0].ProviderName != "facebook" ||
+		len(stats.Posts) != 1 || stats.Posts[0].PostID != post.ID || stats.Posts[0].Clicks != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	postLinks, err := PostLinks(db, "alice", post.ID)
+	if err != nil || len(postLinks) != 2 || postLinks[0].Clicks != 2 || postLinks[0].LastClickedAt == nil {
+		t.Errorf("Unexpected post links %+v, %v", postLinks, err)
+	}
+}
+
+func TestRewrite_WithoutRuleAndDiscard(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	now := time.Now()
+
+	content := "See https://example.com"
+	pub := &Publication{ProviderID: 1}
+	if got, err := Rewrite(db, "alice", content, pub, "", nil, now); err != nil || got != content {
+		t.Fatalf("Expected content unchanged without rules, got %q, %v", got, err)
+	}
+
+	if _, err := CreateRule(context.Background(), db, "alice", RuleInput{Shorten: true, UTMSource: "socgo"}); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	// Without a public URL links are only tagged
+	if got, err := Rewrite(db, "alice", content, pub, "", nil, now); err != nil || got != content+"?utm_source=socgo" || len(pub.Links) != 0 {
+		t.Fatalf("Expected the tagged link without shortening, got %q, %+v, %v", got, pub.Links, err)
+	}
+	if _, err := Rewrite(db, "alice", content, pub, "https://socgo.example", nil, now); err != nil || len(pub.Links) != 1 {
+		t.Fatalf("Expected a short link, got %+v, %v", pub.Links, err)
+	}
+	if err := Discard(db, pub); err != nil {
+		t.Fatalf("Discard failed: %v", err)
+	}
+	var count int64
+	db.Model(&database.ShortLink{}).Count(&count)
+	if count != 0 || pub.Links != nil {
+		t.Errorf("Expected the short links to be discarded, got %d", count)
+	}
+}
+
+func TestRules_Validate(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	missing := uint(42)
+
+	for _, in := range []RuleInput{
+		{},
+		{UTMSource: "  "},
+		{ProviderID: &missing, Shorten: true},
+		{CampaignID: &missing, Shorten: true},
+	} {
+		if _, err := CreateRule(ctx, db, "alice", in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for %+v, got %v", in, err)
+		}
+	}
+
+	rule, err := CreateRule(ctx, db, "alice", RuleInput{UTMSource: "socgo"})
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if _, err := CreateRule(ctx, db, "alice", RuleInput{UTMMedium: "social"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a second default rule to be rejected, got %v", err)
+	}
+	if updated, err := UpdateRule(ctx, db, "alice", rule.ID, RuleInput{UTMMedium: "social", Shorten: true}); err != nil || updated.UTMSource != "" || !updated.Shorten {
+		t.Errorf("Unexpected update %+v, %v", updated, err)
+	}
+	if err := DeleteRule(ctx, db, "alice", rule.ID); err != nil {
+		t.Fatalf("DeleteRule failed: %v", err)
+	}
+	if _, err := GetRule(db, "alice", rule.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTrimLink(t *testing.T) {
+	for match, want := range map[string]string{
+		"https://example.com/a.":                      "https://example.com/a",
+		"https://en.wikipedia.org/wiki/Go_(language)": "https://en.wikipedia.org/wiki/Go_(language)",
+		"https://example.com/b),":                     "https://example.com/b",
+	} {
+		if link, trailing := trimLink(match); link != want || link+trailing != match {
+			t.Errorf("trimLink(%q) = %q, %q", match, link, trailing)
+		}
+	}
+	if !strings.HasPrefix(tag("https://example.com/?a=1", nil), "https://example.com/?a=1") {
+		t.Error("Expected a link without params to be unchanged")
+	}
+}
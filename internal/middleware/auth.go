@@ -3,6 +3,7 @@ package middleware
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
 )
 
 type AuthMiddleware struct {
@@ -49,11 +51,18 @@ func (m *AuthMiddleware) APIAuthMiddleware(next http.Handler) http.Handler {
 		tokenHash := sha256.Sum256([]byte(token))
 		tokenHashString := fmt.Sprintf("%x", tokenHash)
 
-		// TODO: Extract user ID from token or use default for now
-		userID := "default_user"
+		// The control plane knows which database holds the token
+		route, err := m.dbManager.LookupToken(tokenHashString)
+		if err != nil {
+			if !errors.Is(err, database.ErrUnknownToken) {
+				log.Printf("Error looking up token: %v", err)
+			}
+			m.writeUnauthorizedResponse(w, "Invalid token")
+			return
+		}
 
-		// Get database instance for user
-		db, err := m.dbManager.GetDB(userID)
+		// Get database instance for the token's owner
+		db, err := m.dbManager.GetDB(route.TenantKey)
 		if err != nil {
 			log.Printf("Error getting database: %v", err)
 			m.writeUnauthorizedResponse(w, "Authentication failed")
@@ -68,6 +77,19 @@ func (m *AuthMiddleware) APIAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Tokens are personal and only valid in their owner's database
+		if apiToken.UserID != route.TenantKey {
+			log.Printf("Token of %s found in the database of %s", apiToken.UserID, route.TenantKey)
+			m.writeUnauthorizedResponse(w, "Invalid token")
+			return
+		}
+
+		// Scoped tokens, such as calendar feed tokens, cannot use the API
+		if apiToken.Scope != database.TokenScopeAPI {
+			m.writeUnauthorizedResponse(w, "Token is limited to the "+apiToken.Scope+" scope")
+			return
+		}
+
 		// Update last_used timestamp
 		now := time.Now()
 		apiToken.LastUsed = &now
@@ -75,11 +97,15 @@ func (m *AuthMiddleware) APIAuthMiddleware(next http.Handler) http.Handler {
 			log.Printf("Error updating token last_used: %v", err)
 		}
 
-		// Set user ID in request context for handlers to use
-		// For now, we'll continue with the existing pattern
-		
+		// Set the token owner in request context for handlers to use
+		ctx := tenant.WithContext(r.Context(), tenant.Context{
+			UserID: apiToken.UserID,
+			Role:   database.WorkspaceRoleOwner,
+			Source: tenant.SourceAPI,
+		})
+
 		// Continue to next handler
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
 )
 
 func TestAuthMiddleware_APIAuthMiddleware_ValidToken(t *testing.T) {
@@ -166,3 +167,63 @@ func TestAuthMiddleware_APIAuthMiddleware_InvalidAuthFormat(t *testing.T) {
 		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
 	}
 }
+
+func TestAuthMiddleware_APIAuthMiddleware_RoutesToTokenOwner(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	token := "alice-token"
+	tokenHash := fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+
+	db, err := dbManager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	if err := db.Create(&database.APIToken{Hash: tokenHash, UserID: "alice"}).Error; err != nil {
+		t.Fatalf("Failed to create API token: %v", err)
+	}
+
+	var userID string
+	handler := NewAuthMiddleware(dbManager).APIAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = tenant.FromRequest(r).UserID
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || userID != "alice" {
+		t.Errorf("Expected the request to act as alice, got %d as %q", rr.Code, userID)
+	}
+}
+
+func TestAuthMiddleware_APIAuthMiddleware_RejectsForeignToken(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	// A token naming alice planted in mallory's database
+	token := "planted-token"
+	tokenHash := fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+	db, err := dbManager.GetDB("mallory")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	if err := db.Create(&database.APIToken{Hash: tokenHash, UserID: "alice"}).Error; err != nil {
+		t.Fatalf("Failed to create API token: %v", err)
+	}
+
+	handler := NewAuthMiddleware(dbManager).APIAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected the request to be rejected, acted as %q", tenant.FromRequest(r).UserID)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/workspace"
+)
+
+const (
+	// WorkspaceHeader selects the workspace for API requests
+	WorkspaceHeader = "X-Workspace-ID"
+	// WorkspaceCookie remembers the workspace selected in the web UI
+	WorkspaceCookie = "socgo_workspace"
+)
+
+type WorkspaceMiddleware struct {
+	workspaces *workspace.Service
+	// viewerWritable lists path prefixes viewers may still post to, such as
+	// switching workspace
+	viewerWritable []string
+}
+
+func NewWorkspaceMiddleware(workspaces *workspace.Service) *WorkspaceMiddleware {
+	return &WorkspaceMiddleware{
+		workspaces: workspaces,
+	}
+}
+
+// AllowViewerWrites lets viewers use unsafe methods on paths with the given
+// prefixes; the handlers behind them enforce their own permissions
+func (m *WorkspaceMiddleware) AllowViewerWrites(prefixes ...string) *WorkspaceMiddleware {
+	m.viewerWritable = append(m.viewerWritable, prefixes...)
+	return m
+}
+
+// Resolve determines the tenant of the request from the selected workspace and
+// the member's role. Viewers may only use safe (read-only) methods.
+func (m *WorkspaceMiddleware) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ident := tenant.FromRequest(r)
+
+		// Token requests are resolved again once APIAuthMiddleware has
+		// identified the token's owner
+		if ident.Source != tenant.SourceAPI && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		workspaceID, fromCookie, err := selectedWorkspace(r)
+		if err != nil {
+			m.writeForbiddenResponse(w, "Invalid workspace")
+			return
+		}
+
+		ident.WorkspaceID = 0
+		ident.Role = database.WorkspaceRoleOwner
+		if workspaceID != 0 {
+			role, err := m.workspaces.Role(workspaceID, ident.UserID)
+			switch {
+			case err == nil:
+				ident.WorkspaceID = workspaceID
+				ident.Role = role
+			case errors.Is(err, workspace.ErrNotMember) && fromCookie:
+				// A stale selection falls back to the personal space
+				http.SetCookie(w, &http.Cookie{Name: WorkspaceCookie, Value: "", Path: "/", MaxAge: -1})
+			default:
+				if !errors.Is(err, workspace.ErrNotMember) {
+					log.Printf("Error resolving workspace role: %v", err)
+				}
+				m.writeForbiddenResponse(w, "Not a member of this workspace")
+				return
+			}
+		}
+
+		if !isSafeMethod(r.Method) && !ident.Can(database.WorkspaceRoleEditor) && !m.viewerMayWrite(r.URL.Path) {
+			m.writeForbiddenResponse(w, "Viewers cannot modify workspace content")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.WithContext(r.Context(), ident)))
+	})
+}
+
+// RequireRole rejects requests whose workspace role is below role
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !tenant.FromRequest(r).Can(role) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			if err := json.NewEncoder(w).Encode(map[string]string{
+				"error":   "Forbidden",
+				"message": "This action requires the " + role + " role",
+			}); err != nil {
+				log.Printf("Error encoding JSON response: %v", err)
+			}
+			return
+		}
+		next(w, r)
+	}
+}
+
+// selectedWorkspace returns the workspace chosen by the header or, failing
+// that, the cookie. Zero means the personal space.
+func selectedWorkspace(r *http.Request) (uint, bool, error) {
+	value := r.Header.Get(WorkspaceHeader)
+	fromCookie := false
+	if value == "" {
+		if cookie, err := r.Cookie(WorkspaceCookie); err == nil {
+			value = cookie.Value
+			fromCookie = true
+		}
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fromCookie, err
+	}
+	return uint(id), fromCookie, nil
+}
+
+func (m *WorkspaceMiddleware) viewerMayWrite(path string) bool {
+	for _, prefix := range m.viewerWritable {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func (m *WorkspaceMiddleware) writeForbiddenResponse(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	response := map[string]string{
+		"error":   "Forbidden",
+		"message": message,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
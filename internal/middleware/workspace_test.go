@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/workspace"
+)
+
+func TestWorkspaceMiddleware_Resolve(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	service := workspace.NewService(dbManager)
+	ws, err := service.Create("owner", "Team")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if _, err := service.SetMember(ws.ID, tenant.DefaultUserID, database.WorkspaceRoleViewer); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	var resolved tenant.Context
+	handler := NewWorkspaceMiddleware(service).AllowViewerWrites("/workspaces").Resolve(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resolved = tenant.FromRequest(r)
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	workspaceID := strconv.FormatUint(uint64(ws.ID), 10)
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		header    string
+		wantCode  int
+		wantDBKey string
+	}{
+		{"personal space", "POST", "/posts", "", http.StatusOK, tenant.DefaultUserID},
+		{"viewer can read", "GET", "/posts/history", workspaceID, http.StatusOK, database.WorkspaceDBKey(ws.ID)},
+		{"viewer cannot write", "POST", "/posts", workspaceID, http.StatusForbidden, ""},
+		{"viewer can switch workspace", "POST", "/workspaces/switch", workspaceID, http.StatusOK, database.WorkspaceDBKey(ws.ID)},
+		{"non-member", "GET", "/posts/history", "999", http.StatusForbidden, ""},
+		{"invalid workspace", "GET", "/posts/history", "abc", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved = tenant.Context{}
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(WorkspaceHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if tt.wantDBKey != "" && resolved.DBKey() != tt.wantDBKey {
+				t.Errorf("Expected database %s, got %s", tt.wantDBKey, resolved.DBKey())
+			}
+		})
+	}
+
+	// A stale cookie falls back to the personal space
+	req := httptest.NewRequest("GET", "/posts/history", nil)
+	req.AddCookie(&http.Cookie{Name: WorkspaceCookie, Value: "999"})
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || resolved.DBKey() != tenant.DefaultUserID {
+		t.Errorf("Expected fallback to personal space, got %d %s", rr.Code, resolved.DBKey())
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/tkowalski/socgo/internal/config"
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
 )
 
 type Handler struct {
@@ -52,7 +53,13 @@ func (h *Handler) HandleConnect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	connectURL, err := h.oauthService.GetConnectURL(userID, providerType, providerName)
+	if !tenant.FromRequest(r).Can(database.WorkspaceRoleOwner) {
+		errorMsg := url.QueryEscape("Only workspace owners can connect providers")
+		http.Redirect(w, r, "/providers?flash="+errorMsg+"&flash_type=error", http.StatusTemporaryRedirect)
+		return
+	}
+
+	connectURL, err := h.oauthService.GetConnectURL(userID, tenant.FromRequest(r).UserID, providerType, providerName)
 	if err != nil {
 		// Redirect with error message
 		errorMsg := url.QueryEscape(fmt.Sprintf("Failed to generate connect URL: %v", err))
@@ -92,19 +99,30 @@ func (h *Handler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse state to get userID and providerName
-	stateParts := strings.Split(state, ":")
-	if len(stateParts) != 2 {
-		// Redirect with error message
-		errorMsg := url.QueryEscape("Invalid state parameter")
+	pending, err := h.oauthService.TakeState(state)
+	if err != nil {
+		errorMsg := url.QueryEscape("Invalid or expired state parameter")
 		http.Redirect(w, r, "/providers?flash="+errorMsg+"&flash_type=error", http.StatusTemporaryRedirect)
 		return
 	}
 
-	userID := stateParts[0]
-	providerName := stateParts[1]
+	// The flow must end where it started, still with an owner
+	ctx := tenant.FromRequest(r)
+	if !database.IsValidKey(pending.TenantKey) || ctx.DBKey() != pending.TenantKey || ctx.UserID != pending.ActorID {
+		errorMsg := url.QueryEscape("This connection was started by another user or workspace")
+		http.Redirect(w, r, "/providers?flash="+errorMsg+"&flash_type=error", http.StatusTemporaryRedirect)
+		return
+	}
+	if !ctx.Can(database.WorkspaceRoleOwner) {
+		errorMsg := url.QueryEscape("Only workspace owners can connect providers")
+		http.Redirect(w, r, "/providers?flash="+errorMsg+"&flash_type=error", http.StatusTemporaryRedirect)
+		return
+	}
+
+	userID := pending.TenantKey
+	providerName := pending.ProviderName
 
-	err := h.oauthService.HandleCallback(userID, providerType, code, providerName)
+	err = h.oauthService.HandleCallback(r.Context(), userID, providerType, code, providerName)
 	if err != nil {
 		// Redirect with error message
 		errorMsg := url.QueryEscape(fmt.Sprintf("Failed to connect provider: %v", err))
@@ -268,8 +286,11 @@ func (h *Handler) handleProvidersHTML(w http.ResponseWriter, providers []databas
 					<button hx-delete="/api/providers/%d" hx-target="closest div" hx-swap="outerHTML" hx-confirm="Are you sure you want to disconnect this provider?" class="bg-red-500 hover:bg-red-700 text-white font-medium py-1 px-3 rounded text-sm transition-colors">
 						Disconnect
 					</button>
+					<button hx-delete="/providers/%d" hx-target="closest .border" hx-swap="delete" hx-confirm="Move this provider to the trash?" class="text-red-600 hover:text-red-800 text-sm">
+						Delete
+					</button>
 				</div>
-			</div>`, iconClass, provider.Type[:3], displayName, provider.CreatedAt.Format("January 2, 2006"), statusClass, status, provider.ID)
+			</div>`, iconClass, provider.Type[:3], displayName, provider.CreatedAt.Format("January 2, 2006"), statusClass, status, provider.ID, provider.ID)
 	}
 	html += `</div>`
 
@@ -363,7 +384,12 @@ func (h *Handler) HandleDisconnect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.oauthService.DisconnectProvider(userID, uint(providerID))
+	if !tenant.FromRequest(r).Can(database.WorkspaceRoleOwner) {
+		http.Error(w, "Only workspace owners can disconnect providers", http.StatusForbidden)
+		return
+	}
+
+	err = h.oauthService.DisconnectProvider(r.Context(), userID, uint(providerID))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to disconnect provider: %v", err), http.StatusInternalServerError)
 		return
@@ -375,5 +401,5 @@ func (h *Handler) HandleDisconnect(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getUserID(r *http.Request) string {
-	return "default_user"
+	return tenant.FromRequest(r).DBKey()
 }
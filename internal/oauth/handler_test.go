@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/config"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+)
+
+func TestHandler_CallbackNeedsItsOwnState(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	t.Cleanup(func() { dbManager.Close() })
+	cfg := &config.Config{}
+	cfg.Providers.Facebook = []config.ProviderInstance{{Name: "main", ClientID: "id", ClientSecret: "secret"}}
+	handler := NewHandler(NewService(dbManager, cfg))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/connect/{provider}", handler.HandleConnect)
+	router.HandleFunc("/oauth/callback/{provider}", handler.HandleCallback)
+	serve := func(path string, as tenant.Context) *url.URL {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		req = req.WithContext(tenant.WithContext(req.Context(), as))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusTemporaryRedirect {
+			t.Fatalf("Expected a redirect for %s, got %d", path, rr.Code)
+		}
+		location, err := url.Parse(rr.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return location
+	}
+	owner := tenant.Context{UserID: "alice", WorkspaceID: 7, Role: database.WorkspaceRoleOwner}
+	connect := func() string {
+		location := serve("/connect/facebook?name=main", owner)
+		state := location.Query().Get("state")
+		if state == "" || strings.Contains(state, "workspace_7") {
+			t.Fatalf("Expected a random state, got %s", location)
+		}
+		return state
+	}
+
+	for _, tc := range []struct {
+		name  string
+		state string
+		as    tenant.Context
+		flash string
+	}{
+		{"forged state", "workspace_7:main", owner, "Invalid or expired state"},
+		{"path in the state", "../x:main", owner, "Invalid or expired state"},
+		{"another user", connect(), tenant.Context{UserID: "mallory", Role: database.WorkspaceRoleOwner}, "started by another user"},
+		{"editor", connect(), tenant.Context{UserID: "alice", WorkspaceID: 7, Role: database.WorkspaceRoleEditor}, "Only workspace owners"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			location := serve("/oauth/callback/facebook?code=abc&state="+url.QueryEscape(tc.state), tc.as)
+			if location.Query().Get("flash_type") != "error" || !strings.Contains(location.Query().Get("flash"), tc.flash) {
+				t.Errorf("Expected %q, got %s", tc.flash, location)
+			}
+		})
+	}
+
+	// States are good for one callback
+	state := connect()
+	if _, err := handler.oauthService.TakeState(state); err != nil {
+		t.Fatalf("TakeState failed: %v", err)
+	}
+	if _, err := handler.oauthService.TakeState(state); err == nil {
+		t.Error("Expected a used state to be rejected")
+	}
+	if dbManager.UserDBExists("workspace_7") {
+		t.Error("Expected no database to be created by the callbacks")
+	}
+}
@@ -1,30 +1,61 @@
 package oauth
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/tkowalski/socgo/internal/audit"
 	"github.com/tkowalski/socgo/internal/config"
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/webhooks"
+	"gorm.io/gorm"
 )
 
+// stateTTL is how long a connect flow may take before its callback
+const stateTTL = 10 * time.Minute
+
+// ErrInvalidState is returned for callbacks whose state was not issued by
+// GetConnectURL, has expired or was already used
+var ErrInvalidState = errors.New("invalid or expired state parameter")
+
+// ConnectState is a connect flow waiting for its callback: the database the
+// provider joins, the user who started it and the provider instance
+type ConnectState struct {
+	TenantKey    string
+	ActorID      string
+	ProviderName string
+	expiresAt    time.Time
+}
+
 type Service struct {
 	dbManager *database.Manager
 	config    *config.Config
+
+	statesMu sync.Mutex
+	states   map[string]ConnectState
 }
 
 func NewService(dbManager *database.Manager, cfg *config.Config) *Service {
 	return &Service{
 		dbManager: dbManager,
 		config:    cfg,
+		states:    make(map[string]ConnectState),
 	}
 }
 
-func (s *Service) GetConnectURL(userID string, providerType ProviderType, providerName string) (string, error) {
+// GetConnectURL returns the authorization URL connecting a provider to the
+// database of userID. Its state is a random nonce remembered with userID and
+// actorID, so that the callback cannot name another database.
+func (s *Service) GetConnectURL(userID, actorID string, providerType ProviderType, providerName string) (string, error) {
 	metadata, exists := SupportedProviders[providerType]
 	if !exists {
 		return "", fmt.Errorf("unsupported provider: %s", providerType)
@@ -41,12 +72,52 @@ func (s *Service) GetConnectURL(userID string, providerType ProviderType, provid
 	params.Add("client_id", providerConfig.ClientID)
 	params.Add("redirect_uri", s.getRedirectURI(providerType))
 	params.Add("scope", strings.Join(metadata.Scopes, " "))
-	params.Add("state", fmt.Sprintf("%s:%s", userID, providerName))
+	state, err := s.newState(ConnectState{TenantKey: userID, ActorID: actorID, ProviderName: providerName})
+	if err != nil {
+		return "", err
+	}
+	params.Add("state", state)
 
 	return metadata.AuthURL + "?" + params.Encode(), nil
 }
 
-func (s *Service) HandleCallback(userID string, providerType ProviderType, code string, providerName string) error {
+// TakeState returns and forgets the connect flow of state. Each state is
+// good for one callback within stateTTL.
+func (s *Service) TakeState(state string) (*ConnectState, error) {
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+
+	pending, ok := s.states[state]
+	delete(s.states, state)
+	if !ok || time.Now().After(pending.expiresAt) {
+		return nil, ErrInvalidState
+	}
+	return &pending, nil
+}
+
+// newState remembers pending under a new random nonce, forgetting expired
+// flows
+func (s *Service) newState(pending ConnectState) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	state := hex.EncodeToString(nonce)
+
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+	now := time.Now()
+	for key, other := range s.states {
+		if now.After(other.expiresAt) {
+			delete(s.states, key)
+		}
+	}
+	pending.expiresAt = now.Add(stateTTL)
+	s.states[state] = pending
+	return state, nil
+}
+
+func (s *Service) HandleCallback(ctx context.Context, userID string, providerType ProviderType, code string, providerName string) error {
 	_, exists := SupportedProviders[providerType]
 	if !exists {
 		return fmt.Errorf("unsupported provider: %s", providerType)
@@ -70,7 +141,7 @@ func (s *Service) HandleCallback(userID string, providerType ProviderType, code
 
 	token.UserInfo = userInfo
 
-	return s.saveProviderConfig(userID, providerType, providerName, token)
+	return s.saveProviderConfig(ctx, userID, providerType, providerName, token)
 }
 
 func (s *Service) exchangeCodeForToken(providerType ProviderType, code string, providerConfig *config.ProviderInstance) (*ProviderConfig, error) {
@@ -155,7 +226,7 @@ func (s *Service) getUserInfo(providerType ProviderType, accessToken string) (*U
 	return &userInfo, nil
 }
 
-func (s *Service) saveProviderConfig(userID string, providerType ProviderType, providerName string, config *ProviderConfig) error {
+func (s *Service) saveProviderConfig(ctx context.Context, userID string, providerType ProviderType, providerName string, config *ProviderConfig) error {
 	db, err := s.dbManager.GetDB(userID)
 	if err != nil {
 		return err
@@ -174,15 +245,28 @@ func (s *Service) saveProviderConfig(userID string, providerType ProviderType, p
 		IsActive: true,
 	}
 
-	var existingProvider database.Provider
-	result := db.Where("user_id = ? AND name = ?", userID, providerName).First(&existingProvider)
-
-	if result.Error == nil {
-		provider.ID = existingProvider.ID
-		return db.Save(provider).Error
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Reconnecting a provider in the trash restores it
+		var existingProvider database.Provider
+		result := tx.Unscoped().Where("user_id = ? AND name = ?", userID, providerName).First(&existingProvider)
+
+		var before interface{}
+		if result.Error == nil {
+			provider.ID = existingProvider.ID
+			provider.CreatedAt = existingProvider.CreatedAt
+			before = existingProvider
+			if err := tx.Unscoped().Save(provider).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Create(provider).Error; err != nil {
+			return err
+		}
 
-	return db.Create(provider).Error
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionProviderConnected, TargetType: audit.TargetProvider, TargetID: provider.ID,
+			Before: before, After: provider,
+		})
+	})
 }
 
 func (s *Service) GetProviders(userID string) ([]database.Provider, error) {
@@ -196,7 +280,7 @@ func (s *Service) GetProviders(userID string) ([]database.Provider, error) {
 	return providers, err
 }
 
-func (s *Service) DisconnectProvider(userID string, providerID uint) error {
+func (s *Service) DisconnectProvider(ctx context.Context, userID string, providerID uint) error {
 	db, err := s.dbManager.GetDB(userID)
 	if err != nil {
 		return err
@@ -212,8 +296,20 @@ func (s *Service) DisconnectProvider(userID string, providerID uint) error {
 	}
 
 	// Set provider as inactive instead of deleting
+	before := provider
 	provider.IsActive = false
-	return db.Save(&provider).Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&provider).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionProviderDisconnected, TargetType: audit.TargetProvider, TargetID: provider.ID,
+			Before: before, After: provider,
+		}); err != nil {
+			return err
+		}
+		return webhooks.Emit(tx, userID, webhooks.EventProviderDisconnected, provider)
+	})
 }
 
 func (s *Service) getRedirectURI(providerType ProviderType) string {
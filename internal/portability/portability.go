@@ -0,0 +1,738 @@
+// Package portability exports the data of a user or workspace database to a
+// versioned zip archive and merges such archives into another database. The
+// archive serves data portability requests and moving accounts between SocGo
+// instances or storage backends.
+//
+// Archive layout (format version 2):
+//
+//	manifest.json        format, version, source and record counts
+//	providers.json       connected accounts; credentials only with secrets
+//	campaigns.json       campaigns posts and jobs refer to (since version 2)
+//	posts.json           posts with their review comments and tags
+//	scheduled_jobs.json  scheduled and past publishing jobs
+//	api_tokens.json      token metadata; hashes only with secrets
+//	webhooks.json        webhook endpoints; signing secrets only with secrets
+//	media/...            files from the tenant's media directory
+package portability
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// Format identifies SocGo archives in the manifest
+const Format = "socgo-export"
+
+// Version is the archive format version written by Export. Import accepts
+// archives up to this version.
+const Version = 2
+
+// maxEntrySize limits how much of a single archive entry is read on import
+const maxEntrySize = 256 << 20
+
+// ErrInvalidArchive is returned when an uploaded file is not a SocGo archive
+var ErrInvalidArchive = errors.New("not a valid SocGo export archive")
+
+// Manifest describes an archive
+type Manifest struct {
+	Format          string         `json:"format"`
+	Version         int            `json:"version"`
+	ExportedAt      time.Time      `json:"exported_at"`
+	Source          string         `json:"source"`
+	Driver          string         `json:"driver"`
+	IncludesSecrets bool           `json:"includes_secrets"`
+	Counts          map[string]int `json:"counts"`
+}
+
+// Provider is an exported provider. Config holds the OAuth credentials and is
+// only exported on request.
+type Provider struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	IsActive  bool      `json:"is_active"`
+	Config    string    `json:"config,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Campaign is an exported campaign
+type Campaign struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Color     string     `json:"color"`
+	StartsOn  *time.Time `json:"starts_on,omitempty"`
+	EndsOn    *time.Time `json:"ends_on,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Post is an exported post with its comments
+type Post struct {
+	ID          uint          `json:"id"`
+	Title       string        `json:"title"`
+	Content     string        `json:"content"`
+	ProviderID  uint          `json:"provider_id"`
+	CampaignID  *uint         `json:"campaign_id,omitempty"`
+	Tags        database.Tags `json:"tags,omitempty"`
+	Status      string        `json:"status"`
+	ReviewerID  string        `json:"reviewer_id,omitempty"`
+	ApprovedBy  string        `json:"approved_by,omitempty"`
+	ApprovedAt  *time.Time    `json:"approved_at,omitempty"`
+	PublishedAt *time.Time    `json:"published_at,omitempty"`
+	Comments    []Comment     `json:"comments,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// Comment is an exported review comment
+type Comment struct {
+	UserID    string    `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScheduledJob is an exported job
+type ScheduledJob struct {
+	ID          uint          `json:"id"`
+	JobType     string        `json:"job_type"`
+	PayloadData string        `json:"payload_data"`
+	ProviderID  uint          `json:"provider_id"`
+	PostID      *uint         `json:"post_id,omitempty"`
+	CampaignID  *uint         `json:"campaign_id,omitempty"`
+	Tags        database.Tags `json:"tags,omitempty"`
+	ScheduledAt time.Time     `json:"scheduled_at"`
+	ExecutedAt  *time.Time    `json:"executed_at,omitempty"`
+	Status      string        `json:"status"`
+	ErrorMsg    string        `json:"error_msg,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// APIToken is exported token metadata. Hash lets the token keep working on
+// another instance and is only exported on request.
+type APIToken struct {
+	ID        uint       `json:"id"`
+	UserID    string     `json:"user_id"`
+	Hash      string     `json:"hash,omitempty"`
+	Scope     string     `json:"scope,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// WebhookEndpoint is an exported webhook endpoint
+type WebhookEndpoint struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    string    `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportOptions controls what Export writes
+type ExportOptions struct {
+	// IncludeSecrets adds provider credentials, token hashes and webhook
+	// signing secrets
+	IncludeSecrets bool
+	// MediaDir is the tenant's media directory; skipped when empty or missing
+	MediaDir string
+}
+
+// ImportOptions controls how Import merges an archive
+type ImportOptions struct {
+	// SkipPendingJobs leaves out jobs that have not run yet, so posts are not
+	// published twice while the source instance is still running
+	SkipPendingJobs bool
+	// MediaDir receives the archive's media files
+	MediaDir string
+	// Registry is the registry database of the server. API tokens are only
+	// imported when it is set, and not when it already routes their hash.
+	Registry *gorm.DB
+}
+
+// ImportReport counts what Import created and what it matched or skipped
+type ImportReport struct {
+	ProvidersCreated int      `json:"providers_created"`
+	ProvidersMatched int      `json:"providers_matched"`
+	Campaigns        int      `json:"campaigns"`
+	Posts            int      `json:"posts"`
+	Jobs             int      `json:"jobs"`
+	Tokens           int      `json:"tokens"`
+	Webhooks         int      `json:"webhooks"`
+	Media            int      `json:"media"`
+	Skipped          int      `json:"skipped"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+// Export writes the data of the database key to w as a zip archive
+func Export(ctx context.Context, w io.Writer, db *gorm.DB, key, driver string, opts ExportOptions) (*Manifest, error) {
+	var providers []database.Provider
+	if err := db.WithContext(ctx).Where("user_id = ?", key).Order("id").Find(&providers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load providers: %w", err)
+	}
+	var campaigns []database.Campaign
+	if err := db.WithContext(ctx).Where("user_id = ?", key).Order("id").Find(&campaigns).Error; err != nil {
+		return nil, fmt.Errorf("failed to load campaigns: %w", err)
+	}
+	var posts []database.Post
+	if err := db.WithContext(ctx).Preload("Comments").Where("user_id = ?", key).Order("id").Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load posts: %w", err)
+	}
+	var jobs []database.ScheduledJob
+	// Feed polls are not publishing jobs and are not exported
+	if err := db.WithContext(ctx).Where("user_id = ? AND job_type = ?", key, database.JobTypePublishPost).Order("id").Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scheduled jobs: %w", err)
+	}
+	var tokens []database.APIToken
+	if err := db.WithContext(ctx).Order("id").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to load API tokens: %w", err)
+	}
+	var endpoints []database.WebhookEndpoint
+	if err := db.WithContext(ctx).Where("user_id = ?", key).Order("id").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to load webhook endpoints: %w", err)
+	}
+
+	manifest := &Manifest{
+		Format:          Format,
+		Version:         Version,
+		ExportedAt:      time.Now().UTC(),
+		Source:          key,
+		Driver:          driver,
+		IncludesSecrets: opts.IncludeSecrets,
+		Counts:          make(map[string]int),
+	}
+
+	exportedProviders := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		provider := Provider{ID: p.ID, Name: p.Name, Type: p.Type, IsActive: p.IsActive, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt}
+		if opts.IncludeSecrets {
+			provider.Config = p.Config
+		}
+		exportedProviders = append(exportedProviders, provider)
+	}
+
+	exportedCampaigns := make([]Campaign, 0, len(campaigns))
+	for _, c := range campaigns {
+		exportedCampaigns = append(exportedCampaigns, Campaign{
+			ID: c.ID, Name: c.Name, Color: c.Color, StartsOn: c.StartsOn, EndsOn: c.EndsOn, CreatedAt: c.CreatedAt,
+		})
+	}
+
+	exportedPosts := make([]Post, 0, len(posts))
+	for _, p := range posts {
+		post := Post{
+			ID: p.ID, Title: p.Title, Content: p.Content, ProviderID: p.ProviderID,
+			CampaignID: p.CampaignID, Tags: p.Tags, Status: p.Status,
+			ReviewerID: p.ReviewerID, ApprovedBy: p.ApprovedBy, ApprovedAt: p.ApprovedAt, PublishedAt: p.PublishedAt,
+			CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt,
+		}
+		for _, c := range p.Comments {
+			post.Comments = append(post.Comments, Comment{UserID: c.UserID, Body: c.Body, CreatedAt: c.CreatedAt})
+		}
+		exportedPosts = append(exportedPosts, post)
+	}
+
+	exportedJobs := make([]ScheduledJob, 0, len(jobs))
+	for _, j := range jobs {
+		exportedJobs = append(exportedJobs, ScheduledJob{
+			ID: j.ID, JobType: j.JobType, PayloadData: j.PayloadData, ProviderID: j.ProviderID, PostID: j.PostID,
+			CampaignID: j.CampaignID, Tags: j.Tags, ScheduledAt: j.ScheduledAt, ExecutedAt: j.ExecutedAt, Status: j.Status, ErrorMsg: j.ErrorMsg,
+			CreatedAt: j.CreatedAt, UpdatedAt: j.UpdatedAt,
+		})
+	}
+
+	exportedTokens := make([]APIToken, 0, len(tokens))
+	for _, t := range tokens {
+		token := APIToken{ID: t.ID, UserID: t.UserID, Scope: t.Scope, CreatedAt: t.CreatedAt, LastUsed: t.LastUsed}
+		if opts.IncludeSecrets {
+			token.Hash = t.Hash
+		}
+		exportedTokens = append(exportedTokens, token)
+	}
+
+	exportedEndpoints := make([]WebhookEndpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		endpoint := WebhookEndpoint{ID: e.ID, URL: e.URL, Events: e.Events, IsActive: e.IsActive, CreatedAt: e.CreatedAt}
+		if opts.IncludeSecrets {
+			endpoint.Secret = e.Secret
+		}
+		exportedEndpoints = append(exportedEndpoints, endpoint)
+	}
+
+	manifest.Counts["providers"] = len(exportedProviders)
+	manifest.Counts["campaigns"] = len(exportedCampaigns)
+	manifest.Counts["posts"] = len(exportedPosts)
+	manifest.Counts["scheduled_jobs"] = len(exportedJobs)
+	manifest.Counts["api_tokens"] = len(exportedTokens)
+	manifest.Counts["webhooks"] = len(exportedEndpoints)
+
+	archive := zip.NewWriter(w)
+	media, err := writeMedia(archive, opts.MediaDir)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Counts["media"] = media
+
+	entries := []struct {
+		name string
+		data interface{}
+	}{
+		{"manifest.json", manifest},
+		{"providers.json", exportedProviders},
+		{"campaigns.json", exportedCampaigns},
+		{"posts.json", exportedPosts},
+		{"scheduled_jobs.json", exportedJobs},
+		{"api_tokens.json", exportedTokens},
+		{"webhooks.json", exportedEndpoints},
+	}
+	for _, entry := range entries {
+		file, err := archive.Create(entry.name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entry.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", entry.name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Import merges the archive in r into the database key. Providers are matched
+// by name, everything else is added, skipping records that already exist, and
+// references between records are remapped to the new IDs. All records are
+// written in one transaction.
+func Import(ctx context.Context, r io.ReaderAt, size int64, db *gorm.DB, key string, opts ImportOptions) (*Manifest, *ImportReport, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, ErrInvalidArchive
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var manifest Manifest
+	if err := readJSON(files, "manifest.json", &manifest); err != nil || manifest.Format != Format {
+		return nil, nil, ErrInvalidArchive
+	}
+	if manifest.Version < 1 || manifest.Version > Version {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, manifest.Version)
+	}
+
+	var providers []Provider
+	var campaigns []Campaign
+	var posts []Post
+	var jobs []ScheduledJob
+	var tokens []APIToken
+	var endpoints []WebhookEndpoint
+	entries := map[string]interface{}{
+		"providers.json":      &providers,
+		"posts.json":          &posts,
+		"scheduled_jobs.json": &jobs,
+		"api_tokens.json":     &tokens,
+		"webhooks.json":       &endpoints,
+	}
+	if manifest.Version >= 2 {
+		entries["campaigns.json"] = &campaigns
+	}
+	for name, target := range entries {
+		if err := readJSON(files, name, target); err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+		}
+	}
+
+	report := &ImportReport{}
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		providerIDs, err := importProviders(tx, key, providers, report)
+		if err != nil {
+			return err
+		}
+		campaignIDs, err := importCampaigns(tx, key, campaigns, report)
+		if err != nil {
+			return err
+		}
+		postIDs, err := importPosts(tx, key, posts, providerIDs, campaignIDs, report)
+		if err != nil {
+			return err
+		}
+		if err := importJobs(tx, key, jobs, providerIDs, postIDs, campaignIDs, opts, report); err != nil {
+			return err
+		}
+		if err := importTokens(tx, key, tokens, opts.Registry, report); err != nil {
+			return err
+		}
+		return importWebhooks(tx, key, endpoints, report)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts.MediaDir != "" {
+		count, err := extractMedia(archive, opts.MediaDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		report.Media = count
+	}
+	return &manifest, report, nil
+}
+
+func importProviders(tx *gorm.DB, key string, providers []Provider, report *ImportReport) (map[uint]uint, error) {
+	ids := make(map[uint]uint, len(providers))
+	for _, p := range providers {
+		// Provider names stay taken while in the trash
+		var existing database.Provider
+		result := tx.Unscoped().Where("user_id = ? AND name = ?", key, p.Name).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			ids[p.ID] = existing.ID
+			report.ProvidersMatched++
+			continue
+		}
+
+		active := p.IsActive && p.Config != ""
+		provider := database.Provider{
+			Name: p.Name, Type: p.Type, Config: p.Config, UserID: key,
+			IsActive: active, CreatedAt: p.CreatedAt,
+		}
+		if err := tx.Create(&provider).Error; err != nil {
+			return nil, fmt.Errorf("failed to import provider %s: %w", p.Name, err)
+		}
+		// Create applies the column default to a false IsActive
+		if !active {
+			if err := tx.Model(&provider).Update("is_active", false).Error; err != nil {
+				return nil, err
+			}
+		}
+		if p.Config == "" {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("provider %s was imported without credentials and must be reconnected", p.Name))
+		}
+		ids[p.ID] = provider.ID
+		report.ProvidersCreated++
+	}
+	return ids, nil
+}
+
+// importCampaigns matches campaigns by name and creates the missing ones
+func importCampaigns(tx *gorm.DB, key string, campaigns []Campaign, report *ImportReport) (map[uint]uint, error) {
+	ids := make(map[uint]uint, len(campaigns))
+	for _, c := range campaigns {
+		var existing database.Campaign
+		result := tx.Where("user_id = ? AND name = ?", key, c.Name).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			ids[c.ID] = existing.ID
+			report.Skipped++
+			continue
+		}
+
+		campaign := database.Campaign{
+			Name: c.Name, Color: c.Color, StartsOn: c.StartsOn, EndsOn: c.EndsOn, UserID: key, CreatedAt: c.CreatedAt,
+		}
+		if err := tx.Create(&campaign).Error; err != nil {
+			return nil, fmt.Errorf("failed to import campaign %s: %w", c.Name, err)
+		}
+		ids[c.ID] = campaign.ID
+		report.Campaigns++
+	}
+	return ids, nil
+}
+
+// remapCampaign returns the imported ID of an exported campaign reference
+func remapCampaign(campaignIDs map[uint]uint, id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+	if newID, ok := campaignIDs[*id]; ok {
+		return &newID
+	}
+	return nil
+}
+
+func importPosts(tx *gorm.DB, key string, posts []Post, providerIDs, campaignIDs map[uint]uint, report *ImportReport) (map[uint]uint, error) {
+	ids := make(map[uint]uint, len(posts))
+	for _, p := range posts {
+		providerID := providerIDs[p.ProviderID]
+
+		var existing database.Post
+		result := tx.Where("user_id = ? AND content = ? AND provider_id = ? AND created_at = ?", key, p.Content, providerID, p.CreatedAt).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			ids[p.ID] = existing.ID
+			report.Skipped++
+			continue
+		}
+
+		post := database.Post{
+			Title: p.Title, Content: p.Content, UserID: key, ProviderID: providerID,
+			CampaignID: remapCampaign(campaignIDs, p.CampaignID), Tags: database.NormalizeTags(p.Tags), Status: p.Status,
+			ReviewerID: p.ReviewerID, ApprovedBy: p.ApprovedBy, ApprovedAt: p.ApprovedAt, PublishedAt: p.PublishedAt,
+			CreatedAt: p.CreatedAt,
+		}
+		if post.Status == "" {
+			post.Status = database.PostStatusPublished
+		}
+		if err := tx.Create(&post).Error; err != nil {
+			return nil, fmt.Errorf("failed to import post %d: %w", p.ID, err)
+		}
+		for _, c := range p.Comments {
+			comment := database.PostComment{PostID: post.ID, UserID: c.UserID, Body: c.Body, CreatedAt: c.CreatedAt}
+			if err := tx.Create(&comment).Error; err != nil {
+				return nil, err
+			}
+		}
+		ids[p.ID] = post.ID
+		report.Posts++
+	}
+	return ids, nil
+}
+
+func importJobs(tx *gorm.DB, key string, jobs []ScheduledJob, providerIDs, postIDs, campaignIDs map[uint]uint, opts ImportOptions, report *ImportReport) error {
+	for _, j := range jobs {
+		if opts.SkipPendingJobs && j.Status == database.JobStatusPending {
+			report.Skipped++
+			continue
+		}
+		providerID, ok := providerIDs[j.ProviderID]
+		if !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("job %d skipped: its provider is not in the archive", j.ID))
+			report.Skipped++
+			continue
+		}
+
+		var existing int64
+		if err := tx.Model(&database.ScheduledJob{}).
+			Where("user_id = ? AND provider_id = ? AND scheduled_at = ? AND payload_data = ?", key, providerID, j.ScheduledAt, j.PayloadData).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			report.Skipped++
+			continue
+		}
+
+		job := database.ScheduledJob{
+			JobType: j.JobType, PayloadData: j.PayloadData, UserID: key, ProviderID: providerID,
+			CampaignID: remapCampaign(campaignIDs, j.CampaignID), Tags: database.NormalizeTags(j.Tags), ScheduledAt: j.ScheduledAt, ExecutedAt: j.ExecutedAt, Status: j.Status, ErrorMsg: j.ErrorMsg,
+			CreatedAt: j.CreatedAt,
+		}
+		if j.PostID != nil {
+			if postID, ok := postIDs[*j.PostID]; ok {
+				job.PostID = &postID
+			}
+		}
+		if err := tx.Create(&job).Error; err != nil {
+			return fmt.Errorf("failed to import job %d: %w", j.ID, err)
+		}
+		report.Jobs++
+	}
+	return nil
+}
+
+// importTokens recreates tokens exported with their hashes as tokens of the
+// importing user; tokens without a hash cannot be used and are skipped.
+// Tokens are personal, so none are imported into a workspace, and a hash the
+// registry already routes stays with the account holding it.
+func importTokens(tx *gorm.DB, key string, tokens []APIToken, registry *gorm.DB, report *ImportReport) error {
+	for _, t := range tokens {
+		if t.Hash == "" {
+			report.Skipped++
+			continue
+		}
+		if registry == nil || database.IsWorkspaceKey(key) {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("API token %d skipped: tokens are only imported into a personal account", t.ID))
+			report.Skipped++
+			continue
+		}
+
+		var route database.TokenRoute
+		result := registry.Where("hash = ?", t.Hash).Limit(1).Find(&route)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 && route.TenantKey != key {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("API token %d skipped: it is in use by another account", t.ID))
+			report.Skipped++
+			continue
+		}
+		var existing int64
+		if err := tx.Model(&database.APIToken{}).Unscoped().Where("hash = ?", t.Hash).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			report.Skipped++
+			continue
+		}
+		token := database.APIToken{Hash: t.Hash, UserID: key, Scope: t.Scope, CreatedAt: t.CreatedAt, LastUsed: t.LastUsed}
+		if err := tx.Create(&token).Error; err != nil {
+			return fmt.Errorf("failed to import API token %d: %w", t.ID, err)
+		}
+		report.Tokens++
+	}
+	return nil
+}
+
+// importWebhooks adds endpoints not registered yet; endpoints exported
+// without their secret get a new one
+func importWebhooks(tx *gorm.DB, key string, endpoints []WebhookEndpoint, report *ImportReport) error {
+	for _, e := range endpoints {
+		var existing int64
+		if err := tx.Model(&database.WebhookEndpoint{}).Where("user_id = ? AND url = ?", key, e.URL).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			report.Skipped++
+			continue
+		}
+
+		secret := e.Secret
+		if secret == "" {
+			buf := make([]byte, 32)
+			if _, err := rand.Read(buf); err != nil {
+				return err
+			}
+			secret = hex.EncodeToString(buf)
+			report.Warnings = append(report.Warnings, fmt.Sprintf("webhook %s got a new signing secret", e.URL))
+		}
+
+		endpoint := database.WebhookEndpoint{UserID: key, URL: e.URL, Secret: secret, Events: e.Events, IsActive: e.IsActive, CreatedAt: e.CreatedAt}
+		if err := tx.Create(&endpoint).Error; err != nil {
+			return fmt.Errorf("failed to import webhook %s: %w", e.URL, err)
+		}
+		if !e.IsActive {
+			if err := tx.Model(&endpoint).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		report.Webhooks++
+	}
+	return nil
+}
+
+func readJSON(files map[string]*zip.File, name string, v interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("missing %s", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(io.LimitReader(rc, maxEntrySize)).Decode(v)
+}
+
+// writeMedia adds every file below dir to the archive under media/
+func writeMedia(archive *zip.Writer, dir string) (int, error) {
+	if dir == "" {
+		return 0, nil
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := archive.Create("media/" + filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to export media: %w", err)
+	}
+	return count, nil
+}
+
+// extractMedia writes the archive's media/ entries below dir, keeping files
+// that already exist
+func extractMedia(archive *zip.Reader, dir string) (int, error) {
+	count := 0
+	for _, file := range archive.File {
+		name, ok := strings.CutPrefix(file.Name, "media/")
+		if !ok || name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		name = path.Clean(name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return count, fmt.Errorf("%w: unsafe media path %s", ErrInvalidArchive, file.Name)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return count, err
+		}
+
+		if err := extractFile(file, target); err != nil {
+			return count, fmt.Errorf("failed to import media %s: %w", name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func extractFile(file *zip.File, target string) error {
+	in, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, io.LimitReader(in, maxEntrySize)); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
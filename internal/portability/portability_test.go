@@ -0,0 +1,297 @@
+package portability
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+func seed(t *testing.T, db *gorm.DB, key string) {
+	t.Helper()
+	provider := database.Provider{Name: "main", Type: "mastodon", Config: `{"access_token":"secret"}`, UserID: key, IsActive: true}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	campaign := database.Campaign{Name: "Launch", Color: "#ff0000", UserID: key}
+	if err := db.Create(&campaign).Error; err != nil {
+		t.Fatalf("Failed to create campaign: %v", err)
+	}
+	post := database.Post{
+		Title: "Hello", Content: "Hello world", UserID: key, ProviderID: provider.ID, Status: database.PostStatusPublished,
+		CampaignID: &campaign.ID, Tags: database.Tags{"launch", "news"},
+	}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	db.Create(&database.PostComment{PostID: post.ID, UserID: key, Body: "Looks good"})
+	db.Create(&database.ScheduledJob{
+		JobType: "publish_post", PayloadData: `{"content":"Later"}`, UserID: key, ProviderID: provider.ID,
+		PostID: &post.ID, ScheduledAt: time.Now().Add(time.Hour).Truncate(time.Second), Status: database.JobStatusPending,
+	})
+	db.Create(&database.ScheduledJob{
+		JobType: "publish_post", PayloadData: `{"content":"Earlier"}`, UserID: key, ProviderID: provider.ID,
+		ScheduledAt: time.Now().Add(-time.Hour).Truncate(time.Second), Status: database.JobStatusCompleted,
+	})
+	db.Create(&database.APIToken{Hash: "abc123", UserID: key})
+	db.Create(&database.WebhookEndpoint{UserID: key, URL: "https://example.com/hook", Secret: "whsec", Events: "post.published", IsActive: true})
+}
+
+func export(t *testing.T, db *gorm.DB, key string, opts ExportOptions) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Export(context.Background(), &buf, db, key, database.DriverSQLite, opts); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExport_OmitsSecretsByDefault(t *testing.T) {
+	manager := database.NewTestManager(t)
+	defer manager.Close()
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	seed(t, db, "alice")
+
+	archive := export(t, db, "alice", ExportOptions{})
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Export is not a zip archive: %v", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+
+	var manifest Manifest
+	if err := readJSON(files, "manifest.json", &manifest); err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if manifest.Format != Format || manifest.Version != Version || manifest.IncludesSecrets {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+	if manifest.Counts["posts"] != 1 || manifest.Counts["scheduled_jobs"] != 2 {
+		t.Errorf("Unexpected counts: %v", manifest.Counts)
+	}
+
+	for _, name := range []string{"providers.json", "api_tokens.json", "webhooks.json"} {
+		rc, _ := files[name].Open()
+		var buf bytes.Buffer
+		buf.ReadFrom(rc)
+		rc.Close()
+		for _, secret := range []string{"access_token", "abc123", "whsec"} {
+			if bytes.Contains(buf.Bytes(), []byte(secret)) {
+				t.Errorf("%s contains secret %q", name, secret)
+			}
+		}
+	}
+}
+
+func TestImport_MergesIntoAnotherDatabase(t *testing.T) {
+	manager := database.NewTestManager(t)
+	defer manager.Close()
+	ctx := context.Background()
+
+	source, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	seed(t, source, "alice")
+
+	mediaDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(mediaDir, "img"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(mediaDir, "img", "cat.png"), []byte("png"), 0644)
+	archive := export(t, source, "alice", ExportOptions{IncludeSecrets: true, MediaDir: mediaDir})
+
+	target, err := manager.GetDB("bob")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	// Bob already connected a provider with the same name and another post
+	existing := database.Provider{Name: "main", Type: "mastodon", Config: "{}", UserID: "bob", IsActive: true}
+	target.Create(&existing)
+	target.Create(&database.Post{Content: "Bob's own", UserID: "bob", ProviderID: existing.ID, Status: database.PostStatusPublished})
+
+	registry, err := manager.GetRegistryDB()
+	if err != nil {
+		t.Fatalf("GetRegistryDB failed: %v", err)
+	}
+	targetMedia := t.TempDir()
+	opts := ImportOptions{MediaDir: targetMedia, Registry: registry}
+	manifest, report, err := Import(ctx, bytes.NewReader(archive), int64(len(archive)), target, "bob", opts)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if manifest.Source != "alice" {
+		t.Errorf("Expected source alice, got %s", manifest.Source)
+	}
+	if report.ProvidersMatched != 1 || report.ProvidersCreated != 0 || report.Campaigns != 1 || report.Posts != 1 || report.Jobs != 2 ||
+		report.Tokens != 0 || report.Webhooks != 1 || report.Media != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	// Alice's token stays hers
+	var tokens int64
+	target.Model(&database.APIToken{}).Count(&tokens)
+	if tokens != 0 || len(report.Warnings) != 1 {
+		t.Errorf("Expected alice's token to be skipped, got %d tokens and warnings %v", tokens, report.Warnings)
+	}
+
+	var posts []database.Post
+	target.Preload("Comments").Where("user_id = ?", "bob").Order("id").Find(&posts)
+	if len(posts) != 2 || posts[1].Content != "Hello world" || posts[1].ProviderID != existing.ID || len(posts[1].Comments) != 1 {
+		t.Fatalf("Unexpected posts after import: %+v", posts)
+	}
+	var campaign database.Campaign
+	if posts[1].CampaignID == nil || target.First(&campaign, *posts[1].CampaignID).Error != nil ||
+		campaign.Name != "Launch" || campaign.UserID != "bob" || posts[1].Tags.String() != "launch,news" {
+		t.Errorf("Campaign and tags were not imported: %+v, %+v", posts[1], campaign)
+	}
+
+	var job database.ScheduledJob
+	target.Where("user_id = ? AND status = ?", "bob", database.JobStatusPending).First(&job)
+	if job.PostID == nil || *job.PostID != posts[1].ID || job.ProviderID != existing.ID {
+		t.Errorf("Job references were not remapped: %+v", job)
+	}
+
+	var endpoint database.WebhookEndpoint
+	target.Where("user_id = ?", "bob").First(&endpoint)
+	if endpoint.Secret != "whsec" {
+		t.Errorf("Expected the exported webhook secret, got %q", endpoint.Secret)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(targetMedia, "img", "cat.png")); err != nil || string(data) != "png" {
+		t.Errorf("Media file not imported: %q, %v", data, err)
+	}
+
+	// Importing the same archive again adds nothing
+	_, again, err := Import(ctx, bytes.NewReader(archive), int64(len(archive)), target, "bob", opts)
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if again.Posts != 0 || again.Jobs != 0 || again.Tokens != 0 || again.Webhooks != 0 || again.Media != 0 {
+		t.Errorf("Expected the second import to skip everything, got %+v", again)
+	}
+}
+
+func TestImport_TokensFromAnotherServer(t *testing.T) {
+	ctx := context.Background()
+	other := database.NewTestManager(t)
+	defer other.Close()
+	source, _ := other.GetDB("alice")
+	seed(t, source, "alice")
+	archive := export(t, source, "alice", ExportOptions{IncludeSecrets: true})
+
+	manager := database.NewTestManager(t)
+	defer manager.Close()
+	registry, err := manager.GetRegistryDB()
+	if err != nil {
+		t.Fatalf("GetRegistryDB failed: %v", err)
+	}
+
+	// Tokens are personal and never land in a workspace
+	workspace, _ := manager.GetDB("workspace_1")
+	_, report, err := Import(ctx, bytes.NewReader(archive), int64(len(archive)), workspace, "workspace_1", ImportOptions{Registry: registry})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if report.Tokens != 0 {
+		t.Errorf("Expected no tokens in a workspace, got %+v", report)
+	}
+
+	target, _ := manager.GetDB("bob")
+	_, report, err = Import(ctx, bytes.NewReader(archive), int64(len(archive)), target, "bob", ImportOptions{Registry: registry})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	var token database.APIToken
+	if report.Tokens != 1 || target.First(&token).Error != nil || token.UserID != "bob" {
+		t.Errorf("Expected the token to be imported for bob, got %+v, %+v", report, token)
+	}
+	route, err := manager.LookupToken(token.Hash)
+	if err != nil || route.TenantKey != "bob" {
+		t.Errorf("Expected the token to route to bob, got %+v, %v", route, err)
+	}
+}
+
+func TestImport_WithoutSecrets(t *testing.T) {
+	manager := database.NewTestManager(t)
+	defer manager.Close()
+	ctx := context.Background()
+
+	source, _ := manager.GetDB("alice")
+	seed(t, source, "alice")
+	archive := export(t, source, "alice", ExportOptions{})
+
+	target, _ := manager.GetDB("carol")
+	_, report, err := Import(ctx, bytes.NewReader(archive), int64(len(archive)), target, "carol", ImportOptions{SkipPendingJobs: true})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if report.ProvidersCreated != 1 || report.Jobs != 1 || report.Tokens != 0 || report.Webhooks != 1 || len(report.Warnings) != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	var provider database.Provider
+	target.Where("user_id = ?", "carol").First(&provider)
+	if provider.IsActive {
+		t.Error("A provider imported without credentials should be inactive")
+	}
+	var endpoint database.WebhookEndpoint
+	target.Where("user_id = ?", "carol").First(&endpoint)
+	if endpoint.Secret == "" {
+		t.Error("Expected a new webhook secret")
+	}
+}
+
+func TestImport_RejectsInvalidArchives(t *testing.T) {
+	manager := database.NewTestManager(t)
+	defer manager.Close()
+	db, _ := manager.GetDB("alice")
+
+	unsafe := func() []byte {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		for _, name := range []string{"providers.json", "posts.json", "scheduled_jobs.json", "api_tokens.json", "webhooks.json"} {
+			w, _ := archive.Create(name)
+			w.Write([]byte("[]"))
+		}
+		w, _ := archive.Create("manifest.json")
+		w.Write([]byte(`{"format":"socgo-export","version":1}`))
+		w, _ = archive.Create("media/../../escape.txt")
+		w.Write([]byte("nope"))
+		archive.Close()
+		return buf.Bytes()
+	}()
+
+	future := func() []byte {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		w, _ := archive.Create("manifest.json")
+		w.Write([]byte(`{"format":"socgo-export","version":99}`))
+		archive.Close()
+		return buf.Bytes()
+	}()
+
+	for name, data := range map[string][]byte{
+		"not a zip":      []byte("plain text"),
+		"future version": future,
+		"unsafe path":    unsafe,
+	} {
+		mediaDir := filepath.Join(t.TempDir(), "media")
+		_, _, err := Import(context.Background(), bytes.NewReader(data), int64(len(data)), db, "alice", ImportOptions{MediaDir: mediaDir})
+		if !errors.Is(err, ErrInvalidArchive) {
+			t.Errorf("%s: expected ErrInvalidArchive, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+// Package posttemplates manages post templates: posts whose content is a
+// text/template rendered with variables on instantiation, with default
+// providers, tags and schedule offset. Instantiations can be previewed and
+// validated per provider before they are scheduled.
+package posttemplates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+	"github.com/tkowalski/socgo/internal/providers"
+	"github.com/tkowalski/socgo/internal/snippets"
+	"github.com/tkowalski/socgo/internal/webhooks"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when the template does not exist
+	ErrNotFound = errors.New("post template not found")
+	// ErrInvalid wraps validation errors of an Input or Request
+	ErrInvalid = errors.New("invalid post template")
+)
+
+// funcs keep the publish-time placeholders of package snippets, which are
+// not template variables, in the rendered content: {{date}} renders as
+// {{date}} and {{snippet "Name"}} as {{snippet:Name}}
+var funcs = template.FuncMap{
+	snippets.VarDate:     placeholder(snippets.VarDate),
+	snippets.VarTime:     placeholder(snippets.VarTime),
+	snippets.VarProvider: placeholder(snippets.VarProvider),
+	snippets.VarCampaign: placeholder(snippets.VarCampaign),
+	snippets.VarTags:     placeholder(snippets.VarTags),
+	"snippet":            func(name string) string { return "{{snippet:" + name + "}}" },
+	"upper":              strings.ToUpper,
+	"lower":              strings.ToLower,
+	"trim":               strings.TrimSpace,
+}
+
+func placeholder(name string) func() string {
+	return func() string { return "{{" + name + "}}" }
+}
+
+// Input holds the editable fields of a template
+type Input struct {
+	Name           string          `json:"name"`
+	Content        string          `json:"content"`
+	ProviderIDs    database.IDList `json:"provider_ids"`
+	ScheduleOffset string          `json:"schedule_offset"`
+	Tags           database.Tags   `json:"tags"`
+}
+
+// Request instantiates a template. ProviderIDs and ScheduleAt override the
+// template's providers and schedule offset when set.
+type Request struct {
+	Variables   map[string]string `json:"variables"`
+	ProviderIDs database.IDList   `json:"provider_ids"`
+	ScheduleAt  string            `json:"schedule_at"`
+}
+
+// Target is the post an instantiation publishes to one provider
+type Target struct {
+	ProviderID uint   `json:"provider_id"`
+	Provider   string `json:"provider"`
+	// Content is the rendered content with its placeholders expanded as
+	// they would be if it were published now
+	Content string `json:"content"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Preview is the result of instantiating a template
+type Preview struct {
+	TemplateID uint          `json:"template_id"`
+	Content    string        `json:"content"`
+	ScheduleAt time.Time     `json:"schedule_at"`
+	Tags       database.Tags `json:"tags"`
+	Targets    []Target      `json:"targets"`
+	// Errors lists the problems that prevent scheduling
+	Errors []string `json:"errors"`
+}
+
+// Valid reports whether the preview can be scheduled
+func (p *Preview) Valid() bool {
+	return len(p.Errors) == 0
+}
+
+// List returns the templates of userID ordered by name
+func List(db *gorm.DB, userID string) ([]database.PostTemplate, error) {
+	var templates []database.PostTemplate
+	err := db.Where("user_id = ?", userID).Order("name").Find(&templates).Error
+	return templates, err
+}
+
+// Get returns the template id of userID
+func Get(db *gorm.DB, userID string, id uint) (*database.PostTemplate, error) {
+	var tpl database.PostTemplate
+	result := db.Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&tpl)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &tpl, nil
+}
+
+// Create adds a template for userID
+func Create(ctx context.Context, db *gorm.DB, userID string, in Input) (*database.PostTemplate, error) {
+	tpl := &database.PostTemplate{UserID: userID}
+	if err := apply(db, tpl, in); err != nil {
+		return nil, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tpl).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionTemplateCreated, TargetType: audit.TargetTemplate, TargetID: tpl.ID, After: tpl,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// Update replaces the fields of the template id with in
+func Update(ctx context.Context, db *gorm.DB, userID string, id uint, in Input) (*database.PostTemplate, error) {
+	tpl, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *tpl
+	if err := apply(db, tpl, in); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(tpl).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionTemplateUpdated, TargetType: audit.TargetTemplate, TargetID: tpl.ID, Before: before, After: tpl,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// Delete removes the template id. Jobs scheduled from it are kept.
+func Delete(ctx context.Context, db *gorm.DB, userID string, id uint) error {
+	tpl, err := Get(db, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(tpl).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionTemplateDeleted, TargetType: audit.TargetTemplate, TargetID: id, Before: tpl,
+		})
+	})
+}
+
+// Variables returns the names of the variables content uses, sorted
+func Variables(content string) ([]string, error) {
+	tmpl, err := parseContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			seen[n.Ident[0]] = true
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(tmpl.Tree.Root)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Render executes content with vars. Every variable content uses must be
+// set.
+func Render(content string, vars map[string]string) (string, error) {
+	tmpl, err := parseContent(content)
+	if err != nil {
+		return "", err
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		var execErr template.ExecError
+		if errors.As(err, &execErr) {
+			err = execErr.Err
+		}
+		return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return sb.String(), nil
+}
+
+// Instantiate renders the template id with req and validates the result for
+// each target provider. Problems are reported in the preview's Errors; the
+// error is only set when the preview cannot be built.
+func Instantiate(db *gorm.DB, userID string, id uint, req Request, now time.Time) (*Preview, error) {
+	tpl, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{TemplateID: tpl.ID, Tags: tpl.Tags, Targets: []Target{}, Errors: []string{}}
+	if preview.ScheduleAt, err = scheduleAt(tpl, req.ScheduleAt, now); err != nil {
+		preview.Errors = append(preview.Errors, err.Error())
+	}
+
+	preview.Content, err = Render(tpl.Content, req.Variables)
+	if err != nil {
+		if !errors.Is(err, ErrInvalid) {
+			return nil, err
+		}
+		preview.Errors = append(preview.Errors, strings.TrimPrefix(err.Error(), ErrInvalid.Error()+": "))
+		return preview, nil
+	}
+
+	providerIDs := tpl.ProviderIDs
+	if len(req.ProviderIDs) > 0 {
+		providerIDs = req.ProviderIDs
+	}
+	if len(providerIDs) == 0 {
+		preview.Errors = append(preview.Errors, "no providers selected")
+		return preview, nil
+	}
+
+	vars, err := snippets.PostVars(db, userID, nil, tpl.Tags)
+	if err != nil {
+		return nil, err
+	}
+	for _, providerID := range providerIDs {
+		target := Target{ProviderID: providerID}
+		var provider database.Provider
+		result := db.Where("user_id = ? AND id = ?", userID, providerID).Limit(1).Find(&provider)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			target.Error = fmt.Sprintf("provider %d not found", providerID)
+		} else {
+			target.Provider = provider.Name
+			vars[snippets.VarProvider] = provider.Name
+			if target.Content, err = snippets.Expand(db, userID, preview.Content, preview.ScheduleAt, vars); err != nil {
+				return nil, err
+			}
+			if err := providers.ValidateContent(providers.ProviderType(provider.Name), target.Content); err != nil {
+				target.Error = err.Error()
+			}
+		}
+		if target.Error != "" {
+			preview.Errors = append(preview.Errors, target.Error)
+		}
+		preview.Targets = append(preview.Targets, target)
+	}
+	return preview, nil
+}
+
+// Schedule instantiates the template id and creates a pending job for each
+// target provider. When the preview has errors nothing is scheduled and the
+// preview is returned with an error wrapping ErrInvalid.
+func Schedule(ctx context.Context, db *gorm.DB, userID string, id uint, req Request, now time.Time) (*Preview, []database.ScheduledJob, error) {
+	preview, err := Instantiate(db, userID, id, req, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, target := range preview.Targets {
+		if target.Error != "" {
+			continue
+		}
+		if err := limits.Validate(db, userID, target.ProviderID, preview.ScheduleAt, 0); err != nil {
+			if !errors.Is(err, limits.ErrConflict) {
+				return nil, nil, err
+			}
+			preview.Targets[i].Error = fmt.Sprintf("%s: %v", target.Provider, err)
+			preview.Errors = append(preview.Errors, preview.Targets[i].Error)
+		}
+	}
+	if !preview.Valid() {
+		return preview, nil, fmt.Errorf("%w: %s", ErrInvalid, strings.Join(preview.Errors, "; "))
+	}
+
+	jobs := make([]database.ScheduledJob, 0, len(preview.Targets))
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, target := range preview.Targets {
+			// The job keeps the placeholders, they are expanded at publish time
+			job := database.ScheduledJob{
+				JobType:     "publish_post",
+				PayloadData: preview.Content,
+				UserID:      userID,
+				ProviderID:  target.ProviderID,
+				ScheduledAt: preview.ScheduleAt,
+				Tags:        preview.Tags,
+				Status:      database.JobStatusPending,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			if err := tx.Create(&job).Error; err != nil {
+				return err
+			}
+			if err := audit.Record(ctx, tx, audit.Entry{
+				Action: audit.ActionJobScheduled, TargetType: audit.TargetJob, TargetID: job.ID, After: job,
+			}); err != nil {
+				return err
+			}
+			if err := webhooks.Emit(tx, userID, webhooks.EventJobScheduled, job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return preview, jobs, nil
+}
+
+// scheduleAt returns the time an instantiation is scheduled at: value, in
+// RFC 3339 or as sent by datetime-local inputs, or now plus the template's
+// offset
+func scheduleAt(tpl *database.PostTemplate, value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		offset, _ := time.ParseDuration(tpl.ScheduleOffset)
+		return now.Add(offset), nil
+	}
+
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if at, err = time.ParseInLocation("2006-01-02T15:04", value, time.Local); err != nil {
+			return now, fmt.Errorf("invalid schedule_at, use ISO8601")
+		}
+	}
+	if at.Before(now) {
+		return at, fmt.Errorf("schedule_at must be in the future")
+	}
+	return at, nil
+}
+
+func parseContent(content string) (*template.Template, error) {
+	tmpl, err := template.New("post").Funcs(funcs).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return tmpl, nil
+}
+
+// apply validates in and copies it onto tpl
+func apply(db *gorm.DB, tpl *database.PostTemplate, in Input) error {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if strings.TrimSpace(in.Content) == "" {
+		return fmt.Errorf("%w: content is required", ErrInvalid)
+	}
+	if _, err := parseContent(in.Content); err != nil {
+		return err
+	}
+
+	offset := strings.TrimSpace(in.ScheduleOffset)
+	if offset != "" {
+		duration, err := time.ParseDuration(offset)
+		if err != nil || duration < 0 {
+			return fmt.Errorf("%w: schedule_offset must be a positive duration such as 24h", ErrInvalid)
+		}
+	}
+
+	providerIDs := database.IDList{}
+	for _, id := range in.ProviderIDs {
+		var count int64
+		if err := db.Model(&database.Provider{}).Where("user_id = ? AND id = ?", tpl.UserID, id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: provider %d not found", ErrInvalid, id)
+		}
+		providerIDs = append(providerIDs, id)
+	}
+
+	var taken int64
+	if err := db.Model(&database.PostTemplate{}).
+		Where("user_id = ? AND name = ? AND id <> ?", tpl.UserID, name, tpl.ID).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("%w: a template named %q already exists", ErrInvalid, name)
+	}
+
+	tpl.Name = name
+	tpl.Content = in.Content
+	tpl.ProviderIDs = providerIDs
+	tpl.ScheduleOffset = offset
+	tpl.Tags = database.NormalizeTags(in.Tags)
+	return nil
+}
@@ -0,0 +1,113 @@
+package posttemplates
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/limits"
+)
+
+func TestRender_VariablesAndPlaceholders(t *testing.T) {
+	content := `{{upper .product}} is here{{if .url}}: {{.url}}{{end}} {{snippet "Footer"}} {{tags}}`
+
+	variables, err := Variables(content)
+	if err != nil {
+		t.Fatalf("Variables failed: %v", err)
+	}
+	if !reflect.DeepEqual(variables, []string{"product", "url"}) {
+		t.Errorf("Variables = %v", variables)
+	}
+
+	got, err := Render(content, map[string]string{"product": "Socgo", "url": "https://example.com"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := "SOCGO is here: https://example.com {{snippet:Footer}} {{tags}}"; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+
+	if _, err := Render(content, map[string]string{"url": ""}); !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "product") {
+		t.Errorf("Expected a missing variable to be reported, got %v", err)
+	}
+	if _, err := Create(context.Background(), database.NewTestDB(t, "alice"), "alice", Input{Name: "Broken", Content: "{{.product"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a template that does not parse to be rejected, got %v", err)
+	}
+}
+
+func TestSchedule_ValidatesPerProvider(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	facebook := database.Provider{Name: "facebook", Type: "facebook", UserID: "alice", IsActive: true}
+	instagram := database.Provider{Name: "instagram", Type: "instagram", UserID: "alice", IsActive: true}
+	db.Create(&facebook)
+	db.Create(&instagram)
+
+	tpl, err := Create(ctx, db, "alice", Input{
+		Name:           "Launch",
+		Content:        "{{.product}} launches {{date}} {{tags}}",
+		ProviderIDs:    database.IDList{facebook.ID, instagram.ID},
+		ScheduleOffset: "24h",
+		Tags:           database.Tags{"Launch"},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := Create(ctx, db, "alice", Input{Name: "Other", Content: "x", ProviderIDs: database.IDList{99}}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected an unknown provider to be rejected, got %v", err)
+	}
+	if _, err := Create(ctx, db, "alice", Input{Name: "Other", Content: "x", ScheduleOffset: "tomorrow"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected an invalid offset to be rejected, got %v", err)
+	}
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	preview, err := Instantiate(db, "alice", tpl.ID, Request{Variables: map[string]string{"product": "Socgo"}}, now)
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	if !preview.Valid() || !preview.ScheduleAt.Equal(now.Add(24*time.Hour)) || len(preview.Targets) != 2 {
+		t.Fatalf("Unexpected preview: %+v", preview)
+	}
+	if preview.Targets[0].Content != "Socgo launches 2026-03-02 #launch" || preview.Content != "Socgo launches {{date}} {{tags}}" {
+		t.Errorf("Unexpected preview content: %q, %q", preview.Content, preview.Targets[0].Content)
+	}
+
+	// Instagram rejects long captions; nothing is scheduled then
+	long := Request{Variables: map[string]string{"product": strings.Repeat("a", 2300)}}
+	preview, jobs, err := Schedule(ctx, db, "alice", tpl.ID, long, now)
+	if !errors.Is(err, ErrInvalid) || len(jobs) != 0 || preview == nil || preview.Targets[0].Error != "" || preview.Targets[1].Error == "" {
+		t.Fatalf("Expected the instagram target to fail validation, got %v %+v", err, preview)
+	}
+
+	at := now.Add(2 * time.Hour)
+	_, jobs, err = Schedule(ctx, db, "alice", tpl.ID, Request{
+		Variables:   map[string]string{"product": "Socgo"},
+		ProviderIDs: database.IDList{facebook.ID},
+		ScheduleAt:  at.Format(time.RFC3339),
+	}, now)
+	if err != nil {
+		t.Fatalf("Schedule failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ProviderID != facebook.ID || !jobs[0].ScheduledAt.Equal(at) || jobs[0].PayloadData != "Socgo launches {{date}} {{tags}}" || jobs[0].Tags.String() != "launch" {
+		t.Errorf("Unexpected jobs: %+v", jobs)
+	}
+	if _, _, err := Schedule(ctx, db, "alice", tpl.ID, Request{Variables: map[string]string{"product": "x"}, ScheduleAt: now.Add(-time.Hour).Format(time.RFC3339)}, now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a past schedule_at to be rejected, got %v", err)
+	}
+
+	// Targets that would break a posting limit fail like invalid content
+	if _, err := limits.Set(ctx, db, "alice", facebook.ID, limits.Input{MinGapMinutes: 60}); err != nil {
+		t.Fatal(err)
+	}
+	preview, jobs, err = Schedule(ctx, db, "alice", tpl.ID, Request{
+		Variables:  map[string]string{"product": "Socgo"},
+		ScheduleAt: at.Add(30 * time.Minute).Format(time.RFC3339),
+	}, now)
+	if !errors.Is(err, ErrInvalid) || len(jobs) != 0 || !strings.Contains(preview.Targets[0].Error, "posting limit") || preview.Targets[1].Error != "" {
+		t.Errorf("Expected the facebook target to break the posting limit, got %v %+v", err, preview)
+	}
+}
@@ -52,7 +52,7 @@ func (p *FacebookProvider) Publish(ctx context.Context, content string) (postID
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return "", statusError(resp.StatusCode)
 	}
 
 	// Parse response
@@ -109,7 +109,7 @@ func (p *FacebookProvider) GetStatus(ctx context.Context, postID string) (status
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return "", statusError(resp.StatusCode)
 	}
 
 	// Parse response
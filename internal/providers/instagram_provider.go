@@ -52,7 +52,7 @@ func (p *InstagramProvider) Publish(ctx context.Context, content string) (postID
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return "", statusError(resp.StatusCode)
 	}
 
 	// Parse response
@@ -108,7 +108,7 @@ func (p *InstagramProvider) GetStatus(ctx context.Context, postID string) (statu
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return "", statusError(resp.StatusCode)
 	}
 
 	// Parse response
@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// contentLimits are the longest posts, in characters, each provider type
+// accepts
+var contentLimits = map[ProviderType]int{
+	ProviderTypeTikTok:    2200,
+	ProviderTypeInstagram: 2200,
+	ProviderTypeFacebook:  63206,
+}
+
+// maxInstagramHashtags is the number of hashtags Instagram accepts per post
+const maxInstagramHashtags = 30
+
+// ValidateContent checks content against the rules of providerType before it
+// is scheduled or published
+func ValidateContent(providerType ProviderType, content string) error {
+	limit, ok := contentLimits[providerType]
+	if !ok {
+		return fmt.Errorf("unsupported provider type: %s", providerType)
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("content is empty")
+	}
+	if length := utf8.RuneCountInString(content); length > limit {
+		return fmt.Errorf("content is %d characters long, %s accepts at most %d", length, providerType, limit)
+	}
+	if providerType == ProviderTypeInstagram {
+		hashtags := 0
+		for _, word := range strings.Fields(content) {
+			if strings.HasPrefix(word, "#") && len(word) > 1 {
+				hashtags++
+			}
+		}
+		if hashtags > maxInstagramHashtags {
+			return fmt.Errorf("content has %d hashtags, instagram accepts at most %d", hashtags, maxInstagramHashtags)
+		}
+	}
+	return nil
+}
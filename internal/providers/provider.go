@@ -2,9 +2,15 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 )
 
+// ErrUnauthorized is wrapped by errors of requests the platform rejected
+// because of the access token
+var ErrUnauthorized = errors.New("access token rejected")
+
 // Provider defines the interface for social media providers
 type Provider interface {
 	// Publish publishes content to the social media platform
@@ -42,3 +48,12 @@ const (
 	PostStatusFailed    PostStatus = "failed"
 	PostStatusDeleted   PostStatus = "deleted"
 )
+
+// statusError describes a failed API request, wrapping ErrUnauthorized when
+// the platform rejected the access token
+func statusError(status int) error {
+	if status == http.StatusUnauthorized {
+		return fmt.Errorf("%w: API request failed with status: %d", ErrUnauthorized, status)
+	}
+	return fmt.Errorf("API request failed with status: %d", status)
+}
@@ -3,12 +3,17 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/links"
 	"github.com/tkowalski/socgo/internal/oauth"
+	"github.com/tkowalski/socgo/internal/snippets"
+	"github.com/tkowalski/socgo/internal/webhooks"
 )
 
 // ProviderService manages social media providers with Strategy pattern
@@ -17,6 +22,7 @@ type ProviderService struct {
 	factory      *ProviderFactory
 	dbManager    *database.Manager
 	oauthService *oauth.Service
+	baseURL      string
 }
 
 // NewProviderService creates a new provider service
@@ -32,7 +38,14 @@ func NewProviderService(dbManager *database.Manager, oauthService *oauth.Service
 	}
 }
 
-// PublishContent publishes content to a specific provider
+// SetBaseURL sets the public URL of the server used in short links
+func (s *ProviderService) SetBaseURL(baseURL string) {
+	s.baseURL = baseURL
+}
+
+// PublishContent publishes content to a specific provider. Placeholders in
+// content are expanded with the vars of ctx, see snippets.WithVars, and its
+// links are rewritten for the publication of ctx, see links.WithPublication.
 func (s *ProviderService) PublishContent(ctx context.Context, userID string, providerName string, content string) (postID string, err error) {
 	// Get provider configuration from database
 	config, err := s.getProviderConfig(ctx, userID, providerName)
@@ -49,15 +62,73 @@ func (s *ProviderService) PublishContent(ctx context.Context, userID string, pro
 		return "", fmt.Errorf("failed to create provider: %w", err)
 	}
 
+	// An expired token is refreshed before publishing rather than sent to be
+	// rejected
+	if config.ExpiresAt > 0 && time.Now().Unix() >= config.ExpiresAt {
+		if err := s.refreshToken(ctx, userID, providerName, provider, config); err != nil {
+			return "", err
+		}
+	}
+
+	content, err = s.expandContent(ctx, userID, providerName, content)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand placeholders: %w", err)
+	}
+
 	// Publish content using provider
 	postID, err = provider.Publish(ctx, content)
 	if err != nil {
+		s.discardLinks(ctx, userID)
+		if errors.Is(err, ErrUnauthorized) {
+			s.emitRefreshFailed(userID, providerName, err)
+		}
 		return "", fmt.Errorf("failed to publish content: %w", err)
 	}
 
 	return postID, nil
 }
 
+// expandContent replaces the snippet and variable placeholders of content
+func (s *ProviderService) expandContent(ctx context.Context, userID string, providerName string, content string) (string, error) {
+	db, err := s.dbManager.GetDB(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get database: %w", err)
+	}
+
+	now := time.Now()
+	vars := snippets.Vars{snippets.VarProvider: providerName}
+	for name, value := range snippets.VarsFromContext(ctx) {
+		vars[name] = value
+	}
+	content, err = snippets.Expand(db, userID, content, now, vars)
+	if err != nil {
+		return "", err
+	}
+
+	// Links are rewritten after expansion to include those of snippets
+	if pub := links.PublicationFromContext(ctx); pub != nil {
+		return links.Rewrite(db, userID, content, pub, s.baseURL, vars, now)
+	}
+	return content, nil
+}
+
+// discardLinks deletes the short links created for content that failed to
+// publish
+func (s *ProviderService) discardLinks(ctx context.Context, userID string) {
+	pub := links.PublicationFromContext(ctx)
+	if pub == nil {
+		return
+	}
+	db, err := s.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Warning: failed to get database: %v", err)
+		return
+	}
+	if err := links.Discard(db, pub); err != nil {
+		log.Printf("Warning: failed to discard short links: %v", err)
+	}
+}
+
 // GetPostStatus retrieves the status of a published post
 func (s *ProviderService) GetPostStatus(ctx context.Context, userID string, providerName string, postID string) (status string, err error) {
 	// Get provider configuration from database
@@ -101,21 +172,41 @@ func (s *ProviderService) RefreshProviderToken(ctx context.Context, userID strin
 		return fmt.Errorf("failed to create provider: %w", err)
 	}
 
-	// Refresh token using provider
-	err = provider.RefreshToken(ctx)
-	if err != nil {
+	return s.refreshToken(ctx, userID, providerName, provider, config)
+}
+
+// refreshToken refreshes the access token of provider and saves config, which
+// the provider updates in place. A failed refresh is emitted as
+// token.refresh_failed.
+func (s *ProviderService) refreshToken(ctx context.Context, userID string, providerName string, provider Provider, config *ProviderConfig) error {
+	if err := provider.RefreshToken(ctx); err != nil {
+		s.emitRefreshFailed(userID, providerName, err)
 		return fmt.Errorf("failed to refresh token: %w", err)
 	}
 
-	// Update provider configuration in database
-	err = s.updateProviderConfig(ctx, userID, providerName, config)
-	if err != nil {
+	if err := s.updateProviderConfig(ctx, userID, providerName, config); err != nil {
 		return fmt.Errorf("failed to update provider config: %w", err)
 	}
-
 	return nil
 }
 
+// emitRefreshFailed notifies webhook subscribers that a provider needs to be
+// reconnected, after a failed refresh or a token rejected while publishing
+func (s *ProviderService) emitRefreshFailed(userID, providerName string, refreshErr error) {
+	db, err := s.dbManager.GetDB(userID)
+	if err != nil {
+		log.Printf("Warning: failed to get database: %v", err)
+		return
+	}
+
+	if err := webhooks.Emit(db, userID, webhooks.EventTokenRefreshFailed, map[string]string{
+		"provider": providerName,
+		"error":    refreshErr.Error(),
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
 // GetSupportedProviders returns all supported provider types
 func (s *ProviderService) GetSupportedProviders() []string {
 	types := s.registry.GetSupportedProviders()
@@ -3,11 +3,16 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/tkowalski/socgo/internal/database"
 	"github.com/tkowalski/socgo/internal/oauth"
+	"github.com/tkowalski/socgo/internal/webhooks"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -201,3 +206,67 @@ func TestProviderService_IsProviderConfigured(t *testing.T) {
 		t.Error("Expected provider to not be configured")
 	}
 }
+
+// rejectingClient answers every request with 401 and records the URLs
+type rejectingClient struct {
+	urls []string
+}
+
+func (c *rejectingClient) Do(req *http.Request) (*http.Response, error) {
+	c.urls = append(c.urls, req.URL.String())
+	return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestProviderService_PublishContentEmitsRefreshFailed(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	if _, err := webhooks.CreateEndpoint(db, "default_user", "https://example.com/hook", []string{webhooks.EventTokenRefreshFailed}); err != nil {
+		t.Fatalf("Failed to create endpoint: %v", err)
+	}
+	createTestProvider(t, db, "default_user", "tiktok")
+
+	client := &rejectingClient{}
+	service := NewProviderService(dbManager, nil)
+	service.factory = NewProviderFactory(client)
+
+	deliveries := func() int64 {
+		var count int64
+		db.Model(&database.WebhookDelivery{}).Where("event = ?", webhooks.EventTokenRefreshFailed).Count(&count)
+		return count
+	}
+
+	// A token rejected while publishing
+	_, err = service.PublishContent(context.Background(), "default_user", "tiktok", "Hello")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected ErrUnauthorized, got %v", err)
+	}
+	if got := deliveries(); got != 1 {
+		t.Errorf("Expected 1 token.refresh_failed delivery, got %d", got)
+	}
+
+	// An expired token is refreshed before publishing
+	var provider database.Provider
+	db.Where("name = ?", "tiktok").First(&provider)
+	var config oauth.ProviderConfig
+	if err := json.Unmarshal([]byte(provider.Config), &config); err != nil {
+		t.Fatal(err)
+	}
+	config.ExpiresAt = time.Now().Add(-time.Hour)
+	updated, _ := json.Marshal(config)
+	db.Model(&provider).Update("config", string(updated))
+
+	client.urls = nil
+	if _, err := service.PublishContent(context.Background(), "default_user", "tiktok", "Hello"); err == nil {
+		t.Fatal("Expected publishing with an expired token to fail")
+	}
+	if len(client.urls) != 1 || !strings.Contains(client.urls[0], "oauth") {
+		t.Errorf("Expected only the refresh request, got %v", client.urls)
+	}
+	if got := deliveries(); got != 2 {
+		t.Errorf("Expected 2 token.refresh_failed deliveries, got %d", got)
+	}
+}
@@ -52,7 +52,7 @@ func (p *TikTokProvider) Publish(ctx context.Context, content string) (postID st
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return "", statusError(resp.StatusCode)
 	}
 
 	// Parse response
@@ -107,7 +107,7 @@ func (p *TikTokProvider) GetStatus(ctx context.Context, postID string) (status s
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return "", statusError(resp.StatusCode)
 	}
 
 	// Parse response
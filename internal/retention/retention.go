@@ -0,0 +1,233 @@
+package retention
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/config"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// Entities the retention rules apply to
+const (
+	EntityJobs              = "jobs"
+	EntityAPITokens         = "api_tokens"
+	EntityWebhookDeliveries = "webhook_deliveries"
+)
+
+// batchSize bounds the number of records archived and deleted at once
+const batchSize = 500
+
+// policy describes which records of an entity expire. Expired records match
+// where, with the cutoff as its only argument.
+type policy struct {
+	entity string
+	model  interface{}
+	where  string
+}
+
+// policies lists the prunable entities; a new entity needs a policy here and
+// a rule in config.RetentionConfig
+var policies = []policy{
+	{
+		entity: EntityJobs,
+		model:  &database.ScheduledJob{},
+		where:  fmt.Sprintf("status IN ('%s', '%s') AND updated_at < ?", database.JobStatusCompleted, database.JobStatusFailed),
+	},
+	{
+		entity: EntityAPITokens,
+		model:  &database.APIToken{},
+		where:  "deleted_at IS NOT NULL AND deleted_at < ?",
+	},
+	{
+		entity: EntityWebhookDeliveries,
+		model:  &database.WebhookDelivery{},
+		where:  fmt.Sprintf("status IN ('%s', '%s') AND updated_at < ?", database.DeliveryStatusDelivered, database.DeliveryStatusFailed),
+	},
+}
+
+// Rule keeps the records of Entity for MaxAge after they were finished
+type Rule struct {
+	Entity  string        `json:"entity"`
+	MaxAge  time.Duration `json:"max_age"`
+	Archive bool          `json:"archive"`
+}
+
+// Count is the number of expired records of an entity in one database
+type Count struct {
+	Entity   string    `json:"entity"`
+	Cutoff   time.Time `json:"cutoff"`
+	Records  int64     `json:"records"`
+	Archived bool      `json:"archived"`
+}
+
+// Report lists the records a run pruned, or with DryRun would have pruned,
+// from the database Key
+type Report struct {
+	Key    string  `json:"key"`
+	DryRun bool    `json:"dry_run"`
+	Counts []Count `json:"counts"`
+}
+
+// Total returns the number of records in the report
+func (r Report) Total() int64 {
+	var total int64
+	for _, count := range r.Counts {
+		total += count.Records
+	}
+	return total
+}
+
+// Service prunes expired records from the tenant databases. Archived records
+// are appended to <archiveDir>/<key>/<entity>-<date>.jsonl before deletion.
+type Service struct {
+	dbManager  *database.Manager
+	rules      []Rule
+	archiveDir string
+}
+
+// NewService creates a retention service applying the rules of cfg with a
+// positive MaxAge
+func NewService(dbManager *database.Manager, cfg config.RetentionConfig) *Service {
+	s := &Service{
+		dbManager:  dbManager,
+		archiveDir: cfg.ArchiveDir,
+	}
+	for _, rule := range []Rule{
+		{Entity: EntityJobs, MaxAge: cfg.Jobs.MaxAge, Archive: cfg.Jobs.Archive},
+		{Entity: EntityAPITokens, MaxAge: cfg.APITokens.MaxAge, Archive: cfg.APITokens.Archive},
+		{Entity: EntityWebhookDeliveries, MaxAge: cfg.WebhookDeliveries.MaxAge, Archive: cfg.WebhookDeliveries.Archive},
+	} {
+		if rule.MaxAge > 0 {
+			s.rules = append(s.rules, rule)
+		}
+	}
+	return s
+}
+
+// Rules returns the rules the service applies
+func (s *Service) Rules() []Rule {
+	return s.rules
+}
+
+// PruneAll prunes every tenant database, continuing past failures. The
+// reports of the databases pruned are returned with the joined errors.
+func (s *Service) PruneAll(now time.Time, dryRun bool) ([]Report, error) {
+	if len(s.rules) == 0 {
+		return nil, nil
+	}
+	keys, err := s.dbManager.TenantKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]Report, 0, len(keys))
+	var problems []error
+	for _, key := range keys {
+		report, err := s.Prune(key, now, dryRun)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, errors.Join(problems...)
+}
+
+// Prune deletes the records of the database key that expired by now. With
+// dryRun the records are only counted.
+func (s *Service) Prune(key string, now time.Time, dryRun bool) (Report, error) {
+	report := Report{Key: key, DryRun: dryRun}
+	db, release, err := s.dbManager.Acquire(key)
+	if err != nil {
+		return report, fmt.Errorf("tenant %s: %w", key, err)
+	}
+	defer release()
+
+	for _, rule := range s.rules {
+		p, ok := findPolicy(rule.Entity)
+		if !ok {
+			return report, fmt.Errorf("tenant %s: unknown entity %q", key, rule.Entity)
+		}
+		count := Count{Entity: rule.Entity, Cutoff: now.Add(-rule.MaxAge), Archived: rule.Archive}
+		if dryRun {
+			err = db.Unscoped().Model(p.model).Where(p.where, count.Cutoff).Count(&count.Records).Error
+		} else {
+			count.Records, err = s.prune(db, key, p, count.Cutoff, rule.Archive)
+		}
+		if err != nil {
+			return report, fmt.Errorf("tenant %s: %s: %w", key, rule.Entity, err)
+		}
+		report.Counts = append(report.Counts, count)
+	}
+	return report, nil
+}
+
+// prune deletes the records of p expired by cutoff in batches, archiving each
+// batch first, and returns how many were deleted
+func (s *Service) prune(db *gorm.DB, key string, p policy, cutoff time.Time, archive bool) (int64, error) {
+	var ids []uint
+	if err := db.Unscoped().Model(p.model).Where(p.where, cutoff).Order("id").Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		if archive {
+			rows := reflect.New(reflect.SliceOf(reflect.TypeOf(p.model).Elem()))
+			if err := db.Unscoped().Where("id IN ?", batch).Order("id").Find(rows.Interface()).Error; err != nil {
+				return deleted, err
+			}
+			if err := s.archive(key, p.entity, rows.Elem()); err != nil {
+				return deleted, err
+			}
+		}
+		result := db.Unscoped().Where("id IN ?", batch).Delete(p.model)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
+
+// archive appends rows, a slice of records, to today's archive of entity
+func (s *Service) archive(key, entity string, rows reflect.Value) error {
+	dir := filepath.Join(s.archiveDir, key)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.jsonl", entity, time.Now().UTC().Format("20060102")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	for i := 0; i < rows.Len(); i++ {
+		if err := encoder.Encode(rows.Index(i).Interface()); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+func findPolicy(entity string) (policy, bool) {
+	for _, p := range policies {
+		if p.entity == entity {
+			return p, true
+		}
+	}
+	return policy{}, false
+}
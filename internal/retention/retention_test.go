@@ -0,0 +1,124 @@
+package retention
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/config"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T, cfg config.RetentionConfig) (*Service, *gorm.DB) {
+	t.Helper()
+	manager := database.NewTestManager(t)
+	t.Cleanup(func() { manager.Close() })
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	return NewService(manager, cfg), db
+}
+
+func TestPrune_DryRunThenDelete(t *testing.T) {
+	service, db := setup(t, config.RetentionConfig{
+		ArchiveDir:        t.TempDir(),
+		Jobs:              config.RetentionRule{MaxAge: 24 * time.Hour, Archive: true},
+		APITokens:         config.RetentionRule{MaxAge: 24 * time.Hour},
+		WebhookDeliveries: config.RetentionRule{},
+	})
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+
+	for _, status := range []string{database.JobStatusCompleted, database.JobStatusFailed, database.JobStatusPending} {
+		job := database.ScheduledJob{JobType: "publish_post", PayloadData: status, UserID: "alice", ScheduledAt: old, Status: status}
+		db.Create(&job)
+		db.Model(&job).UpdateColumn("updated_at", old)
+	}
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "recent", UserID: "alice", ScheduledAt: now, Status: database.JobStatusCompleted})
+
+	revoked := database.APIToken{Hash: "revoked", UserID: "alice"}
+	live := database.APIToken{Hash: "live", UserID: "alice"}
+	db.Create(&revoked)
+	db.Create(&live)
+	db.Model(&revoked).UpdateColumn("deleted_at", old)
+
+	db.Create(&database.WebhookDelivery{EndpointID: 1, Event: "post.published", Status: database.DeliveryStatusDelivered})
+
+	if rules := service.Rules(); len(rules) != 2 {
+		t.Fatalf("Expected rules without a max age to be skipped, got %+v", rules)
+	}
+
+	report, err := service.Prune("alice", now, true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if report.Total() != 3 || report.Counts[0].Records != 2 || report.Counts[1].Records != 1 {
+		t.Fatalf("Unexpected dry run report: %+v", report)
+	}
+	var jobs int64
+	db.Model(&database.ScheduledJob{}).Count(&jobs)
+	if jobs != 4 {
+		t.Fatalf("Expected a dry run to keep every job, %d remain", jobs)
+	}
+
+	reports, err := service.PruneAll(now, false)
+	if err != nil {
+		t.Fatalf("PruneAll failed: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Total() != 3 {
+		t.Fatalf("Unexpected reports: %+v", reports)
+	}
+
+	var payloads []string
+	db.Model(&database.ScheduledJob{}).Order("id").Pluck("payload_data", &payloads)
+	if len(payloads) != 2 || payloads[0] != database.JobStatusPending || payloads[1] != "recent" {
+		t.Errorf("Expected the pending and recent jobs to remain, got %v", payloads)
+	}
+	var hashes []string
+	db.Unscoped().Model(&database.APIToken{}).Pluck("hash", &hashes)
+	if len(hashes) != 1 || hashes[0] != "live" {
+		t.Errorf("Expected only the live token to remain, got %v", hashes)
+	}
+	var deliveries int64
+	db.Model(&database.WebhookDelivery{}).Count(&deliveries)
+	if deliveries != 1 {
+		t.Errorf("Expected deliveries to be kept, %d remain", deliveries)
+	}
+
+	// Pruned jobs were archived, API tokens were not
+	paths, _ := filepath.Glob(filepath.Join(service.archiveDir, "alice", "*.jsonl"))
+	if len(paths) != 1 || !strings.HasPrefix(filepath.Base(paths[0]), EntityJobs) {
+		t.Fatalf("Expected one jobs archive, got %v", paths)
+	}
+	file, err := os.Open(paths[0])
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+	var archived []database.ScheduledJob
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var job database.ScheduledJob
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			t.Fatalf("Invalid archive line: %v", err)
+		}
+		archived = append(archived, job)
+	}
+	if len(archived) != 2 || archived[0].Status != database.JobStatusCompleted || archived[1].Status != database.JobStatusFailed {
+		t.Errorf("Unexpected archived jobs: %+v", archived)
+	}
+}
+
+func TestPruneAll_WithoutRules(t *testing.T) {
+	service, _ := setup(t, config.RetentionConfig{})
+	reports, err := service.PruneAll(time.Now(), false)
+	if err != nil || len(reports) != 0 {
+		t.Errorf("Expected nothing to be pruned, got %+v, %v", reports, err)
+	}
+}
@@ -3,10 +3,21 @@ package scheduler
 import (
 	"context"
 	"log"
+	"net/http"
 	"time"
 
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/blackouts"
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/feeds"
+	"github.com/tkowalski/socgo/internal/limits"
+	"github.com/tkowalski/socgo/internal/links"
 	"github.com/tkowalski/socgo/internal/providers"
+	"github.com/tkowalski/socgo/internal/retention"
+	"github.com/tkowalski/socgo/internal/snippets"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/trash"
+	"github.com/tkowalski/socgo/internal/webhooks"
 	"gorm.io/gorm"
 )
 
@@ -14,6 +25,12 @@ import (
 type Scheduler struct {
 	dbManager       *database.Manager
 	providerService *providers.ProviderService
+	webhooks        *webhooks.Deliverer
+	feedClient      *http.Client
+	trashRetention  time.Duration
+	retention       *retention.Service
+	pruneInterval   time.Duration
+	lastPruned      time.Time
 	ticker          *time.Ticker
 	stopChan        chan struct{}
 }
@@ -23,10 +40,25 @@ func New(dbManager *database.Manager, providerService *providers.ProviderService
 	return &Scheduler{
 		dbManager:       dbManager,
 		providerService: providerService,
+		webhooks:        webhooks.NewDeliverer(nil),
+		feedClient:      &http.Client{Timeout: 30 * time.Second},
 		stopChan:        make(chan struct{}),
 	}
 }
 
+// SetTrashRetention sets how long deleted posts and providers stay in the
+// trash before they are purged. Zero or negative keeps them.
+func (s *Scheduler) SetTrashRetention(retention time.Duration) {
+	s.trashRetention = retention
+}
+
+// SetRetention makes the scheduler prune expired records with service every
+// interval
+func (s *Scheduler) SetRetention(service *retention.Service, interval time.Duration) {
+	s.retention = service
+	s.pruneInterval = interval
+}
+
 // Start begins the scheduler worker
 func (s *Scheduler) Start() {
 	log.Println("Starting job scheduler...")
@@ -66,13 +98,97 @@ func (s *Scheduler) processJobs() {
 
 	ctx := context.Background()
 
-	// Get all user databases
-	userDatabases := s.dbManager.GetAllUserDatabases()
+	// Jobs are run on behalf of their author by the scheduler itself
+	ctx = tenant.WithContext(ctx, tenant.Context{
+		UserID: tenant.SystemUserID,
+		Role:   database.WorkspaceRoleOwner,
+		Source: tenant.SourceScheduler,
+	})
+
+	// The control plane knows which databases have work due, open or not
+	userIDs, err := s.dbManager.DueTenants(time.Now())
+	if err != nil {
+		log.Printf("Error finding databases with due jobs: %v", err)
+		return
+	}
 
-	for userID, db := range userDatabases {
+	for _, userID := range userIDs {
+		// The database is held for the whole run so the pool cannot close it
+		// while a job is publishing
+		db, release, err := s.dbManager.Acquire(userID)
+		if err != nil {
+			log.Printf("Error opening database for user %s: %v", userID, err)
+			continue
+		}
 		if err := s.processUserJobs(ctx, userID, db); err != nil {
 			log.Printf("Error processing jobs for user %s: %v", userID, err)
 		}
+		if err := s.webhooks.ProcessDue(ctx, db); err != nil {
+			log.Printf("Error delivering webhooks for user %s: %v", userID, err)
+		}
+		if err := s.dbManager.RefreshSchedule(userID, db); err != nil {
+			log.Printf("Error updating schedule for user %s: %v", userID, err)
+		}
+		release()
+	}
+
+	s.purgeTrash(ctx)
+	s.pruneExpired()
+}
+
+// pruneExpired runs the retention rules over every database once per prune
+// interval
+func (s *Scheduler) pruneExpired() {
+	if s.retention == nil || len(s.retention.Rules()) == 0 || time.Since(s.lastPruned) < s.pruneInterval {
+		return
+	}
+	s.lastPruned = time.Now()
+
+	reports, err := s.retention.PruneAll(s.lastPruned, false)
+	if err != nil {
+		log.Printf("Error pruning expired records: %v", err)
+	}
+	for _, report := range reports {
+		for _, count := range report.Counts {
+			if count.Records > 0 {
+				log.Printf("Pruned %d expired %s of user %s", count.Records, count.Entity, report.Key)
+			}
+		}
+	}
+}
+
+// purgeTrash permanently deletes the items kept in the trash past the
+// retention period
+func (s *Scheduler) purgeTrash(ctx context.Context) {
+	if s.trashRetention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.trashRetention)
+
+	userIDs, err := s.dbManager.ExpiredTrash(cutoff)
+	if err != nil {
+		log.Printf("Error finding databases with expired trash: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		db, release, err := s.dbManager.Acquire(userID)
+		if err != nil {
+			log.Printf("Error opening database for user %s: %v", userID, err)
+			continue
+		}
+		count, err := trash.PurgeExpired(ctx, db, userID, cutoff)
+		if err != nil {
+			log.Printf("Error purging trash for user %s: %v", userID, err)
+		} else {
+			if count > 0 {
+				log.Printf("Purged %d items from the trash of user %s", count, userID)
+			}
+			if err := s.dbManager.RefreshTrash(userID, db); err != nil {
+				log.Printf("Error updating trash marker for user %s: %v", userID, err)
+			}
+		}
+		release()
 	}
 }
 
@@ -91,9 +207,43 @@ func (s *Scheduler) processUserJobs(ctx context.Context, userID string, db *gorm
 	}
 
 	log.Printf("Found %d pending jobs for user %s", len(jobs), userID)
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	// Without the blackout rules or posting limits nothing is published,
+	// rather than too much
+	rules, err := blackouts.Load(db, userID)
+	if err != nil {
+		return err
+	}
+	postingLimits, err := limits.Load(db, userID)
+	if err != nil {
+		return err
+	}
 
 	// Process each job
 	for _, job := range jobs {
+		if job.JobType == database.JobTypePublishPost {
+			if decision := rules.Check(job.ProviderID, now); decision.Blocked {
+				if err := s.blockJob(ctx, userID, db, &job, decision); err != nil {
+					log.Printf("Error deferring job %d: %v", job.ID, err)
+				}
+				continue
+			}
+			// Jobs published earlier in this run count against the limit
+			decision, err := postingLimits.Check(db, userID, job.ProviderID, time.Now())
+			if err != nil {
+				log.Printf("Error checking posting limit of job %d: %v", job.ID, err)
+				continue
+			}
+			if decision.Blocked {
+				if err := s.deferJob(ctx, userID, db, &job, decision.Until, decision.Reason); err != nil {
+					log.Printf("Error deferring job %d: %v", job.ID, err)
+				}
+				continue
+			}
+		}
 		if err := s.processJob(ctx, userID, db, &job); err != nil {
 			log.Printf("Error processing job %d: %v", job.ID, err)
 		}
@@ -102,24 +252,76 @@ func (s *Scheduler) processUserJobs(ctx context.Context, userID string, db *gorm
 	return nil
 }
 
+// blockJob keeps a publish job blocked by a blackout window or pause from
+// running. Held jobs stay due and run once nothing blocks them; deferred
+// jobs are moved to the end of the window.
+func (s *Scheduler) blockJob(ctx context.Context, userID string, db *gorm.DB, job *database.ScheduledJob, decision blackouts.Decision) error {
+	if decision.Hold {
+		log.Printf("Job %d held: %s", job.ID, decision.Reason)
+		return nil
+	}
+	return s.deferJob(ctx, userID, db, job, decision.Until, decision.Reason)
+}
+
+// deferJob moves a publish job that may not run yet to until, unless it was
+// rescheduled, cancelled or claimed since it was read
+func (s *Scheduler) deferJob(ctx context.Context, userID string, db *gorm.DB, job *database.ScheduledJob, until time.Time, reason string) error {
+	before := *job
+	now := time.Now()
+	result := db.Model(&database.ScheduledJob{}).
+		Where("id = ? AND status = ? AND scheduled_at = ?", job.ID, database.JobStatusPending, job.ScheduledAt).
+		Updates(map[string]interface{}{"scheduled_at": until, "updated_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("Job %d changed since it was read, not deferring", job.ID)
+		return nil
+	}
+	job.ScheduledAt = until
+	job.UpdatedAt = now
+	if err := audit.Record(ctx, db, audit.Entry{
+		UserID:     userID,
+		Action:     audit.ActionJobDeferred,
+		TargetType: audit.TargetJob,
+		TargetID:   job.ID,
+		Before:     before,
+		After:      job,
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	log.Printf("Job %d deferred to %s: %s", job.ID, job.ScheduledAt.Format(time.RFC3339), reason)
+	return nil
+}
+
 // processJob processes a single scheduled job
 func (s *Scheduler) processJob(ctx context.Context, userID string, db *gorm.DB, job *database.ScheduledJob) error {
 	log.Printf("Processing job %d: %s for user %s", job.ID, job.JobType, userID)
 
-	// Mark job as executing
-	job.Status = database.JobStatusExecuting
-	job.UpdatedAt = time.Now()
-
-	if err := db.Save(job).Error; err != nil {
-		return err
+	// Claim the job, unless it was rescheduled or claimed since it was read
+	now := time.Now()
+	result := db.Model(&database.ScheduledJob{}).
+		Where("id = ? AND status = ? AND scheduled_at = ?", job.ID, database.JobStatusPending, job.ScheduledAt).
+		Updates(map[string]interface{}{"status": database.JobStatusExecuting, "updated_at": now})
+	if result.Error != nil {
+		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		log.Printf("Job %d changed since it was read, skipping", job.ID)
+		return nil
+	}
+	job.Status = database.JobStatusExecuting
+	job.UpdatedAt = now
 
 	// Process different job types
 	switch job.JobType {
-	case "publish_post":
+	case database.JobTypePublishPost:
 		return s.processPublishPostJob(ctx, userID, db, job)
+	case database.JobTypePollFeed:
+		return s.processPollFeedJob(ctx, userID, db, job)
 	default:
-		return s.markJobFailed(db, job, "Unknown job type: "+job.JobType)
+		return s.markJobFailed(ctx, userID, db, job, "Unknown job type: "+job.JobType)
 	}
 }
 
@@ -127,27 +329,73 @@ func (s *Scheduler) processJob(ctx context.Context, userID string, db *gorm.DB,
 func (s *Scheduler) processPublishPostJob(ctx context.Context, userID string, db *gorm.DB, job *database.ScheduledJob) error {
 	// Get provider name from the job
 	if job.Provider.Name == "" {
-		return s.markJobFailed(db, job, "Provider name not found")
+		return s.markJobFailed(ctx, userID, db, job, "Provider name not found")
+	}
+
+	// Jobs created from the review workflow may only publish approved posts
+	var post database.Post
+	if job.PostID != nil {
+		if err := db.First(&post, *job.PostID).Error; err != nil {
+			return s.markJobFailed(ctx, userID, db, job, "Post not found")
+		}
+		if post.Status != database.PostStatusApproved && post.Status != database.PostStatusScheduled {
+			return s.markJobFailed(ctx, userID, db, job, "Post is not approved for publishing (status: "+post.Status+")")
+		}
+	}
+
+	// Placeholders take the campaign and tags of the job
+	vars, err := snippets.PostVars(db, userID, job.CampaignID, job.Tags)
+	if err != nil {
+		return s.markJobFailed(ctx, userID, db, job, "Failed to load placeholder values: "+err.Error())
 	}
 
 	// Publish content using provider service
-	postID, err := s.providerService.PublishContent(ctx, userID, job.Provider.Name, job.PayloadData)
+	pub := &links.Publication{ProviderID: job.ProviderID, CampaignID: job.CampaignID}
+	publishCtx := links.WithPublication(snippets.WithVars(ctx, vars), pub)
+	postID, err := s.providerService.PublishContent(publishCtx, userID, job.Provider.Name, job.PayloadData)
 	if err != nil {
-		return s.markJobFailed(db, job, "Failed to publish content: "+err.Error())
+		return s.markJobFailed(ctx, userID, db, job, "Failed to publish content: "+err.Error())
 	}
 
-	// Create post record
-	post := database.Post{
-		Content:    job.PayloadData,
-		UserID:     userID,
-		ProviderID: job.ProviderID,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	now := time.Now()
+	if job.PostID != nil {
+		// Mark the reviewed post as published
+		post.Status = database.PostStatusPublished
+		post.PublishedAt = &now
+		post.UpdatedAt = now
+		if err := db.Save(&post).Error; err != nil {
+			log.Printf("Warning: Failed to update post record for job %d: %v", job.ID, err)
+		}
+	} else {
+		// Create post record
+		post = database.Post{
+			Content:     job.PayloadData,
+			UserID:      userID,
+			ProviderID:  job.ProviderID,
+			CampaignID:  job.CampaignID,
+			Tags:        job.Tags,
+			Status:      database.PostStatusPublished,
+			PublishedAt: &now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		if err := db.Create(&post).Error; err != nil {
+			log.Printf("Warning: Failed to save post record for job %d: %v", job.ID, err)
+			// Continue - post was published successfully
+		} else {
+			// Link the job to its post so the timeline shows them as one
+			job.PostID = &post.ID
+		}
 	}
 
-	if err := db.Create(&post).Error; err != nil {
-		log.Printf("Warning: Failed to save post record for job %d: %v", job.ID, err)
-		// Continue - post was published successfully
+	if post.ID != 0 {
+		if err := links.Attach(db, pub, post.ID); err != nil {
+			log.Printf("Warning: Failed to attach short links to post %d: %v", post.ID, err)
+		}
+		if err := links.LinkPost(db, &post); err != nil {
+			log.Printf("Warning: Failed to link post %d for the link-in-bio page: %v", post.ID, err)
+		}
 	}
 
 	// Mark job as completed
@@ -158,13 +406,52 @@ func (s *Scheduler) processPublishPostJob(ctx context.Context, userID string, db
 	if err := db.Save(job).Error; err != nil {
 		return err
 	}
+	s.recordJobOutcome(ctx, userID, db, job, audit.ActionJobCompleted)
+	if err := webhooks.Emit(db, userID, webhooks.EventPostPublished, post); err != nil {
+		log.Printf("Warning: %v", err)
+	}
 
 	log.Printf("Job %d completed successfully. Post ID: %s", job.ID, postID)
 	return nil
 }
 
+// processPollFeedJob polls a feed for new items and schedules its next poll
+func (s *Scheduler) processPollFeedJob(ctx context.Context, userID string, db *gorm.DB, job *database.ScheduledJob) error {
+	feedID, err := feeds.FeedID(job)
+	if err != nil {
+		return s.markJobFailed(ctx, userID, db, job, err.Error())
+	}
+	feed, err := feeds.Get(db, userID, feedID)
+	if err != nil {
+		// The feed was deleted; its polls end here
+		return s.markJobFailed(ctx, userID, db, job, "Feed not found")
+	}
+
+	now := time.Now()
+	result, pollErr := feeds.Poll(ctx, db, userID, feedID, s.feedClient, now)
+	if feed.IsActive {
+		if err := feeds.SchedulePoll(db, feed, feeds.NextPoll(feed, now)); err != nil {
+			log.Printf("Warning: Failed to schedule next poll of feed %d: %v", feedID, err)
+		}
+	}
+	if pollErr != nil {
+		return s.markJobFailed(ctx, userID, db, job, "Failed to poll feed: "+pollErr.Error())
+	}
+
+	// Polls recur every interval, so completed ones are not audited
+	job.Status = database.JobStatusCompleted
+	job.ExecutedAt = &now
+	job.UpdatedAt = time.Now()
+	if err := db.Save(job).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Job %d polled feed %d: %d new items, %d drafts, %d jobs", job.ID, feedID, result.New, result.Drafts, result.Jobs)
+	return nil
+}
+
 // markJobFailed marks a job as failed with error message
-func (s *Scheduler) markJobFailed(db *gorm.DB, job *database.ScheduledJob, errorMsg string) error {
+func (s *Scheduler) markJobFailed(ctx context.Context, userID string, db *gorm.DB, job *database.ScheduledJob, errorMsg string) error {
 	job.Status = database.JobStatusFailed
 	job.ErrorMsg = errorMsg
 	job.UpdatedAt = time.Now()
@@ -172,7 +459,26 @@ func (s *Scheduler) markJobFailed(db *gorm.DB, job *database.ScheduledJob, error
 	if err := db.Save(job).Error; err != nil {
 		return err
 	}
+	s.recordJobOutcome(ctx, userID, db, job, audit.ActionJobFailed)
+	if job.JobType == database.JobTypePublishPost {
+		if err := webhooks.Emit(db, userID, webhooks.EventPostFailed, job); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
 
 	log.Printf("Job %d failed: %s", job.ID, errorMsg)
 	return nil
 }
+
+// recordJobOutcome adds the result of a job to the audit log
+func (s *Scheduler) recordJobOutcome(ctx context.Context, userID string, db *gorm.DB, job *database.ScheduledJob, action string) {
+	if err := audit.Record(ctx, db, audit.Entry{
+		UserID:     userID,
+		Action:     action,
+		TargetType: audit.TargetJob,
+		TargetID:   job.ID,
+		After:      job,
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
@@ -2,12 +2,20 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/blackouts"
 	"github.com/tkowalski/socgo/internal/config"
 	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/feeds"
+	"github.com/tkowalski/socgo/internal/limits"
 	"github.com/tkowalski/socgo/internal/oauth"
 	"github.com/tkowalski/socgo/internal/providers"
 )
@@ -120,3 +128,290 @@ func TestScheduler_E2E(t *testing.T) {
 
 	t.Log("E2E test completed successfully")
 }
+
+func TestScheduler_RefusesUnapprovedPosts(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	oauthService := oauth.NewService(dbManager, &config.Config{})
+	scheduler := New(dbManager, providers.NewProviderService(dbManager, oauthService))
+
+	userID := "test_user"
+	db, err := dbManager.GetDB(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := database.Provider{Name: "facebook", Type: "facebook", UserID: userID, IsActive: true}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	post := database.Post{Content: "Needs review", UserID: userID, ProviderID: provider.ID, Status: database.PostStatusInReview}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	job := database.ScheduledJob{
+		JobType:     "publish_post",
+		PayloadData: post.Content,
+		UserID:      userID,
+		ProviderID:  provider.ID,
+		PostID:      &post.ID,
+		ScheduledAt: time.Now().Add(-time.Minute),
+		Status:      database.JobStatusPending,
+	}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.processUserJobs(context.Background(), userID, db); err != nil {
+		t.Fatal(err)
+	}
+
+	var processed database.ScheduledJob
+	if err := db.First(&processed, job.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if processed.Status != database.JobStatusFailed {
+		t.Fatalf("Expected unapproved job to fail, got %s", processed.Status)
+	}
+	if !strings.Contains(processed.ErrorMsg, "not approved") {
+		t.Errorf("Expected approval error, got %q", processed.ErrorMsg)
+	}
+}
+
+func TestScheduler_PollsFeeds(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	oauthService := oauth.NewService(dbManager, &config.Config{})
+	scheduler := New(dbManager, providers.NewProviderService(dbManager, oauthService))
+
+	userID := "test_user"
+	db, err := dbManager.GetDB(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<rss version="2.0"><channel><item><guid>1</guid><title>Hello</title><link>https://example.com/1</link><pubDate>%s</pubDate></item></channel></rss>`,
+			time.Now().Add(time.Hour).Format(time.RFC1123Z))
+	}))
+	defer server.Close()
+	scheduler.feedClient = server.Client()
+
+	feed, err := feeds.Create(context.Background(), db, userID, feeds.Input{Name: "Blog", URL: server.URL, PollInterval: "30m"}, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.processUserJobs(context.Background(), userID, db); err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs []database.ScheduledJob
+	if err := db.Where("job_type = ?", database.JobTypePollFeed).Order("id").Find(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].Status != database.JobStatusCompleted || jobs[1].Status != database.JobStatusPending {
+		t.Fatalf("Expected a completed poll and the next one pending, got %+v", jobs)
+	}
+	if next := time.Until(jobs[1].ScheduledAt); next < 29*time.Minute || next > 31*time.Minute {
+		t.Errorf("Expected the next poll in 30 minutes, got %v", next)
+	}
+
+	var drafts int64
+	db.Model(&database.Post{}).Where("status = ? AND title = ?", database.PostStatusDraft, "Hello").Count(&drafts)
+	if drafts != 1 {
+		t.Errorf("Expected the feed item to become a draft, got %d", drafts)
+	}
+	if polled, _ := feeds.Get(db, userID, feed.ID); polled.LastPolledAt == nil {
+		t.Error("Expected the poll to be recorded on the feed")
+	}
+}
+
+func TestScheduler_BlackoutsDeferAndHoldJobs(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	oauthService := oauth.NewService(dbManager, &config.Config{})
+	scheduler := New(dbManager, providers.NewProviderService(dbManager, oauthService))
+	ctx := context.Background()
+
+	userID := "test_user"
+	db, err := dbManager.GetDB(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facebook := database.Provider{Name: "facebook", Type: "facebook", UserID: userID, IsActive: true}
+	tiktok := database.Provider{Name: "tiktok", Type: "tiktok", UserID: userID, IsActive: true}
+	db.Create(&facebook)
+	db.Create(&tiktok)
+
+	now := time.Now()
+	endsAt := now.Add(2 * time.Hour).UTC().Truncate(time.Second)
+	if _, err := blackouts.Create(ctx, db, userID, blackouts.Input{
+		Name: "Incident", ProviderID: &facebook.ID, StartsAt: now.Add(-time.Hour).UTC().Format(time.RFC3339), EndsAt: endsAt.Format(time.RFC3339),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blackouts.Pause(ctx, db, userID, "Incident"); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduledAt := now.Add(-time.Minute)
+	jobs := []database.ScheduledJob{
+		{JobType: database.JobTypePublishPost, PayloadData: "Deferred", UserID: userID, ProviderID: facebook.ID, ScheduledAt: scheduledAt, Status: database.JobStatusPending},
+		{JobType: database.JobTypePublishPost, PayloadData: "Held", UserID: userID, ProviderID: tiktok.ID, ScheduledAt: scheduledAt, Status: database.JobStatusPending},
+	}
+	if err := db.Create(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// While paused every job is held where it is
+	if err := scheduler.processUserJobs(ctx, userID, db); err != nil {
+		t.Fatalf("processUserJobs failed: %v", err)
+	}
+	for _, job := range jobs {
+		var updated database.ScheduledJob
+		db.First(&updated, job.ID)
+		if updated.Status != database.JobStatusPending || !updated.ScheduledAt.Equal(job.ScheduledAt) {
+			t.Errorf("Expected job %d to be held, got %s at %v", job.ID, updated.Status, updated.ScheduledAt)
+		}
+	}
+
+	// Once resumed, the window defers the Facebook job to its end and the
+	// TikTok job runs
+	if err := blackouts.Resume(ctx, db, userID); err != nil {
+		t.Fatal(err)
+	}
+	if err := scheduler.processUserJobs(ctx, userID, db); err != nil {
+		t.Fatalf("processUserJobs failed: %v", err)
+	}
+
+	var deferred, ran database.ScheduledJob
+	db.First(&deferred, jobs[0].ID)
+	db.First(&ran, jobs[1].ID)
+	if deferred.Status != database.JobStatusPending || !deferred.ScheduledAt.Equal(endsAt) {
+		t.Errorf("Expected the job to be deferred to %v, got %s at %v", endsAt, deferred.Status, deferred.ScheduledAt)
+	}
+	if ran.Status == database.JobStatusPending {
+		t.Error("Expected the job of another provider to run")
+	}
+
+	var entries int64
+	db.Model(&database.AuditLog{}).Where("action = ? AND target_id = ?", audit.ActionJobDeferred, deferred.ID).Count(&entries)
+	if entries != 1 {
+		t.Errorf("Expected the deferral to be audited, got %d entries", entries)
+	}
+}
+
+func TestScheduler_PostingLimitsDeferJobs(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	oauthService := oauth.NewService(dbManager, &config.Config{})
+	scheduler := New(dbManager, providers.NewProviderService(dbManager, oauthService))
+	ctx := context.Background()
+
+	userID := "test_user"
+	db, err := dbManager.GetDB(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facebook := database.Provider{Name: "facebook", Type: "facebook", UserID: userID, IsActive: true}
+	tiktok := database.Provider{Name: "tiktok", Type: "tiktok", UserID: userID, IsActive: true}
+	db.Create(&facebook)
+	db.Create(&tiktok)
+	if _, err := limits.Set(ctx, db, userID, facebook.ID, limits.Input{MinGapMinutes: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limits.Set(ctx, db, userID, tiktok.ID, limits.Input{DailyCap: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	publishedAt := time.Now().Add(-time.Second).UTC().Truncate(time.Second)
+	for _, provider := range []database.Provider{facebook, tiktok} {
+		if err := db.Create(&database.Post{Content: "Earlier", UserID: userID, ProviderID: provider.ID,
+			Status: database.PostStatusPublished, PublishedAt: &publishedAt}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scheduledAt := time.Now().Add(-time.Minute)
+	jobs := []database.ScheduledJob{
+		{JobType: database.JobTypePublishPost, PayloadData: "Too soon", UserID: userID, ProviderID: facebook.ID, ScheduledAt: scheduledAt, Status: database.JobStatusPending},
+		{JobType: database.JobTypePublishPost, PayloadData: "Over the cap", UserID: userID, ProviderID: tiktok.ID, ScheduledAt: scheduledAt, Status: database.JobStatusPending},
+	}
+	if err := db.Create(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.processUserJobs(ctx, userID, db); err != nil {
+		t.Fatalf("processUserJobs failed: %v", err)
+	}
+
+	var gapped, capped database.ScheduledJob
+	db.First(&gapped, jobs[0].ID)
+	db.First(&capped, jobs[1].ID)
+	if want := publishedAt.Add(time.Hour); gapped.Status != database.JobStatusPending || !gapped.ScheduledAt.Equal(want) {
+		t.Errorf("Expected the job to be deferred to %v, got %s at %v", want, gapped.Status, gapped.ScheduledAt)
+	}
+	day := publishedAt.Truncate(24 * time.Hour)
+	if want := day.AddDate(0, 0, 1); capped.Status != database.JobStatusPending || !capped.ScheduledAt.Equal(want) {
+		t.Errorf("Expected the job to be deferred to %v, got %s at %v", want, capped.Status, capped.ScheduledAt)
+	}
+}
+
+func TestScheduler_SkipsJobsChangedSinceRead(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	oauthService := oauth.NewService(dbManager, &config.Config{})
+	scheduler := New(dbManager, providers.NewProviderService(dbManager, oauthService))
+	ctx := context.Background()
+
+	userID := "test_user"
+	db, err := dbManager.GetDB(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := database.ScheduledJob{JobType: database.JobTypePublishPost, PayloadData: "Moved", UserID: userID,
+		ScheduledAt: time.Now().Add(-time.Minute), Status: database.JobStatusPending}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// The job is rescheduled after the scheduler read it
+	movedTo := time.Now().Add(time.Hour)
+	if err := db.Model(&database.ScheduledJob{}).Where("id = ?", job.ID).Update("scheduled_at", movedTo).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := scheduler.processJob(ctx, userID, db, &job); err != nil {
+		t.Fatalf("processJob failed: %v", err)
+	}
+
+	var stored database.ScheduledJob
+	db.First(&stored, job.ID)
+	if stored.Status != database.JobStatusPending || !stored.ScheduledAt.Equal(movedTo) {
+		t.Errorf("Expected the rescheduled job to stay pending at %v, got %s at %v", movedTo, stored.Status, stored.ScheduledAt)
+	}
+
+	// Nor does a blackout read before the change move it back
+	if err := scheduler.deferJob(ctx, userID, db, &job, time.Now().Add(2*time.Hour), "test"); err != nil {
+		t.Fatalf("deferJob failed: %v", err)
+	}
+	db.First(&stored, job.ID)
+	if !stored.ScheduledAt.Equal(movedTo) {
+		t.Errorf("Expected the rescheduled job to stay at %v, got %v", movedTo, stored.ScheduledAt)
+	}
+	var entries int64
+	db.Model(&database.AuditLog{}).Where("action = ? AND target_id = ?", audit.ActionJobDeferred, job.ID).Count(&entries)
+	if entries != 0 {
+		t.Errorf("Expected no deferral to be audited, got %d entries", entries)
+	}
+}
@@ -0,0 +1,147 @@
+package search
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// ErrEmptyQuery is returned when the search text has no words to match
+var ErrEmptyQuery = errors.New("search query has no words")
+
+// Markers put around matches by the database before the snippet is escaped
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// postgresDocument must match the expression of the idx_posts_search index
+const postgresDocument = "to_tsvector('simple', coalesce(posts.title, '') || ' ' || posts.content || ' ' || coalesce(posts.tags, ''))"
+
+// Query selects the posts of a user whose title, content or tags match Text
+type Query struct {
+	Text       string
+	ProviderID uint
+	Status     string
+	CampaignID uint
+	// Tag matches posts carrying the tag
+	Tag string
+	// From and To bound the creation time; either may be zero
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// Result is a matching post. Snippet is HTML: the matched text escaped, with
+// the matching words wrapped in <mark>.
+type Result struct {
+	ID         uint          `json:"id"`
+	Title      string        `json:"title"`
+	Content    string        `json:"content"`
+	Snippet    string        `json:"snippet"`
+	ProviderID uint          `json:"provider_id"`
+	Provider   string        `json:"provider"`
+	CampaignID *uint         `json:"campaign_id,omitempty"`
+	Tags       database.Tags `json:"tags"`
+	Status     string        `json:"status"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// Posts returns the posts of userID matching q, newest first, and the total
+// number of matches
+func Posts(db *gorm.DB, userID string, q Query) ([]Result, int64, error) {
+	terms := Terms(q.Text)
+	if len(terms) == 0 {
+		return nil, 0, ErrEmptyQuery
+	}
+
+	query := db.Model(&database.Post{}).
+		Joins("LEFT JOIN providers ON providers.id = posts.provider_id").
+		Where("posts.user_id = ?", userID)
+
+	var snippet string
+	var args []interface{}
+	if db.Dialector.Name() == database.DriverPostgres {
+		match := make([]string, len(terms))
+		for i, term := range terms {
+			match[i] = term + ":*"
+		}
+		tsquery := strings.Join(match, " & ")
+		query = query.Where(postgresDocument+" @@ to_tsquery('simple', ?)", tsquery)
+		snippet = "ts_headline('simple', coalesce(posts.title, '') || ' ' || posts.content, to_tsquery('simple', ?), ?)"
+		args = []interface{}{tsquery, "StartSel=" + markStart + ", StopSel=" + markEnd + ", MaxWords=32, MinWords=12"}
+	} else {
+		// Terms are lower case, so none of them reads as an operator
+		match := make([]string, len(terms))
+		for i, term := range terms {
+			match[i] = term + "*"
+		}
+		query = query.Joins("JOIN posts_fts ON posts_fts.docid = posts.id").
+			Where("posts_fts MATCH ?", strings.Join(match, " "))
+		snippet = "snippet(posts_fts, ?, ?, '…', -1, 16)"
+		args = []interface{}{markStart, markEnd}
+	}
+
+	if q.ProviderID != 0 {
+		query = query.Where("posts.provider_id = ?", q.ProviderID)
+	}
+	if q.Status != "" {
+		query = query.Where("posts.status = ?", q.Status)
+	}
+	if q.CampaignID != 0 {
+		query = query.Where("posts.campaign_id = ?", q.CampaignID)
+	}
+	if pattern := database.TagPattern(q.Tag); pattern != "" {
+		query = query.Where(database.TagCondition("posts.tags"), pattern)
+	}
+	if !q.From.IsZero() {
+		query = query.Where("posts.created_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		query = query.Where("posts.created_at < ?", q.To)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Select("posts.id, posts.title, posts.content, "+snippet+" AS snippet, "+
+		"posts.provider_id, providers.name AS provider, posts.campaign_id, posts.tags, posts.status, posts.created_at", args...).
+		Order("posts.created_at DESC, posts.id DESC").
+		Offset(q.Offset)
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var results []Result
+	if err := query.Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range results {
+		results[i].Snippet = highlight(results[i].Snippet)
+	}
+	return results, total, nil
+}
+
+// Terms splits text into the lower-cased words a search matches. Everything
+// else, including query operators, is dropped.
+func Terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// highlight escapes a snippet and turns the match markers into <mark> tags
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, markStart, "<mark>")
+	return strings.ReplaceAll(escaped, markEnd, "</mark>")
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestPosts_MatchesAndHighlights(t *testing.T) {
+	manager := database.NewTestManager(t)
+	defer manager.Close()
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+
+	main := database.Provider{Name: "main", Type: "mastodon", Config: "{}", UserID: "alice", IsActive: true}
+	other := database.Provider{Name: "other", Type: "tiktok", Config: "{}", UserID: "alice", IsActive: true}
+	db.Create(&main)
+	db.Create(&other)
+
+	old := time.Now().AddDate(0, -2, 0)
+	posts := []database.Post{
+		{Title: "Launch", Content: "Our <new> release is out", UserID: "alice", ProviderID: main.ID, Status: database.PostStatusPublished},
+		{Content: "Release notes draft", UserID: "alice", ProviderID: other.ID, Status: database.PostStatusDraft},
+		{Content: "Releases from last quarter", UserID: "alice", ProviderID: main.ID, Status: database.PostStatusPublished, CreatedAt: old},
+		{Content: "Nothing to see", UserID: "alice", ProviderID: main.ID, Status: database.PostStatusPublished},
+		{Content: "Someone else's release", UserID: "bob", ProviderID: main.ID, Status: database.PostStatusPublished},
+	}
+	for i := range posts {
+		if err := db.Create(&posts[i]).Error; err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	results, total, err := Posts(db, "alice", Query{Text: "releas"})
+	if err != nil {
+		t.Fatalf("Posts failed: %v", err)
+	}
+	if total != 3 || len(results) != 3 {
+		t.Fatalf("Expected 3 prefix matches, got %d (%d)", len(results), total)
+	}
+	if results[2].ID != posts[2].ID {
+		t.Errorf("Expected the oldest match last, got %+v", results)
+	}
+	launch := results[1]
+	if !strings.Contains(launch.Snippet, "<mark>release</mark>") || !strings.Contains(launch.Snippet, "&lt;new&gt;") {
+		t.Errorf("Expected an escaped, highlighted snippet, got %q", launch.Snippet)
+	}
+	if launch.Provider != "main" {
+		t.Errorf("Expected the provider name, got %q", launch.Provider)
+	}
+
+	results, total, _ = Posts(db, "alice", Query{Text: "release", ProviderID: main.ID, Status: database.PostStatusPublished, From: old.AddDate(0, 0, 1)})
+	if total != 1 || results[0].ID != posts[0].ID {
+		t.Errorf("Expected the filters to leave the launch post, got %+v", results)
+	}
+
+	// Edits and deletes reach the index
+	db.Model(&posts[3]).Update("content", "Release day")
+	db.Delete(&posts[0])
+	results, total, _ = Posts(db, "alice", Query{Text: "release day"})
+	if total != 1 || results[0].ID != posts[3].ID {
+		t.Errorf("Expected the edited post only, got %+v", results)
+	}
+}
+
+func TestPosts_RejectsEmptyQuery(t *testing.T) {
+	manager := database.NewTestManager(t)
+	defer manager.Close()
+	db, _ := manager.GetDB("alice")
+
+	if _, _, err := Posts(db, "alice", Query{Text: ` "* -- `}); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("Expected ErrEmptyQuery, got %v", err)
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := Terms(`Zażółć "gęślą" OR-jaźń*`)
+	want := []string{"zażółć", "gęślą", "or", "jaźń"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Terms() = %v, want %v", got, want)
+	}
+}
+
+func TestPosts_MatchesTags(t *testing.T) {
+	manager := database.NewTestManager(t)
+	defer manager.Close()
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+
+	post := database.Post{Content: "Hello world", UserID: "alice", Status: database.PostStatusPublished, Tags: database.Tags{"launch", "summer"}}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	results, total, err := Posts(db, "alice", Query{Text: "summer"})
+	if err != nil {
+		t.Fatalf("Posts failed: %v", err)
+	}
+	if total != 1 || results[0].ID != post.ID {
+		t.Fatalf("Expected a tag word to find the post, got %+v", results)
+	}
+
+	// Retagging reaches the index
+	db.Model(&post).Update("tags", database.Tags{"winter"})
+	if _, total, _ := Posts(db, "alice", Query{Text: "summer"}); total != 0 {
+		t.Errorf("Expected the old tag to stop matching, got %d", total)
+	}
+	if _, total, _ := Posts(db, "alice", Query{Text: "winter"}); total != 1 {
+		t.Errorf("Expected the new tag to match, got %d", total)
+	}
+}
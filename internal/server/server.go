@@ -4,10 +4,12 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/tkowalski/socgo/internal/database"
 	"github.com/tkowalski/socgo/internal/di"
 	"github.com/tkowalski/socgo/internal/handlers"
 	"github.com/tkowalski/socgo/internal/middleware"
 	"github.com/tkowalski/socgo/internal/oauth"
+	"github.com/tkowalski/socgo/internal/workspace"
 )
 
 func New(container *di.Container) http.Handler {
@@ -28,31 +30,201 @@ func New(container *di.Container) http.Handler {
 
 	// API token handler
 	apiTokenHandler := handlers.NewAPITokenHandler(container.GetDBManager())
+	apiTokenHandler.SetBaseURL(container.GetConfig().Server.BaseURL)
+
+	// Workspace service and handler
+	workspaceService := workspace.NewService(container.GetDBManager())
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService)
+
+	// Audit log handler
+	auditHandler := handlers.NewAuditHandler(container.GetDBManager())
+
+	// Webhook handler
+	webhookHandler := handlers.NewWebhookHandler(container.GetDBManager())
+
+	// Campaign handler
+	campaignHandler := handlers.NewCampaignHandler(container.GetDBManager())
+
+	// Snippet handler
+	snippetHandler := handlers.NewSnippetHandler(container.GetDBManager())
+
+	// Post template handler
+	postTemplateHandler := handlers.NewPostTemplateHandler(container.GetDBManager())
+
+	// Feed handler
+	feedHandler := handlers.NewFeedHandler(container.GetDBManager())
+
+	// Calendar feed and external calendar handler
+	calendarHandler := handlers.NewCalendarHandler(container.GetDBManager())
+
+	// Link rule and short link handler
+	linkHandler := handlers.NewLinkHandler(container.GetDBManager())
+	bioHandler := handlers.NewBioHandler(container.GetDBManager())
+	bioHandler.SetBaseURL(container.GetConfig().Server.BaseURL)
+	blackoutHandler := handlers.NewBlackoutHandler(container.GetDBManager())
+	limitHandler := handlers.NewLimitHandler(container.GetDBManager())
+
+	// Trash handler
+	trashHandler := handlers.NewTrashHandler(container.GetDBManager(), container.GetConfig().Trash.Retention)
+
+	// Database export handler
+	backupHandler := handlers.NewBackupHandler(container.GetDBManager())
+
+	// Account data export and import handler
+	portabilityHandler := handlers.NewPortabilityHandler(container.GetDBManager())
+
+	// Database pool statistics handler
+	statsHandler := handlers.NewStatsHandler(container.GetDBManager())
 
 	// Auth middleware
 	authMiddleware := middleware.NewAuthMiddleware(container.GetDBManager())
 
+	// Workspace middleware selects the tenant database and enforces roles
+	workspaceMiddleware := middleware.NewWorkspaceMiddleware(workspaceService).
+		AllowViewerWrites("/workspaces", "/api/workspaces")
+	r.Use(workspaceMiddleware.Resolve)
+
 	// Web routes (UI pages)
 	r.HandleFunc("/", webHandler.HomePage).Methods("GET")
 	r.HandleFunc("/dashboard", webHandler.DashboardPage).Methods("GET")
 	r.HandleFunc("/providers", webHandler.ProvidersPage).Methods("GET")
 	r.HandleFunc("/posts", webHandler.PostsPage).Methods("GET")
 	r.HandleFunc("/calendar", webHandler.CalendarPage).Methods("GET")
+	r.HandleFunc("/workspaces", workspaceHandler.WorkspacesPage).Methods("GET")
+	r.HandleFunc("/audit", auditHandler.AuditPage).Methods("GET")
+	r.HandleFunc("/campaigns", campaignHandler.CampaignsPage).Methods("GET")
+	r.HandleFunc("/snippets", snippetHandler.SnippetsPage).Methods("GET")
+	r.HandleFunc("/templates", postTemplateHandler.PostTemplatesPage).Methods("GET")
+	r.HandleFunc("/feeds", feedHandler.FeedsPage).Methods("GET")
+	r.HandleFunc("/links", linkHandler.LinksPage).Methods("GET")
+	r.HandleFunc("/blackouts", blackoutHandler.BlackoutsPage).Methods("GET")
+	r.HandleFunc("/webhooks", webhookHandler.WebhooksPage).Methods("GET")
+	r.HandleFunc("/trash", trashHandler.TrashPage).Methods("GET")
+	r.HandleFunc("/account", portabilityHandler.AccountPage).Methods("GET")
 	r.HandleFunc("/health", handlers.HealthHandler)
+	r.HandleFunc("/health/db", statsHandler.HandleDatabaseStats).Methods("GET")
 
-	// Web form handlers
-	r.HandleFunc("/posts", webHandler.HandlePost).Methods("POST")
+	// Web form handlers. Publishing or scheduling without review is for
+	// owners; editors go through drafts.
+	r.HandleFunc("/posts", middleware.RequireRole(database.WorkspaceRoleOwner, webHandler.HandlePost)).Methods("POST")
 
 	// HTMX/AJAX endpoints for web UI
+	r.HandleFunc("/posts/import", middleware.RequireRole(database.WorkspaceRoleOwner, postHandler.HandleImportPosts)).Methods("POST")
 	r.HandleFunc("/posts/history", postHandler.HandleHistory).Methods("GET")
+	r.HandleFunc("/posts/search", postHandler.HandleSearch).Methods("GET")
 	r.HandleFunc("/posts/calendar", postHandler.HandleCalendar).Methods("GET")
+	r.HandleFunc("/posts/calendar/day", postHandler.HandleCalendarDay).Methods("GET")
+	r.HandleFunc("/posts/jobs/{id}/reschedule", postHandler.HandleRescheduleJob).Methods("POST", "PATCH")
 	r.HandleFunc("/posts/calendar-page", postHandler.HandleCalendarPage).Methods("GET")
 
+	// Draft review workflow
+	r.HandleFunc("/posts/drafts", postHandler.HandleListDrafts).Methods("GET")
+	r.HandleFunc("/posts/drafts", postHandler.HandleCreateDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}", postHandler.HandleGetDraft).Methods("GET")
+	r.HandleFunc("/posts/drafts/{id}", postHandler.HandleUpdateDraft).Methods("PUT")
+	r.HandleFunc("/posts/drafts/{id}/submit", postHandler.HandleSubmitDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}/approve", postHandler.HandleApproveDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}/reject", postHandler.HandleRejectDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}/comments", postHandler.HandleCommentDraft).Methods("POST")
+	r.HandleFunc("/posts/drafts/{id}/schedule", postHandler.HandleScheduleDraft).Methods("POST")
+
+	// Audit log
+	r.HandleFunc("/audit/logs", auditHandler.HandleListAudit).Methods("GET")
+	r.HandleFunc("/audit/export.csv", auditHandler.HandleExportAudit).Methods("GET")
+
+	// Campaigns
+	r.HandleFunc("/campaigns", campaignHandler.HandleCreateCampaign).Methods("POST")
+	r.HandleFunc("/campaigns/list", campaignHandler.HandleCampaignStats).Methods("GET")
+	r.HandleFunc("/campaigns/options", campaignHandler.HandleCampaignOptions).Methods("GET")
+	r.HandleFunc("/campaigns/{id:[0-9]+}", campaignHandler.HandleUpdateCampaign).Methods("PUT")
+	r.HandleFunc("/campaigns/{id:[0-9]+}", campaignHandler.HandleDeleteCampaign).Methods("DELETE")
+
+	// Snippets and hashtag groups
+	r.HandleFunc("/snippets", snippetHandler.HandleCreateSnippet).Methods("POST")
+	r.HandleFunc("/snippets/list", snippetHandler.HandleListSnippets).Methods("GET")
+	r.HandleFunc("/snippets/options", snippetHandler.HandleSnippetOptions).Methods("GET")
+	r.HandleFunc("/snippets/{id:[0-9]+}", snippetHandler.HandleUpdateSnippet).Methods("PUT")
+	r.HandleFunc("/snippets/{id:[0-9]+}", snippetHandler.HandleDeleteSnippet).Methods("DELETE")
+
+	// Post templates
+	r.HandleFunc("/templates", postTemplateHandler.HandleCreatePostTemplate).Methods("POST")
+	r.HandleFunc("/templates/list", postTemplateHandler.HandleListPostTemplates).Methods("GET")
+	r.HandleFunc("/templates/{id:[0-9]+}", postTemplateHandler.HandleUpdatePostTemplate).Methods("PUT")
+	r.HandleFunc("/templates/{id:[0-9]+}", postTemplateHandler.HandleDeletePostTemplate).Methods("DELETE")
+	r.HandleFunc("/templates/{id:[0-9]+}/preview", postTemplateHandler.HandlePreviewPostTemplate).Methods("POST")
+	r.HandleFunc("/templates/{id:[0-9]+}/schedule", middleware.RequireRole(database.WorkspaceRoleOwner, postTemplateHandler.HandleSchedulePostTemplate)).Methods("POST")
+
+	// External calendars
+	r.HandleFunc("/calendars", calendarHandler.HandleImportCalendar).Methods("POST")
+	r.HandleFunc("/calendars/list", calendarHandler.HandleListCalendars).Methods("GET")
+	r.HandleFunc("/calendars/{id:[0-9]+}/sync", calendarHandler.HandleSyncCalendar).Methods("POST")
+	r.HandleFunc("/calendars/{id:[0-9]+}", calendarHandler.HandleDeleteCalendar).Methods("DELETE")
+
+	// Feeds
+	r.HandleFunc("/feeds", feedHandler.HandleCreateFeed).Methods("POST")
+	r.HandleFunc("/feeds/list", feedHandler.HandleListFeeds).Methods("GET")
+	r.HandleFunc("/feeds/{id:[0-9]+}", feedHandler.HandleUpdateFeed).Methods("PUT")
+	r.HandleFunc("/feeds/{id:[0-9]+}", feedHandler.HandleDeleteFeed).Methods("DELETE")
+	r.HandleFunc("/feeds/{id:[0-9]+}/poll", feedHandler.HandlePollFeed).Methods("POST")
+
+	// Link rules and click stats
+	r.HandleFunc("/links/rules", linkHandler.HandleCreateRule).Methods("POST")
+	r.HandleFunc("/links/rules/list", linkHandler.HandleListRules).Methods("GET")
+	r.HandleFunc("/links/rules/{id:[0-9]+}", linkHandler.HandleUpdateRule).Methods("PUT")
+	r.HandleFunc("/links/rules/{id:[0-9]+}", linkHandler.HandleDeleteRule).Methods("DELETE")
+	r.HandleFunc("/links/stats", linkHandler.HandleLinkStats).Methods("GET")
+	r.HandleFunc("/links/bio", bioHandler.HandleGetBio).Methods("GET")
+	r.HandleFunc("/links/bio", bioHandler.HandleSaveBio).Methods("PUT")
+	r.HandleFunc("/links/bio/links", bioHandler.HandleCreateLink).Methods("POST")
+	r.HandleFunc("/links/bio/links/list", bioHandler.HandleListLinks).Methods("GET")
+	r.HandleFunc("/links/bio/links/{id:[0-9]+}", bioHandler.HandleDeleteLink).Methods("DELETE")
+	// Blackout windows, pauses and posting limits (changes are for owners only)
+	r.HandleFunc("/blackouts/windows", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandleCreateWindow)).Methods("POST")
+	r.HandleFunc("/blackouts/windows/list", blackoutHandler.HandleListWindows).Methods("GET")
+	r.HandleFunc("/blackouts/windows/{id:[0-9]+}", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandleDeleteWindow)).Methods("DELETE")
+	r.HandleFunc("/blackouts/status", blackoutHandler.HandleStatus).Methods("GET")
+	r.HandleFunc("/blackouts/pause", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandlePause)).Methods("POST")
+	r.HandleFunc("/blackouts/resume", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandleResume)).Methods("POST")
+	r.HandleFunc("/blackouts/limits", middleware.RequireRole(database.WorkspaceRoleOwner, limitHandler.HandleSetLimit)).Methods("POST")
+	r.HandleFunc("/blackouts/limits/list", limitHandler.HandleListLimits).Methods("GET")
+	r.HandleFunc("/blackouts/limits/{provider_id:[0-9]+}", middleware.RequireRole(database.WorkspaceRoleOwner, limitHandler.HandleDeleteLimit)).Methods("DELETE")
+
+	// Outgoing webhooks (owners only)
+	r.HandleFunc("/webhooks/endpoints", webhookHandler.HandleListWebhooks).Methods("GET")
+	r.HandleFunc("/webhooks/endpoints", middleware.RequireRole(database.WorkspaceRoleOwner, webhookHandler.HandleCreateWebhook)).Methods("POST")
+	r.HandleFunc("/webhooks/endpoints/{id}", middleware.RequireRole(database.WorkspaceRoleOwner, webhookHandler.HandleDeleteWebhook)).Methods("DELETE")
+	r.HandleFunc("/webhooks/endpoints/{id}/deliveries", webhookHandler.HandleListDeliveries).Methods("GET")
+
+	// Trash of deleted posts and providers (purging is for owners only)
+	r.HandleFunc("/{kind:posts|providers}/{id:[0-9]+}", trashHandler.HandleDelete).Methods("DELETE")
+	r.HandleFunc("/trash/items", trashHandler.HandleListTrash).Methods("GET")
+	r.HandleFunc("/trash/{kind}/{id}/restore", trashHandler.HandleRestore).Methods("POST")
+	r.HandleFunc("/trash/{kind}/{id}", middleware.RequireRole(database.WorkspaceRoleOwner, trashHandler.HandlePurge)).Methods("DELETE")
+
+	// Point-in-time database export (owners only)
+	r.HandleFunc("/backups/export.db", middleware.RequireRole(database.WorkspaceRoleOwner, backupHandler.HandleExportDatabase)).Methods("GET")
+
+	// Account data archive (owners only)
+	r.HandleFunc("/account/export", middleware.RequireRole(database.WorkspaceRoleOwner, portabilityHandler.HandleExport)).Methods("GET")
+	r.HandleFunc("/account/import", middleware.RequireRole(database.WorkspaceRoleOwner, portabilityHandler.HandleImport)).Methods("POST")
+
+	// Workspaces and members
+	r.HandleFunc("/workspaces", workspaceHandler.HandleCreateWorkspace).Methods("POST")
+	r.HandleFunc("/workspaces/list", workspaceHandler.HandleListWorkspaces).Methods("GET")
+	r.HandleFunc("/workspaces/switch", workspaceHandler.HandleSwitchWorkspace).Methods("POST")
+	r.HandleFunc("/workspaces/{id}/members", workspaceHandler.HandleListMembers).Methods("GET")
+	r.HandleFunc("/workspaces/{id}/members", workspaceHandler.HandleSetMember).Methods("POST")
+	r.HandleFunc("/workspaces/{id}/members/{user}", workspaceHandler.HandleSetMember).Methods("PUT")
+	r.HandleFunc("/workspaces/{id}/members/{user}", workspaceHandler.HandleRemoveMember).Methods("DELETE")
+
 	// Stats endpoints for dashboard
 	r.HandleFunc("/api/stats/providers", webHandler.HandleProvidersCount).Methods("GET")
 	r.HandleFunc("/api/stats/published", webHandler.HandlePublishedCount).Methods("GET")
 	r.HandleFunc("/api/stats/scheduled", webHandler.HandleScheduledCount).Methods("GET")
 	r.HandleFunc("/api/stats/monthly", webHandler.HandleMonthlyCount).Methods("GET")
+	r.HandleFunc("/api/stats/campaigns", campaignHandler.HandleCampaignStats).Methods("GET")
+	r.HandleFunc("/api/stats/campaigns/{id:[0-9]+}", campaignHandler.HandleCampaignStat).Methods("GET")
+	r.HandleFunc("/api/stats/links", linkHandler.HandleLinkStats).Methods("GET")
 	r.HandleFunc("/api/providers/options", webHandler.HandleProvidersOptions).Methods("GET")
 
 	// OAuth routes
@@ -65,12 +237,103 @@ func New(container *di.Container) http.Handler {
 	// API token generation endpoint (public)
 	r.HandleFunc("/api-tokens", apiTokenHandler.HandleCreateToken).Methods("POST")
 
+	// ICS feed, authenticated by a calendar token in the URL
+	r.HandleFunc("/calendar.ics", calendarHandler.HandleFeed).Methods("GET")
+
+	// Short links and link-in-bio pages (public)
+	r.HandleFunc("/l/{code}", linkHandler.HandleRedirect).Methods("GET")
+	r.HandleFunc("/bio/{slug}", bioHandler.HandlePage).Methods("GET")
+
 	// Protected API routes with auth middleware
 	apiRouter := r.PathPrefix("/api").Subrouter()
-	apiRouter.Use(authMiddleware.APIAuthMiddleware)
+	apiRouter.Use(authMiddleware.APIAuthMiddleware, workspaceMiddleware.Resolve)
 
-	// JSON API endpoints (for external integrations)
-	apiRouter.HandleFunc("/posts", postHandler.HandlePost).Methods("POST")
+	// JSON API endpoints (for external integrations). Publishing or
+	// scheduling without review is for owners; editors go through drafts.
+	apiRouter.HandleFunc("/posts", middleware.RequireRole(database.WorkspaceRoleOwner, postHandler.HandlePost)).Methods("POST")
+	apiRouter.HandleFunc("/posts/import", middleware.RequireRole(database.WorkspaceRoleOwner, postHandler.HandleImportPosts)).Methods("POST")
+	apiRouter.HandleFunc("/posts/search", postHandler.HandleSearch).Methods("GET")
+	apiRouter.HandleFunc("/drafts", postHandler.HandleListDrafts).Methods("GET")
+	apiRouter.HandleFunc("/drafts", postHandler.HandleCreateDraft).Methods("POST")
+	apiRouter.HandleFunc("/drafts/{id}", postHandler.HandleGetDraft).Methods("GET")
+	apiRouter.HandleFunc("/drafts/{id}", postHandler.HandleUpdateDraft).Methods("PUT")
+	apiRouter.HandleFunc("/drafts/{id}/submit", postHandler.HandleSubmitDraft).Methods("POST")
+	apiRouter.HandleFunc("/drafts/{id}/approve", postHandler.HandleApproveDraft).Methods("POST")
+	apiRouter.HandleFunc("/drafts/{id}/reject", postHandler.HandleRejectDraft).Methods("POST")
+	apiRouter.HandleFunc("/drafts/{id}/comments", postHandler.HandleCommentDraft).Methods("POST")
+	apiRouter.HandleFunc("/drafts/{id}/schedule", postHandler.HandleScheduleDraft).Methods("POST")
+	apiRouter.HandleFunc("/audit", auditHandler.HandleListAudit).Methods("GET")
+	apiRouter.HandleFunc("/audit/export.csv", auditHandler.HandleExportAudit).Methods("GET")
+	apiRouter.HandleFunc("/campaigns", campaignHandler.HandleCampaignStats).Methods("GET")
+	apiRouter.HandleFunc("/campaigns", campaignHandler.HandleCreateCampaign).Methods("POST")
+	apiRouter.HandleFunc("/campaigns/{id:[0-9]+}", campaignHandler.HandleCampaignStat).Methods("GET")
+	apiRouter.HandleFunc("/campaigns/{id:[0-9]+}", campaignHandler.HandleUpdateCampaign).Methods("PUT")
+	apiRouter.HandleFunc("/campaigns/{id:[0-9]+}", campaignHandler.HandleDeleteCampaign).Methods("DELETE")
+	apiRouter.HandleFunc("/snippets", snippetHandler.HandleListSnippets).Methods("GET")
+	apiRouter.HandleFunc("/snippets", snippetHandler.HandleCreateSnippet).Methods("POST")
+	apiRouter.HandleFunc("/snippets/{id:[0-9]+}", snippetHandler.HandleGetSnippet).Methods("GET")
+	apiRouter.HandleFunc("/snippets/{id:[0-9]+}", snippetHandler.HandleUpdateSnippet).Methods("PUT")
+	apiRouter.HandleFunc("/snippets/{id:[0-9]+}", snippetHandler.HandleDeleteSnippet).Methods("DELETE")
+	apiRouter.HandleFunc("/templates", postTemplateHandler.HandleListPostTemplates).Methods("GET")
+	apiRouter.HandleFunc("/templates", postTemplateHandler.HandleCreatePostTemplate).Methods("POST")
+	apiRouter.HandleFunc("/templates/{id:[0-9]+}", postTemplateHandler.HandleGetPostTemplate).Methods("GET")
+	apiRouter.HandleFunc("/templates/{id:[0-9]+}", postTemplateHandler.HandleUpdatePostTemplate).Methods("PUT")
+	apiRouter.HandleFunc("/templates/{id:[0-9]+}", postTemplateHandler.HandleDeletePostTemplate).Methods("DELETE")
+	apiRouter.HandleFunc("/templates/{id:[0-9]+}/preview", postTemplateHandler.HandlePreviewPostTemplate).Methods("POST")
+	apiRouter.HandleFunc("/templates/{id:[0-9]+}/schedule", middleware.RequireRole(database.WorkspaceRoleOwner, postTemplateHandler.HandleSchedulePostTemplate)).Methods("POST")
+	apiRouter.HandleFunc("/calendars", calendarHandler.HandleListCalendars).Methods("GET")
+	apiRouter.HandleFunc("/calendars", calendarHandler.HandleImportCalendar).Methods("POST")
+	apiRouter.HandleFunc("/calendars/{id:[0-9]+}/sync", calendarHandler.HandleSyncCalendar).Methods("POST")
+	apiRouter.HandleFunc("/calendars/{id:[0-9]+}", calendarHandler.HandleDeleteCalendar).Methods("DELETE")
+	apiRouter.HandleFunc("/feeds", feedHandler.HandleListFeeds).Methods("GET")
+	apiRouter.HandleFunc("/feeds", feedHandler.HandleCreateFeed).Methods("POST")
+	apiRouter.HandleFunc("/feeds/{id:[0-9]+}", feedHandler.HandleGetFeed).Methods("GET")
+	apiRouter.HandleFunc("/feeds/{id:[0-9]+}", feedHandler.HandleUpdateFeed).Methods("PUT")
+	apiRouter.HandleFunc("/feeds/{id:[0-9]+}", feedHandler.HandleDeleteFeed).Methods("DELETE")
+	apiRouter.HandleFunc("/feeds/{id:[0-9]+}/poll", feedHandler.HandlePollFeed).Methods("POST")
+	apiRouter.HandleFunc("/links/rules", linkHandler.HandleListRules).Methods("GET")
+	apiRouter.HandleFunc("/links/rules", linkHandler.HandleCreateRule).Methods("POST")
+	apiRouter.HandleFunc("/links/rules/{id:[0-9]+}", linkHandler.HandleGetRule).Methods("GET")
+	apiRouter.HandleFunc("/links/rules/{id:[0-9]+}", linkHandler.HandleUpdateRule).Methods("PUT")
+	apiRouter.HandleFunc("/links/rules/{id:[0-9]+}", linkHandler.HandleDeleteRule).Methods("DELETE")
+	apiRouter.HandleFunc("/posts/{id:[0-9]+}/links", linkHandler.HandlePostLinks).Methods("GET")
+	apiRouter.HandleFunc("/bio", bioHandler.HandleGetBio).Methods("GET")
+	apiRouter.HandleFunc("/bio", bioHandler.HandleSaveBio).Methods("PUT")
+	apiRouter.HandleFunc("/bio/links", bioHandler.HandleListLinks).Methods("GET")
+	apiRouter.HandleFunc("/bio/links", bioHandler.HandleCreateLink).Methods("POST")
+	apiRouter.HandleFunc("/bio/links/{id:[0-9]+}", bioHandler.HandleGetLink).Methods("GET")
+	apiRouter.HandleFunc("/bio/links/{id:[0-9]+}", bioHandler.HandleUpdateLink).Methods("PUT")
+	apiRouter.HandleFunc("/bio/links/{id:[0-9]+}", bioHandler.HandleDeleteLink).Methods("DELETE")
+	apiRouter.HandleFunc("/blackouts", blackoutHandler.HandleListWindows).Methods("GET")
+	apiRouter.HandleFunc("/blackouts", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandleCreateWindow)).Methods("POST")
+	apiRouter.HandleFunc("/blackouts/{id:[0-9]+}", blackoutHandler.HandleGetWindow).Methods("GET")
+	apiRouter.HandleFunc("/blackouts/{id:[0-9]+}", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandleUpdateWindow)).Methods("PUT")
+	apiRouter.HandleFunc("/blackouts/{id:[0-9]+}", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandleDeleteWindow)).Methods("DELETE")
+	apiRouter.HandleFunc("/publishing", blackoutHandler.HandleStatus).Methods("GET")
+	apiRouter.HandleFunc("/publishing/pause", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandlePause)).Methods("POST")
+	apiRouter.HandleFunc("/publishing/resume", middleware.RequireRole(database.WorkspaceRoleOwner, blackoutHandler.HandleResume)).Methods("POST")
+	apiRouter.HandleFunc("/limits", limitHandler.HandleListLimits).Methods("GET")
+	apiRouter.HandleFunc("/limits/{provider_id:[0-9]+}", limitHandler.HandleGetLimit).Methods("GET")
+	apiRouter.HandleFunc("/limits/{provider_id:[0-9]+}", middleware.RequireRole(database.WorkspaceRoleOwner, limitHandler.HandleSetLimit)).Methods("PUT")
+	apiRouter.HandleFunc("/limits/{provider_id:[0-9]+}", middleware.RequireRole(database.WorkspaceRoleOwner, limitHandler.HandleDeleteLimit)).Methods("DELETE")
+	apiRouter.HandleFunc("/webhooks", webhookHandler.HandleListWebhooks).Methods("GET")
+	apiRouter.HandleFunc("/webhooks", middleware.RequireRole(database.WorkspaceRoleOwner, webhookHandler.HandleCreateWebhook)).Methods("POST")
+	apiRouter.HandleFunc("/webhooks/{id}", middleware.RequireRole(database.WorkspaceRoleOwner, webhookHandler.HandleDeleteWebhook)).Methods("DELETE")
+	apiRouter.HandleFunc("/webhooks/{id}/deliveries", webhookHandler.HandleListDeliveries).Methods("GET")
+	apiRouter.HandleFunc("/{kind:posts}/{id:[0-9]+}", trashHandler.HandleDelete).Methods("DELETE")
+	apiRouter.HandleFunc("/trash", trashHandler.HandleListTrash).Methods("GET")
+	apiRouter.HandleFunc("/trash/{kind}/{id}", trashHandler.HandleDelete).Methods("POST")
+	apiRouter.HandleFunc("/trash/{kind}/{id}/restore", trashHandler.HandleRestore).Methods("POST")
+	apiRouter.HandleFunc("/trash/{kind}/{id}", middleware.RequireRole(database.WorkspaceRoleOwner, trashHandler.HandlePurge)).Methods("DELETE")
+	apiRouter.HandleFunc("/backups/export.db", middleware.RequireRole(database.WorkspaceRoleOwner, backupHandler.HandleExportDatabase)).Methods("GET")
+	apiRouter.HandleFunc("/export", middleware.RequireRole(database.WorkspaceRoleOwner, portabilityHandler.HandleExport)).Methods("GET")
+	apiRouter.HandleFunc("/import", middleware.RequireRole(database.WorkspaceRoleOwner, portabilityHandler.HandleImport)).Methods("POST")
+	apiRouter.HandleFunc("/workspaces", workspaceHandler.HandleListWorkspaces).Methods("GET")
+	apiRouter.HandleFunc("/workspaces", workspaceHandler.HandleCreateWorkspace).Methods("POST")
+	apiRouter.HandleFunc("/workspaces/{id}/members", workspaceHandler.HandleListMembers).Methods("GET")
+	apiRouter.HandleFunc("/workspaces/{id}/members", workspaceHandler.HandleSetMember).Methods("POST")
+	apiRouter.HandleFunc("/workspaces/{id}/members/{user}", workspaceHandler.HandleSetMember).Methods("PUT")
+	apiRouter.HandleFunc("/workspaces/{id}/members/{user}", workspaceHandler.HandleRemoveMember).Methods("DELETE")
 
 	return r
 }
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/config"
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/di"
+	"github.com/tkowalski/socgo/internal/middleware"
+	"github.com/tkowalski/socgo/internal/oauth"
+	"github.com/tkowalski/socgo/internal/providers"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"github.com/tkowalski/socgo/internal/workspace"
+)
+
+func TestNew_PublishingControlsNeedOwners(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	cfg := &config.Config{}
+	container := di.NewContainer()
+	container.Register("config", cfg)
+	container.Register("database", dbManager)
+	container.Register("provider_service", providers.NewProviderService(dbManager, oauth.NewService(dbManager, cfg)))
+	handler := New(container)
+
+	// The default user edits a workspace owned by someone else
+	ws, err := workspace.NewService(dbManager).Create("owner", "Team")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if _, err := workspace.NewService(dbManager).SetMember(ws.ID, tenant.DefaultUserID, database.WorkspaceRoleEditor); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	db, err := dbManager.GetDB(tenant.DefaultUserID)
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	token := "editor-token"
+	if err := db.Create(&database.APIToken{Hash: fmt.Sprintf("%x", sha256.Sum256([]byte(token))), UserID: tenant.DefaultUserID}).Error; err != nil {
+		t.Fatalf("Failed to create API token: %v", err)
+	}
+
+	for _, route := range []struct {
+		method, path string
+		forbidden    bool
+	}{
+		{"POST", "/blackouts/windows", true},
+		{"DELETE", "/blackouts/windows/1", true},
+		{"POST", "/blackouts/pause", true},
+		{"POST", "/blackouts/resume", true},
+		{"POST", "/blackouts/limits", true},
+		{"DELETE", "/blackouts/limits/1", true},
+		{"GET", "/blackouts/windows/list", false},
+		{"POST", "/api/blackouts", true},
+		{"PUT", "/api/blackouts/1", true},
+		{"DELETE", "/api/blackouts/1", true},
+		{"POST", "/api/publishing/pause", true},
+		{"POST", "/api/publishing/resume", true},
+		{"PUT", "/api/limits/1", true},
+		{"DELETE", "/api/limits/1", true},
+		{"GET", "/api/limits", false},
+	} {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json")
+			if strings.HasPrefix(route.path, "/api/") {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			req.Header.Set(middleware.WorkspaceHeader, strconv.FormatUint(uint64(ws.ID), 10))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			forbidden := rr.Code == http.StatusForbidden && strings.Contains(rr.Body.String(), "requires the owner role")
+			if forbidden != route.forbidden {
+				t.Errorf("Expected forbidden=%v for an editor, got %d %s", route.forbidden, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+// Package snippets manages the reusable snippets and hashtag groups of the
+// post composer and expands the placeholders of post content at publish time.
+package snippets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when the snippet does not exist
+	ErrNotFound = errors.New("snippet not found")
+	// ErrInvalid wraps validation errors of an Input
+	ErrInvalid = errors.New("invalid snippet")
+)
+
+// Placeholders a post may contain. Besides these, {{snippet:Name}} inserts
+// the body of the snippet or hashtag group Name.
+const (
+	VarDate     = "date"     // publish date, YYYY-MM-DD
+	VarTime     = "time"     // publish time, HH:MM
+	VarProvider = "provider" // provider the post is published to
+	VarCampaign = "campaign" // name of the post's campaign
+	VarTags     = "tags"     // the post's tags as hashtags
+)
+
+// placeholderPattern matches {{name}} and {{name:argument}}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*(?::\s*([^{}]*?)\s*)?\}\}`)
+
+// Input holds the editable fields of a snippet
+type Input struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Body string `json:"body"`
+}
+
+// Vars are the values of placeholders, keyed by placeholder name
+type Vars map[string]string
+
+type varsKey struct{}
+
+// WithVars returns a context carrying vars for the expansion of the content
+// published with it
+func WithVars(ctx context.Context, vars Vars) context.Context {
+	return context.WithValue(ctx, varsKey{}, vars)
+}
+
+// VarsFromContext returns the vars set by WithVars, or nil
+func VarsFromContext(ctx context.Context) Vars {
+	vars, _ := ctx.Value(varsKey{}).(Vars)
+	return vars
+}
+
+// PostVars returns the vars of a post or job in campaignID with tags
+func PostVars(db *gorm.DB, userID string, campaignID *uint, tags database.Tags) (Vars, error) {
+	vars := Vars{VarCampaign: "", VarTags: Hashtags(tags)}
+	if campaignID != nil {
+		var campaign database.Campaign
+		result := db.Where("user_id = ? AND id = ?", userID, *campaignID).Limit(1).Find(&campaign)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to load campaign: %w", result.Error)
+		}
+		vars[VarCampaign] = campaign.Name
+	}
+	return vars, nil
+}
+
+// Hashtags formats tags as space separated hashtags
+func Hashtags(tags []string) string {
+	hashtags := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(tag), "#")), "")
+		if tag != "" {
+			hashtags = append(hashtags, "#"+tag)
+		}
+	}
+	return strings.Join(hashtags, " ")
+}
+
+// Expand replaces the placeholders of content: the date and time of now, the
+// values of vars and the snippets of userID. Unknown placeholders are kept, so
+// text that merely looks like one is published unchanged.
+func Expand(db *gorm.DB, userID, content string, now time.Time, vars Vars) (string, error) {
+	if !strings.Contains(content, "{{") {
+		return content, nil
+	}
+
+	values := Vars{VarDate: now.Format("2006-01-02"), VarTime: now.Format("15:04")}
+	for name, value := range vars {
+		values[name] = value
+	}
+
+	var snippets map[string]string
+	var err error
+	expanded := placeholderPattern.ReplaceAllStringFunc(content, func(match string) string {
+		parts := placeholderPattern.FindStringSubmatch(match)
+		name, argument := parts[1], parts[2]
+		if name != "snippet" {
+			if value, ok := values[name]; ok && argument == "" {
+				return value
+			}
+			return match
+		}
+
+		if snippets == nil && err == nil {
+			snippets, err = bodies(db, userID)
+		}
+		body, ok := snippets[strings.ToLower(argument)]
+		if !ok {
+			return match
+		}
+		// Snippets may use variables but not other snippets
+		return placeholderPattern.ReplaceAllStringFunc(body, func(inner string) string {
+			parts := placeholderPattern.FindStringSubmatch(inner)
+			if value, ok := values[parts[1]]; ok && parts[2] == "" {
+				return value
+			}
+			return inner
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// bodies returns the bodies of the snippets of userID by lower case name
+func bodies(db *gorm.DB, userID string) (map[string]string, error) {
+	var snippets []database.Snippet
+	if err := db.Where("user_id = ?", userID).Find(&snippets).Error; err != nil {
+		return nil, fmt.Errorf("failed to load snippets: %w", err)
+	}
+	result := make(map[string]string, len(snippets))
+	for _, snippet := range snippets {
+		result[strings.ToLower(snippet.Name)] = snippet.Body
+	}
+	return result, nil
+}
+
+// List returns the snippets of userID of kind, or of every kind when kind is
+// empty, ordered by name
+func List(db *gorm.DB, userID, kind string) ([]database.Snippet, error) {
+	query := db.Where("user_id = ?", userID)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var snippets []database.Snippet
+	err := query.Order("kind, name").Find(&snippets).Error
+	return snippets, err
+}
+
+// Get returns the snippet id of userID
+func Get(db *gorm.DB, userID string, id uint) (*database.Snippet, error) {
+	var snippet database.Snippet
+	result := db.Where("user_id = ? AND id = ?", userID, id).Limit(1).Find(&snippet)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &snippet, nil
+}
+
+// Create adds a snippet for userID
+func Create(ctx context.Context, db *gorm.DB, userID string, in Input) (*database.Snippet, error) {
+	snippet := &database.Snippet{UserID: userID}
+	if err := apply(db, snippet, in); err != nil {
+		return nil, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(snippet).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionSnippetCreated, TargetType: audit.TargetSnippet, TargetID: snippet.ID, After: snippet,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// Update replaces the fields of the snippet id with in
+func Update(ctx context.Context, db *gorm.DB, userID string, id uint, in Input) (*database.Snippet, error) {
+	snippet, err := Get(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *snippet
+	if err := apply(db, snippet, in); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(snippet).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionSnippetUpdated, TargetType: audit.TargetSnippet, TargetID: snippet.ID, Before: before, After: snippet,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// Delete removes the snippet id. Posts referring to it by name keep the
+// placeholder.
+func Delete(ctx context.Context, db *gorm.DB, userID string, id uint) error {
+	snippet, err := Get(db, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(snippet).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			Action: audit.ActionSnippetDeleted, TargetType: audit.TargetSnippet, TargetID: id, Before: snippet,
+		})
+	})
+}
+
+// apply validates in and copies it onto snippet. Hashtag groups are stored
+// as space separated hashtags.
+func apply(db *gorm.DB, snippet *database.Snippet, in Input) error {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if strings.ContainsAny(name, "{}:") {
+		return fmt.Errorf("%w: name may not contain braces or colons", ErrInvalid)
+	}
+
+	kind := strings.TrimSpace(in.Kind)
+	if kind == "" {
+		kind = database.SnippetKindText
+	}
+	body := strings.TrimSpace(in.Body)
+	switch kind {
+	case database.SnippetKindText:
+	case database.SnippetKindHashtags:
+		body = Hashtags(strings.FieldsFunc(body, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\n' || r == '\t' || r == '\r'
+		}))
+	default:
+		return fmt.Errorf("%w: kind must be %s or %s", ErrInvalid, database.SnippetKindText, database.SnippetKindHashtags)
+	}
+	if body == "" {
+		return fmt.Errorf("%w: body is required", ErrInvalid)
+	}
+
+	var taken int64
+	if err := db.Model(&database.Snippet{}).
+		Where("user_id = ? AND LOWER(name) = ? AND id <> ?", snippet.UserID, strings.ToLower(name), snippet.ID).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("%w: a snippet named %q already exists", ErrInvalid, name)
+	}
+
+	snippet.Name = name
+	snippet.Kind = kind
+	snippet.Body = body
+	return nil
+}
@@ -0,0 +1,72 @@
+package snippets
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestCreate_Validates(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+
+	group, err := Create(ctx, db, "alice", Input{Name: " Launch ", Kind: database.SnippetKindHashtags, Body: "launch, #Spring\nnew product"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if group.Name != "Launch" || group.Body != "#launch #Spring #new #product" {
+		t.Errorf("Unexpected hashtag group: %+v", group)
+	}
+
+	for name, in := range map[string]Input{
+		"no name":    {Body: "text"},
+		"no body":    {Name: "Empty"},
+		"bad name":   {Name: "a:b", Body: "text"},
+		"bad kind":   {Name: "Other", Kind: "emoji", Body: "text"},
+		"taken name": {Name: "launch", Body: "text"},
+	} {
+		if _, err := Create(ctx, db, "alice", in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+
+	if _, err := Update(ctx, db, "alice", group.ID, Input{Name: "Launch", Body: "Plain text"}); err != nil {
+		t.Errorf("Update failed: %v", err)
+	}
+	if err := Delete(ctx, db, "bob", group.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another user's snippet, got %v", err)
+	}
+}
+
+func TestExpand_ReplacesPlaceholders(t *testing.T) {
+	db := database.NewTestDB(t, "alice")
+	ctx := context.Background()
+	Create(ctx, db, "alice", Input{Name: "Footer", Body: "More at example.com during {{campaign}}"})
+	Create(ctx, db, "alice", Input{Name: "Spring", Kind: database.SnippetKindHashtags, Body: "spring sale"})
+	campaign := database.Campaign{Name: "Spring sale", Color: "#3b82f6", UserID: "alice"}
+	db.Create(&campaign)
+
+	vars, err := PostVars(db, "alice", &campaign.ID, database.Tags{"launch", "video"})
+	if err != nil {
+		t.Fatalf("PostVars failed: %v", err)
+	}
+	vars[VarProvider] = "mastodon"
+	now := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	got, err := Expand(db, "alice", "On {{date}} at {{ time }} via {{provider}}: {{snippet:footer}} {{snippet: Spring}} {{tags}} {{unknown}} {{snippet:Missing}}", now, vars)
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	want := "On 2026-03-01 at 09:30 via mastodon: More at example.com during Spring sale #spring #sale #launch #video {{unknown}} {{snippet:Missing}}"
+	if got != want {
+		t.Errorf("Expand = %q, want %q", got, want)
+	}
+
+	// Snippets of other users are not visible
+	if got, _ := Expand(db, "bob", "{{snippet:Footer}}", now, nil); got != "{{snippet:Footer}}" {
+		t.Errorf("Expected another user's snippet to stay unexpanded, got %q", got)
+	}
+}
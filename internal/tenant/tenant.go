@@ -0,0 +1,82 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+// DefaultUserID identifies the single local user until real user
+// authentication exists
+const DefaultUserID = "default_user"
+
+// SystemUserID is the actor of work done in the background, such as the
+// scheduler publishing a post
+const SystemUserID = "system"
+
+// Request sources
+const (
+	SourceUI        = "ui"
+	SourceAPI       = "api_token"
+	SourceScheduler = "scheduler"
+)
+
+// Context describes who is making a request and which database it targets
+type Context struct {
+	UserID      string
+	WorkspaceID uint
+	Role        string
+	Source      string
+}
+
+type contextKey struct{}
+
+var roleRank = map[string]int{
+	database.WorkspaceRoleViewer: 1,
+	database.WorkspaceRoleEditor: 2,
+	database.WorkspaceRoleOwner:  3,
+}
+
+// DBKey returns the database key for the request: the workspace database when
+// a workspace is selected, otherwise the user's personal database
+func (c Context) DBKey() string {
+	if c.WorkspaceID != 0 {
+		return database.WorkspaceDBKey(c.WorkspaceID)
+	}
+	return c.UserID
+}
+
+// Can reports whether the context's role is at least the given role
+func (c Context) Can(role string) bool {
+	return roleRank[c.Role] >= roleRank[role]
+}
+
+// IsValidRole reports whether role is a known workspace role
+func IsValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// WithContext stores the tenant context in ctx
+func WithContext(ctx context.Context, c Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the tenant context stored in ctx. Without one, the
+// default user acts as owner of their personal database.
+func FromContext(ctx context.Context) Context {
+	if c, ok := ctx.Value(contextKey{}).(Context); ok {
+		return c
+	}
+	return Context{
+		UserID: DefaultUserID,
+		Role:   database.WorkspaceRoleOwner,
+		Source: SourceUI,
+	}
+}
+
+// FromRequest returns the tenant context of an HTTP request
+func FromRequest(r *http.Request) Context {
+	return FromContext(r.Context())
+}
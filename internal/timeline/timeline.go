@@ -0,0 +1,273 @@
+package timeline
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// The timeline lists one item per logical post across its lifecycle: every
+// post, with the state of its latest publishing job, and every job that has
+// not produced a post yet. A job that completed is shown as the post it
+// published.
+
+// Statuses of timeline items besides those of posts
+const (
+	StatusPublishing = "publishing"
+	StatusFailed     = "failed"
+)
+
+// Item kinds
+const (
+	KindPost = "post"
+	KindJob  = "job"
+)
+
+// Fields items can be sorted by
+const (
+	SortAt        = "at"
+	SortCreatedAt = "created_at"
+)
+
+// ErrInvalidCursor is returned for cursors not issued by Query
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Item is one logical post. ID is the post ID for posts and the job ID for
+// jobs that have not produced a post.
+type Item struct {
+	Kind        string        `json:"kind"`
+	ID          uint          `json:"id"`
+	JobID       *uint         `json:"job_id,omitempty"`
+	Title       string        `json:"title,omitempty"`
+	Content     string        `json:"content"`
+	ProviderID  uint          `json:"provider_id"`
+	Provider    string        `json:"provider"`
+	CampaignID  *uint         `json:"campaign_id,omitempty"`
+	Tags        database.Tags `json:"tags"`
+	Status      string        `json:"status"`
+	ScheduledAt *time.Time    `json:"scheduled_at,omitempty"`
+	PublishedAt *time.Time    `json:"published_at,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	// At places the item on the timeline: when it was published, else when
+	// it is scheduled, else when it was created
+	At time.Time `json:"at"`
+}
+
+// Filter selects and orders timeline items
+type Filter struct {
+	ProviderID uint
+	Status     string
+	CampaignID uint
+	// Tag matches items carrying the tag
+	Tag string
+	// From and To bound At; either may be zero
+	From time.Time
+	To   time.Time
+	// SortBy is SortAt or SortCreatedAt; Ascending reverses the default
+	// newest-first order
+	SortBy    string
+	Ascending bool
+	Limit     int
+	// Cursor continues from the page that returned it
+	Cursor string
+}
+
+// Page is a slice of the timeline. Total counts all items matching the
+// filter, regardless of the cursor.
+type Page struct {
+	Items      []Item `json:"items"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the sort key of the last item of a page
+type cursor struct {
+	Value time.Time `json:"v"`
+	Kind  string    `json:"k"`
+	ID    uint      `json:"i"`
+}
+
+// Query returns a page of the timeline of userID
+func Query(db *gorm.DB, userID string, filter Filter) (*Page, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = SortAt
+	}
+	if sortBy != SortAt && sortBy != SortCreatedAt {
+		return nil, fmt.Errorf("unknown sort field %q", sortBy)
+	}
+
+	query := db.Table("(? UNION ALL ?) AS timeline", postItems(db, userID), jobItems(db, userID))
+	if filter.ProviderID != 0 {
+		query = query.Where("timeline.provider_id = ?", filter.ProviderID)
+	}
+	if filter.Status != "" {
+		query = query.Where("timeline.status = ?", filter.Status)
+	}
+	if filter.CampaignID != 0 {
+		query = query.Where("timeline.campaign_id = ?", filter.CampaignID)
+	}
+	if pattern := database.TagPattern(filter.Tag); pattern != "" {
+		query = query.Where(database.TagCondition("timeline.tags"), pattern)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("timeline.at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("timeline.at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	direction, op := "DESC", "<"
+	if filter.Ascending {
+		direction, op = "ASC", ">"
+	}
+	if filter.Cursor != "" {
+		after, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		column := "timeline." + sortBy
+		query = query.Where(fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND (timeline.kind %[2]s ? OR (timeline.kind = ? AND timeline.id %[2]s ?)))", column, op),
+			after.Value, after.Value, after.Kind, after.Kind, after.ID)
+	}
+	query = query.Order(fmt.Sprintf("timeline.%[1]s %[2]s, timeline.kind %[2]s, timeline.id %[2]s", sortBy, direction))
+	if filter.Limit > 0 {
+		// One more than asked shows whether there is a next page
+		query = query.Limit(filter.Limit + 1)
+	}
+
+	var rows []row
+	if err := query.Select("timeline.*").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page{Items: make([]Item, 0, len(rows)), Total: total}
+	for _, r := range rows {
+		page.Items = append(page.Items, r.item())
+	}
+	if filter.Limit > 0 && len(page.Items) > filter.Limit {
+		page.Items = page.Items[:filter.Limit]
+		last := page.Items[filter.Limit-1]
+		next := cursor{Value: last.At, Kind: last.Kind, ID: last.ID}
+		if sortBy == SortCreatedAt {
+			next.Value = last.CreatedAt
+		}
+		page.NextCursor = next.encode()
+	}
+	return page, nil
+}
+
+// postItems selects posts with the state of their latest job
+func postItems(db *gorm.DB, userID string) *gorm.DB {
+	return db.Table("posts").
+		Select(`? AS kind, posts.id AS id, jobs.id AS job_id, posts.title AS title, posts.content AS content,
+			posts.provider_id AS provider_id, providers.name AS provider,
+			posts.campaign_id AS campaign_id, posts.tags AS tags,
+			CASE WHEN posts.status = ? AND jobs.status = ? THEN ?
+				WHEN posts.status = ? AND jobs.status = ? THEN ?
+				ELSE posts.status END AS status,
+			jobs.scheduled_at AS scheduled_at, posts.published_at AS published_at,
+			CASE WHEN jobs.status = ? THEN jobs.error_msg ELSE '' END AS error,
+			posts.created_at AS created_at,
+			COALESCE(posts.published_at, jobs.scheduled_at, posts.created_at) AS at`,
+			KindPost,
+			database.PostStatusScheduled, database.JobStatusFailed, StatusFailed,
+			database.PostStatusScheduled, database.JobStatusExecuting, StatusPublishing,
+			database.JobStatusFailed).
+		Joins("LEFT JOIN scheduled_jobs jobs ON jobs.id = (SELECT MAX(id) FROM scheduled_jobs WHERE scheduled_jobs.post_id = posts.id)").
+		Joins("LEFT JOIN providers ON providers.id = posts.provider_id").
+		Where("posts.user_id = ? AND posts.deleted_at IS NULL", userID)
+}
+
+// jobItems selects the publishing jobs that have not produced a post
+func jobItems(db *gorm.DB, userID string) *gorm.DB {
+	return db.Table("scheduled_jobs jobs").
+		Select(`? AS kind, jobs.id AS id, jobs.id AS job_id, '' AS title, jobs.payload_data AS content,
+			jobs.provider_id AS provider_id, providers.name AS provider,
+			jobs.campaign_id AS campaign_id, jobs.tags AS tags,
+			CASE jobs.status WHEN ? THEN ? WHEN ? THEN ? ELSE ? END AS status,
+			jobs.scheduled_at AS scheduled_at, NULL AS published_at,
+			CASE WHEN jobs.status = ? THEN jobs.error_msg ELSE '' END AS error,
+			jobs.created_at AS created_at, jobs.scheduled_at AS at`,
+			KindJob,
+			database.JobStatusFailed, StatusFailed, database.JobStatusExecuting, StatusPublishing, database.PostStatusScheduled,
+			database.JobStatusFailed).
+		Joins("LEFT JOIN providers ON providers.id = jobs.provider_id").
+		Where("jobs.user_id = ? AND jobs.post_id IS NULL AND jobs.job_type = ? AND jobs.status <> ?",
+			userID, "publish_post", database.JobStatusCompleted)
+}
+
+// row is an item as scanned. SQLite returns computed dates as text, so the
+// dates are parsed by timestamp.
+type row struct {
+	Kind        string
+	ID          uint
+	JobID       *uint
+	Title       *string
+	Content     string
+	ProviderID  uint
+	Provider    *string
+	CampaignID  *uint
+	Tags        database.Tags
+	Status      string
+	ScheduledAt timestamp
+	PublishedAt timestamp
+	Error       *string
+	CreatedAt   timestamp
+	At          timestamp
+}
+
+func (r row) item() Item {
+	return Item{
+		Kind:        r.Kind,
+		ID:          r.ID,
+		JobID:       r.JobID,
+		Title:       deref(r.Title),
+		Content:     r.Content,
+		ProviderID:  r.ProviderID,
+		Provider:    deref(r.Provider),
+		CampaignID:  r.CampaignID,
+		Tags:        r.Tags,
+		Status:      r.Status,
+		ScheduledAt: r.ScheduledAt.ptr(),
+		PublishedAt: r.PublishedAt.ptr(),
+		Error:       deref(r.Error),
+		CreatedAt:   r.CreatedAt.Time,
+		At:          r.At.Time,
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || (c.Kind != KindPost && c.Kind != KindJob) {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
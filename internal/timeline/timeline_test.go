@@ -0,0 +1,164 @@
+package timeline
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) (*gorm.DB, database.Provider) {
+	t.Helper()
+	manager := database.NewTestManager(t)
+	t.Cleanup(func() { manager.Close() })
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	provider := database.Provider{Name: "main", Type: "mastodon", Config: "{}", UserID: "alice", IsActive: true}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return db, provider
+}
+
+func TestQuery_OneItemPerLogicalPost(t *testing.T) {
+	db, provider := setup(t)
+	base := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+	published := at(1)
+
+	// A post published right away
+	db.Create(&database.Post{Content: "Now", UserID: "alice", ProviderID: provider.ID, Status: database.PostStatusPublished, PublishedAt: &published, CreatedAt: at(1)})
+	// A scheduled job that published a post
+	post := database.Post{Content: "Done", UserID: "alice", ProviderID: provider.ID, Status: database.PostStatusPublished, PublishedAt: ptr(at(3)), CreatedAt: at(3)}
+	db.Create(&post)
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "Done", UserID: "alice", ProviderID: provider.ID, PostID: &post.ID, ScheduledAt: at(3), Status: database.JobStatusCompleted, CreatedAt: at(0)})
+	// An approved draft whose job failed
+	draft := database.Post{Content: "Reviewed", UserID: "alice", ProviderID: provider.ID, Status: database.PostStatusScheduled, CreatedAt: at(0)}
+	db.Create(&draft)
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "Reviewed", UserID: "alice", ProviderID: provider.ID, PostID: &draft.ID, ScheduledAt: at(5), Status: database.JobStatusFailed, ErrorMsg: "boom", CreatedAt: at(0)})
+	// A job still waiting
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "Later", UserID: "alice", ProviderID: provider.ID, ScheduledAt: at(30), Status: database.JobStatusPending, CreatedAt: at(2)})
+	// Someone else's post
+	db.Create(&database.Post{Content: "Not mine", UserID: "bob", ProviderID: provider.ID, Status: database.PostStatusPublished, CreatedAt: at(4)})
+
+	page, err := Query(db, "alice", Filter{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if page.Total != 4 || len(page.Items) != 4 {
+		t.Fatalf("Expected 4 items, got %d of %d: %+v", len(page.Items), page.Total, page.Items)
+	}
+
+	want := []struct{ kind, content, status string }{
+		{KindJob, "Later", database.PostStatusScheduled},
+		{KindPost, "Reviewed", StatusFailed},
+		{KindPost, "Done", database.PostStatusPublished},
+		{KindPost, "Now", database.PostStatusPublished},
+	}
+	for i, w := range want {
+		item := page.Items[i]
+		if item.Kind != w.kind || item.Content != w.content || item.Status != w.status {
+			t.Errorf("Item %d: expected %s %q %s, got %s %q %s", i, w.kind, w.content, w.status, item.Kind, item.Content, item.Status)
+		}
+	}
+	if failed := page.Items[1]; failed.Error != "boom" || failed.ScheduledAt == nil || !failed.At.Equal(at(5)) || failed.Provider != "main" {
+		t.Errorf("Unexpected failed item: %+v", failed)
+	}
+
+	page, _ = Query(db, "alice", Filter{Status: StatusFailed})
+	if page.Total != 1 || page.Items[0].ID != draft.ID {
+		t.Errorf("Expected the failed draft, got %+v", page.Items)
+	}
+	page, _ = Query(db, "alice", Filter{From: at(2), To: at(6), SortBy: SortAt, Ascending: true})
+	if page.Total != 2 || page.Items[0].Content != "Done" || page.Items[1].Content != "Reviewed" {
+		t.Errorf("Expected the items between 2h and 6h oldest first, got %+v", page.Items)
+	}
+	page, _ = Query(db, "alice", Filter{SortBy: SortCreatedAt})
+	if page.Items[0].Content != "Done" {
+		t.Errorf("Expected the most recently created item first, got %+v", page.Items[0])
+	}
+}
+
+func TestQuery_CursorPagination(t *testing.T) {
+	db, provider := setup(t)
+	// Items sharing a time must not be skipped or repeated across pages
+	same := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		db.Create(&database.Post{Content: "Post", UserID: "alice", ProviderID: provider.ID, Status: database.PostStatusPublished, PublishedAt: &same})
+	}
+	for i := 0; i < 2; i++ {
+		db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "Job", UserID: "alice", ProviderID: provider.ID, ScheduledAt: same, Status: database.JobStatusPending})
+	}
+
+	seen := make(map[string]bool)
+	filter := Filter{Limit: 3}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Pagination did not end")
+		}
+		page, err := Query(db, "alice", filter)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if page.Total != 7 {
+			t.Errorf("Expected a stable total of 7, got %d", page.Total)
+		}
+		for _, item := range page.Items {
+			key := fmt.Sprint(item.Kind, item.ID)
+			if seen[key] {
+				t.Errorf("Item %s %d repeated", item.Kind, item.ID)
+			}
+			seen[key] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	if len(seen) != 7 {
+		t.Errorf("Expected 7 distinct items, got %d", len(seen))
+	}
+
+	if _, err := Query(db, "alice", Filter{Cursor: "garbage"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestQuery_FiltersByCampaignAndTag(t *testing.T) {
+	db, provider := setup(t)
+	campaign := database.Campaign{Name: "Launch", Color: "#ff0000", UserID: "alice"}
+	db.Create(&campaign)
+
+	db.Create(&database.Post{Content: "Teaser", UserID: "alice", ProviderID: provider.ID, Status: database.PostStatusPublished, CampaignID: &campaign.ID, Tags: database.Tags{"launch", "video"}})
+	db.Create(&database.Post{Content: "Other", UserID: "alice", ProviderID: provider.ID, Status: database.PostStatusPublished, Tags: database.Tags{"videos"}})
+	db.Create(&database.ScheduledJob{JobType: "publish_post", PayloadData: "Countdown", UserID: "alice", ProviderID: provider.ID, ScheduledAt: time.Now().Add(time.Hour), Status: database.JobStatusPending, CampaignID: &campaign.ID, Tags: database.Tags{"launch"}})
+
+	page, err := Query(db, "alice", Filter{CampaignID: campaign.ID})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if page.Total != 2 || page.Items[0].Content != "Countdown" || page.Items[1].Content != "Teaser" {
+		t.Fatalf("Unexpected campaign items: %+v", page.Items)
+	}
+	if page.Items[1].CampaignID == nil || *page.Items[1].CampaignID != campaign.ID || page.Items[1].Tags.String() != "launch,video" {
+		t.Errorf("Expected the campaign and tags on the item, got %+v", page.Items[1])
+	}
+
+	// Tags match whole words, case insensitively
+	page, err = Query(db, "alice", Filter{Tag: "#Video"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if page.Total != 1 || page.Items[0].Content != "Teaser" {
+		t.Errorf("Expected only the post tagged video, got %+v", page.Items)
+	}
+}
@@ -0,0 +1,65 @@
+package timeline
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// sqliteLayouts are the formats the SQLite driver writes dates in
+var sqliteLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// timestamp scans a nullable date returned as a time or as SQLite text
+type timestamp struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t *timestamp) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*t = timestamp{}
+		return nil
+	case time.Time:
+		*t = timestamp{Time: v, Valid: true}
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+	return fmt.Errorf("cannot scan %T into a timestamp", value)
+}
+
+func (t *timestamp) parse(s string) error {
+	for _, layout := range sqliteLayouts {
+		if parsed, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			*t = timestamp{Time: parsed, Valid: true}
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as a timestamp", s)
+}
+
+func (t timestamp) Value() (driver.Value, error) {
+	if !t.Valid {
+		return nil, nil
+	}
+	return t.Time, nil
+}
+
+func (t timestamp) ptr() *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	value := t.Time
+	return &value
+}
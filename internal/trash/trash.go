@@ -0,0 +1,247 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// The trash holds soft-deleted posts and providers. They can be restored
+// until they are purged, by hand or once they have been in the trash longer
+// than the retention period.
+
+// Kinds of items the trash holds
+const (
+	KindPost     = "post"
+	KindProvider = "provider"
+)
+
+var (
+	// ErrUnknownKind is returned for kinds other than KindPost and KindProvider
+	ErrUnknownKind = errors.New("unknown kind of item")
+	// ErrNotFound is returned when the item is missing, or not where the
+	// action expects it: live for Delete, in the trash for Restore and Purge
+	ErrNotFound = errors.New("item not found")
+	// ErrProviderInUse is returned when deleting a provider with pending jobs
+	ErrProviderInUse = errors.New("provider has pending jobs")
+)
+
+// Item is a post or provider in the trash
+type Item struct {
+	Kind string `json:"kind"`
+	ID   uint   `json:"id"`
+	// Name is the title or content of a post and the name of a provider
+	Name      string    `json:"name"`
+	Detail    string    `json:"detail,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgeAt is when the item is purged, if the trash has a retention period
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+// List returns the items in the trash of db, most recently deleted first.
+// retention sets PurgeAt; zero leaves it empty.
+func List(db *gorm.DB, retention time.Duration) ([]Item, error) {
+	var posts []database.Post
+	if err := db.Unscoped().Preload("Provider", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Where("deleted_at IS NOT NULL").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	var providers []database.Provider
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(posts)+len(providers))
+	for _, post := range posts {
+		name := post.Title
+		if name == "" {
+			name = post.Content
+		}
+		items = append(items, Item{Kind: KindPost, ID: post.ID, Name: name, Detail: post.Provider.Name, DeletedAt: post.DeletedAt.Time})
+	}
+	for _, provider := range providers {
+		items = append(items, Item{Kind: KindProvider, ID: provider.ID, Name: provider.Name, Detail: provider.Type, DeletedAt: provider.DeletedAt.Time})
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	if retention > 0 {
+		for i := range items {
+			purgeAt := items[i].DeletedAt.Add(retention)
+			items[i].PurgeAt = &purgeAt
+		}
+	}
+	return items, nil
+}
+
+// Delete moves a post or provider into the trash. Jobs of a deleted post fail
+// if they come due before it is restored; providers with pending jobs cannot
+// be deleted.
+func Delete(ctx context.Context, db *gorm.DB, key, kind string, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		switch kind {
+		case KindPost:
+			var post database.Post
+			if err := find(tx, &post, id, false); err != nil {
+				return err
+			}
+			if err := tx.Delete(&post).Error; err != nil {
+				return err
+			}
+			return audit.Record(ctx, tx, audit.Entry{
+				UserID: key, Action: audit.ActionPostDeleted, TargetType: audit.TargetPost, TargetID: id, Before: post,
+			})
+
+		case KindProvider:
+			var provider database.Provider
+			if err := find(tx, &provider, id, false); err != nil {
+				return err
+			}
+			var pending int64
+			if err := tx.Model(&database.ScheduledJob{}).
+				Where("provider_id = ? AND status = ?", id, database.JobStatusPending).
+				Count(&pending).Error; err != nil {
+				return err
+			}
+			if pending > 0 {
+				return ErrProviderInUse
+			}
+			if err := tx.Delete(&provider).Error; err != nil {
+				return err
+			}
+			return audit.Record(ctx, tx, audit.Entry{
+				UserID: key, Action: audit.ActionProviderDeleted, TargetType: audit.TargetProvider, TargetID: id, Before: provider,
+			})
+		}
+		return ErrUnknownKind
+	})
+}
+
+// Restore takes a post or provider out of the trash
+func Restore(ctx context.Context, db *gorm.DB, key, kind string, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var model interface{}
+		var action, target string
+		switch kind {
+		case KindPost:
+			model, action, target = &database.Post{}, audit.ActionPostRestored, audit.TargetPost
+		case KindProvider:
+			model, action, target = &database.Provider{}, audit.ActionProviderRestored, audit.TargetProvider
+		default:
+			return ErrUnknownKind
+		}
+
+		if err := find(tx, model, id, true); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(model).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{UserID: key, Action: action, TargetType: target, TargetID: id})
+	})
+}
+
+// Purge permanently deletes a post or provider from the trash. A post takes
+// its comments and jobs with it; posts and jobs of a provider keep their
+// reference to it.
+func Purge(ctx context.Context, db *gorm.DB, key, kind string, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return purge(ctx, tx, key, kind, id)
+	})
+}
+
+// PurgeExpired permanently deletes the items deleted before cutoff and
+// returns how many there were
+func PurgeExpired(ctx context.Context, db *gorm.DB, key string, cutoff time.Time) (int, error) {
+	count := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for kind, model := range map[string]interface{}{KindPost: &database.Post{}, KindProvider: &database.Provider{}} {
+			var ids []uint
+			if err := tx.Unscoped().Model(model).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := purge(ctx, tx, key, kind, id); err != nil {
+					return err
+				}
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func purge(ctx context.Context, tx *gorm.DB, key, kind string, id uint) error {
+	switch kind {
+	case KindPost:
+		var post database.Post
+		if err := find(tx, &post, id, true); err != nil {
+			return err
+		}
+		if err := tx.Where("post_id = ?", id).Delete(&database.PostComment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id = ?", id).Delete(&database.ScheduledJob{}).Error; err != nil {
+			return err
+		}
+		// Published short links keep redirecting without their post
+		if err := tx.Model(&database.ShortLink{}).Where("post_id = ?", id).Update("post_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&post).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			UserID: key, Action: audit.ActionPostPurged, TargetType: audit.TargetPost, TargetID: id, Before: post,
+		})
+
+	case KindProvider:
+		var provider database.Provider
+		if err := find(tx, &provider, id, true); err != nil {
+			return err
+		}
+		if err := tx.Where("provider_id = ?", id).Delete(&database.LinkRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("provider_id = ?", id).Delete(&database.BlackoutWindow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("provider_id = ?", id).Delete(&database.PostingLimit{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&provider).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			UserID: key, Action: audit.ActionProviderPurged, TargetType: audit.TargetProvider, TargetID: id, Before: provider,
+		})
+	}
+	return ErrUnknownKind
+}
+
+// find loads the item id into model, from the trash when trashed is set and
+// from the live items otherwise
+func find(tx *gorm.DB, model interface{}, id uint, trashed bool) error {
+	query := tx.Where("id = ?", id)
+	if trashed {
+		query = tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id)
+	}
+	result := query.Limit(1).Find(model)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/audit"
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) (*gorm.DB, database.Provider) {
+	t.Helper()
+	manager := database.NewTestManager(t)
+	t.Cleanup(func() { manager.Close() })
+	db, err := manager.GetDB("alice")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	provider := database.Provider{Name: "main", Type: "mastodon", Config: "{}", UserID: "alice", IsActive: true}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return db, provider
+}
+
+func TestDeleteRestorePurge_Post(t *testing.T) {
+	db, provider := setup(t)
+	ctx := context.Background()
+	post := database.Post{Content: "Hello", UserID: "alice", ProviderID: provider.ID}
+	db.Create(&post)
+	db.Create(&database.PostComment{PostID: post.ID, UserID: "alice", Body: "Nice"})
+	db.Create(&database.ScheduledJob{JobType: "publish_post", UserID: "alice", ProviderID: provider.ID, PostID: &post.ID,
+		ScheduledAt: time.Now(), Status: database.JobStatusCompleted})
+
+	if err := Delete(ctx, db, "alice", KindPost, post.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := Delete(ctx, db, "alice", KindPost, post.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected deleting twice to fail with ErrNotFound, got %v", err)
+	}
+	var live int64
+	db.Model(&database.Post{}).Count(&live)
+	if live != 0 {
+		t.Errorf("Expected the post to be hidden, %d live posts", live)
+	}
+
+	items, err := List(db, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Kind != KindPost || items[0].Name != "Hello" || items[0].Detail != "main" {
+		t.Fatalf("Unexpected trash: %+v", items)
+	}
+	if items[0].PurgeAt == nil || !items[0].PurgeAt.Equal(items[0].DeletedAt.Add(24*time.Hour)) {
+		t.Errorf("Expected purge a day after deletion, got %v", items[0].PurgeAt)
+	}
+
+	if err := Restore(ctx, db, "alice", KindPost, post.ID); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	db.Model(&database.Post{}).Count(&live)
+	if live != 1 {
+		t.Errorf("Expected the post to be restored, %d live posts", live)
+	}
+	if err := Purge(ctx, db, "alice", KindPost, post.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected purging a live post to fail with ErrNotFound, got %v", err)
+	}
+
+	Delete(ctx, db, "alice", KindPost, post.ID)
+	if err := Purge(ctx, db, "alice", KindPost, post.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	var remaining int64
+	db.Unscoped().Model(&database.Post{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected the post to be gone, %d remain", remaining)
+	}
+	db.Model(&database.PostComment{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected the comments to be gone, %d remain", remaining)
+	}
+	db.Model(&database.ScheduledJob{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected the jobs to be gone, %d remain", remaining)
+	}
+
+	var actions []string
+	db.Model(&database.AuditLog{}).Order("id").Pluck("action", &actions)
+	want := []string{audit.ActionPostDeleted, audit.ActionPostRestored, audit.ActionPostDeleted, audit.ActionPostPurged}
+	if len(actions) != len(want) {
+		t.Fatalf("Expected audit actions %v, got %v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("Expected audit actions %v, got %v", want, actions)
+			break
+		}
+	}
+}
+
+func TestDelete_ProviderWithPendingJobs(t *testing.T) {
+	db, provider := setup(t)
+	ctx := context.Background()
+	job := database.ScheduledJob{JobType: "publish_post", UserID: "alice", ProviderID: provider.ID,
+		ScheduledAt: time.Now().Add(time.Hour), Status: database.JobStatusPending}
+	db.Create(&job)
+
+	if err := Delete(ctx, db, "alice", KindProvider, provider.ID); !errors.Is(err, ErrProviderInUse) {
+		t.Fatalf("Expected ErrProviderInUse, got %v", err)
+	}
+
+	db.Model(&job).Update("status", database.JobStatusFailed)
+	if err := Delete(ctx, db, "alice", KindProvider, provider.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := Delete(ctx, db, "alice", "comment", 1); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("Expected ErrUnknownKind, got %v", err)
+	}
+
+	items, _ := List(db, 0)
+	if len(items) != 1 || items[0].Kind != KindProvider || items[0].PurgeAt != nil {
+		t.Errorf("Unexpected trash: %+v", items)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	db, provider := setup(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	old := database.Post{Content: "Old", UserID: "alice", ProviderID: provider.ID}
+	recent := database.Post{Content: "Recent", UserID: "alice", ProviderID: provider.ID}
+	live := database.Post{Content: "Live", UserID: "alice", ProviderID: provider.ID}
+	db.Create(&old)
+	db.Create(&recent)
+	db.Create(&live)
+	db.Unscoped().Model(&old).Update("deleted_at", now.Add(-48*time.Hour))
+	db.Unscoped().Model(&recent).Update("deleted_at", now.Add(-time.Hour))
+	db.Unscoped().Model(&provider).Update("deleted_at", now.Add(-72*time.Hour))
+
+	count, err := PurgeExpired(ctx, db, "alice", now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeExpired failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 items purged, got %d", count)
+	}
+
+	var contents []string
+	db.Unscoped().Model(&database.Post{}).Order("id").Pluck("content", &contents)
+	if len(contents) != 2 || contents[0] != "Recent" || contents[1] != "Live" {
+		t.Errorf("Expected the recent and live posts to remain, got %v", contents)
+	}
+	var providers int64
+	db.Unscoped().Model(&database.Provider{}).Count(&providers)
+	if providers != 0 {
+		t.Errorf("Expected the provider to be purged, %d remain", providers)
+	}
+}
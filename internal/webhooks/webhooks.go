@@ -0,0 +1,258 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"gorm.io/gorm"
+)
+
+// Events that endpoints can subscribe to
+const (
+	EventPostPublished        = "post.published"
+	EventPostFailed           = "post.failed"
+	EventJobScheduled         = "job.scheduled"
+	EventProviderDisconnected = "provider.disconnected"
+	EventTokenRefreshFailed   = "token.refresh_failed"
+)
+
+// Events lists every supported event
+var Events = []string{
+	EventPostPublished,
+	EventPostFailed,
+	EventJobScheduled,
+	EventProviderDisconnected,
+	EventTokenRefreshFailed,
+}
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Socgo-Event"
+	HeaderDelivery  = "X-Socgo-Delivery"
+	HeaderTimestamp = "X-Socgo-Timestamp"
+	HeaderSignature = "X-Socgo-Signature"
+)
+
+// MaxAttempts is the number of delivery attempts before a delivery fails
+const MaxAttempts = 6
+
+// retryBackoff is the delay before each retry, indexed by attempts made so far
+var retryBackoff = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	6 * time.Hour,
+}
+
+// Payload is the JSON body posted to endpoints
+type Payload struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// IsValidEvent reports whether event is a supported event name
+func IsValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateEndpoint registers an endpoint for the given events and generates its
+// signing secret
+func CreateEndpoint(db *gorm.DB, userID, endpointURL string, events []string) (*database.WebhookEndpoint, error) {
+	parsed, err := url.Parse(strings.TrimSpace(endpointURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("at least one event is required")
+	}
+	for _, event := range events {
+		if !IsValidEvent(event) {
+			return nil, fmt.Errorf("unsupported event: %s", event)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	endpoint := &database.WebhookEndpoint{
+		UserID:   userID,
+		URL:      parsed.String(),
+		Secret:   hex.EncodeToString(secret),
+		Events:   strings.Join(events, ","),
+		IsActive: true,
+	}
+	if err := db.Create(endpoint).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// Emit queues a delivery of event to every active endpoint of userID that is
+// subscribed to it. Deliveries are sent later by the scheduler.
+func Emit(db *gorm.DB, userID, event string, data interface{}) error {
+	var endpoints []database.WebhookEndpoint
+	if err := db.Where("user_id = ? AND is_active = ?", userID, true).Find(&endpoints).Error; err != nil {
+		return fmt.Errorf("failed to load webhook endpoints: %w", err)
+	}
+
+	var body []byte
+	now := time.Now()
+	for _, endpoint := range endpoints {
+		if !subscribed(endpoint, event) {
+			continue
+		}
+
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(Payload{Event: event, CreatedAt: now.UTC(), Data: data}); err != nil {
+				return fmt.Errorf("failed to encode webhook payload: %w", err)
+			}
+		}
+
+		delivery := database.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        database.DeliveryStatusPending,
+			NextAttemptAt: now,
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" using secret.
+// Receivers recompute it to verify the X-Socgo-Signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliverer sends queued deliveries
+type Deliverer struct {
+	client *http.Client
+}
+
+// NewDeliverer creates a deliverer; a nil client uses a client with a 10s timeout
+func NewDeliverer(client *http.Client) *Deliverer {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Deliverer{
+		client: client,
+	}
+}
+
+// ProcessDue attempts every pending delivery in db whose next attempt is due
+func (d *Deliverer) ProcessDue(ctx context.Context, db *gorm.DB) error {
+	var deliveries []database.WebhookDelivery
+	if err := db.Where("status = ? AND next_attempt_at <= ?", database.DeliveryStatusPending, time.Now()).
+		Order("id").Find(&deliveries).Error; err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		var endpoint database.WebhookEndpoint
+		if err := db.First(&endpoint, delivery.EndpointID).Error; err != nil || !endpoint.IsActive {
+			delivery.Status = database.DeliveryStatusFailed
+			delivery.LastError = "endpoint removed or disabled"
+		} else {
+			d.attempt(ctx, &endpoint, delivery)
+		}
+
+		if err := db.Save(delivery).Error; err != nil {
+			log.Printf("Error saving webhook delivery %d: %v", delivery.ID, err)
+		}
+	}
+	return nil
+}
+
+// attempt sends a delivery once and updates its status and retry time
+func (d *Deliverer) attempt(ctx context.Context, endpoint *database.WebhookEndpoint, delivery *database.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.UpdatedAt = time.Now()
+
+	code, err := d.send(ctx, endpoint, delivery)
+	delivery.ResponseCode = code
+	if err == nil {
+		now := time.Now()
+		delivery.Status = database.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= MaxAttempts {
+		delivery.Status = database.DeliveryStatusFailed
+		log.Printf("Webhook delivery %d to %s failed permanently: %v", delivery.ID, endpoint.URL, err)
+		return
+	}
+	delivery.NextAttemptAt = time.Now().Add(retryBackoff[delivery.Attempts-1])
+}
+
+func (d *Deliverer) send(ctx context.Context, endpoint *database.WebhookEndpoint, delivery *database.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func subscribed(endpoint database.WebhookEndpoint, event string) bool {
+	for _, e := range strings.Split(endpoint.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestEmitAndDeliver(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+
+	var received int32
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderEvent) != EventPostPublished {
+			t.Errorf("Unexpected event header: %s", r.Header.Get(HeaderEvent))
+		}
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		endpoint := database.WebhookEndpoint{}
+		if err := db.First(&endpoint).Error; err != nil {
+			t.Errorf("Failed to load endpoint: %v", err)
+		}
+		if got := Sign(endpoint.Secret, r.Header.Get(HeaderTimestamp), body); got != r.Header.Get(HeaderSignature) {
+			t.Errorf("Signature mismatch: %s != %s", got, r.Header.Get(HeaderSignature))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if _, err := CreateEndpoint(db, "default_user", "ftp://example.com", []string{EventPostPublished}); err == nil {
+		t.Error("Expected non-http URL to be rejected")
+	}
+	if _, err := CreateEndpoint(db, "default_user", server.URL, []string{"post.exploded"}); err == nil {
+		t.Error("Expected unknown event to be rejected")
+	}
+	endpoint, err := CreateEndpoint(db, "default_user", server.URL, []string{EventPostPublished})
+	if err != nil {
+		t.Fatalf("Failed to create endpoint: %v", err)
+	}
+
+	// Only subscribed events are queued
+	if err := Emit(db, "default_user", EventPostFailed, map[string]string{"id": "1"}); err != nil {
+		t.Fatalf("Failed to emit event: %v", err)
+	}
+	if err := Emit(db, "default_user", EventPostPublished, map[string]string{"id": "1"}); err != nil {
+		t.Fatalf("Failed to emit event: %v", err)
+	}
+
+	var deliveries []database.WebhookDelivery
+	db.Where("endpoint_id = ?", endpoint.ID).Find(&deliveries)
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 queued delivery, got %d", len(deliveries))
+	}
+
+	deliverer := NewDeliverer(server.Client())
+
+	// A failing endpoint is retried later
+	fail.Store(true)
+	if err := deliverer.ProcessDue(context.Background(), db); err != nil {
+		t.Fatalf("Failed to process deliveries: %v", err)
+	}
+	var delivery database.WebhookDelivery
+	db.First(&delivery, deliveries[0].ID)
+	if delivery.Status != database.DeliveryStatusPending || delivery.Attempts != 1 || delivery.ResponseCode != http.StatusBadGateway {
+		t.Fatalf("Expected pending retry, got %+v", delivery)
+	}
+	if !delivery.NextAttemptAt.After(time.Now()) {
+		t.Errorf("Expected next attempt in the future, got %v", delivery.NextAttemptAt)
+	}
+
+	// Once due again the delivery succeeds
+	fail.Store(false)
+	db.Model(&delivery).Update("next_attempt_at", time.Now().Add(-time.Second))
+	if err := deliverer.ProcessDue(context.Background(), db); err != nil {
+		t.Fatalf("Failed to process deliveries: %v", err)
+	}
+	db.First(&delivery, deliveries[0].ID)
+	if delivery.Status != database.DeliveryStatusDelivered || delivery.Attempts != 2 || delivery.DeliveredAt == nil {
+		t.Errorf("Expected delivered, got %+v", delivery)
+	}
+	if got := atomic.LoadInt32(&received); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+}
+
+func TestDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	db, err := dbManager.GetDB("default_user")
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, err := CreateEndpoint(db, "default_user", server.URL, []string{EventPostFailed}); err != nil {
+		t.Fatalf("Failed to create endpoint: %v", err)
+	}
+	if err := Emit(db, "default_user", EventPostFailed, nil); err != nil {
+		t.Fatalf("Failed to emit event: %v", err)
+	}
+
+	deliverer := NewDeliverer(server.Client())
+	for i := 0; i < MaxAttempts; i++ {
+		db.Model(&database.WebhookDelivery{}).Where("1 = 1").Update("next_attempt_at", time.Now().Add(-time.Second))
+		if err := deliverer.ProcessDue(context.Background(), db); err != nil {
+			t.Fatalf("Failed to process deliveries: %v", err)
+		}
+	}
+
+	var delivery database.WebhookDelivery
+	db.First(&delivery)
+	if delivery.Status != database.DeliveryStatusFailed || delivery.Attempts != MaxAttempts {
+		t.Errorf("Expected failed delivery after %d attempts, got %+v", MaxAttempts, delivery)
+	}
+}
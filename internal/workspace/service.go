@@ -0,0 +1,184 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tkowalski/socgo/internal/database"
+	"github.com/tkowalski/socgo/internal/tenant"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotMember   = errors.New("user is not a member of the workspace")
+	ErrInvalidRole = errors.New("invalid workspace role")
+	ErrLastOwner   = errors.New("workspace must keep at least one owner")
+)
+
+// Service manages workspaces and their members in the registry database
+type Service struct {
+	dbManager *database.Manager
+}
+
+// NewService creates a new workspace service
+func NewService(dbManager *database.Manager) *Service {
+	return &Service{
+		dbManager: dbManager,
+	}
+}
+
+// Create creates a workspace owned by ownerID
+func (s *Service) Create(ownerID, name string) (*database.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("workspace name is required")
+	}
+
+	db, err := s.dbManager.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &database.Workspace{Name: name, OwnerID: ownerID}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ws).Error; err != nil {
+			return err
+		}
+		return tx.Create(&database.WorkspaceMember{
+			WorkspaceID: ws.ID,
+			UserID:      ownerID,
+			Role:        database.WorkspaceRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	return ws, nil
+}
+
+// ListForUser returns the memberships of a user, including each workspace
+func (s *Service) ListForUser(userID string) ([]database.WorkspaceMember, error) {
+	db, err := s.dbManager.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var memberships []database.WorkspaceMember
+	err = db.Preload("Workspace").Where("user_id = ?", userID).Order("workspace_id").Find(&memberships).Error
+	return memberships, err
+}
+
+// Role returns the role of a user in a workspace
+func (s *Service) Role(workspaceID uint, userID string) (string, error) {
+	db, err := s.dbManager.GetRegistryDB()
+	if err != nil {
+		return "", err
+	}
+
+	var member database.WorkspaceMember
+	err = db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNotMember
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+// Members lists the members of a workspace
+func (s *Service) Members(workspaceID uint) ([]database.WorkspaceMember, error) {
+	db, err := s.dbManager.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var members []database.WorkspaceMember
+	err = db.Where("workspace_id = ?", workspaceID).Order("created_at").Find(&members).Error
+	return members, err
+}
+
+// SetMember adds a user to a workspace or changes their role
+func (s *Service) SetMember(workspaceID uint, userID, role string) (*database.WorkspaceMember, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if !tenant.IsValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	db, err := s.dbManager.GetRegistryDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var member database.WorkspaceMember
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			member = database.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Role: role}
+			return tx.Create(&member).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if member.Role == database.WorkspaceRoleOwner && role != database.WorkspaceRoleOwner {
+			if err := ensureAnotherOwner(tx, workspaceID, userID); err != nil {
+				return err
+			}
+		}
+
+		member.Role = role
+		return tx.Save(&member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// RemoveMember removes a user from a workspace
+func (s *Service) RemoveMember(workspaceID uint, userID string) error {
+	db, err := s.dbManager.GetRegistryDB()
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var member database.WorkspaceMember
+		err := tx.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotMember
+		}
+		if err != nil {
+			return err
+		}
+
+		if member.Role == database.WorkspaceRoleOwner {
+			if err := ensureAnotherOwner(tx, workspaceID, userID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&member).Error
+	})
+}
+
+func ensureAnotherOwner(tx *gorm.DB, workspaceID uint, userID string) error {
+	var owners int64
+	if err := tx.Model(&database.WorkspaceMember{}).
+		Where("workspace_id = ? AND role = ? AND user_id <> ?", workspaceID, database.WorkspaceRoleOwner, userID).
+		Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
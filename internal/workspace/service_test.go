@@ -0,0 +1,68 @@
+package workspace
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tkowalski/socgo/internal/database"
+)
+
+func TestService_MembersAndRoles(t *testing.T) {
+	dbManager := database.NewTestManager(t)
+	defer dbManager.Close()
+
+	service := NewService(dbManager)
+
+	ws, err := service.Create("alice", "Agency")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	role, err := service.Role(ws.ID, "alice")
+	if err != nil || role != database.WorkspaceRoleOwner {
+		t.Fatalf("Expected creator to be owner, got %q (%v)", role, err)
+	}
+
+	if _, err := service.Role(ws.ID, "bob"); !errors.Is(err, ErrNotMember) {
+		t.Errorf("Expected ErrNotMember, got %v", err)
+	}
+
+	if _, err := service.SetMember(ws.ID, "bob", "admin"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("Expected ErrInvalidRole, got %v", err)
+	}
+	if _, err := service.SetMember(ws.ID, "bob", database.WorkspaceRoleEditor); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	memberships, err := service.ListForUser("bob")
+	if err != nil {
+		t.Fatalf("Failed to list workspaces: %v", err)
+	}
+	if len(memberships) != 1 || memberships[0].Workspace.Name != "Agency" || memberships[0].Role != database.WorkspaceRoleEditor {
+		t.Errorf("Unexpected memberships: %+v", memberships)
+	}
+
+	// The only owner can neither be demoted nor removed
+	if _, err := service.SetMember(ws.ID, "alice", database.WorkspaceRoleViewer); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("Expected ErrLastOwner when demoting, got %v", err)
+	}
+	if err := service.RemoveMember(ws.ID, "alice"); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("Expected ErrLastOwner when removing, got %v", err)
+	}
+
+	// With a second owner the first can step down
+	if _, err := service.SetMember(ws.ID, "bob", database.WorkspaceRoleOwner); err != nil {
+		t.Fatalf("Failed to promote member: %v", err)
+	}
+	if err := service.RemoveMember(ws.ID, "alice"); err != nil {
+		t.Errorf("Expected owner removal to succeed, got %v", err)
+	}
+
+	members, err := service.Members(ws.ID)
+	if err != nil {
+		t.Fatalf("Failed to list members: %v", err)
+	}
+	if len(members) != 1 || members[0].UserID != "bob" {
+		t.Errorf("Unexpected members: %+v", members)
+	}
+}
@@ -0,0 +1,40 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.906
+package templates
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+func AccountContent() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Account data</h1><p class=\"mb-4\">Download everything SocGo stores for you as a zip archive, or merge an archive exported from another SocGo instance into this one.</p><div class=\"grid grid-cols-1 lg:grid-cols-2 gap-8\"><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Export</h2><p class=\"text-gray-600 mb-4\">The archive contains providers, posts with comments, scheduled jobs, API token metadata, webhook endpoints and media files.</p><form action=\"/account/export\" method=\"get\" class=\"space-y-3\"><label class=\"block text-sm\"><input type=\"checkbox\" name=\"secrets\" value=\"true\"> Include provider credentials, token hashes and webhook secrets</label> <button type=\"submit\" class=\"bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded\">Download archive</button></form></div><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Import</h2><p class=\"text-gray-600 mb-4\">Existing providers are matched by name; posts, jobs, tokens and webhooks already present are skipped.</p><form hx-post=\"/account/import\" hx-encoding=\"multipart/form-data\" hx-target=\"#import-result\" class=\"space-y-3\"><input type=\"file\" name=\"archive\" accept=\".zip,application/zip\" required class=\"block w-full text-sm\"> <label class=\"block text-sm\"><input type=\"checkbox\" name=\"skip_pending_jobs\" value=\"true\" checked> Skip jobs that have not run yet</label> <button type=\"submit\" class=\"bg-green-500 hover:bg-green-700 text-white font-bold py-2 px-4 rounded\">Import archive</button></form><div id=\"import-result\" class=\"mt-4\"></div></div></div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
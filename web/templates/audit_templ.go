@@ -0,0 +1,40 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.906
+package templates
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+func AuditContent() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Audit log</h1><p class=\"mb-4\">Who published, scheduled, edited or connected what, and whether it came from the UI, an API token or the scheduler.</p><div class=\"bg-white rounded-lg shadow-md p-6\"><form id=\"audit-filter\" hx-get=\"/audit/logs\" hx-target=\"#audit-rows\" hx-trigger=\"load, change, submit\" class=\"grid grid-cols-2 md:grid-cols-6 gap-2 mb-4\"><input type=\"text\" name=\"actor_id\" placeholder=\"Actor\" class=\"border rounded px-2 py-1\"> <select name=\"action\" class=\"border rounded px-2 py-1\"><option value=\"\">All actions</option> <option value=\"post.published\">post.published</option> <option value=\"post.created\">post.created</option> <option value=\"post.updated\">post.updated</option> <option value=\"post.submitted\">post.submitted</option> <option value=\"post.approved\">post.approved</option> <option value=\"post.rejected\">post.rejected</option> <option value=\"post.scheduled\">post.scheduled</option> <option value=\"job.scheduled\">job.scheduled</option> <option value=\"job.rescheduled\">job.rescheduled</option> <option value=\"job.completed\">job.completed</option> <option value=\"job.failed\">job.failed</option> <option value=\"provider.connected\">provider.connected</option> <option value=\"provider.disconnected\">provider.disconnected</option> <option value=\"token.created\">token.created</option></select> <select name=\"target_type\" class=\"border rounded px-2 py-1\"><option value=\"\">All targets</option> <option value=\"post\">Posts</option> <option value=\"job\">Jobs</option> <option value=\"provider\">Providers</option> <option value=\"token\">Tokens</option></select> <select name=\"source\" class=\"border rounded px-2 py-1\"><option value=\"\">All sources</option> <option value=\"ui\">UI</option> <option value=\"api_token\">API token</option> <option value=\"scheduler\">Scheduler</option></select> <input type=\"date\" name=\"from\" class=\"border rounded px-2 py-1\"> <input type=\"date\" name=\"to\" class=\"border rounded px-2 py-1\"></form><div class=\"mb-4 text-right\"><a id=\"audit-export\" href=\"/audit/export.csv\" class=\"bg-gray-500 hover:bg-gray-700 text-white font-bold py-2 px-4 rounded\">Export CSV</a></div><table class=\"w-full text-sm text-left\"><thead><tr class=\"border-b\"><th class=\"py-2 pr-4\">Time</th><th class=\"py-2 pr-4\">Actor</th><th class=\"py-2 pr-4\">Action</th><th class=\"py-2 pr-4\">Target</th><th class=\"py-2 pr-4\">Source</th><th class=\"py-2\">Details</th></tr></thead> <tbody id=\"audit-rows\"></tbody></table></div><script>\n      (function() {\n        var form = document.getElementById('audit-filter');\n        var link = document.getElementById('audit-export');\n        form.addEventListener('change', function() {\n          link.href = '/audit/export.csv?' + new URLSearchParams(new FormData(form)).toString();\n        });\n      })();\n    </script></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
@@ -0,0 +1,76 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.906
+package templates
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+func BlackoutsContent() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Blackouts</h1><p class=\"mb-4\">Blackout windows stop the scheduler from publishing, for every provider or just one. Jobs falling due inside a window are either deferred to its end or held and published as soon as it ends. The pause switch stops all publishing, including immediate posts, until it is resumed.</p><div class=\"mb-8\" hx-get=\"/blackouts/status\" hx-trigger=\"load, publishing-changed from:body, blackouts-changed from:body\"><p class=\"text-gray-500\">Loading publishing status...</p></div><div class=\"grid grid-cols-1 lg:grid-cols-2 gap-8\"><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">New blackout window</h2><form hx-post=\"/blackouts/windows\" hx-swap=\"none\" hx-on::after-request=\"if(event.detail.successful) this.reset()\" class=\"space-y-3\"><div class=\"grid grid-cols-2 gap-3\"><input type=\"text\" name=\"name\" placeholder=\"Name, e.g. Christmas\" required class=\"border rounded px-3 py-2\"> <select name=\"provider_id\" hx-get=\"/api/providers/options\" hx-trigger=\"load\" class=\"border rounded px-3 py-2\"><option value=\"\">All providers</option></select> <select name=\"action\" class=\"border rounded px-3 py-2\"><option value=\"defer\">Defer due posts to the end</option> <option value=\"hold\">Hold due posts until it ends</option></select> <input type=\"text\" name=\"timezone\" placeholder=\"Timezone, e.g. Europe/Warsaw\" class=\"border rounded px-3 py-2\"></div><fieldset class=\"border rounded p-3\"><legend class=\"text-sm font-medium text-gray-700 px-1\">One-off window</legend><div class=\"grid grid-cols-2 gap-3\"><input type=\"datetime-local\" name=\"starts_at\" class=\"border rounded px-3 py-2\"> <input type=\"datetime-local\" name=\"ends_at\" class=\"border rounded px-3 py-2\"></div></fieldset><fieldset class=\"border rounded p-3\"><legend class=\"text-sm font-medium text-gray-700 px-1\"><label class=\"flex items-center space-x-2\"><input type=\"checkbox\" name=\"recurring\"> <span>Recurring quiet hours instead</span></label></legend><div class=\"grid grid-cols-2 gap-3 mb-2\"><input type=\"time\" name=\"start_time\" class=\"border rounded px-3 py-2\"> <input type=\"time\" name=\"end_time\" class=\"border rounded px-3 py-2\"></div><div class=\"flex flex-wrap gap-3 text-sm\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		for _, day := range []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"} {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<label class=\"flex items-center space-x-1\"><input type=\"checkbox\" name=\"weekdays\" value=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var2 string
+			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(day)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/blackouts.templ`, Line: 46, Col: 68}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"> <span>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(day)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/blackouts.templ`, Line: 47, Col: 29}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</span></label>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</div><p class=\"text-xs text-gray-500 mt-2\">No days means every day. Hours may run past midnight, e.g. 22:00–07:00.</p></fieldset><button type=\"submit\" class=\"bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded\">Add window</button></form></div><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Your windows</h2><ul hx-get=\"/blackouts/windows/list\" hx-trigger=\"load, blackouts-changed from:body\"><li class=\"text-gray-500\">Loading windows...</li></ul></div></div><h2 class=\"text-2xl font-bold mt-10 mb-2\">Posting limits</h2><p class=\"mb-4\">A daily cap and a minimum gap between posts keep a provider from being flooded. Posts breaking a limit cannot be scheduled or moved there, the scheduler defers jobs that would break it and the calendar marks them as conflicts.</p><div class=\"grid grid-cols-1 lg:grid-cols-2 gap-8\"><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Set a limit</h2><form hx-post=\"/blackouts/limits\" hx-swap=\"none\" hx-on::after-request=\"if(event.detail.successful) this.reset()\" class=\"space-y-3\"><div class=\"grid grid-cols-2 gap-3\"><select name=\"provider_id\" required hx-get=\"/api/providers/options\" hx-trigger=\"load\" class=\"border rounded px-3 py-2\"><option value=\"\">Choose a provider</option></select> <input type=\"text\" name=\"timezone\" placeholder=\"Timezone of the day, e.g. Europe/Warsaw\" class=\"border rounded px-3 py-2\"> <input type=\"number\" name=\"daily_cap\" min=\"0\" placeholder=\"Posts per day\" class=\"border rounded px-3 py-2\"> <input type=\"number\" name=\"min_gap_minutes\" min=\"0\" placeholder=\"Minutes between posts\" class=\"border rounded px-3 py-2\"></div><p class=\"text-xs text-gray-500\">Leave a field empty for no limit. Setting a provider again replaces its limit.</p><button type=\"submit\" class=\"bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded\">Save limit</button></form></div><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Your limits</h2><ul hx-get=\"/blackouts/limits/list\" hx-trigger=\"load, limits-changed from:body\"><li class=\"text-gray-500\">Loading limits...</li></ul></div></div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
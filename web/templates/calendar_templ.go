@@ -29,7 +29,7 @@ func CalendarContent() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Calendar</h1><p class=\"mb-4\">View and manage your scheduled posts.</p><!-- Dodaj tu resztę zawartości calendar.tmpl --></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Calendar</h1><p class=\"mb-4\">View and manage your scheduled posts. Drag a pending post onto another day to reschedule it.</p><div class=\"grid grid-cols-1 lg:grid-cols-3 gap-8\"><div class=\"lg:col-span-2 bg-white rounded-lg shadow-md p-6\"><div class=\"mb-4 flex flex-wrap justify-between items-center gap-2\"><div class=\"flex space-x-2\"><button type=\"button\" data-calendar-nav=\"-1\" class=\"bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded\">← Previous</button> <button type=\"button\" data-calendar-nav=\"0\" class=\"bg-gray-200 hover:bg-gray-300 text-gray-800 font-bold py-2 px-4 rounded\">Today</button> <button type=\"button\" data-calendar-nav=\"1\" class=\"bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded\">Next →</button></div><h2 id=\"calendar-title\" class=\"text-xl font-semibold\"></h2><div class=\"flex space-x-1\"><button type=\"button\" data-calendar-view=\"month\" class=\"calendar-view-btn py-2 px-3 rounded border\">Month</button> <button type=\"button\" data-calendar-view=\"week\" class=\"calendar-view-btn py-2 px-3 rounded border\">Week</button> <button type=\"button\" data-calendar-view=\"agenda\" class=\"calendar-view-btn py-2 px-3 rounded border\">Agenda</button></div></div><form id=\"calendar-filter\" class=\"mb-4 flex flex-wrap gap-2\"><select name=\"campaign_id\" hx-get=\"/campaigns/options\" hx-trigger=\"load\" hx-target=\"this\" class=\"border rounded px-3 py-2\"><option value=\"\">Any campaign</option></select> <input type=\"text\" name=\"tag\" placeholder=\"Tag\" class=\"border rounded px-3 py-2\"></form><div id=\"calendar-grid\" hx-trigger=\"calendar-changed from:body\" hx-get=\"/posts/calendar\">Loading calendar...</div></div><div class=\"space-y-8\"><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Day details</h2><div id=\"calendar-day-detail\" class=\"text-gray-500\">Click a day to see its posts.</div></div><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-2\">Calendar apps</h2><p class=\"text-sm text-gray-600 mb-2\">Subscribe to your scheduled and published posts in Google Calendar, Outlook or Apple Calendar with a private feed URL.</p><button type=\"button\" hx-post=\"/api-tokens\" hx-vals='{\"scope\": \"calendar\"}' hx-target=\"#calendar-feed-url\" class=\"bg-gray-200 hover:bg-gray-300 py-1 px-3 rounded text-sm\">Create feed URL</button><div id=\"calendar-feed-url\" class=\"mt-2\"></div><h3 class=\"font-semibold mt-6 mb-2\">Other calendars</h3><p class=\"text-sm text-gray-600 mb-2\">Show holidays, launches or other events from an ICS file or URL next to your posts.</p><ul hx-get=\"/calendars/list\" hx-trigger=\"load, calendars-changed from:body\" class=\"mb-3\"><li class=\"text-gray-500 text-sm\">Loading calendars...</li></ul><form hx-post=\"/calendars\" hx-encoding=\"multipart/form-data\" hx-swap=\"none\" hx-on::after-request=\"if(event.detail.successful) this.reset()\" class=\"space-y-2 text-sm\"><input type=\"text\" name=\"name\" required placeholder=\"Public holidays\" class=\"w-full border rounded px-2 py-1\"> <input type=\"url\" name=\"url\" placeholder=\"https://example.com/holidays.ics\" class=\"w-full border rounded px-2 py-1\"> <input type=\"file\" name=\"file\" accept=\".ics,text/calendar\" class=\"w-full\"><div class=\"flex items-center space-x-2\"><input type=\"color\" name=\"color\" value=\"#6b7280\" class=\"h-8 w-12\"> <button type=\"submit\" class=\"bg-blue-500 hover:bg-blue-700 text-white py-1 px-3 rounded\">Import</button></div></form></div></div></div><script>\n      (function () {\n        const state = { view: 'month', date: new Date() };\n        const grid = document.getElementById('calendar-grid');\n        const title = document.getElementById('calendar-title');\n        const filter = document.getElementById('calendar-filter');\n\n        function isoDate(d) {\n          return d.getFullYear() + '-' + String(d.getMonth() + 1).padStart(2, '0') + '-' + String(d.getDate()).padStart(2, '0');\n        }\n\n        function filterQuery() {\n          const params = new URLSearchParams();\n          new FormData(filter).forEach(function (value, key) {\n            if (value) params.set(key, value);\n          });\n          const query = params.toString();\n          return query ? '&' + query : '';\n        }\n\n        function calendarURL() {\n          if (state.view === 'month') {\n            return '/posts/calendar?year=' + state.date.getFullYear() + '&month=' + (state.date.getMonth() + 1) + filterQuery();\n          }\n          return '/posts/calendar?view=' + state.view + '&date=' + isoDate(state.date) + filterQuery();\n        }\n\n        function load() {\n          const url = calendarURL();\n          grid.setAttribute('hx-get', url);\n          title.textContent = state.view === 'month'\n            ? state.date.toLocaleString('default', { month: 'long', year: 'numeric' })\n            : 'From ' + isoDate(state.date);\n          document.querySelectorAll('.calendar-view-btn').forEach(function (btn) {\n            btn.classList.toggle('bg-blue-100', btn.dataset.calendarView === state.view);\n          });\n          htmx.ajax('GET', url, { target: '#calendar-grid' });\n        }\n\n        document.querySelectorAll('[data-calendar-nav]').forEach(function (btn) {\n          btn.addEventListener('click', function () {\n            const step = parseInt(btn.dataset.calendarNav, 10);\n            if (step === 0) {\n              state.date = new Date();\n            } else if (state.view === 'month') {\n              state.date = new Date(state.date.getFullYear(), state.date.getMonth() + step, 1);\n            } else {\n              const days = state.view === 'week' ? 7 : 30;\n              state.date = new Date(state.date.getFullYear(), state.date.getMonth(), state.date.getDate() + step * days);\n            }\n            load();\n          });\n        });\n\n        document.querySelectorAll('[data-calendar-view]').forEach(function (btn) {\n          btn.addEventListener('click', function () {\n            state.view = btn.dataset.calendarView;\n            load();\n          });\n        });\n\n        filter.addEventListener('change', load);\n        filter.addEventListener('submit', function (e) {\n          e.preventDefault();\n          load();\n        });\n\n        // Drag and drop rescheduling of pending jobs\n        grid.addEventListener('dragstart', function (e) {\n          const job = e.target.closest('.calendar-job');\n          if (!job) return;\n          e.dataTransfer.setData('text/plain', job.dataset.jobId);\n          e.dataTransfer.effectAllowed = 'move';\n        });\n        grid.addEventListener('dragover', function (e) {\n          if (e.target.closest('.calendar-day')) e.preventDefault();\n        });\n        grid.addEventListener('drop', function (e) {\n          const day = e.target.closest('.calendar-day');\n          const jobID = e.dataTransfer.getData('text/plain');\n          if (!day || !jobID) return;\n          e.preventDefault();\n          htmx.ajax('POST', '/posts/jobs/' + jobID + '/reschedule', {\n            values: { date: day.dataset.date },\n            swap: 'none'\n          }).then(load);\n        });\n        document.body.addEventListener('htmx:responseError', function (e) {\n          if (e.detail.pathInfo && e.detail.pathInfo.requestPath.indexOf('/reschedule') !== -1) {\n            alert(e.detail.xhr.responseText);\n          }\n        });\n\n        document.addEventListener('DOMContentLoaded', load);\n      })();\n    </script></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -29,7 +29,7 @@ func DashboardContent() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Dashboard</h1><p class=\"mb-4\">Welcome to your dashboard!</p><!-- Dodaj tu resztę zawartości dashboard.tmpl --></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Dashboard</h1><p class=\"mb-4\">Welcome to your dashboard!</p><div class=\"mb-6\" hx-get=\"/blackouts/status\" hx-trigger=\"load, publishing-changed from:body\"></div><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Link clicks</h2><div hx-get=\"/links/stats\" hx-trigger=\"load\"><p class=\"text-gray-500\">Loading clicks...</p></div></div><!-- Dodaj tu resztę zawartości dashboard.tmpl --></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,105 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.906
+package templates
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+func LinksContent() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Links</h1><p class=\"mb-4\">Link rules tag the links of posts with UTM parameters when they are published, and can replace them with short links that count clicks. The most specific rule applies: provider and campaign, then campaign, then provider, then the default rule.</p><div class=\"grid grid-cols-1 lg:grid-cols-2 gap-8\"><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">New rule</h2><form hx-post=\"/links/rules\" hx-swap=\"none\" hx-on::after-request=\"if(event.detail.successful) this.reset()\" class=\"space-y-3\"><div class=\"grid grid-cols-2 gap-3\"><label class=\"text-sm font-medium text-gray-700\">Provider (empty for any)</label> <label class=\"text-sm font-medium text-gray-700\">Campaign (empty for any)</label> <select name=\"provider_id\" hx-get=\"/api/providers/options\" hx-trigger=\"load\" class=\"border rounded px-3 py-2\"><option value=\"\">Any provider</option></select> <select name=\"campaign_id\" hx-get=\"/campaigns/options\" hx-trigger=\"load\" class=\"border rounded px-3 py-2\"><option value=\"\">Any campaign</option></select> <input type=\"text\" name=\"utm_source\" placeholder=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 string
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs("utm_source, e.g. {{provider}}")
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/links.templ`, Line: 20, Col: 94}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" class=\"border rounded px-3 py-2\"> <input type=\"text\" name=\"utm_medium\" placeholder=\"utm_medium, e.g. social\" class=\"border rounded px-3 py-2\"> <input type=\"text\" name=\"utm_campaign\" placeholder=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 string
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs("utm_campaign, e.g. {{campaign}}")
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/links.templ`, Line: 22, Col: 98}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" class=\"border rounded px-3 py-2\"> <input type=\"text\" name=\"utm_content\" placeholder=\"utm_content\" class=\"border rounded px-3 py-2\"> <input type=\"text\" name=\"utm_term\" placeholder=\"utm_term\" class=\"border rounded px-3 py-2\"></div><label class=\"flex items-center space-x-2\"><input type=\"checkbox\" name=\"shorten\"> <span>Replace links with short links and count clicks</span></label> <button type=\"submit\" class=\"bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded\">Add rule</button></form><div class=\"mt-6 text-sm text-gray-600\"><h3 class=\"font-semibold mb-1\">Placeholders</h3><p>UTM values may use <code>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 string
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs("{{provider}}")
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/links.templ`, Line: 34, Col: 54}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</code>, <code>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var5 string
+		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs("{{campaign}}")
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/links.templ`, Line: 34, Col: 87}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</code> and <code>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var6 string
+		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs("{{date}}")
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/links.templ`, Line: 34, Col: 119}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</code>. Parameters a link already has are kept.</p></div></div><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Your rules</h2><ul hx-get=\"/links/rules/list\" hx-trigger=\"load, link-rules-changed from:body\"><li class=\"text-gray-500\">Loading rules...</li></ul></div></div><h2 class=\"text-2xl font-bold mt-10 mb-4\">Link in bio</h2><p class=\"mb-4\">A public page for the single profile link of Instagram or TikTok. It lists your pinned links, then the links of your latest published posts, and counts their clicks.</p><div class=\"grid grid-cols-1 lg:grid-cols-2 gap-8\"><div class=\"bg-white rounded-lg shadow-md p-6\"><h3 class=\"text-xl font-semibold mb-4\">Page</h3><div hx-get=\"/links/bio\" hx-trigger=\"load, bio-changed from:body\"><p class=\"text-gray-500\">Loading page...</p></div></div><div class=\"bg-white rounded-lg shadow-md p-6\"><h3 class=\"text-xl font-semibold mb-4\">Pinned links</h3><form hx-post=\"/links/bio/links\" hx-swap=\"none\" hx-on::after-request=\"if(event.detail.successful) this.reset()\" class=\"grid grid-cols-1 md:grid-cols-4 gap-3 mb-4\"><input type=\"text\" name=\"title\" placeholder=\"Title\" required class=\"border rounded px-3 py-2\"> <input type=\"url\" name=\"url\" placeholder=\"https://\" required class=\"border rounded px-3 py-2 md:col-span-2\"> <input type=\"number\" name=\"position\" placeholder=\"Position\" class=\"border rounded px-3 py-2\"> <button type=\"submit\" class=\"bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded md:col-span-4\">Pin link</button></form><ul hx-get=\"/links/bio/links/list\" hx-trigger=\"load, bio-links-changed from:body\"><li class=\"text-gray-500\">Loading pinned links...</li></ul></div></div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
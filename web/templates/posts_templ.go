@@ -29,7 +29,43 @@ func PostsContent() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div><h1 class=\"text-4xl font-bold mb-6\">Create Post</h1><p class=\"mb-4\">Create and schedule your posts here.</p><!-- Dodaj tu resztę zawartości posts.tmpl --></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"max-w-4xl mx-auto\"><h1 class=\"text-4xl font-bold mb-6\">Create Post</h1><p class=\"mb-4\">Create and schedule your posts here, or save a draft for review.</p><div class=\"bg-white rounded-lg shadow-md p-6 mb-8\"><form id=\"post-form\" method=\"POST\" action=\"/posts\" hx-post=\"/posts\" hx-target=\"#post-result\" class=\"space-y-4\"><div class=\"form-group\"><label for=\"provider_id\" class=\"block text-sm font-medium text-gray-700 mb-1\">Provider</label> <select id=\"provider_id\" name=\"provider_id\" hx-get=\"/api/providers/options\" hx-trigger=\"load\" class=\"w-full border rounded px-3 py-2\"><option value=\"\">Loading providers...</option></select></div><div class=\"form-group\"><label for=\"title\" class=\"block text-sm font-medium text-gray-700 mb-1\">Title (optional)</label> <input type=\"text\" id=\"title\" name=\"title\" class=\"w-full border rounded px-3 py-2\"></div><div class=\"form-group\"><label for=\"content\" class=\"block text-sm font-medium text-gray-700 mb-1\">Content</label> <textarea id=\"content\" name=\"content\" rows=\"5\" required class=\"w-full border rounded px-3 py-2\"></textarea><div class=\"mt-2 flex flex-wrap gap-2 items-center\" hx-get=\"/snippets/options\" hx-trigger=\"load\"><span class=\"text-sm text-gray-500\">Loading snippets...</span></div><div class=\"mt-2 flex flex-wrap gap-2 items-center text-sm text-gray-600\"><span>Insert placeholder:</span> ")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		for _, placeholder := range []string{"{{date}}", "{{time}}", "{{provider}}", "{{campaign}}", "{{tags}}"} {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<button type=\"button\" class=\"border rounded px-2 py-1\" data-body=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var2 string
+			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(placeholder)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/posts.templ`, Line: 28, Col: 92}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" onclick=\"insertSnippet(this.dataset.body)\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(placeholder)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/posts.templ`, Line: 28, Col: 151}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</button>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</div><script>\n            function insertSnippet(text) {\n              const content = document.getElementById('content');\n              const start = content.selectionStart, end = content.selectionEnd;\n              content.value = content.value.slice(0, start) + text + content.value.slice(end);\n              content.selectionStart = content.selectionEnd = start + text.length;\n              content.focus();\n            }\n          </script></div><div class=\"form-group grid grid-cols-1 md:grid-cols-2 gap-4\"><div><label for=\"campaign_id\" class=\"block text-sm font-medium text-gray-700 mb-1\">Campaign</label> <select id=\"campaign_id\" name=\"campaign_id\" hx-get=\"/campaigns/options\" hx-trigger=\"load\" class=\"w-full border rounded px-3 py-2\"><option value=\"\">No campaign</option></select></div><div><label for=\"tags\" class=\"block text-sm font-medium text-gray-700 mb-1\">Tags (comma separated)</label> <input type=\"text\" id=\"tags\" name=\"tags\" placeholder=\"launch, promo\" class=\"w-full border rounded px-3 py-2\"></div></div><div class=\"form-group flex items-center space-x-4\"><label><input type=\"radio\" name=\"schedule_type\" value=\"now\" checked> Publish now</label> <label><input type=\"radio\" name=\"schedule_type\" value=\"scheduled\"> Schedule</label> <input type=\"datetime-local\" name=\"schedule_at\" class=\"border rounded px-3 py-2\"></div><div class=\"form-group\"><label for=\"reviewer_id\" class=\"block text-sm font-medium text-gray-700 mb-1\">Reviewer (for drafts)</label> <input type=\"text\" id=\"reviewer_id\" name=\"reviewer_id\" class=\"w-full border rounded px-3 py-2\"></div><div class=\"flex space-x-2\"><button type=\"submit\" class=\"bg-blue-600 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded\">Publish</button> <button type=\"button\" hx-post=\"/posts/drafts\" hx-include=\"#post-form\" hx-swap=\"none\" class=\"js-only bg-gray-500 hover:bg-gray-700 text-white font-bold py-2 px-4 rounded\">Save as draft</button></div></form><div id=\"post-result\" class=\"mt-4\"></div></div><div class=\"bg-white rounded-lg shadow-md p-6 mb-8\"><h2 class=\"text-xl font-semibold mb-4\">Bulk import</h2><p class=\"text-sm text-gray-600 mb-3\">Schedule many posts from a CSV file with the columns <code>content</code>, <code>providers</code> (names separated by <code>|</code>), <code>schedule_at</code> (<code>YYYY-MM-DD HH:MM</code>), <code>timezone</code> and <code>tags</code>, or from a JSON array of objects with the same fields. Every row is checked first; if any row has an error nothing is scheduled.</p><form hx-post=\"/posts/import\" hx-encoding=\"multipart/form-data\" hx-target=\"#import-result\" class=\"flex flex-wrap items-center gap-4\"><input type=\"file\" name=\"file\" accept=\".csv,.json,text/csv,application/json\" required> <label class=\"text-sm\"><input type=\"checkbox\" name=\"dry_run\" value=\"true\"> Only validate</label> <button type=\"submit\" class=\"bg-blue-600 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded\">Import</button></form><div id=\"import-result\" class=\"mt-4\"></div></div><div class=\"bg-white rounded-lg shadow-md p-6\"><h2 class=\"text-xl font-semibold mb-4\">Drafts &amp; reviews</h2><div id=\"drafts-list\" hx-get=\"/posts/drafts\" hx-trigger=\"load, drafts-changed from:body\" hx-swap=\"innerHTML\">Loading drafts...</div></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}